@@ -25,15 +25,52 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 const (
-	CFAPIKind          Kind   = "CFAPI"
-	Version            Kind   = "v1alpha1"
-	GatewayTypeContour string = "contour"
-	GatewayTypeIstio   string = "istio"
+	CFAPIKind              Kind   = "CFAPI"
+	Version                Kind   = "v1alpha1"
+	GatewayTypeContour     string = "contour"
+	GatewayTypeIstio       string = "istio"
+	GatewayTypeIstioNative string = "istio-native"
+	GatewayTypeExternal    string = "external"
+
+	// CutoverGatewayName is the temporary gateway serving the CF API and
+	// apps while the gateway type is switched
+	CutoverGatewayName string = "korifi-cutover"
+
+	GatewayMigrationPhaseProvisioning string = "Provisioning"
+	GatewayMigrationPhaseCuttingOver  string = "CuttingOver"
+	GatewayMigrationPhaseSwitching    string = "Switching"
+	GatewayMigrationPhaseFinalizing   string = "Finalizing"
+
+	RoleMappingScopeCluster      string = "Cluster"
+	RoleMappingScopeOrganization string = "Organization"
+	RoleMappingScopeSpace        string = "Space"
+
+	IngressModeLoadBalancer string = "LoadBalancer"
+	IngressModeNodePort     string = "NodePort"
+	IngressModeStatic       string = "Static"
+	// IngressModeStaticAlias is accepted as an alias of IngressModeStatic
+	IngressModeStaticAlias string = "static"
+
+	DefaultIngressNodePort int32 = 30443
+
+	HibernationStateRunning     string = "Running"
+	HibernationStateHibernating string = "Hibernating"
+	HibernationStateHibernated  string = "Hibernated"
+	HibernationStateResuming    string = "Resuming"
+
+	ZoneSpreadPreferred string = "Preferred"
+	ZoneSpreadRequired  string = "Required"
+
+	DefaultHighAvailabilityReplicas int32 = 2
+
+	ProfileKyma  string = "kyma"
+	ProfileLocal string = "local"
 )
 
 type Kind string
@@ -42,9 +79,16 @@ var (
 	// GroupVersion is group version used to register these objects.
 	GroupVersion = schema.GroupVersion{Group: "operator.kyma-project.io", Version: "v1alpha1"}
 
-	ConditionTypeConfiguration = "Configuration"
-	ConditionTypeInstallation  = "Installation"
-	ConditionTypeDeletion      = "Deletion"
+	ConditionTypeConfiguration    = "Configuration"
+	ConditionTypeInstallation     = "Installation"
+	ConditionTypeDeletion         = "Deletion"
+	ConditionTypeUAA              = "UAA"
+	ConditionTypeSharedResources  = "SharedResources"
+	ConditionTypeGatewayMigration = "GatewayMigration"
+	ConditionTypeRegistry         = "Registry"
+	ConditionTypeBuilder          = "Builder"
+	ConditionTypeImageMirror      = "ImageMirror"
+	ConditionTypeHighAvailability = "HighAvailability"
 )
 
 type CFAPIStatus struct {
@@ -62,6 +106,78 @@ type CFAPIStatus struct {
 	// to consume the CF API.
 	//+kubebuilder:validation:Optional
 	URL string `json:"url,omitempty"`
+
+	// GatewayMigration tracks a switch of the gateway type that is in progress
+	//+kubebuilder:validation:Optional
+	GatewayMigration *GatewayMigration `json:"gatewayMigration,omitempty"`
+
+	// Build records the digests the buildpack and stack images were last
+	// resolved to by the build updates
+	//+kubebuilder:validation:Optional
+	Build *BuildStatus `json:"build,omitempty"`
+
+	// Hibernation reports whether the platform is hibernated
+	//+kubebuilder:validation:Optional
+	Hibernation *HibernationStatus `json:"hibernation,omitempty"`
+}
+
+type HibernationStatus struct {
+	// One of `Running`, `Hibernating`, `Hibernated` and `Resuming`
+	State string `json:"state"`
+	// When the state last changed
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+	// When the schedule next hibernates or resumes the platform
+	//+kubebuilder:validation:Optional
+	NextTransitionTime *metav1.Time `json:"nextTransitionTime,omitempty"`
+}
+
+type BuildStatus struct {
+	// The buildpack, build and run images with the digests they resolved to
+	//+kubebuilder:validation:Optional
+	Images []ResolvedImage `json:"images,omitempty"`
+	// When the image tags were last resolved
+	//+kubebuilder:validation:Optional
+	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
+	// When any of the digests last changed
+	//+kubebuilder:validation:Optional
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+	// When the digest of the run image last changed. Droplets staged before are rebased when `rebaseDroplets` is set
+	//+kubebuilder:validation:Optional
+	RunImageUpdateTime *metav1.Time `json:"runImageUpdateTime,omitempty"`
+}
+
+type ResolvedImage struct {
+	Image  string `json:"image"`
+	Digest string `json:"digest"`
+}
+
+// GatewayMigration describes the switch from one gateway type to another. The
+// gateway settings of both types are recorded when the migration starts, so
+// that the migration can complete even if the spec changes in the meantime
+type GatewayMigration struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// One of `Provisioning`, `CuttingOver`, `Switching` and `Finalizing`
+	Phase string `json:"phase"`
+	// PhaseStartTime is when the current phase started
+	PhaseStartTime metav1.Time `json:"phaseStartTime"`
+
+	//+kubebuilder:validation:Optional
+	FromClassName string `json:"fromClassName,omitempty"`
+	//+kubebuilder:validation:Optional
+	FromIngressNamespace string `json:"fromIngressNamespace,omitempty"`
+	//+kubebuilder:validation:Optional
+	FromIngressService string `json:"fromIngressService,omitempty"`
+	//+kubebuilder:validation:Optional
+	ToClassName string `json:"toClassName,omitempty"`
+	//+kubebuilder:validation:Optional
+	ToIngressNamespace string `json:"toIngressNamespace,omitempty"`
+	//+kubebuilder:validation:Optional
+	ToIngressService string `json:"toIngressService,omitempty"`
+
+	// CutoverAddress is the load balancer address of the cutover gateway
+	//+kubebuilder:validation:Optional
+	CutoverAddress string `json:"cutoverAddress,omitempty"`
 }
 
 type InstallationConfig struct {
@@ -73,32 +189,137 @@ type InstallationConfig struct {
 	DisableContainerRegistrySecretPropagation bool `json:"disableContainerRegistrySecretPropagation,omitempty"`
 	//+kubebuilder:validation:Optional
 	ContainerRegistryURL string `json:"containerRegistryUrl"`
+	// ContainerRegistryPullURL is the address the nodes pull images from when
+	// it differs from ContainerRegistryURL, e.g. the node port of an in-cluster
+	// registry
+	//+kubebuilder:validation:Optional
+	ContainerRegistryPullURL string `json:"containerRegistryPullUrl,omitempty"`
 	//+kubebuilder:validation:Optional
 	ContainerRepositoryPrefix string `json:"containerRepositoryPrefix"`
 	//+kubebuilder:validation:Optional
 	BuilderRepository string `json:"builderRepository"`
 	//+kubebuilder:validation:Optional
+	PackageRegistrySecret string `json:"packageRegistrySecret,omitempty"`
+	//+kubebuilder:validation:Optional
+	PackageRepositoryPrefix string `json:"packageRepositoryPrefix,omitempty"`
+	//+kubebuilder:validation:Optional
+	DropletRegistrySecret string `json:"dropletRegistrySecret,omitempty"`
+	//+kubebuilder:validation:Optional
+	DropletRepositoryPrefix string `json:"dropletRepositoryPrefix,omitempty"`
+	//+kubebuilder:validation:Optional
+	BuilderRegistrySecret string `json:"builderRegistrySecret,omitempty"`
+	//+kubebuilder:validation:Optional
 	UAAURL string `json:"uaaUrl"`
 	//+kubebuilder:validation:Optional
+	OIDCUsernamePrefix string `json:"oidcUsernamePrefix"`
+	//+kubebuilder:validation:Optional
+	OIDCGroupsPrefix string `json:"oidcGroupsPrefix"`
+	//+kubebuilder:validation:Optional
 	CFAdmins []string `json:"cfAdmins"`
 	//+kubebuilder:validation:Optional
+	CFAdminGroups []string `json:"cfAdminGroups"`
+	//+kubebuilder:validation:Optional
 	CFDomain string `json:"cfDomain"`
 	//+kubebuilder:validation:Optional
+	Profile string `json:"profile"`
+	//+kubebuilder:validation:Optional
+	CFDomainSource string `json:"cfDomainSource"`
+	//+kubebuilder:validation:Optional
+	IngressMode string `json:"ingressMode"`
+	//+kubebuilder:validation:Optional
+	IngressHost string `json:"ingressHost"`
+	//+kubebuilder:validation:Optional
+	IngressPort int32 `json:"ingressPort"`
+	//+kubebuilder:validation:Optional
 	KorifiIngressService string `json:"korifiIngressService"`
 	//+kubebuilder:validation:Optional
+	KorifiIngressNamespace string `json:"korifiIngressNamespace"`
+	//+kubebuilder:validation:Optional
 	UseSelfSignedCertificates bool `json:"useSelfSignedCertificates"`
 	//+kubebuilder:validation:Optional
 	GatewayType string `json:"gatewayType"`
+	//+kubebuilder:validation:Optional
+	IstioGateway string `json:"istioGateway"`
+	//+kubebuilder:validation:Optional
+	IstioAppsGateway string `json:"istioAppsGateway,omitempty"`
+	//+kubebuilder:validation:Optional
+	GatewayClassName string `json:"gatewayClassName"`
+	//+kubebuilder:validation:Optional
+	PreviousGatewayType string `json:"previousGatewayType,omitempty"`
+	//+kubebuilder:validation:Optional
+	GatewayCutoverAddress string `json:"gatewayCutoverAddress,omitempty"`
+	//+kubebuilder:validation:Optional
+	ClusterBuilderName string `json:"clusterBuilderName,omitempty"`
+	//+kubebuilder:validation:Optional
+	Buildpacks []string `json:"buildpacks,omitempty"`
+	//+kubebuilder:validation:Optional
+	Stack *BuildStack `json:"stack,omitempty"`
+	//+kubebuilder:validation:Optional
+	BuildpackOrder []BuildpackGroup `json:"buildpackOrder,omitempty"`
+	//+kubebuilder:validation:Optional
+	BuildImageDigests map[string]string `json:"buildImageDigests,omitempty"`
+	//+kubebuilder:validation:Optional
+	BuildCacheMB int64 `json:"buildCacheMB,omitempty"`
+	//+kubebuilder:validation:Optional
+	BuildCacheStorageClassName string `json:"buildCacheStorageClassName,omitempty"`
+	//+kubebuilder:validation:Optional
+	BuildMemoryMB int64 `json:"buildMemoryMB,omitempty"`
+	//+kubebuilder:validation:Optional
+	BuildDiskMB int64 `json:"buildDiskMB,omitempty"`
+	//+kubebuilder:validation:Optional
+	ImageRewrites []ImageRewrite `json:"imageRewrites,omitempty"`
+	//+kubebuilder:validation:Optional
+	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
+	//+kubebuilder:validation:Optional
+	TrustedCA *TrustedCA `json:"trustedCA,omitempty"`
+	//+kubebuilder:validation:Optional
+	Proxy *ProxyConfig `json:"proxy,omitempty"`
+	//+kubebuilder:validation:Optional
+	HighAvailability *HighAvailability `json:"highAvailability,omitempty"`
+}
+
+// TrustedCA is the source of the trusted CA bundle and the hash of its content
+type TrustedCA struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Key       string `json:"key"`
+	Hash      string `json:"hash"`
+}
+
+// ProxyConfig is the egress proxy with the computed hosts bypassing it, in
+// the format of the `HTTP_PROXY`, `HTTPS_PROXY` and `NO_PROXY` variables
+type ProxyConfig struct {
+	//+kubebuilder:validation:Optional
+	HTTPProxy string `json:"httpProxy,omitempty"`
+	//+kubebuilder:validation:Optional
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+	//+kubebuilder:validation:Optional
+	NoProxy string `json:"noProxy,omitempty"`
+	//+kubebuilder:validation:Optional
+	InjectIntoApps bool `json:"injectIntoApps,omitempty"`
 }
 
 type CFAPISpec struct {
-	// The Korifi root namespace. Defaults to `cf`
+	// The environment the module is installed in. `kyma` relies on the Gardener and Kyma services of a Kyma cluster, `local` is self-contained and meant for kind or k3d clusters. Defaults to `kyma`
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Enum=kyma;local
+	Profile string `json:"profile,omitempty"`
+	// Configuration of the `local` profile
+	//+kubebuilder:validation:Optional
+	Local *LocalProfile `json:"local,omitempty"`
+	// The Korifi root namespace. Defaults to `cf`. Cannot be changed once CF is installed
 	//+kubebuilder:validation:Optional
 	RootNamespace string `json:"rootNamespace,omitempty"`
-	// The container registry secret to be used when pushing droplets and workloads images. Defaults to the Kyma docker registry module secret (`dockerregistry-config-external`)
+	// The container registry secret to be used when pushing droplets and workloads images. Defaults to the in-cluster secret of the Kyma docker registry module (`dockerregistry-config`), or to its external secret (`dockerregistry-config-external`) when `useExternalDockerRegistry` is set
 	//+kubebuilder:validation:Optional
 	ContainerRegistrySecret string `json:"containerRegistrySecret,omitempty"`
-	// Whether to disable container registry secret propagation to workload namepsaces.
+	// Whether to push images to the external address of the Kyma docker registry module, which requires its external access to be enabled. By default images are pushed to the in-cluster address of the registry and pulled by the nodes from its node port. Only applies when `containerRegistrySecret` is not set. Defaults to `false`
+	//+kubebuilder:validation:Optional
+	UseExternalDockerRegistry bool `json:"useExternalDockerRegistry,omitempty"`
+	// The registry of the container registry secret to push images to. Required to be one of the registries in `containerRegistrySecret` when the secret holds credentials for more than one registry. Defaults to the first registry of the secret in lexical order
+	//+kubebuilder:validation:Optional
+	ContainerRegistryURL string `json:"containerRegistryURL,omitempty"`
+	// Whether to disable container registry secret propagation to the root, org and space namespaces. Existing copies are removed
 	//+kubebuilder:validation:Optional
 	DisableContainerRegistrySecretPropagation bool `json:"disableContainerRegistrySecretPropagation,omitempty"`
 	// The prefix of the container repository where package and droplet images will be pushed. This is suffixed with the app GUID and `-packages` or `-droplets`. For example, a value of `index.docker.io/korifi/` will result in `index.docker.io/korifi/<appGUID>-packages` and `index.docker.io/korifi/<appGUID>-droplets` being pushed. Defaults to `container_registry_url_from_secret + "/"`
@@ -107,24 +328,385 @@ type CFAPISpec struct {
 	// Container image repository to store the Korifi `ClusterBuilder` image. Defaults to `container_registry_url_from_secret + "/cfapi/kpack-builder"`
 	//+kubebuilder:validation:Optional
 	BuilderRepository string `json:"builderRepository"`
+	// Separate container registries for package images, droplet images and the kpack builder image, e.g. to keep the builder image in a shared registry and app images in a registry of the cluster. Each of them defaults to `containerRegistrySecret` and `containerRepositoryPrefix` or `builderRepository`
+	//+kubebuilder:validation:Optional
+	ContainerRegistries *ContainerRegistries `json:"containerRegistries,omitempty"`
+	// Short-lived registry credentials the operator obtains from a token endpoint and keeps refreshed in `containerRegistrySecret`, e.g. for cloud registries that do not accept long-lived passwords. Requires `containerRegistrySecret` to be set
+	//+kubebuilder:validation:Optional
+	ContainerRegistryCredentials *ContainerRegistryCredentials `json:"containerRegistryCredentials,omitempty"`
+	// Configuration of the container registry check reported in the `Registry` status condition
+	//+kubebuilder:validation:Optional
+	ContainerRegistryCheck *ContainerRegistryCheck `json:"containerRegistryCheck,omitempty"`
+	// Deletion of the package and droplet images of deleted apps, and of outdated droplets of existing apps, from the container registries. Disabled by default
+	//+kubebuilder:validation:Optional
+	RegistryGarbageCollection *RegistryGarbageCollection `json:"registryGarbageCollection,omitempty"`
+	// The kpack builder apps are staged with, and the cache and resources of build pods. Defaults to the buildpacks and stack of the Korifi `cf-kpack-cluster-builder`
+	//+kubebuilder:validation:Optional
+	Build *Build `json:"build,omitempty"`
+	// Mirror registries the images of the installed components, the builder and the builds are pulled from instead of their public registries
+	//+kubebuilder:validation:Optional
+	ImageMirror *ImageMirror `json:"imageMirror,omitempty"`
+	// ConfigMap in the CFAPI namespace with additional CA certificates trusted by Korifi, kpack, the BTP service broker, builds and apps
+	//+kubebuilder:validation:Optional
+	TrustedCABundle *TrustedCABundle `json:"trustedCABundle,omitempty"`
+	// The egress proxy used by Korifi, kpack, the BTP service broker, builds and the operator to reach services outside of the cluster
+	//+kubebuilder:validation:Optional
+	Proxy *Proxy `json:"proxy,omitempty"`
+	// Runs the Korifi API and controllers, Contour, the kpack webhook and the BTP service broker with several replicas spread across nodes and zones, protected by PodDisruptionBudgets. Reported in the `HighAvailability` status condition
+	//+kubebuilder:validation:Optional
+	HighAvailability *HighAvailability `json:"highAvailability,omitempty"`
+	// Scales the Korifi, kpack, Contour and BTP service broker deployments and, optionally, the CF apps to zero, either manually or on a schedule. Reported in `status.hibernation`
+	//+kubebuilder:validation:Optional
+	Hibernation *Hibernation `json:"hibernation,omitempty"`
 	// The UAA url, used for getting user authentication tokens. Defaults to the subaccount UAA
 	//+kubebuilder:validation:Optional
 	UAA string `json:"uaa,omitempty"`
 	// List of users to ba assigned with the Korifi CFAdmin role. Defaults to the Kyma cluster admin users
 	//+kubebuilder:validation:Optional
 	CFAdmins []string `json:"cfadmins,omitempty"`
+	// List of groups to be assigned with the Korifi CFAdmin role. Defaults to the groups bound to the Kyma `cluster-admin` cluster role. Neither users nor groups are discovered if either `cfadmins` or `cfadminGroups` is set
+	//+kubebuilder:validation:Optional
+	CFAdminGroups []string `json:"cfadminGroups,omitempty"`
 	// Whether to use self-signed certificates for the Korif API and workloads. Defaults to `false`
 	//+kubebuilder:validation:Optional
 	UseSelfSignedCertificates bool `json:"useSelfSignedCertificates"`
-	// The type of the Korifi ingress gateway. Should be one of "contour" or "istio". Defaluts to contour.
+	// The type of the Korifi ingress gateway. Should be one of "contour", "istio", "istio-native" or "external". Defaluts to contour.
+	// "istio-native" exposes CF through the kyma gateway without the alpha Gateway API support of istio
+	// "external" uses a Gateway API implementation already running in the cluster, configured in `gateway`
 	//+kubebuilder:validation:Optional
 	GatewayType string `json:"gatewayType"`
+	// The Gateway API implementation used with the `external` gateway type
+	//+kubebuilder:validation:Optional
+	Gateway *ExternalGateway `json:"gateway,omitempty"`
+	// The Istio gateway whose wildcard host determines the CF domain. Defaults to `kyma-system/kyma-gateway`, falling back to the Gardener `kube-system/shoot-info` config map
+	//+kubebuilder:validation:Optional
+	KymaGateway *NamespacedObjectReference `json:"kymaGateway,omitempty"`
+	// How the Korifi ingress gateway is exposed. Defaults to a `LoadBalancer` service
+	//+kubebuilder:validation:Optional
+	Ingress *Ingress `json:"ingress,omitempty"`
+	// Whether to disable syncing CF roles from Kubernetes role bindings. Defaults to `false`
+	//+kubebuilder:validation:Optional
+	DisableRoleSync bool `json:"disableRoleSync,omitempty"`
+	// Mappings of Kubernetes cluster roles to CF roles, used by the CF role sync. Defaults to mapping cluster wide `view` to `global_auditor` and `admin` in space namespaces to `space_developer`
+	//+kubebuilder:validation:Optional
+	RoleMappings []RoleMapping `json:"roleMappings,omitempty"`
+}
+
+type ContainerRegistries struct {
+	// The registry the CF API pushes package images to
+	//+kubebuilder:validation:Optional
+	Packages *ContainerRepository `json:"packages,omitempty"`
+	// The registry kpack pushes droplet images to and app workloads pull them from
+	//+kubebuilder:validation:Optional
+	Droplets *ContainerRepository `json:"droplets,omitempty"`
+	// The registry of the kpack `ClusterBuilder` image
+	//+kubebuilder:validation:Optional
+	Builder *ContainerRepository `json:"builder,omitempty"`
+}
+
+type ContainerRepository struct {
+	// The container registry secret with the credentials for the repository. It has to be of type `docker-registry`. Defaults to `containerRegistrySecret`
+	//+kubebuilder:validation:Optional
+	Secret string `json:"secret,omitempty"`
+	// The repository prefix of package and droplet images, e.g. `index.docker.io/korifi/`, or the repository of the builder image. Defaults to the first registry of `secret`, suffixed with `/` or `/cfapi/kpack-builder` respectively, if `secret` is set, and to `containerRepositoryPrefix` or `builderRepository` otherwise
+	//+kubebuilder:validation:Optional
+	Repository string `json:"repository,omitempty"`
+}
+
+type ContainerRegistryCheck struct {
+	// Whether to skip checking the container registry, e.g. when the registry is not reachable from the operator. Defaults to `false`
+	//+kubebuilder:validation:Optional
+	Disabled bool `json:"disabled,omitempty"`
+	// Whether to check that images can be pushed to `containerRepositoryPrefix` and `builderRepository` by starting a blob upload, which is cancelled right away. Defaults to `false`
+	//+kubebuilder:validation:Optional
+	Push bool `json:"push,omitempty"`
+}
+
+type RegistryGarbageCollection struct {
+	// Whether to collect garbage in the package and droplet registries. Defaults to `false`
+	//+kubebuilder:validation:Optional
+	Enabled bool `json:"enabled,omitempty"`
+	// Whether to only report what would be deleted, without deleting anything. Defaults to `false`
+	//+kubebuilder:validation:Optional
+	DryRun bool `json:"dryRun,omitempty"`
+	// Whether to delete the images of apps that do not exist in the cluster. Only enable it when the package and droplet repository prefixes are not shared with other clusters, whose apps would look deleted. Defaults to `false`
+	//+kubebuilder:validation:Optional
+	CollectDeletedApps bool `json:"collectDeletedApps,omitempty"`
+	// How often garbage is collected. Defaults to `24h`
+	//+kubebuilder:validation:Optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// How long the images of a deleted app are kept after the collection first found them orphaned. Defaults to `72h`
+	//+kubebuilder:validation:Optional
+	GracePeriod *metav1.Duration `json:"gracePeriod,omitempty"`
+	// The number of most recent droplets kept per existing app. The current droplet of an app is always kept. Defaults to `5`
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Minimum=1
+	KeepDroplets *int32 `json:"keepDroplets,omitempty"`
+}
+
+type ImageMirror struct {
+	// Prefixes of image references and the prefixes they are rewritten to. The longest matching prefix applies. Docker Hub images match `docker.io/` in their full form, e.g. `docker.io/library/nginx` for `nginx`
+	//+kubebuilder:validation:MinItems=1
+	Rewrites []ImageRewrite `json:"rewrites"`
+	// Names of `kubernetes.io/dockerconfigjson` secrets in the CFAPI namespace to pull the rewritten images with. They are propagated to the namespaces of the installed components and of the CF orgs and spaces
+	//+kubebuilder:validation:Optional
+	PullSecrets []string `json:"pullSecrets,omitempty"`
+}
+
+type ImageRewrite struct {
+	// The prefix to replace, e.g. `ghcr.io/`
+	//+kubebuilder:validation:MinLength=1
+	From string `json:"from"`
+	// The prefix to replace it with, e.g. `mirror.example.com/ghcr/`
+	//+kubebuilder:validation:MinLength=1
+	To string `json:"to"`
+}
+
+type TrustedCABundle struct {
+	// Name of the ConfigMap
+	//+kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+	// Key of the PEM encoded CA certificates in the ConfigMap
+	//+kubebuilder:default=ca.crt
+	//+kubebuilder:validation:Optional
+	Key string `json:"key,omitempty"`
+}
+
+type Proxy struct {
+	// URL of the proxy for HTTP requests, e.g. `http://proxy.example.com:3128`
+	//+kubebuilder:validation:Optional
+	HTTPProxy string `json:"httpProxy,omitempty"`
+	// URL of the proxy for HTTPS requests. Defaults to `httpProxy`
+	//+kubebuilder:validation:Optional
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+	// Additional hosts, domains (e.g. `.corp.example.com`), IP addresses and CIDRs reached without the proxy. The cluster networks, the cluster DNS suffixes and the CF domain are always reached directly
+	//+kubebuilder:validation:Optional
+	NoProxy []string `json:"noProxy,omitempty"`
+	// Also sets the proxy variables in the environment of every CF app, so that apps reach the internet through the proxy. Builds always get them. Defaults to false
+	//+kubebuilder:validation:Optional
+	InjectIntoApps bool `json:"injectIntoApps,omitempty"`
+}
+
+type HighAvailability struct {
+	// Number of replicas of each component. Defaults to 2
+	//+kubebuilder:default=2
+	//+kubebuilder:validation:Minimum=2
+	//+kubebuilder:validation:Optional
+	Replicas int32 `json:"replicas,omitempty"`
+	// Whether the replicas of a component must be spread across zones (`Required`), leaving replicas pending when there are not enough zones, or only preferably (`Preferred`). Replicas are always preferably spread across nodes. Defaults to `Preferred`
+	//+kubebuilder:default=Preferred
+	//+kubebuilder:validation:Enum=Preferred;Required
+	//+kubebuilder:validation:Optional
+	ZoneSpread string `json:"zoneSpread,omitempty"`
+}
+
+type Hibernation struct {
+	// Hibernates the platform until set to `false`, regardless of the schedule
+	//+kubebuilder:validation:Optional
+	Hibernated bool `json:"hibernated,omitempty"`
+	// Times at which the platform is hibernated and resumed
+	//+kubebuilder:validation:Optional
+	Schedule *HibernationSchedule `json:"schedule,omitempty"`
+	// Also scales the workloads of CF apps to zero. Apps are restored with their previous number of instances on resume
+	//+kubebuilder:validation:Optional
+	IncludeApps bool `json:"includeApps,omitempty"`
+}
+
+type HibernationSchedule struct {
+	// Cron expression of the times the platform is hibernated at, e.g. `0 20 * * mon-fri`
+	//+kubebuilder:validation:MinLength=1
+	Hibernate string `json:"hibernate"`
+	// Cron expression of the times the platform is resumed at, e.g. `0 7 * * mon-fri`
+	//+kubebuilder:validation:MinLength=1
+	Resume string `json:"resume"`
+	// IANA time zone of the cron expressions, e.g. `Europe/Berlin`. Defaults to `UTC`
+	//+kubebuilder:validation:Optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+type Build struct {
+	// The buildpack images of the kpack `ClusterStore`, e.g. `paketobuildpacks/java` or `paketobuildpacks/dotnet-core`. Requires `order` to be set. Defaults to the Paketo Java, Node.js, Ruby, Procfile and Go buildpacks
+	//+kubebuilder:validation:Optional
+	Buildpacks []string `json:"buildpacks,omitempty"`
+	// The build and run images of the kpack `ClusterStack`. Defaults to the Paketo Jammy full stack
+	//+kubebuilder:validation:Optional
+	Stack *BuildStack `json:"stack,omitempty"`
+	// The groups of buildpacks the builder tries in order, the first group detecting the app builds it. Defaults to a group for each of the default buildpacks
+	//+kubebuilder:validation:Optional
+	Order []BuildpackGroup `json:"order,omitempty"`
+	// The persistent build cache of each app
+	//+kubebuilder:validation:Optional
+	Cache *BuildCache `json:"cache,omitempty"`
+	// The resources requested by build pods
+	//+kubebuilder:validation:Optional
+	Resources *BuildResources `json:"resources,omitempty"`
+	// Periodic updates of the buildpacks and the stack to the images their tags currently refer to. Enabling updates renders a custom builder, with the Korifi buildpacks and stack unless configured otherwise
+	//+kubebuilder:validation:Optional
+	Updates *BuildUpdates `json:"updates,omitempty"`
+}
+
+type BuildUpdates struct {
+	// Whether to update the buildpacks and the stack. Defaults to `false`
+	//+kubebuilder:validation:Optional
+	Enabled bool `json:"enabled,omitempty"`
+	// How often the image tags are resolved. Defaults to `24h`
+	//+kubebuilder:validation:Optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// The window updates are restricted to. Defaults to any time
+	//+kubebuilder:validation:Optional
+	MaintenanceWindow *MaintenanceWindow `json:"maintenanceWindow,omitempty"`
+	// Whether to restage the apps onto an updated run image within the maintenance window, so that their droplets get the OS patches of the stack. Apps are restarted with a rolling deployment. Defaults to `false`
+	//+kubebuilder:validation:Optional
+	RebaseDroplets bool `json:"rebaseDroplets,omitempty"`
+}
+
+type MaintenanceWindow struct {
+	// The days of the week the window opens on. Defaults to every day
+	//+kubebuilder:validation:Optional
+	Days []MaintenanceDay `json:"days,omitempty"`
+	// The time of day the window opens, in UTC, e.g. `02:00`
+	//+kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+	// How long the window stays open, e.g. `4h`
+	Duration metav1.Duration `json:"duration"`
+}
+
+// MaintenanceDay is a day of the week
+// +kubebuilder:validation:Enum=Monday;Tuesday;Wednesday;Thursday;Friday;Saturday;Sunday
+type MaintenanceDay string
+
+type BuildStack struct {
+	// The id of the stack, e.g. `io.buildpacks.stacks.jammy`
+	ID string `json:"id"`
+	// The image apps are built in
+	BuildImage string `json:"buildImage"`
+	// The base image of droplets
+	RunImage string `json:"runImage"`
+}
+
+type BuildpackGroup struct {
+	// The buildpacks of the group
+	//+kubebuilder:validation:MinItems=1
+	Group []BuildpackRef `json:"group"`
+}
+
+type BuildpackRef struct {
+	// The id of the buildpack, e.g. `paketo-buildpacks/java`. It has to be provided by one of the buildpack images
+	ID string `json:"id"`
+	// Whether the group also detects apps the buildpack does not apply to
+	//+kubebuilder:validation:Optional
+	Optional bool `json:"optional,omitempty"`
+}
+
+type BuildCache struct {
+	// The size of the build cache volume of each app. Defaults to `2Gi`. It cannot be decreased, as existing volumes cannot shrink
+	//+kubebuilder:validation:Optional
+	Size *resource.Quantity `json:"size,omitempty"`
+	// The storage class of build cache volumes created from now on. Existing volumes keep their storage class. Defaults to the default storage class of the cluster
+	//+kubebuilder:validation:Optional
+	StorageClassName string `json:"storageClassName,omitempty"`
+}
+
+type BuildResources struct {
+	// The memory requested by build pods. Defaults to no request
+	//+kubebuilder:validation:Optional
+	Memory *resource.Quantity `json:"memory,omitempty"`
+	// The ephemeral storage requested by build pods. Defaults to no request
+	//+kubebuilder:validation:Optional
+	EphemeralStorage *resource.Quantity `json:"ephemeralStorage,omitempty"`
+}
+
+type ContainerRegistryCredentials struct {
+	// The credential provider. `oauth2` exchanges client credentials, a refresh token or a service account token at an OAuth2 token endpoint. It is the only provider, cloud provider APIs such as `GetAuthorizationToken` of Amazon ECR are not supported
+	//+kubebuilder:validation:Required
+	//+kubebuilder:validation:Enum=oauth2
+	Provider string `json:"provider"`
+	// The registry the credentials are issued for, e.g. `my-registry.example.com`
+	//+kubebuilder:validation:Required
+	Registry string `json:"registry"`
+	// The token endpoint of the provider
+	//+kubebuilder:validation:Required
+	TokenURL string `json:"tokenURL"`
+	// A secret in the CFAPI namespace holding the long-lived credentials, either `clientID` and `clientSecret` or a `refreshToken`. A refresh token rotated by the provider is written back to the secret
+	//+kubebuilder:validation:Optional
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+	// A service account in the CFAPI namespace whose token is exchanged for registry credentials, used as workload identity when `credentialsSecret` is not set
+	//+kubebuilder:validation:Optional
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+	// The audience of the service account token. Defaults to `tokenURL`
+	//+kubebuilder:validation:Optional
+	Audience string `json:"audience,omitempty"`
+	// The scopes to request
+	//+kubebuilder:validation:Optional
+	Scopes []string `json:"scopes,omitempty"`
+	// The username the registry expects together with the issued token. Defaults to `oauth2accesstoken`
+	//+kubebuilder:validation:Optional
+	Username string `json:"username,omitempty"`
+}
+
+type LocalProfile struct {
+	// The wildcard DNS service used to derive the CF domain from the ingress IP address. Defaults to `nip.io`
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Enum=nip.io;sslip.io
+	DomainSuffix string `json:"domainSuffix,omitempty"`
+	// The OIDC issuer the Kubernetes API server is configured with, e.g. a Dex instance run next to the cluster. The operator does not install the issuer nor configure the API server. When not set, users authenticate with their Kubernetes credentials
+	//+kubebuilder:validation:Optional
+	OIDC *LocalOIDC `json:"oidc,omitempty"`
+}
+
+type LocalOIDC struct {
+	// The issuer URL, it has to serve an OIDC discovery document
+	IssuerURL string `json:"issuerURL"`
+	// The prefix the Kubernetes API server prepends to user names from the issuer
+	//+kubebuilder:validation:Optional
+	UsernamePrefix string `json:"usernamePrefix,omitempty"`
+	// The prefix the Kubernetes API server prepends to groups from the issuer
+	//+kubebuilder:validation:Optional
+	GroupsPrefix string `json:"groupsPrefix,omitempty"`
+}
+
+type Ingress struct {
+	// The ingress exposure mode. `LoadBalancer` uses the load balancer ingress of the gateway service, `NodePort` exposes the gateway service on a node port of the given host and `Static` (or `static`) uses the given host as is. Defaults to `LoadBalancer`
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Enum=LoadBalancer;NodePort;Static;static
+	Mode string `json:"mode,omitempty"`
+	// The IP address or hostname the ingress gateway is reachable at. Required for the `NodePort` and `Static` modes
+	//+kubebuilder:validation:Optional
+	Host string `json:"host,omitempty"`
+	// The HTTPS node port of the gateway service in `NodePort` mode. Defaults to `30443`
+	//+kubebuilder:validation:Optional
+	//+kubebuilder:validation:Minimum=30000
+	//+kubebuilder:validation:Maximum=32767
+	NodePort int32 `json:"nodePort,omitempty"`
+}
+
+type ExternalGateway struct {
+	// The name of the GatewayClass of the Korifi gateway. The class has to be accepted by its controller
+	ClassName string `json:"className"`
+	// The Service the gateway implementation provisions for the Korifi gateway. Its load balancer ingress is used as the target of the CF DNS entries
+	IngressService NamespacedObjectReference `json:"ingressService"`
+}
+
+type NamespacedObjectReference struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+type RoleMapping struct {
+	// The name of the Kubernetes cluster role whose bindings are mapped
+	ClusterRole string `json:"clusterRole"`
+	// Where the bindings are looked up. `Cluster` maps cluster role bindings to global CF roles, `Organization` and `Space` map role bindings in CF org and space namespaces respectively
+	//+kubebuilder:validation:Enum=Cluster;Organization;Space
+	Scope string `json:"scope"`
+	// The CF role to assign, e.g. `global_auditor`, `organization_manager` or `space_developer`
+	CFRole string `json:"cfRole"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:printcolumn:name="State",type=string,JSONPath=".status.state"
 //+kubebuilder:printcolumn:name="URL",type=string,JSONPath=".status.url"
+//+kubebuilder:printcolumn:name="Hibernation",type=string,JSONPath=".status.hibernation.state"
 
 // CFAPI is the Schema for the samples API.
 type CFAPI struct {
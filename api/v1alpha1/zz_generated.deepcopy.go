@@ -25,6 +25,205 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Build) DeepCopyInto(out *Build) {
+	*out = *in
+	if in.Buildpacks != nil {
+		in, out := &in.Buildpacks, &out.Buildpacks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Stack != nil {
+		in, out := &in.Stack, &out.Stack
+		*out = new(BuildStack)
+		**out = **in
+	}
+	if in.Order != nil {
+		in, out := &in.Order, &out.Order
+		*out = make([]BuildpackGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Cache != nil {
+		in, out := &in.Cache, &out.Cache
+		*out = new(BuildCache)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(BuildResources)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Updates != nil {
+		in, out := &in.Updates, &out.Updates
+		*out = new(BuildUpdates)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Build.
+func (in *Build) DeepCopy() *Build {
+	if in == nil {
+		return nil
+	}
+	out := new(Build)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildCache) DeepCopyInto(out *BuildCache) {
+	*out = *in
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildCache.
+func (in *BuildCache) DeepCopy() *BuildCache {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildCache)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildResources) DeepCopyInto(out *BuildResources) {
+	*out = *in
+	if in.Memory != nil {
+		in, out := &in.Memory, &out.Memory
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.EphemeralStorage != nil {
+		in, out := &in.EphemeralStorage, &out.EphemeralStorage
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildResources.
+func (in *BuildResources) DeepCopy() *BuildResources {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildResources)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildStack) DeepCopyInto(out *BuildStack) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildStack.
+func (in *BuildStack) DeepCopy() *BuildStack {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildStack)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildStatus) DeepCopyInto(out *BuildStatus) {
+	*out = *in
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]ResolvedImage, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastCheckTime != nil {
+		in, out := &in.LastCheckTime, &out.LastCheckTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastUpdateTime != nil {
+		in, out := &in.LastUpdateTime, &out.LastUpdateTime
+		*out = (*in).DeepCopy()
+	}
+	if in.RunImageUpdateTime != nil {
+		in, out := &in.RunImageUpdateTime, &out.RunImageUpdateTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildStatus.
+func (in *BuildStatus) DeepCopy() *BuildStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildUpdates) DeepCopyInto(out *BuildUpdates) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.MaintenanceWindow != nil {
+		in, out := &in.MaintenanceWindow, &out.MaintenanceWindow
+		*out = new(MaintenanceWindow)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildUpdates.
+func (in *BuildUpdates) DeepCopy() *BuildUpdates {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildUpdates)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildpackGroup) DeepCopyInto(out *BuildpackGroup) {
+	*out = *in
+	if in.Group != nil {
+		in, out := &in.Group, &out.Group
+		*out = make([]BuildpackRef, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildpackGroup.
+func (in *BuildpackGroup) DeepCopy() *BuildpackGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildpackGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildpackRef) DeepCopyInto(out *BuildpackRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildpackRef.
+func (in *BuildpackRef) DeepCopy() *BuildpackRef {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildpackRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CFAPI) DeepCopyInto(out *CFAPI) {
 	*out = *in
@@ -87,11 +286,91 @@ func (in *CFAPIList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CFAPISpec) DeepCopyInto(out *CFAPISpec) {
 	*out = *in
+	if in.Local != nil {
+		in, out := &in.Local, &out.Local
+		*out = new(LocalProfile)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ContainerRegistries != nil {
+		in, out := &in.ContainerRegistries, &out.ContainerRegistries
+		*out = new(ContainerRegistries)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ContainerRegistryCredentials != nil {
+		in, out := &in.ContainerRegistryCredentials, &out.ContainerRegistryCredentials
+		*out = new(ContainerRegistryCredentials)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ContainerRegistryCheck != nil {
+		in, out := &in.ContainerRegistryCheck, &out.ContainerRegistryCheck
+		*out = new(ContainerRegistryCheck)
+		**out = **in
+	}
+	if in.RegistryGarbageCollection != nil {
+		in, out := &in.RegistryGarbageCollection, &out.RegistryGarbageCollection
+		*out = new(RegistryGarbageCollection)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Build != nil {
+		in, out := &in.Build, &out.Build
+		*out = new(Build)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImageMirror != nil {
+		in, out := &in.ImageMirror, &out.ImageMirror
+		*out = new(ImageMirror)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TrustedCABundle != nil {
+		in, out := &in.TrustedCABundle, &out.TrustedCABundle
+		*out = new(TrustedCABundle)
+		**out = **in
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HighAvailability != nil {
+		in, out := &in.HighAvailability, &out.HighAvailability
+		*out = new(HighAvailability)
+		**out = **in
+	}
+	if in.Hibernation != nil {
+		in, out := &in.Hibernation, &out.Hibernation
+		*out = new(Hibernation)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.CFAdmins != nil {
 		in, out := &in.CFAdmins, &out.CFAdmins
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.CFAdminGroups != nil {
+		in, out := &in.CFAdminGroups, &out.CFAdminGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Gateway != nil {
+		in, out := &in.Gateway, &out.Gateway
+		*out = new(ExternalGateway)
+		**out = **in
+	}
+	if in.KymaGateway != nil {
+		in, out := &in.KymaGateway, &out.KymaGateway
+		*out = new(NamespacedObjectReference)
+		**out = **in
+	}
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		*out = new(Ingress)
+		**out = **in
+	}
+	if in.RoleMappings != nil {
+		in, out := &in.RoleMappings, &out.RoleMappings
+		*out = make([]RoleMapping, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CFAPISpec.
@@ -116,6 +395,21 @@ func (in *CFAPIStatus) DeepCopyInto(out *CFAPIStatus) {
 		}
 	}
 	in.InstallationConfig.DeepCopyInto(&out.InstallationConfig)
+	if in.GatewayMigration != nil {
+		in, out := &in.GatewayMigration, &out.GatewayMigration
+		*out = new(GatewayMigration)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Build != nil {
+		in, out := &in.Build, &out.Build
+		*out = new(BuildStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Hibernation != nil {
+		in, out := &in.Hibernation, &out.Hibernation
+		*out = new(HibernationStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CFAPIStatus.
@@ -128,6 +422,243 @@ func (in *CFAPIStatus) DeepCopy() *CFAPIStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerRegistries) DeepCopyInto(out *ContainerRegistries) {
+	*out = *in
+	if in.Packages != nil {
+		in, out := &in.Packages, &out.Packages
+		*out = new(ContainerRepository)
+		**out = **in
+	}
+	if in.Droplets != nil {
+		in, out := &in.Droplets, &out.Droplets
+		*out = new(ContainerRepository)
+		**out = **in
+	}
+	if in.Builder != nil {
+		in, out := &in.Builder, &out.Builder
+		*out = new(ContainerRepository)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerRegistries.
+func (in *ContainerRegistries) DeepCopy() *ContainerRegistries {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerRegistries)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerRegistryCheck) DeepCopyInto(out *ContainerRegistryCheck) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerRegistryCheck.
+func (in *ContainerRegistryCheck) DeepCopy() *ContainerRegistryCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerRegistryCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerRegistryCredentials) DeepCopyInto(out *ContainerRegistryCredentials) {
+	*out = *in
+	if in.Scopes != nil {
+		in, out := &in.Scopes, &out.Scopes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerRegistryCredentials.
+func (in *ContainerRegistryCredentials) DeepCopy() *ContainerRegistryCredentials {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerRegistryCredentials)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerRepository) DeepCopyInto(out *ContainerRepository) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerRepository.
+func (in *ContainerRepository) DeepCopy() *ContainerRepository {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerRepository)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalGateway) DeepCopyInto(out *ExternalGateway) {
+	*out = *in
+	out.IngressService = in.IngressService
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalGateway.
+func (in *ExternalGateway) DeepCopy() *ExternalGateway {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalGateway)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GatewayMigration) DeepCopyInto(out *GatewayMigration) {
+	*out = *in
+	in.PhaseStartTime.DeepCopyInto(&out.PhaseStartTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GatewayMigration.
+func (in *GatewayMigration) DeepCopy() *GatewayMigration {
+	if in == nil {
+		return nil
+	}
+	out := new(GatewayMigration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Hibernation) DeepCopyInto(out *Hibernation) {
+	*out = *in
+	if in.Schedule != nil {
+		in, out := &in.Schedule, &out.Schedule
+		*out = new(HibernationSchedule)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Hibernation.
+func (in *Hibernation) DeepCopy() *Hibernation {
+	if in == nil {
+		return nil
+	}
+	out := new(Hibernation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HibernationSchedule) DeepCopyInto(out *HibernationSchedule) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HibernationSchedule.
+func (in *HibernationSchedule) DeepCopy() *HibernationSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(HibernationSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HibernationStatus) DeepCopyInto(out *HibernationStatus) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	if in.NextTransitionTime != nil {
+		in, out := &in.NextTransitionTime, &out.NextTransitionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HibernationStatus.
+func (in *HibernationStatus) DeepCopy() *HibernationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HibernationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HighAvailability) DeepCopyInto(out *HighAvailability) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HighAvailability.
+func (in *HighAvailability) DeepCopy() *HighAvailability {
+	if in == nil {
+		return nil
+	}
+	out := new(HighAvailability)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageMirror) DeepCopyInto(out *ImageMirror) {
+	*out = *in
+	if in.Rewrites != nil {
+		in, out := &in.Rewrites, &out.Rewrites
+		*out = make([]ImageRewrite, len(*in))
+		copy(*out, *in)
+	}
+	if in.PullSecrets != nil {
+		in, out := &in.PullSecrets, &out.PullSecrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageMirror.
+func (in *ImageMirror) DeepCopy() *ImageMirror {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageMirror)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageRewrite) DeepCopyInto(out *ImageRewrite) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageRewrite.
+func (in *ImageRewrite) DeepCopy() *ImageRewrite {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageRewrite)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Ingress) DeepCopyInto(out *Ingress) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Ingress.
+func (in *Ingress) DeepCopy() *Ingress {
+	if in == nil {
+		return nil
+	}
+	out := new(Ingress)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InstallationConfig) DeepCopyInto(out *InstallationConfig) {
 	*out = *in
@@ -136,6 +667,60 @@ func (in *InstallationConfig) DeepCopyInto(out *InstallationConfig) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.CFAdminGroups != nil {
+		in, out := &in.CFAdminGroups, &out.CFAdminGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Buildpacks != nil {
+		in, out := &in.Buildpacks, &out.Buildpacks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Stack != nil {
+		in, out := &in.Stack, &out.Stack
+		*out = new(BuildStack)
+		**out = **in
+	}
+	if in.BuildpackOrder != nil {
+		in, out := &in.BuildpackOrder, &out.BuildpackOrder
+		*out = make([]BuildpackGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BuildImageDigests != nil {
+		in, out := &in.BuildImageDigests, &out.BuildImageDigests
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ImageRewrites != nil {
+		in, out := &in.ImageRewrites, &out.ImageRewrites
+		*out = make([]ImageRewrite, len(*in))
+		copy(*out, *in)
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TrustedCA != nil {
+		in, out := &in.TrustedCA, &out.TrustedCA
+		*out = new(TrustedCA)
+		**out = **in
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(ProxyConfig)
+		**out = **in
+	}
+	if in.HighAvailability != nil {
+		in, out := &in.HighAvailability, &out.HighAvailability
+		*out = new(HighAvailability)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstallationConfig.
@@ -148,6 +733,172 @@ func (in *InstallationConfig) DeepCopy() *InstallationConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocalOIDC) DeepCopyInto(out *LocalOIDC) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocalOIDC.
+func (in *LocalOIDC) DeepCopy() *LocalOIDC {
+	if in == nil {
+		return nil
+	}
+	out := new(LocalOIDC)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocalProfile) DeepCopyInto(out *LocalProfile) {
+	*out = *in
+	if in.OIDC != nil {
+		in, out := &in.OIDC, &out.OIDC
+		*out = new(LocalOIDC)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocalProfile.
+func (in *LocalProfile) DeepCopy() *LocalProfile {
+	if in == nil {
+		return nil
+	}
+	out := new(LocalProfile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]MaintenanceDay, len(*in))
+		copy(*out, *in)
+	}
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespacedObjectReference) DeepCopyInto(out *NamespacedObjectReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespacedObjectReference.
+func (in *NamespacedObjectReference) DeepCopy() *NamespacedObjectReference {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespacedObjectReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Proxy) DeepCopyInto(out *Proxy) {
+	*out = *in
+	if in.NoProxy != nil {
+		in, out := &in.NoProxy, &out.NoProxy
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Proxy.
+func (in *Proxy) DeepCopy() *Proxy {
+	if in == nil {
+		return nil
+	}
+	out := new(Proxy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxyConfig) DeepCopyInto(out *ProxyConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxyConfig.
+func (in *ProxyConfig) DeepCopy() *ProxyConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxyConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryGarbageCollection) DeepCopyInto(out *RegistryGarbageCollection) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.GracePeriod != nil {
+		in, out := &in.GracePeriod, &out.GracePeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.KeepDroplets != nil {
+		in, out := &in.KeepDroplets, &out.KeepDroplets
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryGarbageCollection.
+func (in *RegistryGarbageCollection) DeepCopy() *RegistryGarbageCollection {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryGarbageCollection)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResolvedImage) DeepCopyInto(out *ResolvedImage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResolvedImage.
+func (in *ResolvedImage) DeepCopy() *ResolvedImage {
+	if in == nil {
+		return nil
+	}
+	out := new(ResolvedImage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RoleMapping) DeepCopyInto(out *RoleMapping) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RoleMapping.
+func (in *RoleMapping) DeepCopy() *RoleMapping {
+	if in == nil {
+		return nil
+	}
+	out := new(RoleMapping)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Status) DeepCopyInto(out *Status) {
 	*out = *in
@@ -162,3 +913,33 @@ func (in *Status) DeepCopy() *Status {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrustedCA) DeepCopyInto(out *TrustedCA) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrustedCA.
+func (in *TrustedCA) DeepCopy() *TrustedCA {
+	if in == nil {
+		return nil
+	}
+	out := new(TrustedCA)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrustedCABundle) DeepCopyInto(out *TrustedCABundle) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrustedCABundle.
+func (in *TrustedCABundle) DeepCopy() *TrustedCABundle {
+	if in == nil {
+		return nil
+	}
+	out := new(TrustedCABundle)
+	in.DeepCopyInto(out)
+	return out
+}
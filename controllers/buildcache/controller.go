@@ -0,0 +1,144 @@
+package buildcache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	korifiv1alpha1 "code.cloudfoundry.org/korifi/controllers/api/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/tools/k8s"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// korifiPendingInterval is how often the reconciler checks whether Korifi has
+// been installed
+const korifiPendingInterval = 30 * time.Second
+
+// Reconciler creates the kpack build cache volume of each app with the storage
+// class of `spec.build.cache`. Korifi does not pass a storage class to kpack,
+// and kpack keeps using an existing volume named `<appGUID>-cache`, so the
+// volume is created as soon as the app exists, ahead of its first build.
+//
+// The CFApp kind only exists once Korifi is installed, which is why apps are
+// watched lazily from the first reconcile after the installation on
+type Reconciler struct {
+	k8sClient client.Client
+	appWatch  *k8s.LazyWatch
+}
+
+func NewReconciler(
+	k8sClient client.Client,
+	informers cache.Informers,
+	log logr.Logger,
+) *k8s.PatchingReconciler[v1alpha1.CFAPI] {
+	r := &Reconciler{
+		k8sClient: k8sClient,
+	}
+	r.appWatch = k8s.NewLazyWatch(informers, &korifiv1alpha1.CFApp{}, r.enqueueCFAPIs)
+
+	return k8s.NewPatchingReconciler(log, k8sClient, r)
+}
+
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) *builder.Builder {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("buildcache").
+		For(&v1alpha1.CFAPI{}).
+		WatchesRawSource(r.appWatch.Source())
+}
+
+func (r *Reconciler) enqueueCFAPIs(ctx context.Context, _ client.Object) []reconcile.Request {
+	cfAPIs := &v1alpha1.CFAPIList{}
+	if err := r.k8sClient.List(ctx, cfAPIs); err != nil {
+		logr.FromContextOrDiscard(ctx).Error(err, "failed to list CFAPIs")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, cfAPI := range cfAPIs.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&cfAPI)})
+	}
+	return requests
+}
+
+func (r *Reconciler) ReconcileResource(ctx context.Context, cfAPI *v1alpha1.CFAPI) (ctrl.Result, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	config := cfAPI.Status.InstallationConfig
+	if config.BuildCacheStorageClassName == "" || !cfAPI.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.appWatch.Start(ctx); err != nil {
+		if meta.IsNoMatchError(err) {
+			log.Info("waiting for Korifi to be installed")
+			return ctrl.Result{RequeueAfter: korifiPendingInterval}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	apps := &korifiv1alpha1.CFAppList{}
+	if err := r.k8sClient.List(ctx, apps); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list apps: %w", err)
+	}
+
+	size, err := resource.ParseQuantity(fmt.Sprintf("%dMi", config.BuildCacheMB))
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("invalid build cache size: %w", err)
+	}
+
+	for _, app := range apps.Items {
+		if err := r.ensureBuildCache(ctx, &app, config.BuildCacheStorageClassName, size); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// ensureBuildCache creates the build cache volume of an app unless kpack or a
+// previous reconcile already created it. The volume is owned by the app, as
+// the kpack image of the app may be recreated by Korifi
+func (r *Reconciler) ensureBuildCache(ctx context.Context, app *korifiv1alpha1.CFApp, storageClassName string, size resource.Quantity) error {
+	buildCache := &corev1.PersistentVolumeClaim{}
+	err := r.k8sClient.Get(ctx, client.ObjectKey{Namespace: app.Namespace, Name: app.Name + "-cache"}, buildCache)
+	if err == nil || !k8serrors.IsNotFound(err) {
+		return client.IgnoreNotFound(err)
+	}
+
+	buildCache = &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: app.Namespace,
+			Name:      app.Name + "-cache",
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: size,
+				},
+			},
+			StorageClassName: &storageClassName,
+		},
+	}
+	if err := controllerutil.SetOwnerReference(app, buildCache, r.k8sClient.Scheme()); err != nil {
+		return fmt.Errorf("failed to set the owner of the build cache of app %s/%s: %w", app.Namespace, app.Name, err)
+	}
+
+	if err := r.k8sClient.Create(ctx, buildCache); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create the build cache of app %s/%s: %w", app.Namespace, app.Name, err)
+	}
+
+	return nil
+}
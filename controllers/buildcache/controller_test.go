@@ -0,0 +1,132 @@
+package buildcache_test
+
+import (
+	korifiv1alpha1 "code.cloudfoundry.org/korifi/controllers/api/v1alpha1"
+	"github.com/google/uuid"
+	v1alpha1 "github.com/kyma-project/cfapi/api/v1alpha1"
+	. "github.com/kyma-project/cfapi/tests/helpers"
+	"github.com/kyma-project/cfapi/tools"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Build Cache", func() {
+	var (
+		storageClassName string
+		spaceNamespace   string
+		existingAppGUID  string
+	)
+
+	createApp := func(appGUID string) {
+		EnsureCreate(adminClient, &korifiv1alpha1.CFApp{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: spaceNamespace,
+				Name:      appGUID,
+			},
+			Spec: korifiv1alpha1.CFAppSpec{
+				DisplayName:  "app-" + appGUID,
+				DesiredState: "STOPPED",
+				Lifecycle:    korifiv1alpha1.Lifecycle{Type: "buildpack"},
+			},
+		})
+	}
+
+	getBuildCache := func(g Gomega, appGUID string) *corev1.PersistentVolumeClaim {
+		buildCache := &corev1.PersistentVolumeClaim{}
+		g.Expect(adminClient.Get(ctx, client.ObjectKey{Namespace: spaceNamespace, Name: appGUID + "-cache"}, buildCache)).To(Succeed())
+		return buildCache
+	}
+
+	BeforeEach(func() {
+		storageClassName = "fast-storage"
+
+		spaceNamespace = uuid.NewString()
+		EnsureCreate(adminClient, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: spaceNamespace},
+		})
+
+		existingAppGUID = uuid.NewString()
+		createApp(existingAppGUID)
+	})
+
+	JustBeforeEach(func() {
+		cfAPI := &v1alpha1.CFAPI{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      uuid.NewString(),
+				Namespace: cfAPINamespace,
+			},
+		}
+		EnsureCreate(adminClient, cfAPI)
+
+		cfAPI.Status.InstallationConfig = v1alpha1.InstallationConfig{
+			RootNamespace:              "cf",
+			BuildCacheMB:               4096,
+			BuildCacheStorageClassName: storageClassName,
+		}
+		Expect(adminClient.Status().Update(ctx, cfAPI)).To(Succeed())
+	})
+
+	It("creates the build cache of existing apps with the storage class", func() {
+		Eventually(func(g Gomega) {
+			buildCache := getBuildCache(g, existingAppGUID)
+			g.Expect(buildCache.Spec.StorageClassName).To(Equal(tools.PtrTo("fast-storage")))
+			g.Expect(buildCache.Spec.AccessModes).To(ConsistOf(corev1.ReadWriteOnce))
+			g.Expect(buildCache.Spec.Resources.Requests).To(HaveKeyWithValue(corev1.ResourceStorage, resource.MustParse("4096Mi")))
+			g.Expect(buildCache.OwnerReferences).To(ConsistOf(HaveField("Name", existingAppGUID)))
+		}).Should(Succeed())
+	})
+
+	It("creates the build cache of new apps", func() {
+		Eventually(func(g Gomega) {
+			getBuildCache(g, existingAppGUID)
+		}).Should(Succeed())
+
+		newAppGUID := uuid.NewString()
+		createApp(newAppGUID)
+
+		Eventually(func(g Gomega) {
+			g.Expect(getBuildCache(g, newAppGUID).Spec.StorageClassName).To(Equal(tools.PtrTo("fast-storage")))
+		}).Should(Succeed())
+	})
+
+	When("the build cache already exists", func() {
+		BeforeEach(func() {
+			EnsureCreate(adminClient, &corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: spaceNamespace,
+					Name:      existingAppGUID + "-cache",
+				},
+				Spec: corev1.PersistentVolumeClaimSpec{
+					AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+					Resources: corev1.VolumeResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("2Gi")},
+					},
+					StorageClassName: tools.PtrTo("standard"),
+				},
+			})
+		})
+
+		It("keeps it", func() {
+			Consistently(func(g Gomega) {
+				g.Expect(getBuildCache(g, existingAppGUID).Spec.StorageClassName).To(Equal(tools.PtrTo("standard")))
+			}).Should(Succeed())
+		})
+	})
+
+	When("no storage class is configured", func() {
+		BeforeEach(func() {
+			storageClassName = ""
+		})
+
+		It("leaves creating the build cache to kpack", func() {
+			Consistently(func(g Gomega) {
+				err := adminClient.Get(ctx, client.ObjectKey{Namespace: spaceNamespace, Name: existingAppGUID + "-cache"}, &corev1.PersistentVolumeClaim{})
+				g.Expect(err).To(MatchError(ContainSubstring("not found")))
+			}).Should(Succeed())
+		})
+	})
+})
@@ -0,0 +1,228 @@
+package buildupdates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/cfapi/secrets"
+	"github.com/kyma-project/cfapi/controllers/imagemirror"
+	"github.com/kyma-project/cfapi/controllers/installable"
+	"github.com/kyma-project/cfapi/tools/k8s"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const DefaultInterval = 24 * time.Hour
+
+// Reconciler resolves the tags of the buildpack and stack images of the
+// builder to digests periodically and records them in `status.build`. The
+// CFAPI controller pins the images of the builder to the recorded digests, so
+// that kpack rebuilds the builder whenever a tag moves. kpack itself resolves
+// tags only once, when the cluster store or stack is created.
+//
+// The first resolution is done right away, as it only records the images in
+// use. Later updates are restricted to the maintenance window, as are the
+// rebases of the droplets onto an updated run image
+type Reconciler struct {
+	k8sClient     client.Client
+	eventRecorder events.EventRecorder
+	docker        *secrets.Docker
+	registry      *secrets.RegistryClient
+	rebaser       *Rebaser
+}
+
+func NewReconciler(
+	k8sClient client.Client,
+	eventRecorder events.EventRecorder,
+	docker *secrets.Docker,
+	registry *secrets.RegistryClient,
+	log logr.Logger,
+) *k8s.PatchingReconciler[v1alpha1.CFAPI] {
+	return k8s.NewPatchingReconciler(log, k8sClient, &Reconciler{
+		k8sClient:     k8sClient,
+		eventRecorder: eventRecorder,
+		docker:        docker,
+		registry:      registry,
+		rebaser:       NewRebaser(k8sClient),
+	})
+}
+
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) *builder.Builder {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("buildupdates").
+		For(&v1alpha1.CFAPI{})
+}
+
+func (r *Reconciler) ReconcileResource(ctx context.Context, cfAPI *v1alpha1.CFAPI) (ctrl.Result, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	config := cfAPI.Status.InstallationConfig
+	if cfAPI.Spec.Build == nil || cfAPI.Spec.Build.Updates == nil || !cfAPI.Spec.Build.Updates.Enabled ||
+		config.RootNamespace == "" || config.Stack == nil || !cfAPI.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+	updates := cfAPI.Spec.Build.Updates
+
+	window, err := NewWindow(updates.MaintenanceWindow)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	interval := DefaultInterval
+	if updates.Interval != nil {
+		interval = updates.Interval.Duration
+	}
+
+	if cfAPI.Status.Build == nil {
+		cfAPI.Status.Build = &v1alpha1.BuildStatus{}
+	}
+	status := cfAPI.Status.Build
+	eventRecorder := installable.NewCFAPIEventRecorder(r.eventRecorder, cfAPI)
+
+	now := time.Now()
+	nextCheck := now
+	if status.LastCheckTime != nil && !hasUnresolvedImages(status, config) {
+		nextCheck = window.Next(status.LastCheckTime.Add(interval))
+	}
+
+	if !now.Before(nextCheck) {
+		log.Info("resolving build images")
+		if err := r.resolveImages(ctx, cfAPI, now); err != nil {
+			eventRecorder.Event(installable.EventWarning, "BuildImageResolutionFailed", err.Error())
+			return ctrl.Result{}, fmt.Errorf("failed to resolve the build images: %w", err)
+		}
+		nextCheck = window.Next(now.Add(interval))
+	}
+
+	requeueAfter := time.Until(nextCheck)
+	if !updates.RebaseDroplets || status.RunImageUpdateTime == nil {
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+
+	if !window.Contains(now) {
+		return ctrl.Result{RequeueAfter: min(requeueAfter, time.Until(window.Next(now)))}, nil
+	}
+
+	result, err := r.rebaser.Rebase(ctx, config, status.RunImageUpdateTime.Time, runImageDigest(status, config))
+	if err != nil {
+		eventRecorder.Event(installable.EventWarning, "DropletRebaseFailed", err.Error())
+		return ctrl.Result{}, fmt.Errorf("failed to rebase droplets: %w", err)
+	}
+	if len(result.Deployed) > 0 {
+		eventRecorder.Event(installable.EventNormal, "DropletsRebased", fmt.Sprintf("Deployed droplets rebased onto run image %s for apps %s", config.Stack.RunImage, strings.Join(result.Deployed, ", ")))
+	}
+	if len(result.Failed) > 0 {
+		eventRecorder.Event(installable.EventWarning, "DropletRebaseFailed", fmt.Sprintf("Rebasing the droplets of apps %s failed", strings.Join(result.Failed, ", ")))
+	}
+	if result.Pending {
+		return ctrl.Result{RequeueAfter: min(requeueAfter, RebasePollInterval)}, nil
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// resolveImages resolves the images of the builder and records their digests.
+// Images which fail to resolve keep the digest they were resolved to before.
+// With an image mirror the images are resolved in the mirror, which is where
+// kpack pulls them from, and recorded under their original reference
+func (r *Reconciler) resolveImages(ctx context.Context, cfAPI *v1alpha1.CFAPI, now time.Time) error {
+	config := cfAPI.Status.InstallationConfig
+	status := cfAPI.Status.Build
+
+	registryConfig, err := r.registryConfig(ctx, cfAPI.Namespace, config)
+	if err != nil {
+		return err
+	}
+	mirror := imagemirror.NewMirror(config)
+
+	previous := digestsOf(status)
+	resolved := []v1alpha1.ResolvedImage{}
+	updated := []string{}
+	errs := []error{}
+	for _, image := range builderImages(config) {
+		mirroredImage, _ := mirror.RewriteImage(image)
+		digest, err := r.registry.ResolveDigest(ctx, registryConfig, mirroredImage)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to resolve image %s: %w", image, err))
+			digest = previous[image]
+		}
+		if digest == "" {
+			continue
+		}
+
+		resolved = append(resolved, v1alpha1.ResolvedImage{Image: image, Digest: digest})
+		if previousDigest, ok := previous[image]; ok && previousDigest != digest {
+			updated = append(updated, fmt.Sprintf("%s@%s", image, digest))
+			if image == config.Stack.RunImage {
+				status.RunImageUpdateTime = &metav1.Time{Time: now}
+			}
+		}
+	}
+
+	status.Images = resolved
+	status.LastCheckTime = &metav1.Time{Time: now}
+	if len(updated) > 0 {
+		status.LastUpdateTime = &metav1.Time{Time: now}
+		installable.NewCFAPIEventRecorder(r.eventRecorder, cfAPI).Event(installable.EventNormal, "BuildImagesUpdated", "Updated build images "+strings.Join(updated, ", "))
+	}
+
+	return errors.Join(errs...)
+}
+
+// registryConfig returns the credentials of the builder registry and of the
+// image mirror
+func (r *Reconciler) registryConfig(ctx context.Context, namespace string, config v1alpha1.InstallationConfig) (secrets.DockerRegistryConfig, error) {
+	registryConfig := secrets.DockerRegistryConfig{Auths: map[string]secrets.DockerRegistryAuth{}}
+	for _, secretName := range append(slices.Clone(config.ImagePullSecrets), config.BuilderRegistrySecret) {
+		if secretName == "" {
+			continue
+		}
+
+		secretConfig, err := r.docker.GetRegistryConfig(ctx, namespace, secretName)
+		if err != nil {
+			return secrets.DockerRegistryConfig{}, err
+		}
+		maps.Copy(registryConfig.Auths, secretConfig.Auths)
+	}
+
+	return registryConfig, nil
+}
+
+// builderImages returns the buildpack and stack images of the builder
+func builderImages(config v1alpha1.InstallationConfig) []string {
+	return append(slices.Clone(config.Buildpacks), config.Stack.BuildImage, config.Stack.RunImage)
+}
+
+func hasUnresolvedImages(status *v1alpha1.BuildStatus, config v1alpha1.InstallationConfig) bool {
+	digests := digestsOf(status)
+	for _, image := range builderImages(config) {
+		if _, ok := digests[image]; !ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func runImageDigest(status *v1alpha1.BuildStatus, config v1alpha1.InstallationConfig) string {
+	return digestsOf(status)[config.Stack.RunImage]
+}
+
+func digestsOf(status *v1alpha1.BuildStatus) map[string]string {
+	digests := map[string]string{}
+	for _, image := range status.Images {
+		digests[image.Image] = image.Digest
+	}
+
+	return digests
+}
@@ -0,0 +1,326 @@
+package buildupdates_test
+
+import (
+	"time"
+
+	korifiv1alpha1 "code.cloudfoundry.org/korifi/controllers/api/v1alpha1"
+	"github.com/google/uuid"
+	v1alpha1 "github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/buildupdates"
+	. "github.com/kyma-project/cfapi/tests/helpers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	buildv1alpha2 "github.com/pivotal/kpack/pkg/apis/build/v1alpha2"
+	corev1alpha1 "github.com/pivotal/kpack/pkg/apis/core/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Build Updates", func() {
+	var (
+		cfAPI         *v1alpha1.CFAPI
+		updates       *v1alpha1.BuildUpdates
+		buildStatus   *v1alpha1.BuildStatus
+		imageRewrites []v1alpha1.ImageRewrite
+	)
+
+	eventReasons := func(g Gomega) []string {
+		eventList := &eventsv1.EventList{}
+		g.Expect(adminClient.List(ctx, eventList, client.InNamespace(cfAPINamespace))).To(Succeed())
+
+		reasons := []string{}
+		for _, event := range eventList.Items {
+			reasons = append(reasons, event.Reason)
+		}
+		return reasons
+	}
+
+	getBuildStatus := func(g Gomega) *v1alpha1.BuildStatus {
+		g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+		g.Expect(cfAPI.Status.Build).NotTo(BeNil())
+		return cfAPI.Status.Build
+	}
+
+	BeforeEach(func() {
+		registry.AddImage("buildpacks/java", "latest", "sha256:java")
+		registry.AddImage("stacks/build", "jammy", "sha256:build")
+		registry.AddImage("stacks/run", "jammy", "sha256:run")
+
+		updates = &v1alpha1.BuildUpdates{Enabled: true}
+		buildStatus = nil
+		imageRewrites = nil
+	})
+
+	JustBeforeEach(func() {
+		cfAPI = &v1alpha1.CFAPI{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      uuid.NewString(),
+				Namespace: cfAPINamespace,
+			},
+			Spec: v1alpha1.CFAPISpec{
+				Build: &v1alpha1.Build{Updates: updates},
+			},
+		}
+		EnsureCreate(adminClient, cfAPI)
+
+		cfAPI.Status.InstallationConfig = v1alpha1.InstallationConfig{
+			RootNamespace:      "cf",
+			ClusterBuilderName: "cfapi-cluster-builder",
+			Buildpacks:         []string{"my-registry.com/buildpacks/java"},
+			Stack: &v1alpha1.BuildStack{
+				ID:         "io.buildpacks.stacks.jammy",
+				BuildImage: "my-registry.com/stacks/build:jammy",
+				RunImage:   "my-registry.com/stacks/run:jammy",
+			},
+			ImageRewrites: imageRewrites,
+		}
+		cfAPI.Status.Build = buildStatus
+		Expect(adminClient.Status().Update(ctx, cfAPI)).To(Succeed())
+	})
+
+	It("records the digests of the build images", func() {
+		Eventually(func(g Gomega) {
+			status := getBuildStatus(g)
+			g.Expect(status.Images).To(ConsistOf(
+				v1alpha1.ResolvedImage{Image: "my-registry.com/buildpacks/java", Digest: "sha256:java"},
+				v1alpha1.ResolvedImage{Image: "my-registry.com/stacks/build:jammy", Digest: "sha256:build"},
+				v1alpha1.ResolvedImage{Image: "my-registry.com/stacks/run:jammy", Digest: "sha256:run"},
+			))
+			g.Expect(status.LastCheckTime).NotTo(BeNil())
+			g.Expect(status.LastUpdateTime).To(BeNil())
+			g.Expect(status.RunImageUpdateTime).To(BeNil())
+		}).Should(Succeed())
+	})
+
+	When("an image mirror is configured", func() {
+		BeforeEach(func() {
+			registry.AddImage("mirror/stacks/run", "jammy", "sha256:mirrored-run")
+			imageRewrites = []v1alpha1.ImageRewrite{{From: "my-registry.com/stacks/run", To: "my-registry.com/mirror/stacks/run"}}
+		})
+
+		It("resolves the images in the mirror", func() {
+			Eventually(func(g Gomega) {
+				status := getBuildStatus(g)
+				g.Expect(status.Images).To(ContainElement(v1alpha1.ResolvedImage{Image: "my-registry.com/stacks/run:jammy", Digest: "sha256:mirrored-run"}))
+			}).Should(Succeed())
+		})
+	})
+
+	When("the tags have moved since the last check", func() {
+		BeforeEach(func() {
+			buildStatus = &v1alpha1.BuildStatus{
+				Images: []v1alpha1.ResolvedImage{
+					{Image: "my-registry.com/buildpacks/java", Digest: "sha256:java"},
+					{Image: "my-registry.com/stacks/build:jammy", Digest: "sha256:build"},
+					{Image: "my-registry.com/stacks/run:jammy", Digest: "sha256:old-run"},
+				},
+				LastCheckTime: &metav1.Time{Time: time.Now().Add(-25 * time.Hour)},
+			}
+		})
+
+		It("updates the digests", func() {
+			Eventually(func(g Gomega) {
+				status := getBuildStatus(g)
+				g.Expect(status.Images).To(ContainElement(v1alpha1.ResolvedImage{Image: "my-registry.com/stacks/run:jammy", Digest: "sha256:run"}))
+				g.Expect(status.LastUpdateTime).NotTo(BeNil())
+				g.Expect(status.RunImageUpdateTime).NotTo(BeNil())
+				g.Expect(eventReasons(g)).To(ContainElement("BuildImagesUpdated"))
+			}).Should(Succeed())
+		})
+
+		When("the maintenance window is closed", func() {
+			BeforeEach(func() {
+				closedAt := time.Now().UTC().Add(-12 * time.Hour)
+				updates.MaintenanceWindow = &v1alpha1.MaintenanceWindow{
+					Start:    closedAt.Format("15:04"),
+					Duration: metav1.Duration{Duration: time.Hour},
+				}
+			})
+
+			It("does not update the digests", func() {
+				Consistently(func(g Gomega) {
+					status := getBuildStatus(g)
+					g.Expect(status.Images).To(ContainElement(v1alpha1.ResolvedImage{Image: "my-registry.com/stacks/run:jammy", Digest: "sha256:old-run"}))
+					g.Expect(status.LastUpdateTime).To(BeNil())
+				}).Should(Succeed())
+			})
+		})
+	})
+
+	When("a tag cannot be resolved", func() {
+		JustBeforeEach(func() {
+			Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+			cfAPI.Status.InstallationConfig.Buildpacks = append(cfAPI.Status.InstallationConfig.Buildpacks, "my-registry.com/buildpacks/unknown")
+			Expect(adminClient.Status().Update(ctx, cfAPI)).To(Succeed())
+		})
+
+		It("records the other images and reports the failure", func() {
+			Eventually(func(g Gomega) {
+				status := getBuildStatus(g)
+				g.Expect(status.Images).To(HaveLen(3))
+				g.Expect(eventReasons(g)).To(ContainElement("BuildImageResolutionFailed"))
+			}).Should(Succeed())
+		})
+	})
+
+	When("droplets are rebased", func() {
+		var (
+			spaceNamespace string
+			app            *korifiv1alpha1.CFApp
+			currentBuild   *korifiv1alpha1.CFBuild
+		)
+
+		getRebaseBuild := func(g Gomega) *korifiv1alpha1.CFBuild {
+			builds := &korifiv1alpha1.CFBuildList{}
+			g.Expect(adminClient.List(ctx, builds,
+				client.InNamespace(spaceNamespace),
+				client.MatchingLabels{buildupdates.RebasedDropletLabelKey: currentBuild.Name},
+			)).To(Succeed())
+			g.Expect(builds.Items).To(HaveLen(1))
+			return &builds.Items[0]
+		}
+
+		BeforeEach(func() {
+			updates.RebaseDroplets = true
+
+			spaceNamespace = uuid.NewString()
+			EnsureCreate(adminClient, &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: spaceNamespace},
+			})
+
+			appGUID := uuid.NewString()
+			currentBuild = &korifiv1alpha1.CFBuild{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: spaceNamespace,
+					Name:      uuid.NewString(),
+					Labels:    map[string]string{korifiv1alpha1.CFAppGUIDLabelKey: appGUID},
+				},
+				Spec: korifiv1alpha1.CFBuildSpec{
+					PackageRef:      corev1.LocalObjectReference{Name: "my-package"},
+					AppRef:          corev1.LocalObjectReference{Name: appGUID},
+					StagingMemoryMB: 1024,
+					Lifecycle:       korifiv1alpha1.Lifecycle{Type: "buildpack"},
+				},
+			}
+			EnsureCreate(adminClient, currentBuild)
+
+			app = &korifiv1alpha1.CFApp{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   spaceNamespace,
+					Name:        appGUID,
+					Annotations: map[string]string{korifiv1alpha1.CFAppRevisionKey: "3"},
+				},
+				Spec: korifiv1alpha1.CFAppSpec{
+					DisplayName:       "my-app",
+					DesiredState:      "STARTED",
+					Lifecycle:         korifiv1alpha1.Lifecycle{Type: "buildpack"},
+					CurrentDropletRef: corev1.LocalObjectReference{Name: currentBuild.Name},
+				},
+			}
+			EnsureCreate(adminClient, app)
+
+			buildStatus = &v1alpha1.BuildStatus{
+				Images: []v1alpha1.ResolvedImage{
+					{Image: "my-registry.com/buildpacks/java", Digest: "sha256:java"},
+					{Image: "my-registry.com/stacks/build:jammy", Digest: "sha256:build"},
+					{Image: "my-registry.com/stacks/run:jammy", Digest: "sha256:run"},
+				},
+				LastCheckTime:      &metav1.Time{Time: time.Now()},
+				RunImageUpdateTime: &metav1.Time{Time: currentBuild.CreationTimestamp.Add(time.Second)},
+			}
+
+			clusterBuilder := &buildv1alpha2.ClusterBuilder{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "cfapi-cluster-builder",
+				},
+				Spec: buildv1alpha2.ClusterBuilderSpec{
+					BuilderSpec: buildv1alpha2.BuilderSpec{
+						Tag: "my-registry.com/cfapi/kpack-builder",
+					},
+				},
+			}
+			EnsureCreate(adminClient, clusterBuilder)
+			DeferCleanup(func() {
+				EnsureDelete(adminClient, clusterBuilder)
+			})
+
+			clusterBuilder.Status.ObservedGeneration = clusterBuilder.Generation
+			clusterBuilder.Status.Stack.RunImage = "my-registry.com/stacks/run@sha256:run"
+			clusterBuilder.Status.Conditions = corev1alpha1.Conditions{{
+				Type:   corev1alpha1.ConditionReady,
+				Status: corev1.ConditionTrue,
+			}}
+			Expect(adminClient.Status().Update(ctx, clusterBuilder)).To(Succeed())
+		})
+
+		It("restages the app onto the updated run image", func() {
+			Eventually(func(g Gomega) {
+				rebaseBuild := getRebaseBuild(g)
+				g.Expect(rebaseBuild.Spec).To(Equal(currentBuild.Spec))
+				g.Expect(rebaseBuild.Labels).To(HaveKeyWithValue(korifiv1alpha1.CFAppGUIDLabelKey, app.Name))
+			}).Should(Succeed())
+		})
+
+		When("the rebase build succeeds", func() {
+			var rebaseBuild *korifiv1alpha1.CFBuild
+
+			JustBeforeEach(func() {
+				Eventually(func(g Gomega) {
+					rebaseBuild = getRebaseBuild(g)
+				}).Should(Succeed())
+
+				meta.SetStatusCondition(&rebaseBuild.Status.Conditions, metav1.Condition{
+					Type:   korifiv1alpha1.SucceededConditionType,
+					Status: metav1.ConditionTrue,
+					Reason: "BuildSucceeded",
+				})
+				Expect(adminClient.Status().Update(ctx, rebaseBuild)).To(Succeed())
+			})
+
+			It("deploys the rebased droplet", func() {
+				Eventually(func(g Gomega) {
+					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(app), app)).To(Succeed())
+					g.Expect(app.Spec.CurrentDropletRef.Name).To(Equal(rebaseBuild.Name))
+					g.Expect(app.Annotations).To(HaveKeyWithValue(korifiv1alpha1.CFAppRevisionKey, "4"))
+					g.Expect(eventReasons(g)).To(ContainElement("DropletsRebased"))
+				}).Should(Succeed())
+			})
+		})
+
+		When("the builder has not been rebuilt with the run image yet", func() {
+			BeforeEach(func() {
+				clusterBuilder := &buildv1alpha2.ClusterBuilder{}
+				Expect(adminClient.Get(ctx, client.ObjectKey{Name: "cfapi-cluster-builder"}, clusterBuilder)).To(Succeed())
+				clusterBuilder.Status.Stack.RunImage = "my-registry.com/stacks/run@sha256:old-run"
+				Expect(adminClient.Status().Update(ctx, clusterBuilder)).To(Succeed())
+			})
+
+			It("does not restage the app", func() {
+				Consistently(func(g Gomega) {
+					builds := &korifiv1alpha1.CFBuildList{}
+					g.Expect(adminClient.List(ctx, builds, client.InNamespace(spaceNamespace))).To(Succeed())
+					g.Expect(builds.Items).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+						"ObjectMeta": MatchFields(IgnoreExtras, Fields{"Name": Equal(currentBuild.Name)}),
+					})))
+				}).Should(Succeed())
+			})
+		})
+	})
+
+	When("updates are disabled", func() {
+		BeforeEach(func() {
+			updates.Enabled = false
+		})
+
+		It("does not resolve the images", func() {
+			Consistently(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.Build).To(BeNil())
+			}).Should(Succeed())
+		})
+	})
+})
@@ -0,0 +1,202 @@
+package buildupdates
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	korifiv1alpha1 "code.cloudfoundry.org/korifi/controllers/api/v1alpha1"
+	"github.com/google/uuid"
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/tools/k8s"
+	buildv1alpha2 "github.com/pivotal/kpack/pkg/apis/build/v1alpha2"
+	corev1alpha1 "github.com/pivotal/kpack/pkg/apis/core/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// RebasedDropletLabelKey marks the builds restaging an app onto an
+	// updated run image with the name of the build of the droplet they replace
+	RebasedDropletLabelKey = "cfapi.kyma-project.io/rebased-droplet"
+
+	// RebasePollInterval is how often the rebase builds are checked
+	RebasePollInterval = 30 * time.Second
+
+	// maxConcurrentRebases limits the rebase builds running at the same time,
+	// so that a run image update does not stage every app at once
+	maxConcurrentRebases = 5
+)
+
+type RebaseResult struct {
+	// Pending is set while the builder or rebase builds are not done yet
+	Pending bool
+	// Deployed are the apps whose rebased droplet has been deployed
+	Deployed []string
+	// Failed are the apps whose rebase build failed. They keep their droplet
+	Failed []string
+}
+
+// Rebaser restages the buildpack apps whose droplet was staged before the run
+// image was updated and deploys the new droplets with a rolling restart. The
+// apps are restaged from their current package, as Korifi does not support
+// rebasing droplets in place
+type Rebaser struct {
+	k8sClient client.Client
+}
+
+func NewRebaser(k8sClient client.Client) *Rebaser {
+	return &Rebaser{
+		k8sClient: k8sClient,
+	}
+}
+
+func (r *Rebaser) Rebase(ctx context.Context, config v1alpha1.InstallationConfig, runImageUpdateTime time.Time, runImageDigest string) (RebaseResult, error) {
+	builderReady, err := r.builderHasRunImage(ctx, config.ClusterBuilderName, runImageDigest)
+	if err != nil || !builderReady {
+		return RebaseResult{Pending: true}, err
+	}
+
+	apps := &korifiv1alpha1.CFAppList{}
+	if err := r.k8sClient.List(ctx, apps); err != nil {
+		return RebaseResult{}, fmt.Errorf("failed to list apps: %w", err)
+	}
+
+	result := RebaseResult{}
+	running := 0
+	outdated := []*korifiv1alpha1.CFBuild{}
+	for _, app := range apps.Items {
+		if app.Spec.Lifecycle.Type != korifiv1alpha1.BuildpackLifecycle || app.Spec.CurrentDropletRef.Name == "" {
+			continue
+		}
+
+		currentBuild := &korifiv1alpha1.CFBuild{}
+		err := r.k8sClient.Get(ctx, client.ObjectKey{Namespace: app.Namespace, Name: app.Spec.CurrentDropletRef.Name}, currentBuild)
+		if err != nil {
+			if client.IgnoreNotFound(err) == nil {
+				continue
+			}
+			return RebaseResult{}, fmt.Errorf("failed to get the build of app %s/%s: %w", app.Namespace, app.Name, err)
+		}
+		if !currentBuild.CreationTimestamp.Time.Before(runImageUpdateTime) {
+			continue
+		}
+
+		rebaseBuild, err := r.rebaseBuild(ctx, currentBuild)
+		if err != nil {
+			return RebaseResult{}, err
+		}
+		if rebaseBuild == nil {
+			outdated = append(outdated, currentBuild)
+			continue
+		}
+
+		succeeded := meta.FindStatusCondition(rebaseBuild.Status.Conditions, korifiv1alpha1.SucceededConditionType)
+		switch {
+		case succeeded == nil || succeeded.Status == metav1.ConditionUnknown:
+			running++
+			result.Pending = true
+		case succeeded.Status == metav1.ConditionFalse:
+			result.Failed = append(result.Failed, app.Spec.DisplayName)
+		default:
+			if err := r.deploy(ctx, &app, rebaseBuild); err != nil {
+				return RebaseResult{}, err
+			}
+			result.Deployed = append(result.Deployed, app.Spec.DisplayName)
+		}
+	}
+
+	for _, currentBuild := range outdated {
+		result.Pending = true
+		if running >= maxConcurrentRebases {
+			break
+		}
+
+		if err := r.createRebaseBuild(ctx, currentBuild); err != nil {
+			return RebaseResult{}, err
+		}
+		running++
+	}
+
+	return result, nil
+}
+
+// builderHasRunImage returns whether kpack has rebuilt the builder with the
+// updated run image, so that rebase builds do not stage onto the old one
+func (r *Rebaser) builderHasRunImage(ctx context.Context, builderName, runImageDigest string) (bool, error) {
+	clusterBuilder := &buildv1alpha2.ClusterBuilder{}
+	if err := r.k8sClient.Get(ctx, client.ObjectKey{Name: builderName}, clusterBuilder); err != nil {
+		if client.IgnoreNotFound(err) == nil || meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get cluster builder %s: %w", builderName, err)
+	}
+
+	return clusterBuilder.Status.GetCondition(corev1alpha1.ConditionReady).IsTrue() &&
+		clusterBuilder.Status.ObservedGeneration >= clusterBuilder.Generation &&
+		strings.HasSuffix(clusterBuilder.Status.Stack.RunImage, "@"+runImageDigest), nil
+}
+
+// rebaseBuild returns the build restaging the droplet of the given build, if
+// it has been created
+func (r *Rebaser) rebaseBuild(ctx context.Context, currentBuild *korifiv1alpha1.CFBuild) (*korifiv1alpha1.CFBuild, error) {
+	builds := &korifiv1alpha1.CFBuildList{}
+	err := r.k8sClient.List(ctx, builds, client.InNamespace(currentBuild.Namespace), client.MatchingLabels{RebasedDropletLabelKey: currentBuild.Name})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the rebase builds of build %s/%s: %w", currentBuild.Namespace, currentBuild.Name, err)
+	}
+	if len(builds.Items) == 0 {
+		return nil, nil
+	}
+
+	return &builds.Items[0], nil
+}
+
+func (r *Rebaser) createRebaseBuild(ctx context.Context, currentBuild *korifiv1alpha1.CFBuild) error {
+	labels := map[string]string{}
+	for key, value := range currentBuild.Labels {
+		labels[key] = value
+	}
+	labels[RebasedDropletLabelKey] = currentBuild.Name
+
+	rebaseBuild := &korifiv1alpha1.CFBuild{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       currentBuild.Namespace,
+			Name:            uuid.NewString(),
+			Labels:          labels,
+			OwnerReferences: currentBuild.OwnerReferences,
+		},
+		Spec: currentBuild.Spec,
+	}
+	if err := r.k8sClient.Create(ctx, rebaseBuild); err != nil {
+		return fmt.Errorf("failed to create the rebase build of build %s/%s: %w", currentBuild.Namespace, currentBuild.Name, err)
+	}
+
+	return nil
+}
+
+// deploy switches the app to the rebased droplet and bumps its revision, which
+// makes Korifi roll out the app instances one by one
+func (r *Rebaser) deploy(ctx context.Context, app *korifiv1alpha1.CFApp, rebaseBuild *korifiv1alpha1.CFBuild) error {
+	revision, err := strconv.Atoi(app.Annotations[korifiv1alpha1.CFAppRevisionKey])
+	if err != nil {
+		revision = 0
+	}
+
+	err = k8s.PatchResource(ctx, r.k8sClient, app, func() {
+		app.Spec.CurrentDropletRef = corev1.LocalObjectReference{Name: rebaseBuild.Name}
+		if app.Annotations == nil {
+			app.Annotations = map[string]string{}
+		}
+		app.Annotations[korifiv1alpha1.CFAppRevisionKey] = strconv.Itoa(revision + 1)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to deploy the rebased droplet of app %s/%s: %w", app.Namespace, app.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package buildupdates_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	korifiv1alpha1 "code.cloudfoundry.org/korifi/controllers/api/v1alpha1"
+	"github.com/google/uuid"
+	v1alpha1 "github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/buildupdates"
+	"github.com/kyma-project/cfapi/controllers/cfapi/secrets"
+	"github.com/kyma-project/cfapi/tests/helpers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	buildv1alpha2 "github.com/pivotal/kpack/pkg/apis/build/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	//+kubebuilder:scaffold:imports
+)
+
+var (
+	stopManager     context.CancelFunc
+	stopClientCache context.CancelFunc
+	testEnv         *envtest.Environment
+	k8sManager      manager.Manager
+	adminClient     client.Client
+	ctx             context.Context
+	cfAPINamespace  string
+	registry        *helpers.ImageRegistryStandIn
+)
+
+func TestBuildUpdatesController(t *testing.T) {
+	SetDefaultEventuallyTimeout(10 * time.Second)
+	SetDefaultEventuallyPollingInterval(250 * time.Millisecond)
+
+	SetDefaultConsistentlyDuration(5 * time.Second)
+	SetDefaultConsistentlyPollingInterval(250 * time.Millisecond)
+
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Build Updates Controller Suite")
+}
+
+var _ = BeforeEach(func() {
+	ctx = context.Background()
+
+	logf.SetLogger(zap.New(zap.WriteTo(GinkgoWriter), zap.UseDevMode(true)))
+
+	testEnv = &envtest.Environment{
+		CRDDirectoryPaths: []string{
+			filepath.Join("..", "..", "config", "crd", "bases"),
+			filepath.Join("..", "..", "module-data", "vendor", "korifi-chart", "controllers", "crds"),
+			filepath.Join("..", "..", "module-data", "vendor", "kpack", "release-0.17.1.yaml"),
+		},
+		ErrorIfCRDPathMissing: true,
+	}
+
+	_, err := testEnv.Start()
+	Expect(err).NotTo(HaveOccurred())
+
+	Expect(v1alpha1.AddToScheme(scheme.Scheme)).To(Succeed())
+	Expect(korifiv1alpha1.AddToScheme(scheme.Scheme)).To(Succeed())
+	Expect(buildv1alpha2.AddToScheme(scheme.Scheme)).To(Succeed())
+
+	k8sManager = helpers.NewK8sManager(testEnv, filepath.Join("config", "rbac", "role.yaml"))
+
+	adminClient, stopClientCache = helpers.NewCachedClient(testEnv.Config)
+
+	cfAPINamespace = uuid.NewString()
+	helpers.EnsureCreate(adminClient, &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: cfAPINamespace,
+		},
+	})
+
+	registry = helpers.NewImageRegistryStandIn()
+
+	err = buildupdates.NewReconciler(
+		k8sManager.GetClient(),
+		k8sManager.GetEventRecorder("buildupdates"),
+		secrets.NewDocker(k8sManager.GetClient()),
+		secrets.NewRegistryClient(helpers.NewStandInHTTPClient(registry.Server)),
+		ctrl.Log.WithName("controllers").WithName("buildupdates"),
+	).SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
+	stopManager = helpers.StartK8sManager(k8sManager)
+})
+
+var _ = AfterEach(func() {
+	stopManager()
+	registry.Close()
+	stopClientCache()
+	Expect(testEnv.Stop()).To(Succeed())
+})
@@ -0,0 +1,98 @@
+package buildupdates
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+)
+
+// Window is a recurring maintenance window in UTC. The zero window is always
+// open
+type Window struct {
+	days     []time.Weekday
+	start    time.Duration
+	duration time.Duration
+}
+
+func NewWindow(maintenanceWindow *v1alpha1.MaintenanceWindow) (Window, error) {
+	if maintenanceWindow == nil {
+		return Window{}, nil
+	}
+
+	start, err := time.Parse("15:04", maintenanceWindow.Start)
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid maintenance window start %q: %w", maintenanceWindow.Start, err)
+	}
+	if maintenanceWindow.Duration.Duration <= 0 {
+		return Window{}, fmt.Errorf("invalid maintenance window duration %s", maintenanceWindow.Duration.Duration)
+	}
+
+	days := []time.Weekday{}
+	for _, day := range maintenanceWindow.Days {
+		weekday, ok := weekdays[day]
+		if !ok {
+			return Window{}, fmt.Errorf("invalid maintenance window day %q", day)
+		}
+		days = append(days, weekday)
+	}
+
+	return Window{
+		days:     days,
+		start:    time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute,
+		duration: maintenanceWindow.Duration.Duration,
+	}, nil
+}
+
+var weekdays = map[v1alpha1.MaintenanceDay]time.Weekday{
+	"Monday":    time.Monday,
+	"Tuesday":   time.Tuesday,
+	"Wednesday": time.Wednesday,
+	"Thursday":  time.Thursday,
+	"Friday":    time.Friday,
+	"Saturday":  time.Saturday,
+	"Sunday":    time.Sunday,
+}
+
+// Contains returns whether the window is open at the given time. A window
+// opening late on one day may stay open into the next one
+func (w Window) Contains(t time.Time) bool {
+	if w.duration == 0 {
+		return true
+	}
+
+	t = t.UTC()
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	for daysBack := 0; time.Duration(daysBack)*24*time.Hour < w.start+w.duration; daysBack++ {
+		opening := midnight.AddDate(0, 0, -daysBack).Add(w.start)
+		if w.opensOn(opening) && !t.Before(opening) && t.Before(opening.Add(w.duration)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Next returns when the window opens next after the given time, or the given
+// time itself if the window is open
+func (w Window) Next(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+
+	t = t.UTC()
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	for daysAhead := 0; daysAhead <= 7; daysAhead++ {
+		opening := midnight.AddDate(0, 0, daysAhead).Add(w.start)
+		if w.opensOn(opening) && opening.After(t) {
+			return opening
+		}
+	}
+
+	return t
+}
+
+func (w Window) opensOn(t time.Time) bool {
+	return len(w.days) == 0 || slices.Contains(w.days, t.Weekday())
+}
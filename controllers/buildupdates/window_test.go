@@ -0,0 +1,52 @@
+package buildupdates_test
+
+import (
+	"time"
+
+	v1alpha1 "github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/buildupdates"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Window", func() {
+	var window buildupdates.Window
+
+	BeforeEach(func() {
+		var err error
+		window, err = buildupdates.NewWindow(&v1alpha1.MaintenanceWindow{
+			Days:     []v1alpha1.MaintenanceDay{"Saturday"},
+			Start:    "22:00",
+			Duration: metav1.Duration{Duration: 4 * time.Hour},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	// 2025-01-04 is a Saturday
+	DescribeTable("Contains",
+		func(t time.Time, contained bool) {
+			Expect(window.Contains(t)).To(Equal(contained))
+		},
+		Entry("before the window", time.Date(2025, 1, 4, 21, 59, 0, 0, time.UTC), false),
+		Entry("at the start", time.Date(2025, 1, 4, 22, 0, 0, 0, time.UTC), true),
+		Entry("past midnight", time.Date(2025, 1, 5, 1, 0, 0, 0, time.UTC), true),
+		Entry("at the end", time.Date(2025, 1, 5, 2, 0, 0, 0, time.UTC), false),
+		Entry("on another day", time.Date(2025, 1, 3, 23, 0, 0, 0, time.UTC), false),
+		Entry("in another time zone", time.Date(2025, 1, 5, 0, 30, 0, 0, time.FixedZone("CET", 3600)), true),
+	)
+
+	DescribeTable("Next",
+		func(t, next time.Time) {
+			Expect(window.Next(t)).To(BeTemporally("==", next))
+		},
+		Entry("before the window", time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC), time.Date(2025, 1, 4, 22, 0, 0, 0, time.UTC)),
+		Entry("within the window", time.Date(2025, 1, 5, 1, 0, 0, 0, time.UTC), time.Date(2025, 1, 5, 1, 0, 0, 0, time.UTC)),
+		Entry("after the window", time.Date(2025, 1, 5, 3, 0, 0, 0, time.UTC), time.Date(2025, 1, 11, 22, 0, 0, 0, time.UTC)),
+	)
+
+	It("rejects invalid windows", func() {
+		_, err := buildupdates.NewWindow(&v1alpha1.MaintenanceWindow{Start: "2am", Duration: metav1.Duration{Duration: time.Hour}})
+		Expect(err).To(MatchError(ContainSubstring("invalid maintenance window start")))
+	})
+})
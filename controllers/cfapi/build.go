@@ -0,0 +1,204 @@
+package cfapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	buildv1alpha2 "github.com/pivotal/kpack/pkg/apis/build/v1alpha2"
+	corev1alpha1 "github.com/pivotal/kpack/pkg/apis/core/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// KorifiClusterBuilderName is the builder of the Korifi chart, used
+	// unless the buildpacks, the stack or the order are configured
+	KorifiClusterBuilderName = "cf-kpack-cluster-builder"
+	// CustomClusterBuilderName is the builder rendered from `spec.build`
+	CustomClusterBuilderName = "cfapi-cluster-builder"
+
+	defaultBuildCacheMB = 2048
+)
+
+// defaultBuildpacks and defaultStack are the buildpacks and stack of the
+// Korifi builder, used for the parts of a custom builder left unconfigured
+var (
+	defaultBuildpacks = []string{
+		"paketobuildpacks/java",
+		"paketobuildpacks/nodejs",
+		"paketobuildpacks/ruby",
+		"paketobuildpacks/procfile",
+		"paketobuildpacks/go",
+	}
+
+	defaultBuildpackOrder = []v1alpha1.BuildpackGroup{
+		{Group: []v1alpha1.BuildpackRef{{ID: "paketo-buildpacks/java"}}},
+		{Group: []v1alpha1.BuildpackRef{{ID: "paketo-buildpacks/go"}}},
+		{Group: []v1alpha1.BuildpackRef{{ID: "paketo-buildpacks/nodejs"}}},
+		{Group: []v1alpha1.BuildpackRef{{ID: "paketo-buildpacks/ruby"}}},
+		{Group: []v1alpha1.BuildpackRef{{ID: "paketo-buildpacks/procfile"}}},
+	}
+
+	defaultStack = v1alpha1.BuildStack{
+		ID:         "io.buildpacks.stacks.jammy",
+		BuildImage: "paketobuildpacks/build-jammy-full",
+		RunImage:   "paketobuildpacks/run-jammy-full",
+	}
+)
+
+// buildConfig is the resolved `spec.build`
+type buildConfig struct {
+	clusterBuilderName    string
+	buildpacks            []string
+	stack                 *v1alpha1.BuildStack
+	order                 []v1alpha1.BuildpackGroup
+	cacheMB               int64
+	cacheStorageClassName string
+	memoryMB              int64
+	diskMB                int64
+	imageDigests          map[string]string
+}
+
+// computeBuild resolves the builder and the build pod settings. The Korifi
+// builder is kept as long as neither the buildpacks, the stack nor the order
+// are configured and updates are disabled, so that existing installations are
+// not rebuilt. The build cache cannot shrink below the installed size
+func computeBuild(cfAPI *v1alpha1.CFAPI) (buildConfig, error) {
+	config := buildConfig{
+		clusterBuilderName: KorifiClusterBuilderName,
+		cacheMB:            defaultBuildCacheMB,
+	}
+
+	build := cfAPI.Spec.Build
+	if build != nil && build.Cache != nil {
+		if build.Cache.Size != nil {
+			config.cacheMB = ceilDiv(build.Cache.Size.Value(), 1024*1024)
+		}
+		config.cacheStorageClassName = build.Cache.StorageClassName
+	}
+
+	// kpack resizes the cache volumes of existing apps to the cache size of
+	// their images, which fails for volumes that would shrink
+	if installedMB := cfAPI.Status.InstallationConfig.BuildCacheMB; config.cacheMB < installedMB {
+		return buildConfig{}, fmt.Errorf("spec.build.cache.size cannot be decreased below the installed %dMi, as the build cache volumes of existing apps cannot be shrunk", installedMB)
+	}
+
+	if build == nil {
+		return config, nil
+	}
+
+	updatesEnabled := build.Updates != nil && build.Updates.Enabled
+	if len(build.Buildpacks) > 0 || build.Stack != nil || len(build.Order) > 0 || updatesEnabled {
+		if len(build.Buildpacks) > 0 && len(build.Order) == 0 {
+			return buildConfig{}, errors.New("spec.build.order is required when spec.build.buildpacks is set, as the ids of the buildpacks in the images are not known")
+		}
+
+		config.clusterBuilderName = CustomClusterBuilderName
+		config.buildpacks = defaultBuildpacks
+		if len(build.Buildpacks) > 0 {
+			config.buildpacks = build.Buildpacks
+		}
+		config.stack = &defaultStack
+		if build.Stack != nil {
+			config.stack = build.Stack
+		}
+		config.order = defaultBuildpackOrder
+		if len(build.Order) > 0 {
+			config.order = build.Order
+		}
+	}
+
+	if updatesEnabled {
+		config.imageDigests = resolvedDigests(cfAPI, config)
+	}
+
+	if build.Resources != nil {
+		config.memoryMB = megabytes(build.Resources.Memory)
+		config.diskMB = megabytes(build.Resources.EphemeralStorage)
+	}
+
+	return config, nil
+}
+
+// resolvedDigests returns the digests the build updates resolved the images of
+// the builder to. Images the updates have not resolved yet are left out and
+// keep being pulled by tag
+func resolvedDigests(cfAPI *v1alpha1.CFAPI, config buildConfig) map[string]string {
+	if cfAPI.Status.Build == nil {
+		return nil
+	}
+
+	images := append(slices.Clone(config.buildpacks), config.stack.BuildImage, config.stack.RunImage)
+	digests := map[string]string{}
+	for _, resolved := range cfAPI.Status.Build.Images {
+		if slices.Contains(images, resolved.Image) {
+			digests[resolved.Image] = resolved.Digest
+		}
+	}
+	if len(digests) == 0 {
+		return nil
+	}
+
+	return digests
+}
+
+// megabytes rounds a quantity up to the megabytes Korifi requests for build
+// pods
+func megabytes(quantity *resource.Quantity) int64 {
+	if quantity == nil {
+		return 0
+	}
+	return ceilDiv(quantity.Value(), 1000*1000)
+}
+
+func ceilDiv(value, divisor int64) int64 {
+	return (value + divisor - 1) / divisor
+}
+
+// checkBuilder reports whether kpack has built the image of the cluster
+// builder in the `Builder` condition and returns whether it is ready
+func (r *Reconciler) checkBuilder(ctx context.Context, cfAPI *v1alpha1.CFAPI) bool {
+	builderName := cfAPI.Status.InstallationConfig.ClusterBuilderName
+
+	clusterBuilder := &buildv1alpha2.ClusterBuilder{}
+	if err := r.k8sClient.Get(ctx, client.ObjectKey{Name: builderName}, clusterBuilder); err != nil {
+		if client.IgnoreNotFound(err) != nil && !meta.IsNoMatchError(err) {
+			setBuilderCondition(cfAPI, metav1.ConditionUnknown, "CheckFailed", fmt.Sprintf("failed to get cluster builder %s: %s", builderName, err))
+			return false
+		}
+		setBuilderCondition(cfAPI, metav1.ConditionUnknown, "BuilderNotFound", fmt.Sprintf("Cluster builder %s does not exist yet", builderName))
+		return false
+	}
+
+	ready := clusterBuilder.Status.GetCondition(corev1alpha1.ConditionReady)
+	if ready == nil || clusterBuilder.Status.ObservedGeneration < clusterBuilder.Generation {
+		setBuilderCondition(cfAPI, metav1.ConditionUnknown, "BuilderPending", fmt.Sprintf("Cluster builder %s is being built", builderName))
+		return false
+	}
+
+	if ready.Status != corev1.ConditionTrue {
+		setBuilderCondition(cfAPI, metav1.ConditionFalse, "BuilderNotReady", fmt.Sprintf("Cluster builder %s is not ready: %s", builderName, ready.Message))
+		return false
+	}
+
+	setBuilderCondition(cfAPI, metav1.ConditionTrue, "BuilderReady", fmt.Sprintf("Cluster builder %s is ready: %s", builderName, clusterBuilder.Status.LatestImage))
+	return true
+}
+
+func setBuilderCondition(cfAPI *v1alpha1.CFAPI, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&cfAPI.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ConditionTypeBuilder,
+		Status:             status,
+		ObservedGeneration: cfAPI.Generation,
+		LastTransitionTime: metav1.NewTime(time.Now()),
+		Reason:             reason,
+		Message:            message,
+	})
+}
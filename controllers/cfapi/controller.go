@@ -24,9 +24,9 @@ package cfapi
 import (
 	"context"
 	"fmt"
-	"maps"
 	"reflect"
 	"slices"
+	"strings"
 	"time"
 
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -36,13 +36,18 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/BooleanCat/go-functional/v2/it"
 	"github.com/go-logr/logr"
 	v1alpha1 "github.com/kyma-project/cfapi/api/v1alpha1"
 	"github.com/kyma-project/cfapi/controllers/cfapi/secrets"
+	"github.com/kyma-project/cfapi/controllers/hibernation"
 	"github.com/kyma-project/cfapi/controllers/installable"
 	"github.com/kyma-project/cfapi/controllers/kyma"
+	"github.com/kyma-project/cfapi/controllers/proxy"
 	"github.com/kyma-project/cfapi/tools/k8s"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
@@ -59,6 +64,10 @@ type Reconciler struct {
 	scheme          *runtime.Scheme
 	kymaClient      *kyma.Client
 	docker          *secrets.Docker
+	registryChecker *secrets.RegistryChecker
+	gatewayMigrator *GatewayMigrator
+	proxySettings   *proxy.Settings
+	scaler          *hibernation.Scaler
 	eventRecorder   events.EventRecorder
 	requeueInterval time.Duration
 	installOrder    []installable.Installable
@@ -70,6 +79,9 @@ func NewReconciler(
 	scheme *runtime.Scheme,
 	kymaClient *kyma.Client,
 	docker *secrets.Docker,
+	registryChecker *secrets.RegistryChecker,
+	gatewayMigrator *GatewayMigrator,
+	proxySettings *proxy.Settings,
 	eventRecorder events.EventRecorder,
 	log logr.Logger,
 	requeueInterval time.Duration,
@@ -81,6 +93,10 @@ func NewReconciler(
 		scheme:          scheme,
 		kymaClient:      kymaClient,
 		docker:          docker,
+		registryChecker: registryChecker,
+		gatewayMigrator: gatewayMigrator,
+		proxySettings:   proxySettings,
+		scaler:          hibernation.NewScaler(k8sClient, installable.SystemNamespaces...),
 		eventRecorder:   eventRecorder,
 		requeueInterval: requeueInterval,
 		installOrder:    installOrder,
@@ -91,7 +107,34 @@ func NewReconciler(
 
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) *builder.Builder {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.CFAPI{})
+		For(&v1alpha1.CFAPI{}).
+		Watches(
+			&rbacv1.ClusterRoleBinding{},
+			handler.EnqueueRequestsFromMapFunc(r.enqueueCFAPIs),
+			builder.WithPredicates(predicate.NewPredicateFuncs(isClusterAdminBinding)),
+		).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.enqueueTrustedCABundleCFAPIs))
+}
+
+// enqueueCFAPIs triggers a reconcile when cluster admins change so that
+// discovered CF admins are kept in sync
+func (r *Reconciler) enqueueCFAPIs(ctx context.Context, _ client.Object) []reconcile.Request {
+	cfAPIs := &v1alpha1.CFAPIList{}
+	if err := r.k8sClient.List(ctx, cfAPIs); err != nil {
+		logr.FromContextOrDiscard(ctx).Error(err, "failed to list CFAPIs")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, cfAPI := range cfAPIs.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&cfAPI)})
+	}
+	return requests
+}
+
+func isClusterAdminBinding(obj client.Object) bool {
+	clusterRoleBinding, ok := obj.(*rbacv1.ClusterRoleBinding)
+	return ok && clusterRoleBinding.RoleRef.Name == "cluster-admin"
 }
 
 func (r *Reconciler) ReconcileResource(ctx context.Context, cfAPI *v1alpha1.CFAPI) (ctrl.Result, error) {
@@ -129,23 +172,62 @@ func (r *Reconciler) ReconcileResource(ctx context.Context, cfAPI *v1alpha1.CFAP
 		Reason:             "ValidConiguration",
 	})
 
+	eventRecorder := installable.NewCFAPIEventRecorder(r.eventRecorder, cfAPI)
+	hibernated, result, err := r.reconcileHibernation(ctx, cfAPI, eventRecorder)
+	if err != nil {
+		log.Error(err, "failed to reconcile the hibernation")
+		return ctrl.Result{}, err
+	}
+	if hibernated {
+		return result, nil
+	}
+
+	if err = r.gatewayMigrator.Prepare(ctx, cfAPI, &installationConfig, eventRecorder); err != nil {
+		log.Error(err, "failed to prepare the gateway migration")
+		return ctrl.Result{}, err
+	}
+
 	cfAPI.Status.InstallationConfig = installationConfig
 
-	installResult, err := r.install(ctx, installationConfig, installable.NewCFAPIEventRecorder(r.eventRecorder, cfAPI))
+	installResult, err := r.install(ctx, installationConfig, eventRecorder)
 	if err != nil {
 		log.Error(err, "failed to install installables")
 		return ctrl.Result{}, err
 	}
 
 	log.Info("installables installed", "installResult", installResult)
-	return r.applyInstallResultToStatus(installResult, cfAPI)
+	setSharedResourcesCondition(cfAPI, installResult.SharedObjects)
+	setImageMirrorCondition(cfAPI, installResult.UnmirroredImages)
+	result, err = r.applyInstallResultToStatus(installResult, cfAPI)
+	if err != nil || installResult.State != installable.ResultStateSuccess {
+		return result, err
+	}
+
+	migrating, err := r.gatewayMigrator.Advance(ctx, cfAPI, installationConfig, eventRecorder)
+	if err != nil {
+		log.Error(err, "failed to advance the gateway migration")
+		return ctrl.Result{}, err
+	}
+	if migrating {
+		result.RequeueAfter = r.requeueInterval
+	}
+
+	if !r.checkBuilder(ctx, cfAPI) {
+		result.RequeueAfter = r.requeueInterval
+	}
+
+	if !r.checkHighAvailability(ctx, cfAPI) {
+		result.RequeueAfter = r.requeueInterval
+	}
+
+	return requeueAt(result, cfAPI), nil
 }
 
 func (r *Reconciler) applyInstallResultToStatus(installResult installable.Result, cfAPI *v1alpha1.CFAPI) (ctrl.Result, error) {
 	switch installResult.State {
 	case installable.ResultStateSuccess:
 		cfAPI.Status.State = v1alpha1.StateReady
-		cfAPI.Status.URL = "https://cfapi." + cfAPI.Status.InstallationConfig.CFDomain
+		cfAPI.Status.URL = cfAPIURL(cfAPI.Status.InstallationConfig)
 
 		meta.SetStatusCondition(&cfAPI.Status.Conditions, metav1.Condition{
 			Type:               v1alpha1.ConditionTypeInstallation,
@@ -185,22 +267,47 @@ func (r *Reconciler) applyInstallResultToStatus(installResult installable.Result
 	}
 }
 
+func cfAPIURL(config v1alpha1.InstallationConfig) string {
+	url := "https://cfapi." + config.CFDomain
+	if config.IngressPort != 0 && config.IngressPort != 443 {
+		url = fmt.Sprintf("%s:%d", url, config.IngressPort)
+	}
+	return url
+}
+
 func (r *Reconciler) compileInstallationConfig(ctx context.Context, cfAPI *v1alpha1.CFAPI) (v1alpha1.InstallationConfig, error) {
 	rootNs := cfAPI.Spec.RootNamespace
 	if rootNs == "" {
 		rootNs = "cf"
 	}
 
+	if err := validateRootNamespace(cfAPI, rootNs); err != nil {
+		return v1alpha1.InstallationConfig{}, err
+	}
+
+	if _, _, err := hibernation.Desired(cfAPI.Spec.Hibernation, time.Now()); err != nil {
+		return v1alpha1.InstallationConfig{}, err
+	}
+
 	if err := r.kymaClient.Gateway.Validate(ctx, cfAPI); err != nil {
 		return v1alpha1.InstallationConfig{}, err
 	}
 
-	kymaDomain, err := r.kymaClient.Gateway.KymaDomain(ctx)
+	ingressMode, ingressHost, ingressPort := r.kymaClient.Gateway.Ingress(cfAPI)
+
+	cfDomain, cfDomainSource, err := r.computeCFDomain(ctx, cfAPI, ingressHost)
+	if err != nil {
+		return v1alpha1.InstallationConfig{}, err
+	}
+
+	// the registry and UAA checks below already go through the proxy
+	proxyConfig, err := r.computeProxy(ctx, cfAPI, cfDomain)
 	if err != nil {
 		return v1alpha1.InstallationConfig{}, err
 	}
+	r.proxySettings.Set(proxyConfig)
 
-	registrySecretName, registryURL, err := r.ensureContainerRegistry(ctx, cfAPI)
+	registrySecretName, registryURL, registryPullURL, err := r.ensureContainerRegistry(ctx, cfAPI)
 	if err != nil {
 		return v1alpha1.InstallationConfig{}, err
 	}
@@ -215,57 +322,183 @@ func (r *Reconciler) compileInstallationConfig(ctx context.Context, cfAPI *v1alp
 		builderRepository = cfAPI.Spec.BuilderRepository
 	}
 
-	uaaURL, err := r.computeUaaURL(ctx, cfAPI)
+	packages, droplets, builder, err := r.computeContainerRepositories(ctx, cfAPI, registrySecretName, containerRepositoryPrefix, builderRepository)
+	if err != nil {
+		return v1alpha1.InstallationConfig{}, err
+	}
+
+	r.checkContainerRegistries(ctx, cfAPI, packages, droplets, builder)
+
+	build, err := computeBuild(cfAPI)
 	if err != nil {
 		return v1alpha1.InstallationConfig{}, err
 	}
 
-	cfAdmins, err := r.computeCFAdmins(ctx, cfAPI)
+	imageRewrites, imagePullSecrets, err := r.computeImageMirror(ctx, cfAPI)
 	if err != nil {
 		return v1alpha1.InstallationConfig{}, err
 	}
 
+	trustedCA, err := r.computeTrustedCA(ctx, cfAPI)
+	if err != nil {
+		return v1alpha1.InstallationConfig{}, err
+	}
+
+	uaaURL, oidcUsernamePrefix, oidcGroupsPrefix := "", kyma.UAAUserPrefix, kyma.UAAGroupPrefix
+	if isLocal(cfAPI) {
+		oidcUsernamePrefix, oidcGroupsPrefix = r.computeLocalOIDC(ctx, cfAPI)
+	} else {
+		uaaURL, err = r.computeUaaURL(ctx, cfAPI)
+		if err != nil {
+			return v1alpha1.InstallationConfig{}, err
+		}
+	}
+
+	cfAdmins, cfAdminGroups, err := r.computeCFAdmins(ctx, cfAPI)
+	if err != nil {
+		return v1alpha1.InstallationConfig{}, err
+	}
+
+	istioGateway, istioAppsGateway := "", ""
+	if r.kymaClient.Gateway.KorifiGatewayType(cfAPI) == v1alpha1.GatewayTypeIstioNative {
+		namespace, name := r.kymaClient.Gateway.KymaGateway(cfAPI)
+		istioGateway = namespace + "/" + name
+		namespace, name = r.kymaClient.Gateway.AppsGateway()
+		istioAppsGateway = namespace + "/" + name
+	}
+
+	korifiIngressNamespace, korifiIngressService := r.kymaClient.Gateway.KorifiIngressService(cfAPI)
+
 	return v1alpha1.InstallationConfig{
-		RootNamespace:                             rootNs,
-		CFDomain:                                  kymaDomain,
-		KorifiIngressService:                      r.kymaClient.Gateway.KorifiIngressService(cfAPI),
-		GatewayType:                               r.kymaClient.Gateway.KorifiGatewayType(cfAPI),
-		UseSelfSignedCertificates:                 cfAPI.Spec.UseSelfSignedCertificates,
-		ContainerRegistrySecret:                   registrySecretName,
-		ContainerRepositoryPrefix:                 containerRepositoryPrefix,
-		ContainerRegistryURL:                      registryURL,
-		BuilderRepository:                         builderRepository,
+		Profile:                   profile(cfAPI),
+		RootNamespace:             rootNs,
+		CFDomain:                  cfDomain,
+		CFDomainSource:            cfDomainSource,
+		IngressMode:               ingressMode,
+		IngressHost:               ingressHost,
+		IngressPort:               ingressPort,
+		KorifiIngressService:      korifiIngressService,
+		KorifiIngressNamespace:    korifiIngressNamespace,
+		GatewayType:               r.kymaClient.Gateway.KorifiGatewayType(cfAPI),
+		GatewayClassName:          r.kymaClient.Gateway.KorifiGatewayClass(cfAPI),
+		IstioGateway:              istioGateway,
+		IstioAppsGateway:          istioAppsGateway,
+		UseSelfSignedCertificates: cfAPI.Spec.UseSelfSignedCertificates,
+		ContainerRegistrySecret:   registrySecretName,
+		ContainerRepositoryPrefix: containerRepositoryPrefix,
+		ContainerRegistryURL:      registryURL,
+		ContainerRegistryPullURL:  registryPullURL,
+		BuilderRepository:         builder.repository,
+		PackageRegistrySecret:     packages.secret,
+		PackageRepositoryPrefix:   packages.repository,
+		DropletRegistrySecret:     droplets.secret,
+		DropletRepositoryPrefix:   droplets.repository,
+		BuilderRegistrySecret:     builder.secret,
 		DisableContainerRegistrySecretPropagation: cfAPI.Spec.DisableContainerRegistrySecretPropagation,
-		UAAURL:   uaaURL,
-		CFAdmins: cfAdmins,
+		UAAURL:                     uaaURL,
+		OIDCUsernamePrefix:         oidcUsernamePrefix,
+		OIDCGroupsPrefix:           oidcGroupsPrefix,
+		CFAdmins:                   cfAdmins,
+		CFAdminGroups:              cfAdminGroups,
+		ClusterBuilderName:         build.clusterBuilderName,
+		Buildpacks:                 build.buildpacks,
+		Stack:                      build.stack,
+		BuildpackOrder:             build.order,
+		BuildImageDigests:          build.imageDigests,
+		BuildCacheMB:               build.cacheMB,
+		BuildMemoryMB:              build.memoryMB,
+		BuildDiskMB:                build.diskMB,
+		BuildCacheStorageClassName: build.cacheStorageClassName,
+		ImageRewrites:              imageRewrites,
+		ImagePullSecrets:           imagePullSecrets,
+		TrustedCA:                  trustedCA,
+		Proxy:                      proxyConfig,
+		HighAvailability:           computeHighAvailability(cfAPI),
 	}, nil
 }
 
+// validateRootNamespace rejects changing the root namespace of an existing
+// installation. The CF orgs, the service broker registration and the
+// propagated registry secret live in the root namespace, and Korifi does not
+// support moving them to another one
+func validateRootNamespace(cfAPI *v1alpha1.CFAPI, rootNs string) error {
+	installedRootNs := cfAPI.Status.InstallationConfig.RootNamespace
+	if installedRootNs == "" || installedRootNs == rootNs {
+		return nil
+	}
+
+	return fmt.Errorf("changing the root namespace from %s to %s is not supported, as the CF orgs, the service broker and the registry secret in the root namespace cannot be moved. "+
+		"Set `spec.rootNamespace` back to %s, or delete the CFAPI resource and create it again to reinstall CF with the new root namespace", installedRootNs, rootNs, installedRootNs)
+}
+
+func (r *Reconciler) computeCFDomain(ctx context.Context, cfAPI *v1alpha1.CFAPI, ingressHost string) (string, string, error) {
+	if isLocal(cfAPI) {
+		return localDomain(cfAPI, ingressHost)
+	}
+
+	return r.kymaClient.Gateway.KymaDomain(ctx, cfAPI)
+}
+
 func (r *Reconciler) computeUaaURL(ctx context.Context, cfAPI *v1alpha1.CFAPI) (string, error) {
 	if cfAPI.Spec.UAA != "" {
+		if err := r.kymaClient.UAA.Validate(ctx, cfAPI.Spec.UAA); err != nil {
+			setUAACondition(cfAPI, metav1.ConditionFalse, "ValidationFailed", err.Error())
+			return cfAPI.Spec.UAA, nil
+		}
+
+		setUAACondition(cfAPI, metav1.ConditionTrue, "UAAConfigured", "Using UAA "+cfAPI.Spec.UAA)
 		return cfAPI.Spec.UAA, nil
 	}
 
-	return r.kymaClient.UAA.GetURL(ctx)
+	uaaURL, err := r.kymaClient.UAA.GetURL(ctx)
+	if err != nil {
+		setUAACondition(cfAPI, metav1.ConditionFalse, "DiscoveryFailed", err.Error())
+		return "", err
+	}
+
+	setUAACondition(cfAPI, metav1.ConditionTrue, "UAADiscovered", "Using UAA "+uaaURL)
+	return uaaURL, nil
 }
 
-func (r *Reconciler) computeCFAdmins(ctx context.Context, cfAPI *v1alpha1.CFAPI) ([]string, error) {
-	if len(cfAPI.Spec.CFAdmins) > 0 {
-		return cfAPI.Spec.CFAdmins, nil
+func setUAACondition(cfAPI *v1alpha1.CFAPI, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&cfAPI.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ConditionTypeUAA,
+		Status:             status,
+		ObservedGeneration: cfAPI.Generation,
+		LastTransitionTime: metav1.NewTime(time.Now()),
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+func (r *Reconciler) computeCFAdmins(ctx context.Context, cfAPI *v1alpha1.CFAPI) ([]string, []string, error) {
+	if len(cfAPI.Spec.CFAdmins) > 0 || len(cfAPI.Spec.CFAdminGroups) > 0 {
+		return cfAPI.Spec.CFAdmins, cfAPI.Spec.CFAdminGroups, nil
 	}
 
 	adminSubjects, err := r.kymaClient.Users.GetClusterAdmins(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	adminGroupSubjects, err := r.kymaClient.Users.GetClusterAdminGroups(ctx)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return slices.Collect(it.Map(slices.Values(adminSubjects), func(s rbacv1.Subject) string {
+	return subjectNames(adminSubjects), subjectNames(adminGroupSubjects), nil
+}
+
+func subjectNames(subjects []rbacv1.Subject) []string {
+	return slices.Collect(it.Map(slices.Values(subjects), func(s rbacv1.Subject) string {
 		return s.Name
-	})), nil
+	}))
 }
 
 func (r *Reconciler) install(ctx context.Context, config v1alpha1.InstallationConfig, eventRecorder installable.EventRecorder) (installable.Result, error) {
 	results := []installable.Result{}
+	sharedObjects := []installable.SharedObject{}
+	unmirroredImages := []string{}
 
 	for _, inst := range r.installOrder {
 		result, err := inst.Install(ctx, config, eventRecorder)
@@ -273,16 +506,59 @@ func (r *Reconciler) install(ctx context.Context, config v1alpha1.InstallationCo
 			return installable.Result{}, err
 		}
 		results = append(results, result)
+		sharedObjects = append(sharedObjects, result.SharedObjects...)
+		unmirroredImages = append(unmirroredImages, result.UnmirroredImages...)
 	}
 
 	slices.SortStableFunc(results, func(r1, r2 installable.Result) int {
 		return int(r2.State) - int(r1.State)
 	})
 
-	return results[0], nil
+	result := results[0]
+	result.SharedObjects = sharedObjects
+	slices.Sort(unmirroredImages)
+	result.UnmirroredImages = slices.Compact(unmirroredImages)
+	return result, nil
+}
+
+// setSharedResourcesCondition reports how the objects other managers
+// installed before the operator, such as Gateway API or kpack CRDs, are used
+func setSharedResourcesCondition(cfAPI *v1alpha1.CFAPI, sharedObjects []installable.SharedObject) {
+	byDecision := map[installable.SharedObjectDecision][]string{}
+	for _, sharedObject := range sharedObjects {
+		byDecision[sharedObject.Decision] = append(byDecision[sharedObject.Decision], sharedObject.String())
+	}
+
+	status, reason, message := metav1.ConditionTrue, "NoSharedResources", "No resources of other managers found"
+	switch {
+	case len(byDecision[installable.SharedObjectRefused]) > 0:
+		status, reason = metav1.ConditionFalse, "SharedResourcesRefused"
+		message = "Refused incompatible resources: " + strings.Join(byDecision[installable.SharedObjectRefused], "; ") +
+			fmt.Sprintf(". Upgrade them or annotate them with %s: \"true\" to let the operator take them over", installable.AdoptAnnotation)
+	case len(sharedObjects) > 0:
+		reason = "SharedResourcesReused"
+		messages := []string{}
+		for _, decision := range []installable.SharedObjectDecision{installable.SharedObjectSkipped, installable.SharedObjectAdopted} {
+			if len(byDecision[decision]) > 0 {
+				messages = append(messages, fmt.Sprintf("%s: %s", decision, strings.Join(byDecision[decision], "; ")))
+			}
+		}
+		message = strings.Join(messages, ". ")
+	}
+
+	meta.SetStatusCondition(&cfAPI.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ConditionTypeSharedResources,
+		Status:             status,
+		ObservedGeneration: cfAPI.Generation,
+		LastTransitionTime: metav1.NewTime(time.Now()),
+		Reason:             reason,
+		Message:            message,
+	})
 }
 
-func (r *Reconciler) ensureContainerRegistry(ctx context.Context, cfAPI *v1alpha1.CFAPI) (string, string, error) {
+// ensureContainerRegistry returns the registry secret, the registry images are
+// pushed to and the registry the nodes pull them from, if that differs
+func (r *Reconciler) ensureContainerRegistry(ctx context.Context, cfAPI *v1alpha1.CFAPI) (string, string, string, error) {
 	if cfAPI.Spec.ContainerRegistrySecret != "" {
 		customSecret := &corev1.Secret{
 			ObjectMeta: metav1.ObjectMeta{
@@ -293,33 +569,84 @@ func (r *Reconciler) ensureContainerRegistry(ctx context.Context, cfAPI *v1alpha
 
 		err := r.k8sClient.Get(ctx, client.ObjectKeyFromObject(customSecret), customSecret)
 		if err != nil {
-			return "", "", err
+			return "", "", "", err
 		}
 
 		registryConfig, err := r.docker.GetRegistryConfig(ctx, cfAPI.Namespace, cfAPI.Spec.ContainerRegistrySecret)
 		if err != nil {
-			return "", "", err
+			return "", "", "", err
 		}
 
-		registryURLs := slices.Collect(maps.Keys(registryConfig.Auths))
+		registryURLs := registryConfig.Registries()
 		if len(registryURLs) == 0 {
-			return "", "", fmt.Errorf("container registry secret %s does not specify container registries", cfAPI.Spec.ContainerRegistrySecret)
+			return "", "", "", fmt.Errorf("container registry secret %s does not specify container registries", cfAPI.Spec.ContainerRegistrySecret)
+		}
+
+		if cfAPI.Spec.ContainerRegistryURL == "" {
+			return customSecret.Name, registryURLs[0], "", nil
 		}
 
-		return customSecret.Name, registryURLs[0], nil
+		registryURL, ok := registryConfig.Registry(cfAPI.Spec.ContainerRegistryURL)
+		if !ok {
+			return "", "", "", fmt.Errorf("container registry secret %s does not specify container registry %s, configured registries are: %s",
+				cfAPI.Spec.ContainerRegistrySecret, cfAPI.Spec.ContainerRegistryURL, strings.Join(registryURLs, ", "))
+		}
+
+		return customSecret.Name, registryURL, "", nil
+	}
+
+	if cfAPI.Spec.ContainerRegistryURL != "" {
+		return "", "", "", fmt.Errorf("containerRegistryURL can only be set together with containerRegistrySecret")
 	}
 
-	kymaRegistrySecret, err := r.kymaClient.ContainerRegistry.GetRegistrySecret(ctx, cfAPI.Namespace)
+	if isLocal(cfAPI) {
+		registrySecretName, registryURL, err := r.ensureLocalRegistrySecret(ctx, cfAPI)
+		return registrySecretName, registryURL, "", err
+	}
+
+	if cfAPI.Spec.UseExternalDockerRegistry {
+		kymaRegistrySecret, err := r.kymaClient.ContainerRegistry.GetRegistrySecret(ctx, cfAPI.Namespace)
+		if err != nil {
+			return "", "", "", err
+		}
+
+		kymaRegistryURL, err := r.kymaClient.ContainerRegistry.GetRegistryURL(ctx, cfAPI.Namespace)
+		if err != nil {
+			return "", "", "", err
+		}
+
+		return kymaRegistrySecret.Name, kymaRegistryURL, "", nil
+	}
+
+	return r.ensureInternalKymaRegistry(ctx, cfAPI)
+}
+
+// ensureInternalKymaRegistry configures the docker registry module without
+// external access. Images are pushed to the in-cluster address of the
+// registry, which the nodes cannot resolve, and pulled from its node port
+func (r *Reconciler) ensureInternalKymaRegistry(ctx context.Context, cfAPI *v1alpha1.CFAPI) (string, string, string, error) {
+	pushURL, pullURL, err := r.kymaClient.ContainerRegistry.GetInternalRegistryURLs(ctx, cfAPI.Namespace)
 	if err != nil {
-		return "", "", err
+		return "", "", "", err
 	}
 
-	kymaRegistryURL, err := r.kymaClient.ContainerRegistry.GetRegistryURL(ctx, cfAPI.Namespace)
+	registryConfig, err := r.docker.GetRegistryConfig(ctx, cfAPI.Namespace, kyma.InternalContainerRegistrySecretName)
 	if err != nil {
-		return "", "", err
+		return "", "", "", err
 	}
 
-	return kymaRegistrySecret.Name, kymaRegistryURL, nil
+	for _, registryURL := range []string{pushURL, pullURL} {
+		if _, ok := registryConfig.Credentials(registryURL); !ok {
+			return "", "", "", fmt.Errorf("container registry secret %s does not specify credentials for %s, configured registries are: %s",
+				kyma.InternalContainerRegistrySecretName, registryURL, strings.Join(registryConfig.Registries(), ", "))
+		}
+	}
+
+	if pullURL == pushURL {
+		pullURL = ""
+	}
+
+	return kyma.InternalContainerRegistrySecretName, pushURL, pullURL, nil
 }
 
 func (r *Reconciler) finalize(ctx context.Context, cfAPI *v1alpha1.CFAPI) (ctrl.Result, error) {
@@ -335,6 +662,22 @@ func (r *Reconciler) finalize(ctx context.Context, cfAPI *v1alpha1.CFAPI) (ctrl.
 		return ctrl.Result{}, nil
 	}
 
+	// Korifi has to run to remove the finalizers of the CF resources
+	if cfAPI.Status.Hibernation != nil && cfAPI.Status.Hibernation.State != v1alpha1.HibernationStateRunning {
+		if err := r.scaler.ScaleUp(ctx); err != nil {
+			log.Error(err, "failed to resume the platform")
+			return ctrl.Result{}, err
+		}
+		cfAPI.Status.Hibernation = nil
+	}
+
+	if cfAPI.Status.GatewayMigration != nil {
+		if err := r.gatewayMigrator.cleanup(ctx); err != nil {
+			log.Error(err, "failed to clean up the gateway migration")
+			return ctrl.Result{}, err
+		}
+	}
+
 	uninstallResult, err := r.uninstall(ctx, uninstallConfig, installable.NewCFAPIEventRecorder(r.eventRecorder, cfAPI))
 	if err != nil {
 		log.Error(err, "failed to uninstall uninstallables")
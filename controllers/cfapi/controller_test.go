@@ -2,23 +2,34 @@ package cfapi_test
 
 import (
 	"errors"
+	"slices"
+	"strings"
 
 	"github.com/google/uuid"
 	v1alpha1 "github.com/kyma-project/cfapi/api/v1alpha1"
 	"github.com/kyma-project/cfapi/controllers/cfapi"
+	"github.com/kyma-project/cfapi/controllers/hibernation"
 	"github.com/kyma-project/cfapi/controllers/installable"
 	"github.com/kyma-project/cfapi/controllers/kyma"
 	. "github.com/kyma-project/cfapi/tests/helpers"
 	. "github.com/kyma-project/cfapi/tests/matchers"
+	"github.com/kyma-project/cfapi/tools"
 	"github.com/kyma-project/cfapi/tools/k8s"
 	"github.com/kyma-project/istio/operator/api/v1alpha2"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	buildv1alpha2 "github.com/pivotal/kpack/pkg/apis/build/v1alpha2"
+	corev1alpha1 "github.com/pivotal/kpack/pkg/apis/core/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
 var _ = Describe("CFDomainReconciler Integration Tests", func() {
@@ -86,17 +97,28 @@ var _ = Describe("CFDomainReconciler Integration Tests", func() {
 		Eventually(func(g Gomega) {
 			g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
 			g.Expect(cfAPI.Status.InstallationConfig).To(Equal(v1alpha1.InstallationConfig{
+				Profile:                   v1alpha1.ProfileKyma,
 				RootNamespace:             "cf",
-				ContainerRegistrySecret:   kyma.ContainerRegistrySecretName,
-				ContainerRegistryURL:      "https://kyma-registry.com",
-				ContainerRepositoryPrefix: "https://kyma-registry.com/",
-				BuilderRepository:         "https://kyma-registry.com/cfapi/kpack-builder",
+				ContainerRegistrySecret:   kyma.InternalContainerRegistrySecretName,
+				ContainerRegistryURL:      "dockerregistry.kyma-system.svc.cluster.local:5000",
+				ContainerRegistryPullURL:  "localhost:32137",
+				ContainerRepositoryPrefix: "dockerregistry.kyma-system.svc.cluster.local:5000/",
+				BuilderRepository:         "dockerregistry.kyma-system.svc.cluster.local:5000/cfapi/kpack-builder",
 				CFDomain:                  "kyma-host.com",
+				CFDomainSource:            "Gateway kyma-system/kyma-gateway",
+				IngressMode:               v1alpha1.IngressModeLoadBalancer,
+				IngressPort:               443,
 				UAAURL:                    "https://uaa.cf.eu12.hana.ondemand.com",
+				OIDCUsernamePrefix:        "sap.ids:",
+				OIDCGroupsPrefix:          "sap.ids.groups:",
 				CFAdmins:                  []string{"default.admin@sap.com"},
 				GatewayType:               "contour",
+				GatewayClassName:          "contour",
 				KorifiIngressService:      "contour-envoy",
+				KorifiIngressNamespace:    "cfapi-system",
 				DisableContainerRegistrySecretPropagation: false,
+				ClusterBuilderName:                        cfapi.KorifiClusterBuilderName,
+				BuildCacheMB:                              2048,
 			}))
 		}).Should(Succeed())
 	})
@@ -108,6 +130,29 @@ var _ = Describe("CFDomainReconciler Integration Tests", func() {
 		}).Should(Succeed())
 	})
 
+	It("sets the shared resources status condition", func() {
+		Eventually(func(g Gomega) {
+			g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+			g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+				HasType(Equal(v1alpha1.ConditionTypeSharedResources)),
+				HasStatus(Equal(metav1.ConditionTrue)),
+				HasReason(Equal("NoSharedResources")),
+			)))
+		}).Should(Succeed())
+	})
+
+	It("sets the uaa status condition", func() {
+		Eventually(func(g Gomega) {
+			g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+			g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+				HasType(Equal(v1alpha1.ConditionTypeUAA)),
+				HasStatus(Equal(metav1.ConditionTrue)),
+				HasReason(Equal("UAADiscovered")),
+				HasMessage(ContainSubstring("https://uaa.cf.eu12.hana.ondemand.com")),
+			)))
+		}).Should(Succeed())
+	})
+
 	It("sets the cf api url on the status", func() {
 		Eventually(func(g Gomega) {
 			g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
@@ -115,13 +160,82 @@ var _ = Describe("CFDomainReconciler Integration Tests", func() {
 		}).Should(Succeed())
 	})
 
-	When("custom root namespace is specified", func() {
+	When("the ingress mode is NodePort", func() {
+		BeforeEach(func() {
+			Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+				cfAPI.Spec.Ingress = &v1alpha1.Ingress{
+					Mode: v1alpha1.IngressModeNodePort,
+					Host: "node.example.com",
+				}
+			})).To(Succeed())
+		})
+
+		It("includes the node port in the cf api url", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.InstallationConfig.IngressHost).To(Equal("node.example.com"))
+				g.Expect(cfAPI.Status.URL).To(Equal("https://cfapi.kyma-host.com:30443"))
+			}).Should(Succeed())
+		})
+	})
+
+	When("the profile is local", func() {
 		BeforeEach(func() {
 			Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
-				cfAPI.Spec.RootNamespace = "custom-root-ns"
+				cfAPI.Spec.Profile = v1alpha1.ProfileLocal
 			})).To(Succeed())
 		})
 
+		It("derives the cf domain from the ingress address", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.InstallationConfig.Profile).To(Equal(v1alpha1.ProfileLocal))
+				g.Expect(cfAPI.Status.InstallationConfig.CFDomain).To(Equal("127.0.0.1.nip.io"))
+				g.Expect(cfAPI.Status.InstallationConfig.IngressMode).To(Equal(v1alpha1.IngressModeNodePort))
+				g.Expect(cfAPI.Status.URL).To(Equal("https://cfapi.127.0.0.1.nip.io:30443"))
+			}).Should(Succeed())
+		})
+
+		It("uses the local registry", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.InstallationConfig.ContainerRegistrySecret).To(Equal(cfapi.LocalRegistrySecretName))
+				g.Expect(cfAPI.Status.InstallationConfig.ContainerRegistryURL).To(Equal(cfapi.LocalRegistryURL))
+
+				registrySecret := &corev1.Secret{}
+				g.Expect(adminClient.Get(ctx, client.ObjectKey{Namespace: cfAPINamespace, Name: cfapi.LocalRegistrySecretName}, registrySecret)).To(Succeed())
+				g.Expect(registrySecret.Type).To(Equal(corev1.SecretTypeDockerConfigJson))
+			}).Should(Succeed())
+		})
+
+		It("does not require the UAA", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.InstallationConfig.UAAURL).To(BeEmpty())
+				g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+					HasType(Equal(v1alpha1.ConditionTypeUAA)),
+					HasStatus(Equal(metav1.ConditionTrue)),
+					HasReason(Equal("UAANotRequired")),
+				)))
+			}).Should(Succeed())
+		})
+	})
+
+	When("custom root namespace is specified", func() {
+		BeforeEach(func() {
+			// the root namespace cannot be changed once installed
+			cfAPI = &v1alpha1.CFAPI{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      uuid.NewString(),
+					Namespace: cfAPINamespace,
+				},
+				Spec: v1alpha1.CFAPISpec{
+					RootNamespace: "custom-root-ns",
+				},
+			}
+			Expect(adminClient.Create(ctx, cfAPI)).To(Succeed())
+		})
+
 		It("uses it", func() {
 			Eventually(func(g Gomega) {
 				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
@@ -130,6 +244,38 @@ var _ = Describe("CFDomainReconciler Integration Tests", func() {
 		})
 	})
 
+	When("the root namespace is changed after installation", func() {
+		BeforeEach(func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.InstallationConfig.RootNamespace).To(Equal("cf"))
+			}).Should(Succeed())
+
+			Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+				cfAPI.Spec.RootNamespace = "other-root-ns"
+			})).To(Succeed())
+		})
+
+		It("rejects the change", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.State).To(Equal(v1alpha1.StateWarning))
+				g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+					HasType(Equal(v1alpha1.ConditionTypeConfiguration)),
+					HasStatus(Equal(metav1.ConditionFalse)),
+					HasMessage(ContainSubstring("changing the root namespace from cf to other-root-ns is not supported")),
+				)))
+			}).Should(Succeed())
+		})
+
+		It("keeps the installed root namespace", func() {
+			Consistently(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.InstallationConfig.RootNamespace).To(Equal("cf"))
+			}, "2s").Should(Succeed())
+		})
+	})
+
 	When("custom uaa usr is specified", func() {
 		BeforeEach(func() {
 			Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
@@ -143,6 +289,17 @@ var _ = Describe("CFDomainReconciler Integration Tests", func() {
 				g.Expect(cfAPI.Status.InstallationConfig.UAAURL).To(Equal("my-own.uaa.com"))
 			}).Should(Succeed())
 		})
+
+		It("reports the uaa validation failure", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+					HasType(Equal(v1alpha1.ConditionTypeUAA)),
+					HasStatus(Equal(metav1.ConditionFalse)),
+					HasReason(Equal("ValidationFailed")),
+				)))
+			}).Should(Succeed())
+		})
 	})
 
 	When("custom admins are specified", func() {
@@ -160,6 +317,47 @@ var _ = Describe("CFDomainReconciler Integration Tests", func() {
 		})
 	})
 
+	When("custom admin groups are specified", func() {
+		BeforeEach(func() {
+			Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+				cfAPI.Spec.CFAdminGroups = []string{"custom-admin-group"}
+			})).To(Succeed())
+		})
+
+		It("uses them without discovering cluster admins", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.InstallationConfig.CFAdminGroups).To(ConsistOf("custom-admin-group"))
+				g.Expect(cfAPI.Status.InstallationConfig.CFAdmins).To(BeEmpty())
+			}).Should(Succeed())
+		})
+	})
+
+	When("cluster admin groups exist", func() {
+		BeforeEach(func() {
+			Expect(adminClient.Create(ctx, &rbacv1.ClusterRoleBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: uuid.NewString(),
+				},
+				Subjects: []rbacv1.Subject{{
+					Kind: "Group",
+					Name: "kyma-admins",
+				}},
+				RoleRef: rbacv1.RoleRef{
+					Kind: "ClusterRole",
+					Name: "cluster-admin",
+				},
+			})).To(Succeed())
+		})
+
+		It("discovers them", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.InstallationConfig.CFAdminGroups).To(ConsistOf("kyma-admins"))
+			}).Should(Succeed())
+		})
+	})
+
 	When("container registry secret propagation is disabled", func() {
 		BeforeEach(func() {
 			Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
@@ -268,6 +466,65 @@ var _ = Describe("CFDomainReconciler Integration Tests", func() {
 		})
 	})
 
+	When("the gateway type is 'istio-native'", func() {
+		BeforeEach(func() {
+			Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+				cfAPI.Spec.GatewayType = v1alpha1.GatewayTypeIstioNative
+			})).To(Succeed())
+		})
+
+		It("uses the kyma gateway", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.InstallationConfig.IstioGateway).To(Equal("kyma-system/kyma-gateway"))
+				g.Expect(cfAPI.Status.InstallationConfig.IstioAppsGateway).To(Equal("cfapi-system/cfapi-apps"))
+				g.Expect(cfAPI.Status.InstallationConfig.KorifiIngressService).To(BeEmpty())
+			}).Should(Succeed())
+		})
+	})
+
+	When("the gateway type is 'external'", func() {
+		BeforeEach(func() {
+			gatewayClass := &gatewayv1.GatewayClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: uuid.NewString(),
+				},
+				Spec: gatewayv1.GatewayClassSpec{
+					ControllerName: "gateway.envoyproxy.io/gatewayclass-controller",
+				},
+			}
+			Expect(adminClient.Create(ctx, gatewayClass)).To(Succeed())
+			Expect(k8s.Patch(ctx, adminClient, gatewayClass, func() {
+				meta.SetStatusCondition(&gatewayClass.Status.Conditions, metav1.Condition{
+					Type:   string(gatewayv1.GatewayClassConditionStatusAccepted),
+					Status: metav1.ConditionTrue,
+					Reason: string(gatewayv1.GatewayClassReasonAccepted),
+				})
+			})).To(Succeed())
+
+			Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+				cfAPI.Spec.GatewayType = v1alpha1.GatewayTypeExternal
+				cfAPI.Spec.Gateway = &v1alpha1.ExternalGateway{
+					ClassName: gatewayClass.Name,
+					IngressService: v1alpha1.NamespacedObjectReference{
+						Namespace: "envoy-gateway-system",
+						Name:      "envoy-korifi",
+					},
+				}
+			})).To(Succeed())
+		})
+
+		It("uses the configured gateway class and ingress service", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.InstallationConfig.GatewayType).To(Equal(v1alpha1.GatewayTypeExternal))
+				g.Expect(cfAPI.Status.InstallationConfig.GatewayClassName).To(Equal(cfAPI.Spec.Gateway.ClassName))
+				g.Expect(cfAPI.Status.InstallationConfig.KorifiIngressNamespace).To(Equal("envoy-gateway-system"))
+				g.Expect(cfAPI.Status.InstallationConfig.KorifiIngressService).To(Equal("envoy-korifi"))
+			}).Should(Succeed())
+		})
+	})
+
 	When("one of the installables returns an error", func() {
 		BeforeEach(func() {
 			secondToInstall.InstallReturns(installable.Result{}, errors.New("second-failed"))
@@ -303,106 +560,1049 @@ var _ = Describe("CFDomainReconciler Integration Tests", func() {
 		})
 	})
 
-	When("one of the installables returns processing result", func() {
+	When("installables report resources of other managers", func() {
 		BeforeEach(func() {
+			firstToInstall.InstallReturns(installable.Result{
+				State: installable.ResultStateSuccess,
+				SharedObjects: []installable.SharedObject{{
+					Kind:     "CustomResourceDefinition",
+					Name:     "images.kpack.io",
+					Manager:  "Helm release kpack/kpack",
+					Decision: installable.SharedObjectSkipped,
+				}},
+			}, nil)
 			secondToInstall.InstallReturns(installable.Result{
-				State: installable.ResultStateInProgress,
+				State:   installable.ResultStateFailed,
+				Message: "conflicts",
+				SharedObjects: []installable.SharedObject{{
+					Kind:     "CustomResourceDefinition",
+					Name:     "tlsroutes.gateway.networking.k8s.io",
+					Manager:  "istio",
+					Decision: installable.SharedObjectRefused,
+					Reason:   "incompatible: versions v1alpha2 are not served",
+				}},
 			}, nil)
 		})
 
-		It("sets processing state in status", func() {
+		It("reports the refused resources in the shared resources condition", func() {
 			Eventually(func(g Gomega) {
 				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
-				g.Expect(cfAPI.Status.State).To(Equal(v1alpha1.StateProcessing))
 				g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
-					HasType(Equal(v1alpha1.ConditionTypeInstallation)),
-					HasStatus(Equal(metav1.ConditionUnknown)),
-					HasReason(Equal("InstallationInProgress")),
+					HasType(Equal(v1alpha1.ConditionTypeSharedResources)),
+					HasStatus(Equal(metav1.ConditionFalse)),
+					HasReason(Equal("SharedResourcesRefused")),
+					HasMessage(ContainSubstring("CustomResourceDefinition tlsroutes.gateway.networking.k8s.io managed by istio (incompatible: versions v1alpha2 are not served)")),
 				)))
 			}).Should(Succeed())
 		})
-	})
 
-	When("the user has specified a custom registry secret", func() {
-		var customSecretName string
+		When("no resource is refused", func() {
+			BeforeEach(func() {
+				secondToInstall.InstallReturns(installable.Result{State: installable.ResultStateSuccess}, nil)
+			})
+
+			It("reports the reused resources", func() {
+				Eventually(func(g Gomega) {
+					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+					g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+						HasType(Equal(v1alpha1.ConditionTypeSharedResources)),
+						HasStatus(Equal(metav1.ConditionTrue)),
+						HasReason(Equal("SharedResourcesReused")),
+						HasMessage(Equal("Skipped: CustomResourceDefinition images.kpack.io managed by Helm release kpack/kpack")),
+					)))
+				}).Should(Succeed())
+			})
+		})
+	})
 
+	When("an image mirror is configured", func() {
 		BeforeEach(func() {
-			customSecretName = uuid.NewString()
 			Expect(adminClient.Create(ctx, &corev1.Secret{
 				ObjectMeta: metav1.ObjectMeta{
 					Namespace: cfAPINamespace,
-					Name:      customSecretName,
+					Name:      "mirror-secret",
 				},
+				Type: corev1.SecretTypeDockerConfigJson,
 				Data: map[string][]byte{
-					corev1.DockerConfigJsonKey: []byte(`{"auths":{"https://my-custom-registry.com": {}}}`),
+					corev1.DockerConfigJsonKey: []byte(`{"auths":{"mirror.example.com": {"username": "mirror-user", "password": "mirror-password"}}}`),
 				},
 			})).To(Succeed())
 
 			Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
-				cfAPI.Spec.ContainerRegistrySecret = customSecretName
+				cfAPI.Spec.ImageMirror = &v1alpha1.ImageMirror{
+					Rewrites:    []v1alpha1.ImageRewrite{{From: "ghcr.io/", To: "mirror.example.com/ghcr/"}},
+					PullSecrets: []string{"mirror-secret"},
+				}
 			})).To(Succeed())
+
+			firstToInstall.InstallReturns(installable.Result{
+				State:            installable.ResultStateSuccess,
+				UnmirroredImages: []string{"quay.io/foo/bar", "gcr.io/foo/bar"},
+			}, nil)
+			secondToInstall.InstallReturns(installable.Result{
+				State:            installable.ResultStateSuccess,
+				UnmirroredImages: []string{"quay.io/foo/bar"},
+			}, nil)
 		})
 
-		It("sets the custom container registry secret in the status", func() {
+		It("passes the mirror to the installables", func() {
 			Eventually(func(g Gomega) {
 				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
-				g.Expect(cfAPI.Status.InstallationConfig.ContainerRegistrySecret).To(Equal(customSecretName))
-				g.Expect(cfAPI.Status.InstallationConfig.ContainerRegistryURL).To(Equal("https://my-custom-registry.com"))
+				g.Expect(cfAPI.Status.InstallationConfig.ImageRewrites).To(Equal([]v1alpha1.ImageRewrite{{From: "ghcr.io/", To: "mirror.example.com/ghcr/"}}))
+				g.Expect(cfAPI.Status.InstallationConfig.ImagePullSecrets).To(Equal([]string{"mirror-secret"}))
 			}).Should(Succeed())
 		})
 
-		When("the custom registry secret does not specify registries", func() {
+		It("reports the images which are not mirrored", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+					HasType(Equal(v1alpha1.ConditionTypeImageMirror)),
+					HasStatus(Equal(metav1.ConditionFalse)),
+					HasReason(Equal("ImagesNotMirrored")),
+					HasMessage(Equal("No image rewrite matches images gcr.io/foo/bar, quay.io/foo/bar")),
+				)))
+			}).Should(Succeed())
+		})
+
+		When("all images are mirrored", func() {
 			BeforeEach(func() {
-				customSecretName = uuid.NewString()
-				Expect(adminClient.Create(ctx, &corev1.Secret{
-					ObjectMeta: metav1.ObjectMeta{
-						Namespace: cfAPINamespace,
-						Name:      customSecretName,
-					},
-					Data: map[string][]byte{
-						corev1.DockerConfigJsonKey: []byte(`{"auths":{}}`),
-					},
-				})).To(Succeed())
+				firstToInstall.InstallReturns(installable.Result{State: installable.ResultStateSuccess}, nil)
+				secondToInstall.InstallReturns(installable.Result{State: installable.ResultStateSuccess}, nil)
+			})
+
+			It("sets the image mirror condition", func() {
+				Eventually(func(g Gomega) {
+					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+					g.Expect(meta.IsStatusConditionTrue(cfAPI.Status.Conditions, v1alpha1.ConditionTypeImageMirror)).To(BeTrue())
+				}).Should(Succeed())
+			})
+		})
 
+		When("a pull secret does not exist", func() {
+			BeforeEach(func() {
 				Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
-					cfAPI.Spec.ContainerRegistrySecret = customSecretName
+					cfAPI.Spec.ImageMirror.PullSecrets = []string{"missing-secret"}
 				})).To(Succeed())
 			})
 
-			It("sets warning status on the cfapi", func() {
+			It("sets the configuration status condition to false", func() {
 				Eventually(func(g Gomega) {
 					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
-					g.Expect(cfAPI.Status.State).To(Equal(v1alpha1.StateWarning))
+					g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+						HasType(Equal(v1alpha1.ConditionTypeConfiguration)),
+						HasStatus(Equal(metav1.ConditionFalse)),
+						HasMessage(ContainSubstring("image mirror pull secret missing-secret")),
+					)))
 				}).Should(Succeed())
 			})
+		})
+	})
 
-			It("sets the configuration status condition to false", func() {
+	When("a trusted CA bundle is configured", func() {
+		var bundle *corev1.ConfigMap
+
+		BeforeEach(func() {
+			bundle = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: cfAPINamespace,
+					Name:      "corporate-ca",
+				},
+				Data: map[string]string{"ca.crt": selfSignedCertificate()},
+			}
+			Expect(adminClient.Create(ctx, bundle)).To(Succeed())
+
+			Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+				cfAPI.Spec.TrustedCABundle = &v1alpha1.TrustedCABundle{Name: "corporate-ca"}
+			})).To(Succeed())
+		})
+
+		It("passes the bundle to the installables", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.InstallationConfig.TrustedCA).To(PointTo(MatchAllFields(Fields{
+					"Namespace": Equal(cfAPINamespace),
+					"Name":      Equal("corporate-ca"),
+					"Key":       Equal("ca.crt"),
+					"Hash":      HaveLen(64),
+				})))
+			}).Should(Succeed())
+		})
+
+		When("the bundle changes", func() {
+			var hash string
+
+			BeforeEach(func() {
 				Eventually(func(g Gomega) {
 					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
-					g.Expect(meta.IsStatusConditionFalse(cfAPI.Status.Conditions, v1alpha1.ConditionTypeConfiguration)).To(BeTrue())
+					g.Expect(cfAPI.Status.InstallationConfig.TrustedCA).NotTo(BeNil())
+					hash = cfAPI.Status.InstallationConfig.TrustedCA.Hash
+				}).Should(Succeed())
+
+				Expect(k8s.Patch(ctx, adminClient, bundle, func() {
+					bundle.Data["ca.crt"] = selfSignedCertificate()
+				})).To(Succeed())
+			})
+
+			It("updates the hash of the bundle", func() {
+				Eventually(func(g Gomega) {
+					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+					g.Expect(cfAPI.Status.InstallationConfig.TrustedCA.Hash).NotTo(Equal(hash))
 				}).Should(Succeed())
 			})
 		})
 
-		When("the custom registry secret does not exists", func() {
+		When("the bundle holds no certificates", func() {
 			BeforeEach(func() {
-				customSecretName = uuid.NewString()
-				Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
-					cfAPI.Spec.ContainerRegistrySecret = customSecretName
+				Expect(k8s.Patch(ctx, adminClient, bundle, func() {
+					bundle.Data["ca.crt"] = "not a certificate"
 				})).To(Succeed())
 			})
 
-			It("sets warning status on the cfapi", func() {
+			It("sets the configuration status condition to false", func() {
 				Eventually(func(g Gomega) {
 					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
-					g.Expect(cfAPI.Status.State).To(Equal(v1alpha1.StateWarning))
+					g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+						HasType(Equal(v1alpha1.ConditionTypeConfiguration)),
+						HasStatus(Equal(metav1.ConditionFalse)),
+						HasMessage(ContainSubstring("trusted CA bundle corporate-ca is invalid")),
+					)))
 				}).Should(Succeed())
 			})
+		})
+
+		When("the bundle key does not exist", func() {
+			BeforeEach(func() {
+				Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+					cfAPI.Spec.TrustedCABundle.Key = "bundle.pem"
+				})).To(Succeed())
+			})
 
 			It("sets the configuration status condition to false", func() {
 				Eventually(func(g Gomega) {
 					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
-					g.Expect(meta.IsStatusConditionFalse(cfAPI.Status.Conditions, v1alpha1.ConditionTypeConfiguration)).To(BeTrue())
+					g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+						HasType(Equal(v1alpha1.ConditionTypeConfiguration)),
+						HasStatus(Equal(metav1.ConditionFalse)),
+						HasMessage(ContainSubstring("does not contain key bundle.pem")),
+					)))
+				}).Should(Succeed())
+			})
+		})
+	})
+
+	When("a proxy is configured", func() {
+		BeforeEach(func() {
+			Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+				cfAPI.Spec.Proxy = &v1alpha1.Proxy{
+					HTTPProxy: "http://proxy.example.com:3128",
+					NoProxy:   []string{".corp.example.com", "localhost"},
+				}
+			})).To(Succeed())
+		})
+
+		It("passes the proxy to the installables", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				proxyConfig := cfAPI.Status.InstallationConfig.Proxy
+				g.Expect(proxyConfig).NotTo(BeNil())
+				g.Expect(proxyConfig.HTTPProxy).To(Equal("http://proxy.example.com:3128"))
+				g.Expect(proxyConfig.HTTPSProxy).To(Equal("http://proxy.example.com:3128"))
+				g.Expect(proxyConfig.InjectIntoApps).To(BeFalse())
+
+				noProxy := strings.Split(proxyConfig.NoProxy, ",")
+				g.Expect(noProxy).To(ContainElements("localhost", ".svc", ".cluster.local", cfAPI.Status.InstallationConfig.CFDomain, ".corp.example.com"))
+				g.Expect(noProxy).To(HaveLen(len(slices.Compact(slices.Sorted(slices.Values(noProxy))))))
+			}).Should(Succeed())
+		})
+
+		When("the proxy is injected into apps", func() {
+			BeforeEach(func() {
+				Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+					cfAPI.Spec.Proxy.InjectIntoApps = true
+				})).To(Succeed())
+			})
+
+			It("passes it to the installation config", func() {
+				Eventually(func(g Gomega) {
+					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+					g.Expect(cfAPI.Status.InstallationConfig.Proxy).NotTo(BeNil())
+					g.Expect(cfAPI.Status.InstallationConfig.Proxy.InjectIntoApps).To(BeTrue())
+				}).Should(Succeed())
+			})
+		})
+
+		When("the proxy url is invalid", func() {
+			BeforeEach(func() {
+				Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+					cfAPI.Spec.Proxy.HTTPProxy = "proxy.example.com:3128"
+				})).To(Succeed())
+			})
+
+			It("sets the configuration status condition to false", func() {
+				Eventually(func(g Gomega) {
+					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+					g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+						HasType(Equal(v1alpha1.ConditionTypeConfiguration)),
+						HasStatus(Equal(metav1.ConditionFalse)),
+						HasMessage(ContainSubstring("invalid proxy url proxy.example.com:3128")),
+					)))
+				}).Should(Succeed())
+			})
+		})
+	})
+
+	It("does not set the high availability condition", func() {
+		Eventually(func(g Gomega) {
+			g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+			g.Expect(cfAPI.Status.State).To(Equal(v1alpha1.StateReady))
+			g.Expect(cfAPI.Status.InstallationConfig.HighAvailability).To(BeNil())
+			g.Expect(meta.FindStatusCondition(cfAPI.Status.Conditions, v1alpha1.ConditionTypeHighAvailability)).To(BeNil())
+		}).Should(Succeed())
+	})
+
+	When("high availability is configured", func() {
+		BeforeEach(func() {
+			Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+				cfAPI.Spec.HighAvailability = &v1alpha1.HighAvailability{
+					ZoneSpread: v1alpha1.ZoneSpreadRequired,
+				}
+			})).To(Succeed())
+		})
+
+		It("passes the settings with their defaults to the installables", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.InstallationConfig.HighAvailability).To(Equal(&v1alpha1.HighAvailability{
+					Replicas:   2,
+					ZoneSpread: v1alpha1.ZoneSpreadRequired,
+				}))
+			}).Should(Succeed())
+		})
+
+		It("reports that the components cannot be spread across zones", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+					HasType(Equal(v1alpha1.ConditionTypeHighAvailability)),
+					HasStatus(Equal(metav1.ConditionFalse)),
+					HasReason(Equal("NotSpread")),
+					HasMessage(ContainSubstring("the cluster has a single zone")),
+				)))
+			}).Should(Succeed())
+		})
+	})
+
+	When("hibernation is configured", func() {
+		var (
+			deployment *appsv1.Deployment
+			operator   *appsv1.Deployment
+		)
+
+		BeforeEach(func() {
+			for _, namespace := range []string{"korifi", "cfapi-system"} {
+				Expect(client.IgnoreAlreadyExists(adminClient.Create(ctx, &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{Name: namespace},
+				}))).To(Succeed())
+			}
+
+			deployment = &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "korifi",
+					Name:      uuid.NewString(),
+					Labels:    map[string]string{installable.InstalledByLabel: installable.InstalledByValue},
+				},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: tools.PtrTo(int32(2)),
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "korifi-api"}},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "korifi-api"}},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "korifi-api", Image: "korifi-api"}},
+						},
+					},
+				},
+			}
+			EnsureCreate(adminClient, deployment)
+
+			operator = &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "cfapi-system",
+					Name:      uuid.NewString(),
+					Labels:    map[string]string{hibernation.OperatorComponentLabel: hibernation.OperatorComponent},
+				},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: tools.PtrTo(int32(1)),
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"control-plane": "operator"}},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"control-plane": "operator"}},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "manager", Image: "cfapi-operator"}},
+						},
+					},
+				},
+			}
+			EnsureCreate(adminClient, operator)
+
+			Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+				cfAPI.Spec.Hibernation = &v1alpha1.Hibernation{Hibernated: true}
+			})).To(Succeed())
+		})
+
+		It("scales the platform to zero", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.Hibernation).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"State": Equal(v1alpha1.HibernationStateHibernated),
+				})))
+				g.Expect(cfAPI.Status.State).To(Equal(v1alpha1.StateReady))
+
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+				g.Expect(deployment.Spec.Replicas).To(PointTo(BeEquivalentTo(0)))
+				g.Expect(deployment.Annotations).To(HaveKeyWithValue(hibernation.ReplicasAnnotation, "2"))
+			}).Should(Succeed())
+		})
+
+		It("keeps the operator running", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.Hibernation).To(PointTo(MatchFields(IgnoreExtras, Fields{
+					"State": Equal(v1alpha1.HibernationStateHibernated),
+				})))
+			}).Should(Succeed())
+
+			Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(operator), operator)).To(Succeed())
+			Expect(operator.Spec.Replicas).To(PointTo(BeEquivalentTo(1)))
+			Expect(operator.Annotations).NotTo(HaveKey(hibernation.ReplicasAnnotation))
+		})
+
+		When("the platform is resumed", func() {
+			BeforeEach(func() {
+				Eventually(func(g Gomega) {
+					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+					g.Expect(cfAPI.Status.Hibernation).NotTo(BeNil())
+					g.Expect(cfAPI.Status.Hibernation.State).To(Equal(v1alpha1.HibernationStateHibernated))
+				}).Should(Succeed())
+
+				Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+					cfAPI.Spec.Hibernation.Hibernated = false
+				})).To(Succeed())
+			})
+
+			It("restores the replicas", func() {
+				Eventually(func(g Gomega) {
+					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+					g.Expect(cfAPI.Status.Hibernation).To(PointTo(MatchFields(IgnoreExtras, Fields{
+						"State": Equal(v1alpha1.HibernationStateRunning),
+					})))
+
+					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)).To(Succeed())
+					g.Expect(deployment.Spec.Replicas).To(PointTo(BeEquivalentTo(2)))
+					g.Expect(deployment.Annotations).NotTo(HaveKey(hibernation.ReplicasAnnotation))
+				}).Should(Succeed())
+			})
+		})
+
+		When("the schedule is invalid", func() {
+			BeforeEach(func() {
+				Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+					cfAPI.Spec.Hibernation.Schedule = &v1alpha1.HibernationSchedule{
+						Hibernate: "0 25 * * *",
+						Resume:    "0 7 * * *",
+					}
+				})).To(Succeed())
+			})
+
+			It("sets the configuration status condition to false", func() {
+				Eventually(func(g Gomega) {
+					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+					g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+						HasType(Equal(v1alpha1.ConditionTypeConfiguration)),
+						HasStatus(Equal(metav1.ConditionFalse)),
+						HasMessage(ContainSubstring("hour 25 is out of range 0-23")),
+					)))
+				}).Should(Succeed())
+			})
+		})
+	})
+
+	When("one of the installables returns processing result", func() {
+		BeforeEach(func() {
+			secondToInstall.InstallReturns(installable.Result{
+				State: installable.ResultStateInProgress,
+			}, nil)
+		})
+
+		It("sets processing state in status", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.State).To(Equal(v1alpha1.StateProcessing))
+				g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+					HasType(Equal(v1alpha1.ConditionTypeInstallation)),
+					HasStatus(Equal(metav1.ConditionUnknown)),
+					HasReason(Equal("InstallationInProgress")),
+				)))
+			}).Should(Succeed())
+		})
+	})
+
+	When("the external address of the kyma docker registry is used", func() {
+		BeforeEach(func() {
+			Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+				cfAPI.Spec.UseExternalDockerRegistry = true
+			})).To(Succeed())
+		})
+
+		It("pushes and pulls images through the external address", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.InstallationConfig.ContainerRegistrySecret).To(Equal(kyma.ContainerRegistrySecretName))
+				g.Expect(cfAPI.Status.InstallationConfig.ContainerRegistryURL).To(Equal("https://kyma-registry.com"))
+				g.Expect(cfAPI.Status.InstallationConfig.ContainerRegistryPullURL).To(BeEmpty())
+				g.Expect(cfAPI.Status.InstallationConfig.ContainerRepositoryPrefix).To(Equal("https://kyma-registry.com/"))
+			}).Should(Succeed())
+		})
+	})
+
+	When("the kyma docker registry secret has no credentials for the pull address", func() {
+		BeforeEach(func() {
+			secret := &corev1.Secret{}
+			Expect(adminClient.Get(ctx, client.ObjectKey{Namespace: cfAPINamespace, Name: kyma.InternalContainerRegistrySecretName}, secret)).To(Succeed())
+			Expect(k8s.Patch(ctx, adminClient, secret, func() {
+				secret.Data[corev1.DockerConfigJsonKey] = []byte(`{"auths":{"dockerregistry.kyma-system.svc.cluster.local:5000":{"username":"kyma-user","password":"kyma-password"}}}`)
+			})).To(Succeed())
+		})
+
+		It("reports the missing credentials", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+					HasType(Equal(v1alpha1.ConditionTypeConfiguration)),
+					HasStatus(Equal(metav1.ConditionFalse)),
+					HasMessage(ContainSubstring("does not specify credentials for localhost:32137")),
+				)))
+			}).Should(Succeed())
+		})
+	})
+
+	When("the user has specified a custom registry secret", func() {
+		var customSecretName string
+
+		BeforeEach(func() {
+			customSecretName = uuid.NewString()
+			Expect(adminClient.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: cfAPINamespace,
+					Name:      customSecretName,
+				},
+				Data: map[string][]byte{
+					corev1.DockerConfigJsonKey: []byte(`{"auths":{"https://my-custom-registry.com": {}}}`),
+				},
+			})).To(Succeed())
+
+			Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+				cfAPI.Spec.ContainerRegistrySecret = customSecretName
+			})).To(Succeed())
+		})
+
+		It("sets the custom container registry secret in the status", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.InstallationConfig.ContainerRegistrySecret).To(Equal(customSecretName))
+				g.Expect(cfAPI.Status.InstallationConfig.ContainerRegistryURL).To(Equal("https://my-custom-registry.com"))
+			}).Should(Succeed())
+		})
+
+		It("reports the failing registry check", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+					HasType(Equal(v1alpha1.ConditionTypeRegistry)),
+					HasStatus(Equal(metav1.ConditionFalse)),
+					HasReason(Equal("CheckFailed")),
+					HasMessage(ContainSubstring("401 Unauthorized")),
+				)))
+			}).Should(Succeed())
+		})
+
+		When("the custom registry secret has valid credentials", func() {
+			BeforeEach(func() {
+				customSecretName = uuid.NewString()
+				Expect(adminClient.Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: cfAPINamespace,
+						Name:      customSecretName,
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte(`{"auths":{"my-custom-registry.com": {"username": "registry-user", "password": "registry-password"}}}`),
+					},
+				})).To(Succeed())
+
+				Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+					cfAPI.Spec.ContainerRegistrySecret = customSecretName
+				})).To(Succeed())
+			})
+
+			It("reports the registry as accessible", func() {
+				Eventually(func(g Gomega) {
+					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+					g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+						HasType(Equal(v1alpha1.ConditionTypeRegistry)),
+						HasStatus(Equal(metav1.ConditionTrue)),
+						HasReason(Equal("RegistryAccessible")),
+					)))
+				}).Should(Succeed())
+			})
+
+			When("the push check is enabled", func() {
+				BeforeEach(func() {
+					Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+						cfAPI.Spec.ContainerRegistryCheck = &v1alpha1.ContainerRegistryCheck{Push: true}
+					})).To(Succeed())
+				})
+
+				It("reports that pushes are allowed", func() {
+					Eventually(func(g Gomega) {
+						g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+						g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+							HasType(Equal(v1alpha1.ConditionTypeRegistry)),
+							HasStatus(Equal(metav1.ConditionTrue)),
+							HasReason(Equal("PushAllowed")),
+							HasMessage(ContainSubstring("my-custom-registry.com/cfapi/kpack-builder")),
+						)))
+					}).Should(Succeed())
+				})
+
+				When("pushing to the builder repository is denied", func() {
+					BeforeEach(func() {
+						Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+							cfAPI.Spec.BuilderRepository = "my-custom-registry.com/" + RegistryStandInDeniedPrefix + "kpack-builder"
+						})).To(Succeed())
+					})
+
+					It("reports the HTTP error of the registry", func() {
+						Eventually(func(g Gomega) {
+							g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+							g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+								HasType(Equal(v1alpha1.ConditionTypeRegistry)),
+								HasStatus(Equal(metav1.ConditionFalse)),
+								HasReason(Equal("CheckFailed")),
+								HasMessage(ContainSubstring("403 Forbidden")),
+							)))
+						}).Should(Succeed())
+					})
+				})
+			})
+
+			When("the registry check is disabled", func() {
+				BeforeEach(func() {
+					Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+						cfAPI.Spec.ContainerRegistryCheck = &v1alpha1.ContainerRegistryCheck{Disabled: true}
+					})).To(Succeed())
+				})
+
+				It("does not check the registry", func() {
+					Eventually(func(g Gomega) {
+						g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+						g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+							HasType(Equal(v1alpha1.ConditionTypeRegistry)),
+							HasStatus(Equal(metav1.ConditionUnknown)),
+							HasReason(Equal("CheckDisabled")),
+						)))
+					}).Should(Succeed())
+				})
+			})
+		})
+
+		When("the custom registry secret specifies multiple registries", func() {
+			BeforeEach(func() {
+				customSecretName = uuid.NewString()
+				Expect(adminClient.Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: cfAPINamespace,
+						Name:      customSecretName,
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte(`{"auths":{"registry-c.com": {}, "registry-a.com": {}, "registry-b.com": {}}}`),
+					},
+				})).To(Succeed())
+
+				Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+					cfAPI.Spec.ContainerRegistrySecret = customSecretName
+				})).To(Succeed())
+			})
+
+			It("picks the first registry in lexical order", func() {
+				Eventually(func(g Gomega) {
+					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+					g.Expect(cfAPI.Status.InstallationConfig.ContainerRegistrySecret).To(Equal(customSecretName))
+					g.Expect(cfAPI.Status.InstallationConfig.ContainerRegistryURL).To(Equal("registry-a.com"))
+				}).Should(Succeed())
+			})
+
+			When("the container registry url is specified", func() {
+				BeforeEach(func() {
+					Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+						cfAPI.Spec.ContainerRegistryURL = "https://registry-b.com/"
+					})).To(Succeed())
+				})
+
+				It("uses the specified registry", func() {
+					Eventually(func(g Gomega) {
+						g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+						g.Expect(cfAPI.Status.InstallationConfig.ContainerRegistryURL).To(Equal("registry-b.com"))
+						g.Expect(cfAPI.Status.InstallationConfig.ContainerRepositoryPrefix).To(Equal("registry-b.com/"))
+					}).Should(Succeed())
+				})
+			})
+
+			When("the container registry url is not in the secret", func() {
+				BeforeEach(func() {
+					Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+						cfAPI.Spec.ContainerRegistryURL = "registry-d.com"
+					})).To(Succeed())
+				})
+
+				It("sets the configuration status condition to false", func() {
+					Eventually(func(g Gomega) {
+						g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+						g.Expect(cfAPI.Status.State).To(Equal(v1alpha1.StateWarning))
+						g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+							HasType(Equal(v1alpha1.ConditionTypeConfiguration)),
+							HasStatus(Equal(metav1.ConditionFalse)),
+							HasMessage(ContainSubstring("configured registries are: registry-a.com, registry-b.com, registry-c.com")),
+						)))
+					}).Should(Succeed())
+				})
+			})
+		})
+
+		When("packages, droplets and the builder use separate registries", func() {
+			var dropletsSecretName, builderSecretName string
+
+			BeforeEach(func() {
+				dropletsSecretName = uuid.NewString()
+				Expect(adminClient.Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: cfAPINamespace,
+						Name:      dropletsSecretName,
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte(`{"auths":{"droplets-registry.com": {"username": "droplets-user", "password": "droplets-password"}}}`),
+					},
+				})).To(Succeed())
+
+				builderSecretName = uuid.NewString()
+				Expect(adminClient.Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: cfAPINamespace,
+						Name:      builderSecretName,
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte(`{"auths":{"builder-registry.com": {"username": "builder-user", "password": "builder-password"}}}`),
+					},
+				})).To(Succeed())
+
+				Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+					cfAPI.Spec.ContainerRegistries = &v1alpha1.ContainerRegistries{
+						Packages: &v1alpha1.ContainerRepository{
+							Repository: "my-custom-registry.com/packages/",
+						},
+						Droplets: &v1alpha1.ContainerRepository{
+							Secret:     dropletsSecretName,
+							Repository: "droplets-registry.com/droplets/",
+						},
+						Builder: &v1alpha1.ContainerRepository{
+							Secret: builderSecretName,
+						},
+					}
+				})).To(Succeed())
+			})
+
+			It("sets the registries in the status", func() {
+				Eventually(func(g Gomega) {
+					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+					g.Expect(cfAPI.Status.InstallationConfig.PackageRegistrySecret).To(Equal(customSecretName))
+					g.Expect(cfAPI.Status.InstallationConfig.PackageRepositoryPrefix).To(Equal("my-custom-registry.com/packages/"))
+					g.Expect(cfAPI.Status.InstallationConfig.DropletRegistrySecret).To(Equal(dropletsSecretName))
+					g.Expect(cfAPI.Status.InstallationConfig.DropletRepositoryPrefix).To(Equal("droplets-registry.com/droplets/"))
+					g.Expect(cfAPI.Status.InstallationConfig.BuilderRegistrySecret).To(Equal(builderSecretName))
+					g.Expect(cfAPI.Status.InstallationConfig.BuilderRepository).To(Equal("builder-registry.com/cfapi/kpack-builder"))
+				}).Should(Succeed())
+			})
+
+			When("a registry secret does not hold credentials for its repository", func() {
+				BeforeEach(func() {
+					Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+						cfAPI.Spec.ContainerRegistries.Droplets.Repository = "other-registry.com/droplets/"
+					})).To(Succeed())
+				})
+
+				It("sets the configuration status condition to false", func() {
+					Eventually(func(g Gomega) {
+						g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+						g.Expect(cfAPI.Status.State).To(Equal(v1alpha1.StateWarning))
+						g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+							HasType(Equal(v1alpha1.ConditionTypeConfiguration)),
+							HasStatus(Equal(metav1.ConditionFalse)),
+							HasMessage(ContainSubstring("of the droplets registry does not specify credentials for other-registry.com/droplets/")),
+						)))
+					}).Should(Succeed())
+				})
+			})
+
+			When("registry secrets specify different credentials for the same registry", func() {
+				BeforeEach(func() {
+					packagesSecretName := uuid.NewString()
+					Expect(adminClient.Create(ctx, &corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{
+							Namespace: cfAPINamespace,
+							Name:      packagesSecretName,
+						},
+						Data: map[string][]byte{
+							corev1.DockerConfigJsonKey: []byte(`{"auths":{"droplets-registry.com": {"username": "packages-user", "password": "packages-password"}}}`),
+						},
+					})).To(Succeed())
+
+					Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+						cfAPI.Spec.ContainerRegistries.Packages = &v1alpha1.ContainerRepository{
+							Secret:     packagesSecretName,
+							Repository: "droplets-registry.com/packages/",
+						}
+					})).To(Succeed())
+				})
+
+				It("sets the configuration status condition to false", func() {
+					Eventually(func(g Gomega) {
+						g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+						g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+							HasType(Equal(v1alpha1.ConditionTypeConfiguration)),
+							HasStatus(Equal(metav1.ConditionFalse)),
+							HasMessage(ContainSubstring("specify different credentials for registry droplets-registry.com")),
+						)))
+					}).Should(Succeed())
+				})
+			})
+		})
+
+		When("the custom registry secret does not specify registries", func() {
+			BeforeEach(func() {
+				customSecretName = uuid.NewString()
+				Expect(adminClient.Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: cfAPINamespace,
+						Name:      customSecretName,
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte(`{"auths":{}}`),
+					},
+				})).To(Succeed())
+
+				Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+					cfAPI.Spec.ContainerRegistrySecret = customSecretName
+				})).To(Succeed())
+			})
+
+			It("sets warning status on the cfapi", func() {
+				Eventually(func(g Gomega) {
+					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+					g.Expect(cfAPI.Status.State).To(Equal(v1alpha1.StateWarning))
+				}).Should(Succeed())
+			})
+
+			It("sets the configuration status condition to false", func() {
+				Eventually(func(g Gomega) {
+					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+					g.Expect(meta.IsStatusConditionFalse(cfAPI.Status.Conditions, v1alpha1.ConditionTypeConfiguration)).To(BeTrue())
+				}).Should(Succeed())
+			})
+		})
+
+		When("the custom registry secret does not exists", func() {
+			BeforeEach(func() {
+				customSecretName = uuid.NewString()
+				Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+					cfAPI.Spec.ContainerRegistrySecret = customSecretName
+				})).To(Succeed())
+			})
+
+			It("sets warning status on the cfapi", func() {
+				Eventually(func(g Gomega) {
+					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+					g.Expect(cfAPI.Status.State).To(Equal(v1alpha1.StateWarning))
+				}).Should(Succeed())
+			})
+
+			It("sets the configuration status condition to false", func() {
+				Eventually(func(g Gomega) {
+					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+					g.Expect(meta.IsStatusConditionFalse(cfAPI.Status.Conditions, v1alpha1.ConditionTypeConfiguration)).To(BeTrue())
+				}).Should(Succeed())
+			})
+		})
+	})
+
+	When("the build is configured", func() {
+		BeforeEach(func() {
+			Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+				cfAPI.Spec.Build = &v1alpha1.Build{
+					Buildpacks: []string{"paketobuildpacks/java", "paketobuildpacks/dotnet-core"},
+					Order: []v1alpha1.BuildpackGroup{
+						{Group: []v1alpha1.BuildpackRef{{ID: "paketo-buildpacks/java"}}},
+						{Group: []v1alpha1.BuildpackRef{{ID: "paketo-buildpacks/dotnet-core"}, {ID: "paketo-buildpacks/procfile", Optional: true}}},
+					},
+					Cache: &v1alpha1.BuildCache{
+						Size:             tools.PtrTo(resource.MustParse("5Gi")),
+						StorageClassName: "fast-storage",
+					},
+					Resources: &v1alpha1.BuildResources{
+						Memory:           tools.PtrTo(resource.MustParse("2G")),
+						EphemeralStorage: tools.PtrTo(resource.MustParse("4Gi")),
+					},
+				}
+			})).To(Succeed())
+		})
+
+		It("sets the build config in the status", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				config := cfAPI.Status.InstallationConfig
+				g.Expect(config.ClusterBuilderName).To(Equal(cfapi.CustomClusterBuilderName))
+				g.Expect(config.Buildpacks).To(Equal([]string{"paketobuildpacks/java", "paketobuildpacks/dotnet-core"}))
+				g.Expect(config.Stack).To(Equal(&v1alpha1.BuildStack{
+					ID:         "io.buildpacks.stacks.jammy",
+					BuildImage: "paketobuildpacks/build-jammy-full",
+					RunImage:   "paketobuildpacks/run-jammy-full",
+				}))
+				g.Expect(config.BuildpackOrder).To(Equal(cfAPI.Spec.Build.Order))
+				g.Expect(config.BuildCacheMB).To(Equal(int64(5120)))
+				g.Expect(config.BuildCacheStorageClassName).To(Equal("fast-storage"))
+				g.Expect(config.BuildMemoryMB).To(Equal(int64(2000)))
+				g.Expect(config.BuildDiskMB).To(Equal(int64(4295)))
+			}).Should(Succeed())
+		})
+
+		When("the build cache size is decreased", func() {
+			BeforeEach(func() {
+				Eventually(func(g Gomega) {
+					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+					g.Expect(cfAPI.Status.InstallationConfig.BuildCacheMB).To(Equal(int64(5120)))
+				}).Should(Succeed())
+
+				Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+					cfAPI.Spec.Build.Cache.Size = tools.PtrTo(resource.MustParse("1Gi"))
+				})).To(Succeed())
+			})
+
+			It("rejects the configuration and keeps the installed size", func() {
+				Eventually(func(g Gomega) {
+					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+					g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+						HasType(Equal(v1alpha1.ConditionTypeConfiguration)),
+						HasStatus(Equal(metav1.ConditionFalse)),
+						HasMessage(ContainSubstring("spec.build.cache.size cannot be decreased")),
+					)))
+					g.Expect(cfAPI.Status.InstallationConfig.BuildCacheMB).To(Equal(int64(5120)))
+				}).Should(Succeed())
+			})
+		})
+
+		It("reports that the builder does not exist yet", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+					HasType(Equal(v1alpha1.ConditionTypeBuilder)),
+					HasStatus(Equal(metav1.ConditionUnknown)),
+					HasReason(Equal("BuilderNotFound")),
+					HasMessage(ContainSubstring(cfapi.CustomClusterBuilderName)),
+				)))
+			}).Should(Succeed())
+		})
+
+		When("the builder is ready", func() {
+			BeforeEach(func() {
+				clusterBuilder := &buildv1alpha2.ClusterBuilder{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: cfapi.CustomClusterBuilderName,
+					},
+					Spec: buildv1alpha2.ClusterBuilderSpec{
+						BuilderSpec: buildv1alpha2.BuilderSpec{
+							Tag: "my-registry.com/cfapi/kpack-builder",
+						},
+					},
+				}
+				EnsureCreate(adminClient, clusterBuilder)
+				DeferCleanup(func() {
+					EnsureDelete(adminClient, clusterBuilder)
+				})
+
+				clusterBuilder.Status.ObservedGeneration = clusterBuilder.Generation
+				clusterBuilder.Status.LatestImage = "my-registry.com/cfapi/kpack-builder@sha256:1"
+				clusterBuilder.Status.Conditions = corev1alpha1.Conditions{{
+					Type:   corev1alpha1.ConditionReady,
+					Status: corev1.ConditionTrue,
+				}}
+				Expect(adminClient.Status().Update(ctx, clusterBuilder)).To(Succeed())
+			})
+
+			It("sets the builder status condition", func() {
+				Eventually(func(g Gomega) {
+					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+					g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+						HasType(Equal(v1alpha1.ConditionTypeBuilder)),
+						HasStatus(Equal(metav1.ConditionTrue)),
+						HasReason(Equal("BuilderReady")),
+						HasMessage(ContainSubstring("my-registry.com/cfapi/kpack-builder@sha256:1")),
+					)))
+				}).Should(Succeed())
+			})
+		})
+
+		When("buildpacks are configured without an order", func() {
+			BeforeEach(func() {
+				Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+					cfAPI.Spec.Build.Order = nil
+				})).To(Succeed())
+			})
+
+			It("sets the configuration status condition to false", func() {
+				Eventually(func(g Gomega) {
+					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+					g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+						HasType(Equal(v1alpha1.ConditionTypeConfiguration)),
+						HasStatus(Equal(metav1.ConditionFalse)),
+						HasMessage(ContainSubstring("spec.build.order is required")),
+					)))
+				}).Should(Succeed())
+			})
+		})
+
+		When("updates are enabled", func() {
+			BeforeEach(func() {
+				Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+					cfAPI.Spec.Build = &v1alpha1.Build{
+						Updates: &v1alpha1.BuildUpdates{Enabled: true},
+					}
+					cfAPI.Status.Build = &v1alpha1.BuildStatus{
+						Images: []v1alpha1.ResolvedImage{
+							{Image: "paketobuildpacks/java", Digest: "sha256:java"},
+							{Image: "paketobuildpacks/run-jammy-full", Digest: "sha256:run"},
+							{Image: "paketobuildpacks/removed", Digest: "sha256:removed"},
+						},
+					}
+				})).To(Succeed())
+			})
+
+			It("pins the resolved images of the default builder", func() {
+				Eventually(func(g Gomega) {
+					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+					config := cfAPI.Status.InstallationConfig
+					g.Expect(config.ClusterBuilderName).To(Equal(cfapi.CustomClusterBuilderName))
+					g.Expect(config.BuildpackOrder).NotTo(BeEmpty())
+					g.Expect(config.BuildImageDigests).To(Equal(map[string]string{
+						"paketobuildpacks/java":           "sha256:java",
+						"paketobuildpacks/run-jammy-full": "sha256:run",
+					}))
 				}).Should(Succeed())
 			})
 		})
@@ -0,0 +1,550 @@
+package cfapi
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	v1alpha1 "github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/installable"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	// CutoverLabel marks the cutover gateway and the routes mirrored to it
+	CutoverLabel = "cfapi.kyma-project.io/gateway-cutover"
+
+	korifiGatewayNamespace = "cfapi-system"
+	korifiGatewayName      = "korifi"
+
+	// safeUpgradesPolicyBinding is shipped with the Gateway API CRDs and
+	// denies replacing the standard channel CRDs with the experimental ones
+	safeUpgradesPolicyBinding = "safe-upgrades.gateway.networking.k8s.io"
+)
+
+// cutoverRouteKinds are the routes Korifi attaches to its gateway
+var cutoverRouteKinds = []schema.GroupVersionKind{
+	{Group: gatewayv1.GroupName, Version: "v1", Kind: "HTTPRoute"},
+	{Group: gatewayv1.GroupName, Version: "v1alpha2", Kind: "TLSRoute"},
+}
+
+// APIProbe checks that the CF API with the given host name is served by the
+// gateway at the given address
+type APIProbe func(ctx context.Context, address, host string) error
+
+// NewHTTPSProbe returns a probe requesting the root of the CF API from the
+// gateway address directly, as the DNS entries may not point to it yet
+func NewHTTPSProbe(timeout time.Duration) APIProbe {
+	return func(ctx context.Context, address, host string) error {
+		httpClient := &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+					return (&net.Dialer{}).DialContext(ctx, network, net.JoinHostPort(address, "443"))
+				},
+				// only the routing is verified, the certificate is verified by the CF clients
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec
+			},
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+host+"/", nil)
+		if err != nil {
+			return fmt.Errorf("failed to create the CF API request: %w", err)
+		}
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to reach the CF API at %s: %w", address, err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("the CF API at %s responded with %s", address, resp.Status)
+		}
+
+		return nil
+	}
+}
+
+// GatewayMigrator switches the gateway type without interrupting the CF API.
+// The new gateway implementation is brought up next to the old one, serving a
+// temporary cutover gateway with copies of the Korifi routes. Once it is
+// reachable, the DNS entries are moved to it, the Korifi gateway is switched
+// to the new implementation, the DNS entries are moved back to the Korifi
+// gateway and only then the old implementation is removed
+type GatewayMigrator struct {
+	k8sClient   client.Client
+	probe       APIProbe
+	drainPeriod time.Duration
+}
+
+// NewGatewayMigrator creates a migrator, waiting drainPeriod after moving the
+// DNS entries, so that cached DNS records expire before the previous gateway
+// is torn down
+func NewGatewayMigrator(k8sClient client.Client, probe APIProbe, drainPeriod time.Duration) *GatewayMigrator {
+	return &GatewayMigrator{
+		k8sClient:   k8sClient,
+		probe:       probe,
+		drainPeriod: drainPeriod,
+	}
+}
+
+type gatewaySettings struct {
+	gatewayType      string
+	className        string
+	ingressNamespace string
+	ingressService   string
+}
+
+// Prepare detects a switch of the gateway type and adjusts the gateway
+// settings of the installation config to the phase of the migration
+func (m *GatewayMigrator) Prepare(ctx context.Context, cfAPI *v1alpha1.CFAPI, config *v1alpha1.InstallationConfig, eventRecorder installable.EventRecorder) error {
+	previous := cfAPI.Status.InstallationConfig
+	from := gatewaySettings{
+		gatewayType:      previous.GatewayType,
+		className:        previous.GatewayClassName,
+		ingressNamespace: previous.KorifiIngressNamespace,
+		ingressService:   previous.KorifiIngressService,
+	}
+
+	if migration := cfAPI.Status.GatewayMigration; migration != nil {
+		if migration.To == config.GatewayType || migration.Phase != v1alpha1.GatewayMigrationPhaseProvisioning {
+			// the new gateway type is applied once the running migration has completed
+			applyMigrationPhase(migration, config)
+			return nil
+		}
+
+		// the new gateway does not serve any traffic yet, so the migration can be abandoned
+		if err := m.cleanup(ctx); err != nil {
+			return err
+		}
+		message := fmt.Sprintf("Aborted the migration from gateway type %s to %s", migration.From, migration.To)
+		eventRecorder.Event(installable.EventNormal, "GatewayMigrationAborted", message)
+		setGatewayMigrationCondition(cfAPI, metav1.ConditionTrue, "MigrationAborted", message)
+
+		cfAPI.Status.GatewayMigration = nil
+		from = gatewaySettings{
+			gatewayType:      migration.From,
+			className:        migration.FromClassName,
+			ingressNamespace: migration.FromIngressNamespace,
+			ingressService:   migration.FromIngressService,
+		}
+	}
+
+	if from.gatewayType == "" || from.gatewayType == config.GatewayType {
+		if meta.FindStatusCondition(cfAPI.Status.Conditions, v1alpha1.ConditionTypeGatewayMigration) == nil {
+			setGatewayMigrationCondition(cfAPI, metav1.ConditionTrue, "NoMigration", "Using gateway type "+config.GatewayType)
+		}
+		return nil
+	}
+
+	blocker, err := m.migrationBlocker(ctx, cfAPI, from.gatewayType, *config)
+	if err != nil {
+		return err
+	}
+	if blocker != "" {
+		return m.switchDirectly(ctx, cfAPI, from.gatewayType, config.GatewayType, blocker, eventRecorder)
+	}
+
+	migration := &v1alpha1.GatewayMigration{
+		From:                 from.gatewayType,
+		To:                   config.GatewayType,
+		Phase:                v1alpha1.GatewayMigrationPhaseProvisioning,
+		PhaseStartTime:       metav1.NewTime(time.Now()),
+		FromClassName:        from.className,
+		FromIngressNamespace: from.ingressNamespace,
+		FromIngressService:   from.ingressService,
+		ToClassName:          config.GatewayClassName,
+		ToIngressNamespace:   config.KorifiIngressNamespace,
+		ToIngressService:     config.KorifiIngressService,
+	}
+	cfAPI.Status.GatewayMigration = migration
+
+	message := fmt.Sprintf("Migrating from gateway type %s to %s", migration.From, migration.To)
+	eventRecorder.Event(installable.EventNormal, "GatewayMigrationStarted", message)
+	setGatewayMigrationCondition(cfAPI, metav1.ConditionFalse, migration.Phase, message)
+
+	applyMigrationPhase(migration, config)
+	return nil
+}
+
+// migrationBlocker returns why the gateway type cannot be switched with a
+// cutover gateway, or an empty string if it can
+func (m *GatewayMigrator) migrationBlocker(ctx context.Context, cfAPI *v1alpha1.CFAPI, fromType string, config v1alpha1.InstallationConfig) (string, error) {
+	switch {
+	case !meta.IsStatusConditionTrue(cfAPI.Status.Conditions, v1alpha1.ConditionTypeInstallation):
+		return "the previous installation has not completed", nil
+	case fromType == v1alpha1.GatewayTypeIstioNative || config.GatewayType == v1alpha1.GatewayTypeIstioNative:
+		return "the istio-native gateway type serves CF through the kyma gateway", nil
+	case config.Profile == v1alpha1.ProfileLocal:
+		return "the local profile does not manage DNS entries", nil
+	case config.IngressMode != v1alpha1.IngressModeLoadBalancer:
+		return fmt.Sprintf("the DNS entries target the configured host in ingress mode %s", config.IngressMode), nil
+	}
+
+	korifiGateway := &gatewayv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: korifiGatewayNamespace,
+			Name:      korifiGatewayName,
+		},
+	}
+	err := m.k8sClient.Get(ctx, client.ObjectKeyFromObject(korifiGateway), korifiGateway)
+	if k8serrors.IsNotFound(err) {
+		return "the Korifi gateway does not exist", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get the korifi gateway: %w", err)
+	}
+
+	return "", nil
+}
+
+func (m *GatewayMigrator) switchDirectly(ctx context.Context, cfAPI *v1alpha1.CFAPI, fromType, toType, blocker string, eventRecorder installable.EventRecorder) error {
+	if fromType == v1alpha1.GatewayTypeIstioNative {
+		if err := m.allowExperimentalChannel(ctx); err != nil {
+			setGatewayMigrationCondition(cfAPI, metav1.ConditionFalse, "SwitchBlocked", err.Error())
+			return err
+		}
+	}
+
+	message := fmt.Sprintf("Switched from gateway type %s to %s without a cutover gateway, as %s", fromType, toType, blocker)
+	eventRecorder.Event(installable.EventNormal, "GatewaySwitchedDirectly", message)
+	setGatewayMigrationCondition(cfAPI, metav1.ConditionTrue, "SwitchedDirectly", message)
+	return nil
+}
+
+// allowExperimentalChannel removes the binding of the safe upgrades policy of
+// the standard channel Gateway API CRDs, which denies their replacement with
+// the experimental ones. The experimental channel installs the binding again
+// once its CRDs are applied
+func (m *GatewayMigrator) allowExperimentalChannel(ctx context.Context) error {
+	binding := &admissionregistrationv1.ValidatingAdmissionPolicyBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: safeUpgradesPolicyBinding,
+		},
+	}
+	err := m.k8sClient.Get(ctx, client.ObjectKeyFromObject(binding), binding)
+	if k8serrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get validating admission policy binding %s: %w", safeUpgradesPolicyBinding, err)
+	}
+
+	if binding.Labels[installable.InstalledByLabel] != installable.InstalledByValue {
+		return fmt.Errorf("validating admission policy binding %s of another manager denies installing the experimental Gateway API CRDs. Delete it to switch from the istio-native gateway type", safeUpgradesPolicyBinding)
+	}
+
+	if err := m.k8sClient.Delete(ctx, binding); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to delete validating admission policy binding %s: %w", safeUpgradesPolicyBinding, err)
+	}
+
+	return nil
+}
+
+// applyMigrationPhase sets the gateway settings of the installation config.
+// The Korifi gateway keeps the previous gateway class until the DNS entries
+// point to the cutover gateway, which they do until the Korifi gateway is
+// served by the new implementation
+func applyMigrationPhase(migration *v1alpha1.GatewayMigration, config *v1alpha1.InstallationConfig) {
+	config.GatewayType = migration.To
+	config.PreviousGatewayType = migration.From
+	config.GatewayClassName = migration.ToClassName
+	config.KorifiIngressNamespace = migration.ToIngressNamespace
+	config.KorifiIngressService = migration.ToIngressService
+
+	switch migration.Phase {
+	case v1alpha1.GatewayMigrationPhaseProvisioning:
+		config.GatewayClassName = migration.FromClassName
+		config.KorifiIngressNamespace = migration.FromIngressNamespace
+		config.KorifiIngressService = migration.FromIngressService
+	case v1alpha1.GatewayMigrationPhaseCuttingOver:
+		config.GatewayClassName = migration.FromClassName
+		config.GatewayCutoverAddress = migration.CutoverAddress
+	case v1alpha1.GatewayMigrationPhaseSwitching:
+		config.GatewayCutoverAddress = migration.CutoverAddress
+	}
+}
+
+// Advance moves the migration to its next phase once the current one is
+// complete. It is called after the installables have been installed
+// successfully and returns whether a migration is still in progress
+func (m *GatewayMigrator) Advance(ctx context.Context, cfAPI *v1alpha1.CFAPI, config v1alpha1.InstallationConfig, eventRecorder installable.EventRecorder) (bool, error) {
+	migration := cfAPI.Status.GatewayMigration
+	if migration == nil {
+		return false, nil
+	}
+
+	apiHost := "cfapi." + config.CFDomain
+
+	if migration.Phase != v1alpha1.GatewayMigrationPhaseFinalizing {
+		if err := m.ensureCutover(ctx, migration.ToClassName); err != nil {
+			return true, err
+		}
+	}
+
+	switch migration.Phase {
+	case v1alpha1.GatewayMigrationPhaseProvisioning:
+		address, err := m.reachableAddress(ctx, v1alpha1.CutoverGatewayName, migration.ToClassName, apiHost)
+		if err != nil {
+			setGatewayMigrationCondition(cfAPI, metav1.ConditionFalse, migration.Phase, "Waiting for the cutover gateway: "+err.Error())
+			return true, nil
+		}
+
+		migration.CutoverAddress = address
+		m.enterPhase(cfAPI, migration, v1alpha1.GatewayMigrationPhaseCuttingOver,
+			fmt.Sprintf("Moving the DNS entries to the cutover gateway at %s", address), eventRecorder)
+	case v1alpha1.GatewayMigrationPhaseCuttingOver:
+		if m.isDraining(cfAPI, migration) {
+			return true, nil
+		}
+
+		m.enterPhase(cfAPI, migration, v1alpha1.GatewayMigrationPhaseSwitching,
+			fmt.Sprintf("Switching the Korifi gateway to gateway class %s", migration.ToClassName), eventRecorder)
+	case v1alpha1.GatewayMigrationPhaseSwitching:
+		address, err := m.reachableAddress(ctx, korifiGatewayName, migration.ToClassName, apiHost)
+		if err != nil {
+			setGatewayMigrationCondition(cfAPI, metav1.ConditionFalse, migration.Phase, "Waiting for the Korifi gateway: "+err.Error())
+			return true, nil
+		}
+
+		m.enterPhase(cfAPI, migration, v1alpha1.GatewayMigrationPhaseFinalizing,
+			fmt.Sprintf("Moving the DNS entries to the Korifi gateway at %s", address), eventRecorder)
+	case v1alpha1.GatewayMigrationPhaseFinalizing:
+		if m.isDraining(cfAPI, migration) {
+			return true, nil
+		}
+
+		if err := m.cleanup(ctx); err != nil {
+			return true, err
+		}
+
+		message := fmt.Sprintf("Migrated from gateway type %s to %s", migration.From, migration.To)
+		eventRecorder.Event(installable.EventNormal, "GatewayMigrationCompleted", message)
+		setGatewayMigrationCondition(cfAPI, metav1.ConditionTrue, "MigrationCompleted", message)
+		cfAPI.Status.GatewayMigration = nil
+	}
+
+	// the previous gateway implementation is uninstalled by the next reconcile
+	return true, nil
+}
+
+func (m *GatewayMigrator) enterPhase(cfAPI *v1alpha1.CFAPI, migration *v1alpha1.GatewayMigration, phase, message string, eventRecorder installable.EventRecorder) {
+	migration.Phase = phase
+	migration.PhaseStartTime = metav1.NewTime(time.Now())
+
+	eventRecorder.Event(installable.EventNormal, "GatewayMigration"+phase, message)
+	setGatewayMigrationCondition(cfAPI, metav1.ConditionFalse, phase, message)
+}
+
+func (m *GatewayMigrator) isDraining(cfAPI *v1alpha1.CFAPI, migration *v1alpha1.GatewayMigration) bool {
+	drainedAt := migration.PhaseStartTime.Add(m.drainPeriod)
+	if time.Now().After(drainedAt) {
+		return false
+	}
+
+	setGatewayMigrationCondition(cfAPI, metav1.ConditionFalse, migration.Phase,
+		fmt.Sprintf("Waiting until %s for cached DNS records to expire", drainedAt.UTC().Format(time.RFC3339)))
+	return true
+}
+
+// reachableAddress returns the address of a gateway of the given class once
+// it has been programmed and serves the CF API
+func (m *GatewayMigrator) reachableAddress(ctx context.Context, name, className, apiHost string) (string, error) {
+	gateway := &gatewayv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: korifiGatewayNamespace,
+			Name:      name,
+		},
+	}
+	if err := m.k8sClient.Get(ctx, client.ObjectKeyFromObject(gateway), gateway); err != nil {
+		return "", fmt.Errorf("failed to get gateway %s/%s: %w", korifiGatewayNamespace, name, err)
+	}
+
+	if string(gateway.Spec.GatewayClassName) != className {
+		return "", fmt.Errorf("gateway %s/%s is not of class %s yet", korifiGatewayNamespace, name, className)
+	}
+
+	programmed := meta.FindStatusCondition(gateway.Status.Conditions, string(gatewayv1.GatewayConditionProgrammed))
+	if programmed == nil || programmed.Status != metav1.ConditionTrue || programmed.ObservedGeneration != gateway.Generation {
+		return "", fmt.Errorf("gateway %s/%s is not programmed yet", korifiGatewayNamespace, name)
+	}
+
+	if len(gateway.Status.Addresses) == 0 {
+		return "", fmt.Errorf("gateway %s/%s has no address yet", korifiGatewayNamespace, name)
+	}
+
+	address := gateway.Status.Addresses[0].Value
+	if err := m.probe(ctx, address, apiHost); err != nil {
+		return "", err
+	}
+
+	return address, nil
+}
+
+// ensureCutover creates the cutover gateway with the listeners of the Korifi
+// gateway and attaches copies of the Korifi routes to it
+func (m *GatewayMigrator) ensureCutover(ctx context.Context, className string) error {
+	korifiGateway := &gatewayv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: korifiGatewayNamespace,
+			Name:      korifiGatewayName,
+		},
+	}
+	if err := m.k8sClient.Get(ctx, client.ObjectKeyFromObject(korifiGateway), korifiGateway); err != nil {
+		return fmt.Errorf("failed to get the korifi gateway: %w", err)
+	}
+
+	cutoverGateway := &gatewayv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: korifiGatewayNamespace,
+			Name:      v1alpha1.CutoverGatewayName,
+		},
+	}
+	if _, err := controllerutil.CreateOrPatch(ctx, m.k8sClient, cutoverGateway, func() error {
+		cutoverGateway.Labels = map[string]string{CutoverLabel: "true"}
+		cutoverGateway.Spec = *korifiGateway.Spec.DeepCopy()
+		cutoverGateway.Spec.GatewayClassName = gatewayv1.ObjectName(className)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to create the cutover gateway: %w", err)
+	}
+
+	for _, gvk := range cutoverRouteKinds {
+		routes := &unstructured.UnstructuredList{}
+		routes.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := m.k8sClient.List(ctx, routes); err != nil {
+			// TLSRoutes are only served by the experimental channel
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return fmt.Errorf("failed to list %ss: %w", gvk.Kind, err)
+		}
+
+		for i := range routes.Items {
+			if err := m.ensureCutoverRoute(ctx, &routes.Items[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *GatewayMigrator) ensureCutoverRoute(ctx context.Context, route *unstructured.Unstructured) error {
+	if route.GetLabels()[CutoverLabel] != "" {
+		return nil
+	}
+
+	spec, ok := runtime.DeepCopyJSONValue(route.Object["spec"]).(map[string]any)
+	if !ok || !attachToCutoverGateway(spec, route.GetNamespace()) {
+		return nil
+	}
+
+	cutoverRoute := &unstructured.Unstructured{}
+	cutoverRoute.SetGroupVersionKind(route.GroupVersionKind())
+	cutoverRoute.SetNamespace(route.GetNamespace())
+	cutoverRoute.SetName(route.GetName() + "-cutover")
+	if _, err := controllerutil.CreateOrPatch(ctx, m.k8sClient, cutoverRoute, func() error {
+		cutoverRoute.SetLabels(map[string]string{CutoverLabel: "true"})
+		cutoverRoute.Object["spec"] = spec
+		// the copy is garbage collected together with the route
+		return controllerutil.SetOwnerReference(route, cutoverRoute, m.k8sClient.Scheme())
+	}); err != nil {
+		return fmt.Errorf("failed to copy %s %s/%s to the cutover gateway: %w", route.GetKind(), route.GetNamespace(), route.GetName(), err)
+	}
+
+	return nil
+}
+
+// attachToCutoverGateway replaces the Korifi gateway parent references of a
+// route spec with the cutover gateway and returns whether there were any
+func attachToCutoverGateway(spec map[string]any, routeNamespace string) bool {
+	parentRefs, _, _ := unstructured.NestedSlice(spec, "parentRefs")
+
+	cutoverParentRefs := []any{}
+	for _, parentRef := range parentRefs {
+		ref, ok := parentRef.(map[string]any)
+		if !ok {
+			continue
+		}
+
+		namespace, _, _ := unstructured.NestedString(ref, "namespace")
+		if namespace == "" {
+			namespace = routeNamespace
+		}
+		name, _, _ := unstructured.NestedString(ref, "name")
+		if name != korifiGatewayName || namespace != korifiGatewayNamespace {
+			continue
+		}
+
+		ref["name"] = v1alpha1.CutoverGatewayName
+		ref["namespace"] = korifiGatewayNamespace
+		cutoverParentRefs = append(cutoverParentRefs, ref)
+	}
+
+	if len(cutoverParentRefs) == 0 {
+		return false
+	}
+
+	spec["parentRefs"] = cutoverParentRefs
+	return true
+}
+
+// cleanup deletes the cutover gateway and the routes attached to it
+func (m *GatewayMigrator) cleanup(ctx context.Context) error {
+	errs := []error{}
+	for _, gvk := range cutoverRouteKinds {
+		routes := &unstructured.UnstructuredList{}
+		routes.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := m.k8sClient.List(ctx, routes, client.HasLabels{CutoverLabel}); err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("failed to list cutover %ss: %w", gvk.Kind, err))
+			continue
+		}
+
+		for i := range routes.Items {
+			if err := m.k8sClient.Delete(ctx, &routes.Items[i]); client.IgnoreNotFound(err) != nil {
+				errs = append(errs, fmt.Errorf("failed to delete cutover %s %s/%s: %w", gvk.Kind, routes.Items[i].GetNamespace(), routes.Items[i].GetName(), err))
+			}
+		}
+	}
+
+	cutoverGateway := &gatewayv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: korifiGatewayNamespace,
+			Name:      v1alpha1.CutoverGatewayName,
+		},
+	}
+	if err := m.k8sClient.Delete(ctx, cutoverGateway); client.IgnoreNotFound(err) != nil && !meta.IsNoMatchError(err) {
+		errs = append(errs, fmt.Errorf("failed to delete the cutover gateway: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+func setGatewayMigrationCondition(cfAPI *v1alpha1.CFAPI, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&cfAPI.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ConditionTypeGatewayMigration,
+		Status:             status,
+		ObservedGeneration: cfAPI.Generation,
+		LastTransitionTime: metav1.NewTime(time.Now()),
+		Reason:             reason,
+		Message:            message,
+	})
+}
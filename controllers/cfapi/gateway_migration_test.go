@@ -0,0 +1,409 @@
+package cfapi_test
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	v1alpha1 "github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/cfapi"
+	"github.com/kyma-project/cfapi/controllers/installable"
+	"github.com/kyma-project/cfapi/tests/helpers"
+	. "github.com/kyma-project/cfapi/tests/matchers"
+	"github.com/kyma-project/cfapi/tools"
+	"github.com/kyma-project/cfapi/tools/k8s"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+var _ = Describe("Gateway migration", func() {
+	var (
+		cfAPI         *v1alpha1.CFAPI
+		gatewayClass  *gatewayv1.GatewayClass
+		korifiGateway *gatewayv1.Gateway
+		appRoute      *gatewayv1.HTTPRoute
+	)
+
+	cutoverGateway := func() *gatewayv1.Gateway {
+		return &gatewayv1.Gateway{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "cfapi-system",
+				Name:      v1alpha1.CutoverGatewayName,
+			},
+		}
+	}
+
+	programGateway := func(gateway *gatewayv1.Gateway, address string) {
+		Eventually(func(g Gomega) {
+			g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(gateway), gateway)).To(Succeed())
+		}).Should(Succeed())
+
+		Expect(k8s.Patch(ctx, adminClient, gateway, func() {
+			gateway.Status.Addresses = []gatewayv1.GatewayStatusAddress{{
+				Type:  tools.PtrTo(gatewayv1.IPAddressType),
+				Value: address,
+			}}
+			meta.SetStatusCondition(&gateway.Status.Conditions, metav1.Condition{
+				Type:               string(gatewayv1.GatewayConditionProgrammed),
+				Status:             metav1.ConditionTrue,
+				Reason:             string(gatewayv1.GatewayReasonProgrammed),
+				ObservedGeneration: gateway.Generation,
+			})
+		})).To(Succeed())
+	}
+
+	migrationPhase := func(g Gomega) string {
+		g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+		g.Expect(cfAPI.Status.GatewayMigration).NotTo(BeNil())
+		return cfAPI.Status.GatewayMigration.Phase
+	}
+
+	switchGatewayType := func(gatewayType string) {
+		Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+			cfAPI.Spec.GatewayType = gatewayType
+			cfAPI.Spec.Gateway = nil
+			if gatewayType == v1alpha1.GatewayTypeExternal {
+				cfAPI.Spec.Gateway = &v1alpha1.ExternalGateway{
+					ClassName: gatewayClass.Name,
+					IngressService: v1alpha1.NamespacedObjectReference{
+						Namespace: "envoy-gateway-system",
+						Name:      "envoy-korifi",
+					},
+				}
+			}
+		})).To(Succeed())
+	}
+
+	waitForGatewayType := func(gatewayType string) {
+		Eventually(func(g Gomega) {
+			g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+			g.Expect(cfAPI.Status.InstallationConfig.GatewayType).To(Equal(gatewayType))
+			g.Expect(meta.IsStatusConditionTrue(cfAPI.Status.Conditions, v1alpha1.ConditionTypeInstallation)).To(BeTrue())
+		}).Should(Succeed())
+	}
+
+	BeforeEach(func() {
+		helpers.EnsureCreate(adminClient, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "cfapi-system",
+			},
+		})
+
+		korifiGateway = &gatewayv1.Gateway{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "cfapi-system",
+				Name:      "korifi",
+			},
+			Spec: gatewayv1.GatewaySpec{
+				GatewayClassName: "contour",
+				Listeners: []gatewayv1.Listener{{
+					Name:     "http-apps",
+					Port:     80,
+					Protocol: gatewayv1.HTTPProtocolType,
+				}},
+			},
+		}
+		helpers.EnsureCreate(adminClient, korifiGateway)
+
+		appRoute = &gatewayv1.HTTPRoute{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: cfAPINamespace,
+				Name:      uuid.NewString(),
+			},
+			Spec: gatewayv1.HTTPRouteSpec{
+				CommonRouteSpec: gatewayv1.CommonRouteSpec{
+					ParentRefs: []gatewayv1.ParentReference{{
+						Namespace: tools.PtrTo(gatewayv1.Namespace("cfapi-system")),
+						Name:      "korifi",
+					}},
+				},
+				Hostnames: []gatewayv1.Hostname{"my-app.apps.kyma-host.com"},
+			},
+		}
+		helpers.EnsureCreate(adminClient, appRoute)
+
+		gatewayClass = &gatewayv1.GatewayClass{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: uuid.NewString(),
+			},
+			Spec: gatewayv1.GatewayClassSpec{
+				ControllerName: "gateway.envoyproxy.io/gatewayclass-controller",
+			},
+		}
+		helpers.EnsureCreate(adminClient, gatewayClass)
+		Expect(k8s.Patch(ctx, adminClient, gatewayClass, func() {
+			meta.SetStatusCondition(&gatewayClass.Status.Conditions, metav1.Condition{
+				Type:   string(gatewayv1.GatewayClassConditionStatusAccepted),
+				Status: metav1.ConditionTrue,
+				Reason: string(gatewayv1.GatewayClassReasonAccepted),
+			})
+		})).To(Succeed())
+
+		cfAPI = &v1alpha1.CFAPI{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      uuid.NewString(),
+				Namespace: cfAPINamespace,
+			},
+		}
+		helpers.EnsureCreate(adminClient, cfAPI)
+		waitForGatewayType(v1alpha1.GatewayTypeContour)
+	})
+
+	It("reports that no migration is in progress", func() {
+		Eventually(func(g Gomega) {
+			g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+			g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+				HasType(Equal(v1alpha1.ConditionTypeGatewayMigration)),
+				HasStatus(Equal(metav1.ConditionTrue)),
+				HasReason(Equal("NoMigration")),
+			)))
+		}).Should(Succeed())
+	})
+
+	When("the gateway type is switched", func() {
+		BeforeEach(func() {
+			switchGatewayType(v1alpha1.GatewayTypeExternal)
+		})
+
+		It("keeps the previous gateway serving while the new one is provisioned", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(migrationPhase(g)).To(Equal(v1alpha1.GatewayMigrationPhaseProvisioning))
+				g.Expect(cfAPI.Status.GatewayMigration.From).To(Equal(v1alpha1.GatewayTypeContour))
+				g.Expect(cfAPI.Status.GatewayMigration.To).To(Equal(v1alpha1.GatewayTypeExternal))
+
+				config := cfAPI.Status.InstallationConfig
+				g.Expect(config.GatewayType).To(Equal(v1alpha1.GatewayTypeExternal))
+				g.Expect(config.PreviousGatewayType).To(Equal(v1alpha1.GatewayTypeContour))
+				g.Expect(config.GatewayClassName).To(Equal("contour"))
+				g.Expect(config.KorifiIngressNamespace).To(Equal("cfapi-system"))
+				g.Expect(config.KorifiIngressService).To(Equal("contour-envoy"))
+				g.Expect(config.GatewayCutoverAddress).To(BeEmpty())
+
+				g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+					HasType(Equal(v1alpha1.ConditionTypeGatewayMigration)),
+					HasStatus(Equal(metav1.ConditionFalse)),
+					HasReason(Equal(v1alpha1.GatewayMigrationPhaseProvisioning)),
+				)))
+			}).Should(Succeed())
+		})
+
+		It("creates a cutover gateway of the new class with the korifi listeners", func() {
+			Eventually(func(g Gomega) {
+				gateway := cutoverGateway()
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(gateway), gateway)).To(Succeed())
+				g.Expect(gateway.Labels).To(HaveKeyWithValue(cfapi.CutoverLabel, "true"))
+				g.Expect(gateway.Spec.GatewayClassName).To(BeEquivalentTo(gatewayClass.Name))
+				g.Expect(gateway.Spec.Listeners).To(Equal(korifiGateway.Spec.Listeners))
+			}).Should(Succeed())
+		})
+
+		It("attaches copies of the korifi routes to the cutover gateway", func() {
+			Eventually(func(g Gomega) {
+				route := &gatewayv1.HTTPRoute{}
+				g.Expect(adminClient.Get(ctx, client.ObjectKey{Namespace: appRoute.Namespace, Name: appRoute.Name + "-cutover"}, route)).To(Succeed())
+				g.Expect(route.Spec.Hostnames).To(Equal(appRoute.Spec.Hostnames))
+				g.Expect(route.Spec.ParentRefs).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+					"Namespace": PointTo(BeEquivalentTo("cfapi-system")),
+					"Name":      BeEquivalentTo(v1alpha1.CutoverGatewayName),
+				})))
+				g.Expect(route.OwnerReferences).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+					"Name": Equal(appRoute.Name),
+				})))
+			}).Should(Succeed())
+		})
+
+		When("the cutover gateway is not reachable", func() {
+			BeforeEach(func() {
+				apiProbe.setErr(errors.New("connection refused"))
+				programGateway(cutoverGateway(), "10.0.0.1")
+			})
+
+			It("does not move the DNS entries", func() {
+				Consistently(func(g Gomega) {
+					g.Expect(migrationPhase(g)).To(Equal(v1alpha1.GatewayMigrationPhaseProvisioning))
+					g.Expect(cfAPI.Status.InstallationConfig.GatewayCutoverAddress).To(BeEmpty())
+				}, "2s").Should(Succeed())
+
+				Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+					HasType(Equal(v1alpha1.ConditionTypeGatewayMigration)),
+					HasMessage(ContainSubstring("connection refused")),
+				)))
+			})
+		})
+
+		When("the cutover gateway serves the CF API", func() {
+			BeforeEach(func() {
+				programGateway(cutoverGateway(), "10.0.0.1")
+			})
+
+			It("moves the DNS entries to the cutover gateway", func() {
+				Eventually(func(g Gomega) {
+					g.Expect(migrationPhase(g)).To(Equal(v1alpha1.GatewayMigrationPhaseSwitching))
+					g.Expect(cfAPI.Status.InstallationConfig.GatewayCutoverAddress).To(Equal("10.0.0.1"))
+				}).Should(Succeed())
+
+				addresses, hosts := apiProbe.probed()
+				Expect(addresses).To(ContainElement("10.0.0.1"))
+				Expect(hosts).To(ContainElement("cfapi.kyma-host.com"))
+			})
+
+			It("switches the korifi gateway to the new class once DNS caches expired", func() {
+				Eventually(func(g Gomega) {
+					g.Expect(migrationPhase(g)).To(Equal(v1alpha1.GatewayMigrationPhaseSwitching))
+					g.Expect(cfAPI.Status.InstallationConfig.GatewayClassName).To(Equal(gatewayClass.Name))
+					g.Expect(cfAPI.Status.InstallationConfig.KorifiIngressService).To(Equal("envoy-korifi"))
+				}).Should(Succeed())
+			})
+
+			When("the korifi gateway is served by the new implementation", func() {
+				BeforeEach(func() {
+					Eventually(migrationPhase).Should(Equal(v1alpha1.GatewayMigrationPhaseSwitching))
+
+					// done by the korifi chart
+					Expect(k8s.Patch(ctx, adminClient, korifiGateway, func() {
+						korifiGateway.Spec.GatewayClassName = gatewayv1.ObjectName(gatewayClass.Name)
+					})).To(Succeed())
+					programGateway(korifiGateway, "10.0.0.2")
+				})
+
+				It("completes the migration", func() {
+					Eventually(func(g Gomega) {
+						g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+						g.Expect(cfAPI.Status.GatewayMigration).To(BeNil())
+						g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+							HasType(Equal(v1alpha1.ConditionTypeGatewayMigration)),
+							HasStatus(Equal(metav1.ConditionTrue)),
+							HasReason(Equal("MigrationCompleted")),
+						)))
+
+						config := cfAPI.Status.InstallationConfig
+						g.Expect(config.PreviousGatewayType).To(BeEmpty())
+						g.Expect(config.GatewayCutoverAddress).To(BeEmpty())
+						g.Expect(config.GatewayClassName).To(Equal(gatewayClass.Name))
+						g.Expect(config.KorifiIngressService).To(Equal("envoy-korifi"))
+					}).Should(Succeed())
+
+					addresses, _ := apiProbe.probed()
+					Expect(addresses).To(ContainElement("10.0.0.2"))
+				})
+
+				It("removes the cutover gateway and routes", func() {
+					Eventually(func(g Gomega) {
+						err := adminClient.Get(ctx, client.ObjectKeyFromObject(cutoverGateway()), &gatewayv1.Gateway{})
+						g.Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+
+						err = adminClient.Get(ctx, client.ObjectKey{Namespace: appRoute.Namespace, Name: appRoute.Name + "-cutover"}, &gatewayv1.HTTPRoute{})
+						g.Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+					}).Should(Succeed())
+				})
+			})
+		})
+
+		When("the switch is reverted before the new gateway serves traffic", func() {
+			BeforeEach(func() {
+				Eventually(migrationPhase).Should(Equal(v1alpha1.GatewayMigrationPhaseProvisioning))
+				switchGatewayType("")
+			})
+
+			It("aborts the migration", func() {
+				Eventually(func(g Gomega) {
+					g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+					g.Expect(cfAPI.Status.GatewayMigration).To(BeNil())
+					g.Expect(cfAPI.Status.InstallationConfig.GatewayType).To(Equal(v1alpha1.GatewayTypeContour))
+					g.Expect(cfAPI.Status.InstallationConfig.PreviousGatewayType).To(BeEmpty())
+					g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+						HasType(Equal(v1alpha1.ConditionTypeGatewayMigration)),
+						HasReason(Equal("MigrationAborted")),
+					)))
+
+					err := adminClient.Get(ctx, client.ObjectKeyFromObject(cutoverGateway()), &gatewayv1.Gateway{})
+					g.Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+				}).Should(Succeed())
+			})
+		})
+	})
+
+	When("the gateway type is switched to istio-native", func() {
+		BeforeEach(func() {
+			switchGatewayType(v1alpha1.GatewayTypeIstioNative)
+		})
+
+		It("switches directly", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+				g.Expect(cfAPI.Status.GatewayMigration).To(BeNil())
+				g.Expect(cfAPI.Status.InstallationConfig.GatewayType).To(Equal(v1alpha1.GatewayTypeIstioNative))
+				g.Expect(cfAPI.Status.InstallationConfig.PreviousGatewayType).To(BeEmpty())
+				g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+					HasType(Equal(v1alpha1.ConditionTypeGatewayMigration)),
+					HasStatus(Equal(metav1.ConditionTrue)),
+					HasReason(Equal("SwitchedDirectly")),
+					HasMessage(ContainSubstring("istio-native")),
+				)))
+			}).Should(Succeed())
+		})
+
+		When("switching back from istio-native", func() {
+			var binding *admissionregistrationv1.ValidatingAdmissionPolicyBinding
+
+			BeforeEach(func() {
+				waitForGatewayType(v1alpha1.GatewayTypeIstioNative)
+
+				binding = &admissionregistrationv1.ValidatingAdmissionPolicyBinding{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "safe-upgrades.gateway.networking.k8s.io",
+					},
+					Spec: admissionregistrationv1.ValidatingAdmissionPolicyBindingSpec{
+						PolicyName:        "safe-upgrades.gateway.networking.k8s.io",
+						ValidationActions: []admissionregistrationv1.ValidationAction{admissionregistrationv1.Deny},
+					},
+				}
+			})
+
+			When("the safe upgrades policy binding was installed by the operator", func() {
+				BeforeEach(func() {
+					binding.Labels = map[string]string{installable.InstalledByLabel: installable.InstalledByValue}
+					helpers.EnsureCreate(adminClient, binding)
+					switchGatewayType("")
+				})
+
+				It("removes the binding so that the experimental CRDs can be installed", func() {
+					Eventually(func(g Gomega) {
+						err := adminClient.Get(ctx, client.ObjectKeyFromObject(binding), binding)
+						g.Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+					}).Should(Succeed())
+
+					waitForGatewayType(v1alpha1.GatewayTypeContour)
+				})
+			})
+
+			When("the safe upgrades policy binding belongs to another manager", func() {
+				BeforeEach(func() {
+					helpers.EnsureCreate(adminClient, binding)
+					switchGatewayType("")
+				})
+
+				It("refuses to switch", func() {
+					Eventually(func(g Gomega) {
+						g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+						g.Expect(cfAPI.Status.Conditions).To(ContainElement(SatisfyAll(
+							HasType(Equal(v1alpha1.ConditionTypeGatewayMigration)),
+							HasStatus(Equal(metav1.ConditionFalse)),
+							HasReason(Equal("SwitchBlocked")),
+						)))
+					}).Should(Succeed())
+
+					Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(binding), binding)).To(Succeed())
+					Expect(cfAPI.Status.InstallationConfig.GatewayType).To(Equal(v1alpha1.GatewayTypeIstioNative))
+				})
+			})
+		})
+	})
+})
@@ -0,0 +1,88 @@
+package cfapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/hibernation"
+	"github.com/kyma-project/cfapi/controllers/installable"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// reconcileHibernation hibernates or resumes the platform as configured in
+// `spec.hibernation` and returns whether it is hibernated. Nothing is
+// installed while the platform is hibernated, as upgrading the components
+// would scale them up again, so that configuration changes are applied on
+// resume
+func (r *Reconciler) reconcileHibernation(ctx context.Context, cfAPI *v1alpha1.CFAPI, eventRecorder installable.EventRecorder) (bool, ctrl.Result, error) {
+	hibernated, next, err := hibernation.Desired(cfAPI.Spec.Hibernation, time.Now())
+	if err != nil {
+		return false, ctrl.Result{}, err
+	}
+
+	if hibernated {
+		stopped, err := r.scaler.ScaleDown(ctx, cfAPI.Spec.Hibernation.IncludeApps)
+		if err != nil {
+			return true, ctrl.Result{}, fmt.Errorf("failed to hibernate the platform: %w", err)
+		}
+		if !stopped {
+			setHibernationState(cfAPI, v1alpha1.HibernationStateHibernating, next)
+			return true, ctrl.Result{RequeueAfter: r.requeueInterval}, nil
+		}
+
+		if cfAPI.Status.Hibernation == nil || cfAPI.Status.Hibernation.State != v1alpha1.HibernationStateHibernated {
+			eventRecorder.Event(installable.EventNormal, "Hibernated", "The platform is hibernated")
+		}
+		setHibernationState(cfAPI, v1alpha1.HibernationStateHibernated, next)
+		cfAPI.Status.State = v1alpha1.StateReady
+		return true, requeueAt(ctrl.Result{}, cfAPI), nil
+	}
+
+	if cfAPI.Status.Hibernation != nil && cfAPI.Status.Hibernation.State != v1alpha1.HibernationStateRunning {
+		setHibernationState(cfAPI, v1alpha1.HibernationStateResuming, next)
+		if err := r.scaler.ScaleUp(ctx); err != nil {
+			return false, ctrl.Result{}, fmt.Errorf("failed to resume the platform: %w", err)
+		}
+		eventRecorder.Event(installable.EventNormal, "Resumed", "The platform is resumed")
+	}
+
+	if cfAPI.Spec.Hibernation == nil {
+		cfAPI.Status.Hibernation = nil
+		return false, ctrl.Result{}, nil
+	}
+	setHibernationState(cfAPI, v1alpha1.HibernationStateRunning, next)
+	return false, ctrl.Result{}, nil
+}
+
+func setHibernationState(cfAPI *v1alpha1.CFAPI, state string, next *time.Time) {
+	status := cfAPI.Status.Hibernation
+	if status == nil || status.State != state {
+		status = &v1alpha1.HibernationStatus{
+			State:              state,
+			LastTransitionTime: metav1.NewTime(time.Now()),
+		}
+	}
+
+	status.NextTransitionTime = nil
+	if next != nil {
+		status.NextTransitionTime = &metav1.Time{Time: *next}
+	}
+	cfAPI.Status.Hibernation = status
+}
+
+// requeueAt makes sure the CFAPI is reconciled again when the hibernation
+// schedule next hibernates or resumes the platform
+func requeueAt(result ctrl.Result, cfAPI *v1alpha1.CFAPI) ctrl.Result {
+	if cfAPI.Status.Hibernation == nil || cfAPI.Status.Hibernation.NextTransitionTime == nil {
+		return result
+	}
+
+	untilNext := max(time.Until(cfAPI.Status.Hibernation.NextTransitionTime.Time), time.Second)
+	if result.RequeueAfter == 0 || untilNext < result.RequeueAfter {
+		result.RequeueAfter = untilNext
+	}
+	return result
+}
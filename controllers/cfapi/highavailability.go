@@ -0,0 +1,154 @@
+package cfapi
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/highavailability"
+	"github.com/kyma-project/cfapi/controllers/installable"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// computeHighAvailability returns `spec.highAvailability` with its defaults
+func computeHighAvailability(cfAPI *v1alpha1.CFAPI) *v1alpha1.HighAvailability {
+	if cfAPI.Spec.HighAvailability == nil {
+		return nil
+	}
+
+	highAvailability := cfAPI.Spec.HighAvailability.DeepCopy()
+	if highAvailability.Replicas == 0 {
+		highAvailability.Replicas = v1alpha1.DefaultHighAvailabilityReplicas
+	}
+	if highAvailability.ZoneSpread == "" {
+		highAvailability.ZoneSpread = v1alpha1.ZoneSpreadPreferred
+	}
+	return highAvailability
+}
+
+// checkHighAvailability reports in the `HighAvailability` condition whether
+// the replicas of the components patched for high availability are ready and
+// actually spread across nodes and, in clusters with several zones, across
+// zones. Returns false while they are not, so that the check is repeated.
+// The condition is only set while high availability is configured
+func (r *Reconciler) checkHighAvailability(ctx context.Context, cfAPI *v1alpha1.CFAPI) bool {
+	if cfAPI.Status.InstallationConfig.HighAvailability == nil {
+		meta.RemoveStatusCondition(&cfAPI.Status.Conditions, v1alpha1.ConditionTypeHighAvailability)
+		return true
+	}
+
+	nodes := &corev1.NodeList{}
+	if err := r.k8sClient.List(ctx, nodes); err != nil {
+		setHighAvailabilityCondition(cfAPI, metav1.ConditionUnknown, "CheckFailed", fmt.Sprintf("failed to list nodes: %s", err))
+		return false
+	}
+	nodeZones := map[string]string{}
+	clusterZones := []string{}
+	for _, node := range nodes.Items {
+		zone := node.Labels[corev1.LabelTopologyZone]
+		nodeZones[node.Name] = zone
+		if zone != "" && !slices.Contains(clusterZones, zone) {
+			clusterZones = append(clusterZones, zone)
+		}
+	}
+
+	problems := []string{}
+	for _, namespace := range installable.SystemNamespaces {
+		deployments := &appsv1.DeploymentList{}
+		err := r.k8sClient.List(ctx, deployments, client.InNamespace(namespace), client.MatchingLabels{highavailability.Label: "true"})
+		if err != nil {
+			setHighAvailabilityCondition(cfAPI, metav1.ConditionUnknown, "CheckFailed", fmt.Sprintf("failed to list deployments in namespace %s: %s", namespace, err))
+			return false
+		}
+
+		for _, deployment := range deployments.Items {
+			problem, err := r.checkSpread(ctx, &deployment, nodeZones, len(clusterZones))
+			if err != nil {
+				setHighAvailabilityCondition(cfAPI, metav1.ConditionUnknown, "CheckFailed", err.Error())
+				return false
+			}
+			if problem != "" {
+				problems = append(problems, problem)
+			}
+		}
+	}
+
+	if len(clusterZones) < 2 {
+		problems = append(problems, "the cluster has a single zone")
+	}
+	if len(problems) > 0 {
+		setHighAvailabilityCondition(cfAPI, metav1.ConditionFalse, "NotSpread", "Components are not highly available: "+strings.Join(problems, "; "))
+		return false
+	}
+
+	setHighAvailabilityCondition(cfAPI, metav1.ConditionTrue, "Spread", fmt.Sprintf("Components run on several nodes across %d zones", len(clusterZones)))
+	return true
+}
+
+// checkSpread returns why the ready pods of a deployment are not spread, or
+// an empty string if they are
+func (r *Reconciler) checkSpread(ctx context.Context, deployment *appsv1.Deployment, nodeZones map[string]string, clusterZoneCount int) (string, error) {
+	name := deployment.Namespace + "/" + deployment.Name
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	if deployment.Status.ReadyReplicas < replicas {
+		return fmt.Sprintf("%s has %d of %d replicas ready", name, deployment.Status.ReadyReplicas, replicas), nil
+	}
+	if deployment.Spec.Selector == nil {
+		return "", nil
+	}
+
+	pods := &corev1.PodList{}
+	err := r.k8sClient.List(ctx, pods, client.InNamespace(deployment.Namespace), client.MatchingLabels(deployment.Spec.Selector.MatchLabels))
+	if err != nil {
+		return "", fmt.Errorf("failed to list pods of %s: %w", name, err)
+	}
+
+	podNodes, podZones := []string{}, []string{}
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName == "" || !pod.DeletionTimestamp.IsZero() || !isPodReady(pod) {
+			continue
+		}
+		if !slices.Contains(podNodes, pod.Spec.NodeName) {
+			podNodes = append(podNodes, pod.Spec.NodeName)
+		}
+		if zone := nodeZones[pod.Spec.NodeName]; zone != "" && !slices.Contains(podZones, zone) {
+			podZones = append(podZones, zone)
+		}
+	}
+
+	switch {
+	case len(podNodes) < 2:
+		return fmt.Sprintf("%s runs on a single node", name), nil
+	case clusterZoneCount >= 2 && len(podZones) < 2:
+		return fmt.Sprintf("%s runs in a single zone", name), nil
+	}
+	return "", nil
+}
+
+func isPodReady(pod corev1.Pod) bool {
+	return slices.ContainsFunc(pod.Status.Conditions, func(condition corev1.PodCondition) bool {
+		return condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue
+	})
+}
+
+func setHighAvailabilityCondition(cfAPI *v1alpha1.CFAPI, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&cfAPI.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ConditionTypeHighAvailability,
+		Status:             status,
+		ObservedGeneration: cfAPI.Generation,
+		LastTransitionTime: metav1.NewTime(time.Now()),
+		Reason:             reason,
+		Message:            message,
+	})
+}
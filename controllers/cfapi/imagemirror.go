@@ -0,0 +1,60 @@
+package cfapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// computeImageMirror returns the image rewrites and pull secrets of
+// `spec.imageMirror`. The pull secrets must exist in the CFAPI namespace, as
+// pods referencing a missing pull secret cannot pull their images
+func (r *Reconciler) computeImageMirror(ctx context.Context, cfAPI *v1alpha1.CFAPI) ([]v1alpha1.ImageRewrite, []string, error) {
+	if cfAPI.Spec.ImageMirror == nil {
+		return nil, nil, nil
+	}
+
+	for _, secretName := range cfAPI.Spec.ImageMirror.PullSecrets {
+		secret := &corev1.Secret{}
+		if err := r.k8sClient.Get(ctx, client.ObjectKey{Namespace: cfAPI.Namespace, Name: secretName}, secret); err != nil {
+			return nil, nil, fmt.Errorf("failed to get image mirror pull secret %s: %w", secretName, err)
+		}
+		if secret.Type != corev1.SecretTypeDockerConfigJson {
+			return nil, nil, fmt.Errorf("image mirror pull secret %s is of type %s, expected %s", secretName, secret.Type, corev1.SecretTypeDockerConfigJson)
+		}
+	}
+
+	return cfAPI.Spec.ImageMirror.Rewrites, cfAPI.Spec.ImageMirror.PullSecrets, nil
+}
+
+// setImageMirrorCondition reports the images of the installed components which
+// are not pulled from the image mirror. The condition is only set while a
+// mirror is configured
+func setImageMirrorCondition(cfAPI *v1alpha1.CFAPI, unmirroredImages []string) {
+	if cfAPI.Spec.ImageMirror == nil {
+		meta.RemoveStatusCondition(&cfAPI.Status.Conditions, v1alpha1.ConditionTypeImageMirror)
+		return
+	}
+
+	status, reason, message := metav1.ConditionTrue, "ImagesMirrored", "All images are pulled from the image mirror"
+	if len(unmirroredImages) > 0 {
+		status, reason = metav1.ConditionFalse, "ImagesNotMirrored"
+		message = "No image rewrite matches images " + strings.Join(unmirroredImages, ", ")
+	}
+
+	meta.SetStatusCondition(&cfAPI.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ConditionTypeImageMirror,
+		Status:             status,
+		ObservedGeneration: cfAPI.Generation,
+		LastTransitionTime: metav1.NewTime(time.Now()),
+		Reason:             reason,
+		Message:            message,
+	})
+}
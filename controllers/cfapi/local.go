@@ -0,0 +1,107 @@
+package cfapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+
+	v1alpha1 "github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/cfapi/secrets"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	LocalRegistrySecretName = "cfapi-local-registry"
+	// LocalRegistryURL is the address of the in-cluster registry installed
+	// with the local profile. The `.local` suffix makes image builders talk
+	// plain HTTP to it
+	LocalRegistryURL = "cfapi-registry.cfapi-system.svc.cluster.local:30050"
+
+	defaultLocalDomainSuffix = "nip.io"
+)
+
+func profile(cfAPI *v1alpha1.CFAPI) string {
+	if cfAPI.Spec.Profile == "" {
+		return v1alpha1.ProfileKyma
+	}
+	return cfAPI.Spec.Profile
+}
+
+func isLocal(cfAPI *v1alpha1.CFAPI) bool {
+	return profile(cfAPI) == v1alpha1.ProfileLocal
+}
+
+// localDomain derives a wildcard DNS domain, e.g. `127.0.0.1.nip.io`, from the
+// ingress IP address, so that no DNS entries need to be managed
+func localDomain(cfAPI *v1alpha1.CFAPI, ingressHost string) (string, string, error) {
+	if net.ParseIP(ingressHost) == nil {
+		return "", "", fmt.Errorf("the local profile requires an IP address as ingress host to derive the CF domain, got %q", ingressHost)
+	}
+
+	suffix := defaultLocalDomainSuffix
+	if cfAPI.Spec.Local != nil && cfAPI.Spec.Local.DomainSuffix != "" {
+		suffix = cfAPI.Spec.Local.DomainSuffix
+	}
+
+	return ingressHost + "." + suffix, "Local profile (" + suffix + ")", nil
+}
+
+func (r *Reconciler) ensureLocalRegistrySecret(ctx context.Context, cfAPI *v1alpha1.CFAPI) (string, string, error) {
+	// the local registry does not require authentication, however Korifi
+	// expects credentials for every registry it pushes to
+	dockerConfig, err := json.Marshal(secrets.DockerRegistryConfig{
+		Auths: map[string]secrets.DockerRegistryAuth{
+			LocalRegistryURL: {
+				Username: "cfapi",
+				Password: "cfapi",
+			},
+		},
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to marshal the local registry config: %w", err)
+	}
+
+	registrySecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: cfAPI.Namespace,
+			Name:      LocalRegistrySecretName,
+		},
+	}
+	_, err = controllerutil.CreateOrPatch(ctx, r.k8sClient, registrySecret, func() error {
+		registrySecret.Type = corev1.SecretTypeDockerConfigJson
+		registrySecret.Data = map[string][]byte{
+			corev1.DockerConfigJsonKey: dockerConfig,
+		}
+		return nil
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to ensure the local registry secret: %w", err)
+	}
+
+	return LocalRegistrySecretName, LocalRegistryURL, nil
+}
+
+// computeLocalOIDC validates the configured local OIDC issuer and returns the
+// prefixes of its users and groups. The Korifi API forwards the tokens of users
+// to the Kubernetes API server, which verifies them with the issuer. The issuer
+// and the OIDC flags of the API server are set up along with the cluster, the
+// operator only checks that the issuer is reachable. Users authenticate with
+// their Kubernetes credentials when no issuer is configured
+func (r *Reconciler) computeLocalOIDC(ctx context.Context, cfAPI *v1alpha1.CFAPI) (string, string) {
+	if cfAPI.Spec.Local == nil || cfAPI.Spec.Local.OIDC == nil {
+		setUAACondition(cfAPI, metav1.ConditionTrue, "UAANotRequired", "The local profile authenticates users with their Kubernetes credentials")
+		return "", ""
+	}
+
+	oidc := cfAPI.Spec.Local.OIDC
+	if err := r.kymaClient.UAA.Validate(ctx, oidc.IssuerURL); err != nil {
+		setUAACondition(cfAPI, metav1.ConditionFalse, "ValidationFailed", err.Error())
+	} else {
+		setUAACondition(cfAPI, metav1.ConditionTrue, "UAANotRequired", "The local profile authenticates users with OIDC issuer "+oidc.IssuerURL)
+	}
+
+	return oidc.UsernamePrefix, oidc.GroupsPrefix
+}
@@ -0,0 +1,88 @@
+package cfapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+)
+
+// clusterNoProxy are the hosts inside the cluster that are always reached
+// without the proxy
+var clusterNoProxy = []string{
+	"localhost",
+	"127.0.0.1",
+	"::1",
+	"kubernetes.default",
+	".svc",
+	".cluster.local",
+}
+
+// computeProxy returns the proxy of `spec.proxy`. Requests to the cluster
+// networks, the cluster DNS suffixes and the CF domain, which Korifi and the
+// apps reach each other through, bypass the proxy
+func (r *Reconciler) computeProxy(ctx context.Context, cfAPI *v1alpha1.CFAPI, cfDomain string) (*v1alpha1.ProxyConfig, error) {
+	if cfAPI.Spec.Proxy == nil {
+		return nil, nil
+	}
+
+	httpProxy := cfAPI.Spec.Proxy.HTTPProxy
+	httpsProxy := cfAPI.Spec.Proxy.HTTPSProxy
+	if httpsProxy == "" {
+		httpsProxy = httpProxy
+	}
+	if httpsProxy == "" {
+		return nil, errors.New("proxy requires httpProxy or httpsProxy to be set")
+	}
+	for _, proxyURL := range []string{httpProxy, httpsProxy} {
+		if err := validateProxyURL(proxyURL); err != nil {
+			return nil, err
+		}
+	}
+
+	clusterNetworks, err := r.kymaClient.Network.ClusterNetworks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine the cluster networks bypassing the proxy: %w", err)
+	}
+
+	noProxy := slices.Concat(clusterNoProxy, clusterNetworks, []string{cfDomain}, cfAPI.Spec.Proxy.NoProxy)
+	noProxy = slices.DeleteFunc(noProxy, func(host string) bool { return host == "" })
+
+	return &v1alpha1.ProxyConfig{
+		HTTPProxy:      httpProxy,
+		HTTPSProxy:     httpsProxy,
+		NoProxy:        strings.Join(uniq(noProxy), ","),
+		InjectIntoApps: cfAPI.Spec.Proxy.InjectIntoApps,
+	}, nil
+}
+
+func validateProxyURL(proxyURL string) error {
+	if proxyURL == "" {
+		return nil
+	}
+
+	parsed, err := url.Parse(proxyURL)
+	if err != nil {
+		return fmt.Errorf("invalid proxy url %s: %w", proxyURL, err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid proxy url %s: expected http(s)://<host>[:<port>]", proxyURL)
+	}
+	return nil
+}
+
+// uniq removes repeated items, keeping the first occurrence
+func uniq(items []string) []string {
+	seen := map[string]bool{}
+	return slices.DeleteFunc(items, func(item string) bool {
+		if seen[item] {
+			return true
+		}
+		seen[item] = true
+		return false
+	})
+}
@@ -0,0 +1,215 @@
+package cfapi
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/cfapi/secrets"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// containerRepository is a registry secret together with the repository, or
+// repository prefix, it holds the credentials for
+type containerRepository struct {
+	secret     string
+	repository string
+}
+
+// computeContainerRepositories resolves the registries of package images,
+// droplet images and the builder image. Each of them defaults to the
+// container registry secret and its repository
+func (r *Reconciler) computeContainerRepositories(
+	ctx context.Context,
+	cfAPI *v1alpha1.CFAPI,
+	registrySecretName, containerRepositoryPrefix, builderRepository string,
+) (containerRepository, containerRepository, containerRepository, error) {
+	registries := cfAPI.Spec.ContainerRegistries
+	if registries == nil {
+		registries = &v1alpha1.ContainerRegistries{}
+	}
+
+	registryConfigs := map[string]secrets.DockerRegistryConfig{}
+
+	packages, err := r.resolveContainerRepository(ctx, cfAPI, "packages", registries.Packages,
+		containerRepository{secret: registrySecretName, repository: containerRepositoryPrefix}, "/", registryConfigs)
+	if err != nil {
+		return containerRepository{}, containerRepository{}, containerRepository{}, err
+	}
+
+	droplets, err := r.resolveContainerRepository(ctx, cfAPI, "droplets", registries.Droplets,
+		containerRepository{secret: registrySecretName, repository: containerRepositoryPrefix}, "/", registryConfigs)
+	if err != nil {
+		return containerRepository{}, containerRepository{}, containerRepository{}, err
+	}
+
+	builder, err := r.resolveContainerRepository(ctx, cfAPI, "builder", registries.Builder,
+		containerRepository{secret: registrySecretName, repository: builderRepository}, "/cfapi/kpack-builder", registryConfigs)
+	if err != nil {
+		return containerRepository{}, containerRepository{}, containerRepository{}, err
+	}
+
+	if err := validateRegistryConfigs(registryConfigs); err != nil {
+		return containerRepository{}, containerRepository{}, containerRepository{}, err
+	}
+
+	return packages, droplets, builder, nil
+}
+
+func (r *Reconciler) resolveContainerRepository(
+	ctx context.Context,
+	cfAPI *v1alpha1.CFAPI,
+	name string,
+	repository *v1alpha1.ContainerRepository,
+	defaultRepository containerRepository,
+	repositorySuffix string,
+	registryConfigs map[string]secrets.DockerRegistryConfig,
+) (containerRepository, error) {
+	if repository == nil || repository.Secret == "" {
+		if repository != nil && repository.Repository != "" {
+			defaultRepository.repository = repository.Repository
+		}
+		return defaultRepository, nil
+	}
+
+	registryConfig, err := r.docker.GetRegistryConfig(ctx, cfAPI.Namespace, repository.Secret)
+	if err != nil {
+		return containerRepository{}, fmt.Errorf("invalid %s registry: %w", name, err)
+	}
+
+	registryURLs := registryConfig.Registries()
+	if len(registryURLs) == 0 {
+		return containerRepository{}, fmt.Errorf("container registry secret %s of the %s registry does not specify container registries", repository.Secret, name)
+	}
+	registryConfigs[repository.Secret] = registryConfig
+
+	if repository.Repository == "" {
+		return containerRepository{secret: repository.Secret, repository: registryURLs[0] + repositorySuffix}, nil
+	}
+
+	if _, ok := registryConfig.Credentials(repository.Repository); !ok {
+		return containerRepository{}, fmt.Errorf("container registry secret %s of the %s registry does not specify credentials for %s, configured registries are: %s",
+			repository.Secret, name, repository.Repository, strings.Join(registryURLs, ", "))
+	}
+
+	return containerRepository{secret: repository.Secret, repository: repository.Repository}, nil
+}
+
+// validateRegistryConfigs rejects registry secrets with different credentials
+// for the same registry. Korifi and kpack get all registry secrets at once and
+// would pick either of the credentials
+func validateRegistryConfigs(registryConfigs map[string]secrets.DockerRegistryConfig) error {
+	secretNames := slices.Sorted(maps.Keys(registryConfigs))
+
+	for i, secretName := range secretNames {
+		for _, otherSecretName := range secretNames[i+1:] {
+			for _, registry := range registryConfigs[secretName].Registries() {
+				otherAuth, ok := registryConfigs[otherSecretName].Credentials(registry)
+				if ok && otherAuth != registryConfigs[secretName].Auths[registry] {
+					return fmt.Errorf("container registry secrets %s and %s specify different credentials for registry %s", secretName, otherSecretName, registry)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkContainerRegistries reports whether the registries accept the
+// credentials of their secrets in the `Registry` condition. A failing check
+// does not block the installation, as the registries may only be unreachable
+// from the operator
+func (r *Reconciler) checkContainerRegistries(ctx context.Context, cfAPI *v1alpha1.CFAPI, packages, droplets, builder containerRepository) {
+	registryCheck := cfAPI.Spec.ContainerRegistryCheck
+	if registryCheck != nil && registryCheck.Disabled {
+		setRegistryCondition(cfAPI, metav1.ConditionUnknown, "CheckDisabled", "The container registry check is disabled")
+		return
+	}
+
+	if isLocal(cfAPI) && packages.secret == LocalRegistrySecretName && droplets.secret == LocalRegistrySecretName && builder.secret == LocalRegistrySecretName {
+		setRegistryCondition(cfAPI, metav1.ConditionUnknown, "CheckSkipped", "The local registry is installed together with CF API and is not checked")
+		return
+	}
+
+	pushCheck := registryCheck != nil && registryCheck.Push
+	checked := []string{}
+	pushed := []string{}
+	for _, target := range []containerRepository{
+		{secret: packages.secret, repository: packages.repository + secrets.RegistryCheckRepository},
+		{secret: droplets.secret, repository: droplets.repository + secrets.RegistryCheckRepository},
+		builder,
+	} {
+		if target.secret == LocalRegistrySecretName {
+			continue
+		}
+
+		registryConfig, err := r.docker.GetRegistryConfig(ctx, cfAPI.Namespace, target.secret)
+		if err != nil {
+			setRegistryCondition(cfAPI, metav1.ConditionFalse, "InvalidSecret", err.Error())
+			return
+		}
+
+		registryURL := repositoryRegistry(target.repository)
+		if key, ok := registryConfig.Registry(registryURL); ok {
+			registryURL = key
+		}
+
+		pushRepositories := []string{}
+		if pushCheck && !slices.Contains(pushed, target.repository) {
+			pushRepositories = append(pushRepositories, target.repository)
+			pushed = append(pushed, target.repository)
+		}
+
+		access := fmt.Sprintf("registry %s with secret %s", registryURL, target.secret)
+		if slices.Contains(checked, access) && len(pushRepositories) == 0 {
+			continue
+		}
+
+		if err := r.registryChecker.Check(ctx, registryConfig, registryURL, pushRepositories...); err != nil {
+			setRegistryCondition(cfAPI, metav1.ConditionFalse, "CheckFailed", err.Error())
+			return
+		}
+
+		if !slices.Contains(checked, access) {
+			checked = append(checked, access)
+		}
+	}
+
+	if pushCheck {
+		setRegistryCondition(cfAPI, metav1.ConditionTrue, "PushAllowed", fmt.Sprintf("Pushes are accepted by %s", strings.Join(pushed, ", ")))
+		return
+	}
+
+	setRegistryCondition(cfAPI, metav1.ConditionTrue, "RegistryAccessible", "Credentials are accepted by "+strings.Join(checked, ", "))
+}
+
+// repositoryRegistry returns the registry of an image repository, keeping an
+// explicit URL scheme
+func repositoryRegistry(repository string) string {
+	scheme := ""
+	for _, prefix := range []string{"https://", "http://"} {
+		if strings.HasPrefix(repository, prefix) {
+			scheme = prefix
+			repository = strings.TrimPrefix(repository, prefix)
+		}
+	}
+
+	registry, _, _ := strings.Cut(repository, "/")
+	return scheme + registry
+}
+
+func setRegistryCondition(cfAPI *v1alpha1.CFAPI, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&cfAPI.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ConditionTypeRegistry,
+		Status:             status,
+		ObservedGeneration: cfAPI.Generation,
+		LastTransitionTime: metav1.NewTime(time.Now()),
+		Reason:             reason,
+		Message:            message,
+	})
+}
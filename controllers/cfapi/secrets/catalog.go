@@ -0,0 +1,218 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const catalogPageSize = "1000"
+
+// manifestMediaTypes are accepted when resolving the digest of a tag, so that
+// registries do not convert image indexes or OCI manifests
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// RegistryClient lists and deletes images through the distribution API of a
+// registry. Repositories are given together with their registry, e.g.
+// `my-registry.com/korifi/my-app-droplets`
+type RegistryClient struct {
+	registryClient
+}
+
+func NewRegistryClient(httpClient *http.Client) *RegistryClient {
+	return &RegistryClient{
+		registryClient: registryClient{httpClient: httpClient},
+	}
+}
+
+// Repositories returns the names of all repositories of the registry, as
+// listed by its catalog. Not every registry serves the catalog, e.g. Docker
+// Hub does not
+func (c *RegistryClient) Repositories(ctx context.Context, config DockerRegistryConfig, registryURL string) ([]string, error) {
+	catalogURL := registryEndpoint(registryURL).JoinPath("v2", "_catalog")
+	catalogURL.RawQuery = url.Values{"n": {catalogPageSize}}.Encode()
+
+	repositories := []string{}
+	err := c.list(ctx, authFor(config, registryURL), catalogURL.String(), "registry:catalog:*", func(page []byte) error {
+		catalog := struct {
+			Repositories []string `json:"repositories"`
+		}{}
+		if err := json.Unmarshal(page, &catalog); err != nil {
+			return fmt.Errorf("failed to decode the catalog of registry %s: %w", registryURL, err)
+		}
+		repositories = append(repositories, catalog.Repositories...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the repositories of registry %s: %w", registryURL, err)
+	}
+
+	return repositories, nil
+}
+
+// Tags returns the tags of a repository. A repository that does not exist,
+// or no longer exists, has no tags
+func (c *RegistryClient) Tags(ctx context.Context, config DockerRegistryConfig, repository string) ([]string, error) {
+	name, err := repositoryName(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	tagsURL := registryEndpoint(repository).JoinPath("v2", name, "tags", "list")
+	tagsURL.RawQuery = url.Values{"n": {catalogPageSize}}.Encode()
+
+	tags := []string{}
+	err = c.list(ctx, authFor(config, repository), tagsURL.String(), "repository:"+name+":pull", func(page []byte) error {
+		tagList := struct {
+			Tags []string `json:"tags"`
+		}{}
+		if err := json.Unmarshal(page, &tagList); err != nil {
+			return fmt.Errorf("failed to decode the tags of repository %s: %w", repository, err)
+		}
+		tags = append(tags, tagList.Tags...)
+		return nil
+	})
+	if errors.Is(err, errNotFound) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the tags of repository %s: %w", repository, err)
+	}
+
+	return tags, nil
+}
+
+// ManifestDigest returns the digest of the manifest a tag refers to
+func (c *RegistryClient) ManifestDigest(ctx context.Context, config DockerRegistryConfig, repository, tag string) (string, error) {
+	name, err := repositoryName(repository)
+	if err != nil {
+		return "", err
+	}
+
+	manifestURL := registryEndpoint(repository).JoinPath("v2", name, "manifests", tag).String()
+	resp, err := c.authorizedDo(ctx, authFor(config, repository), http.MethodHead, manifestURL, "repository:"+name+":pull", http.Header{
+		"Accept": {strings.Join(manifestMediaTypes, ", ")},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", statusError(http.MethodHead, manifestURL, resp)
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("HEAD %s did not return a manifest digest", manifestURL)
+	}
+
+	return digest, nil
+}
+
+// DeleteManifest deletes a manifest, and with it all tags referring to it. A
+// manifest that is already gone is not an error. The storage of the layers is
+// only reclaimed by the garbage collection of the registry itself
+func (c *RegistryClient) DeleteManifest(ctx context.Context, config DockerRegistryConfig, repository, digest string) error {
+	name, err := repositoryName(repository)
+	if err != nil {
+		return err
+	}
+
+	manifestURL := registryEndpoint(repository).JoinPath("v2", name, "manifests", digest).String()
+	resp, err := c.authorizedDo(ctx, authFor(config, repository), http.MethodDelete, manifestURL, "repository:"+name+":pull,delete", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusAccepted, http.StatusOK, http.StatusNotFound:
+		return nil
+	default:
+		return statusError(http.MethodDelete, manifestURL, resp)
+	}
+}
+
+var errNotFound = errors.New("not found")
+
+// list gets all pages of a paginated list, following the `Link` header of
+// each page
+func (c *RegistryClient) list(ctx context.Context, auth DockerRegistryAuth, listURL, scope string, handlePage func([]byte) error) error {
+	for listURL != "" {
+		resp, err := c.authorizedDo(ctx, auth, http.MethodGet, listURL, scope, nil)
+		if err != nil {
+			return err
+		}
+
+		page, nextURL, err := readPage(resp)
+		if err != nil {
+			return err
+		}
+
+		if err := handlePage(page); err != nil {
+			return err
+		}
+		listURL = nextURL
+	}
+
+	return nil
+}
+
+func readPage(resp *http.Response) ([]byte, string, error) {
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, "", fmt.Errorf("%w: %w", errNotFound, statusError(resp.Request.Method, resp.Request.URL.String(), resp))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", statusError(resp.Request.Method, resp.Request.URL.String(), resp)
+	}
+
+	page := json.RawMessage{}
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, "", fmt.Errorf("failed to decode the response of %s: %w", resp.Request.URL, err)
+	}
+
+	nextURL := ""
+	if link := nextLink(resp.Header.Get("Link")); link != "" {
+		next, err := resp.Request.URL.Parse(link)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid link %q returned by %s: %w", link, resp.Request.URL, err)
+		}
+		nextURL = next.String()
+	}
+
+	return page, nextURL, nil
+}
+
+// nextLink returns the target of a `Link` header such as
+// `</v2/_catalog?last=b&n=1000>; rel="next"`
+func nextLink(header string) string {
+	for link := range strings.SplitSeq(header, ",") {
+		target, params, _ := strings.Cut(strings.TrimSpace(link), ";")
+		if strings.Contains(strings.ReplaceAll(params, " ", ""), `rel="next"`) {
+			return strings.Trim(strings.TrimSpace(target), "<>")
+		}
+	}
+
+	return ""
+}
+
+func repositoryName(repository string) (string, error) {
+	_, name, ok := strings.Cut(normalizeRegistry(repository), "/")
+	if !ok || name == "" {
+		return "", fmt.Errorf("repository %s does not specify a registry and a name", repository)
+	}
+
+	return name, nil
+}
@@ -0,0 +1,80 @@
+package secrets_test
+
+import (
+	"github.com/kyma-project/cfapi/controllers/cfapi/secrets"
+	"github.com/kyma-project/cfapi/tests/helpers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RegistryClient", func() {
+	var (
+		standIn *helpers.ImageRegistryStandIn
+		client  *secrets.RegistryClient
+		config  secrets.DockerRegistryConfig
+	)
+
+	BeforeEach(func() {
+		standIn = helpers.NewImageRegistryStandIn()
+		DeferCleanup(standIn.Close)
+
+		standIn.AddImage("korifi/app-1-droplets", "latest", "sha256:1")
+		standIn.AddImage("korifi/app-1-droplets", "b1.20250101.120000", "sha256:1")
+		standIn.AddImage("korifi/app-1-droplets", "b2.20250102.120000", "sha256:2")
+		standIn.AddImage("korifi/app-1-packages", "package-1", "sha256:3")
+		standIn.AddImage("other/image", "latest", "sha256:4")
+
+		client = secrets.NewRegistryClient(helpers.NewStandInHTTPClient(standIn.Server))
+		config = secrets.DockerRegistryConfig{}
+	})
+
+	Describe("Repositories", func() {
+		It("lists all pages of the catalog", func() {
+			Expect(client.Repositories(ctx, config, "my-registry.com")).To(Equal([]string{
+				"korifi/app-1-droplets",
+				"korifi/app-1-packages",
+				"other/image",
+			}))
+		})
+	})
+
+	Describe("Tags", func() {
+		It("lists all pages of the tags", func() {
+			Expect(client.Tags(ctx, config, "my-registry.com/korifi/app-1-droplets")).To(ConsistOf(
+				"latest", "b1.20250101.120000", "b2.20250102.120000",
+			))
+		})
+
+		When("the repository does not exist", func() {
+			It("returns no tags", func() {
+				Expect(client.Tags(ctx, config, "my-registry.com/korifi/unknown")).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("ManifestDigest", func() {
+		It("returns the digest of the tag", func() {
+			Expect(client.ManifestDigest(ctx, config, "my-registry.com/korifi/app-1-droplets", "b2.20250102.120000")).To(Equal("sha256:2"))
+		})
+
+		When("the tag does not exist", func() {
+			It("returns an error", func() {
+				_, err := client.ManifestDigest(ctx, config, "my-registry.com/korifi/app-1-droplets", "unknown")
+				Expect(err).To(MatchError(ContainSubstring("404 Not Found")))
+			})
+		})
+	})
+
+	Describe("DeleteManifest", func() {
+		It("deletes the manifest with all its tags", func() {
+			Expect(client.DeleteManifest(ctx, config, "my-registry.com/korifi/app-1-droplets", "sha256:1")).To(Succeed())
+			Expect(standIn.Tags("korifi/app-1-droplets")).To(ConsistOf("b2.20250102.120000"))
+		})
+
+		When("the manifest is already gone", func() {
+			It("succeeds", func() {
+				Expect(client.DeleteManifest(ctx, config, "my-registry.com/korifi/app-1-droplets", "sha256:unknown")).To(Succeed())
+			})
+		})
+	})
+})
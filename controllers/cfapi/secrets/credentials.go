@@ -0,0 +1,283 @@
+package secrets
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kyma-project/cfapi/tools"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// CredentialsRefreshAtAnnotation holds the time the short-lived
+	// credentials of a registry secret are refreshed next
+	CredentialsRefreshAtAnnotation = "cfapi.kyma-project.io/credentials-refresh-at"
+	// CredentialsExpireAtAnnotation holds the time the short-lived
+	// credentials of a registry secret expire
+	CredentialsExpireAtAnnotation = "cfapi.kyma-project.io/credentials-expire-at"
+	// CredentialsConfigAnnotation holds the hash of the configuration the
+	// credentials were issued for, so that changing it refreshes them
+	CredentialsConfigAnnotation = "cfapi.kyma-project.io/credentials-config"
+
+	serviceAccountTokenLifetime = 10 * time.Minute
+)
+
+// CredentialRequest is what a CredentialProvider exchanges for short-lived
+// registry credentials
+type CredentialRequest struct {
+	TokenURL string
+	Scopes   []string
+	Username string
+	// Credentials are long-lived credentials, e.g. `clientID` and
+	// `clientSecret` or a `refreshToken`
+	Credentials map[string][]byte
+	// SubjectToken is a token of the workload identity, used when there are
+	// no long-lived credentials
+	SubjectToken string
+}
+
+type RegistryCredentials struct {
+	Auth      DockerRegistryAuth
+	ExpiresAt time.Time
+	// RotatedCredentials are long-lived credentials the provider replaced
+	// while issuing the registry credentials, e.g. a rotated refresh token
+	RotatedCredentials map[string][]byte
+}
+
+// CredentialProvider exchanges long-lived credentials or a workload identity
+// for short-lived docker registry credentials
+type CredentialProvider interface {
+	GetCredentials(ctx context.Context, request CredentialRequest) (RegistryCredentials, error)
+}
+
+// RegistryCredentialsConfig configures the registry credentials of a
+// registry secret
+type RegistryCredentialsConfig struct {
+	Provider          string
+	Registry          string
+	TokenURL          string
+	Scopes            []string
+	Username          string
+	CredentialsSecret string
+	ServiceAccount    string
+	Audience          string
+}
+
+type CredentialRefresher struct {
+	k8sClient client.Client
+	providers map[string]CredentialProvider
+}
+
+func NewCredentialRefresher(k8sClient client.Client, providers map[string]CredentialProvider) *CredentialRefresher {
+	return &CredentialRefresher{
+		k8sClient: k8sClient,
+		providers: providers,
+	}
+}
+
+// Refresh writes short-lived credentials for the configured registry to the
+// registry secret, unless the credentials in the secret were issued for the
+// same configuration and are not due for refresh yet. Credentials are
+// refreshed when two thirds of their lifetime have passed. Returns the time
+// of the next refresh and whether the credentials were refreshed
+func (r *CredentialRefresher) Refresh(ctx context.Context, namespace, secretName string, config RegistryCredentialsConfig) (time.Time, bool, error) {
+	configHash, err := hashConfig(config)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      secretName,
+		},
+	}
+	err = r.k8sClient.Get(ctx, client.ObjectKeyFromObject(secret), secret)
+	if client.IgnoreNotFound(err) != nil {
+		return time.Time{}, false, fmt.Errorf("failed to get registry secret %s/%s: %w", namespace, secretName, err)
+	}
+
+	if refreshAt, ok := dueTime(secret, configHash); ok && time.Now().Before(refreshAt) {
+		return refreshAt, false, nil
+	}
+
+	provider, ok := r.providers[config.Provider]
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("unknown registry credential provider %q", config.Provider)
+	}
+
+	request, err := r.credentialRequest(ctx, namespace, config)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	issuedAt := time.Now()
+	credentials, err := provider.GetCredentials(ctx, request)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to get credentials for registry %s: %w", config.Registry, err)
+	}
+
+	if err := r.storeRotatedCredentials(ctx, namespace, config.CredentialsSecret, credentials.RotatedCredentials); err != nil {
+		return time.Time{}, false, err
+	}
+
+	refreshAt := issuedAt.Add(credentials.ExpiresAt.Sub(issuedAt) * 2 / 3)
+	if err := r.writeSecret(ctx, secret, config.Registry, configHash, credentials, refreshAt); err != nil {
+		return time.Time{}, false, err
+	}
+
+	return refreshAt, true, nil
+}
+
+func dueTime(secret *corev1.Secret, configHash string) (time.Time, bool) {
+	if secret.Annotations[CredentialsConfigAnnotation] != configHash {
+		return time.Time{}, false
+	}
+
+	refreshAt, err := time.Parse(time.RFC3339, secret.Annotations[CredentialsRefreshAtAnnotation])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return refreshAt, true
+}
+
+func (r *CredentialRefresher) credentialRequest(ctx context.Context, namespace string, config RegistryCredentialsConfig) (CredentialRequest, error) {
+	request := CredentialRequest{
+		TokenURL: config.TokenURL,
+		Scopes:   config.Scopes,
+		Username: config.Username,
+	}
+
+	switch {
+	case config.CredentialsSecret != "":
+		credentialsSecret := &corev1.Secret{}
+		if err := r.k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: config.CredentialsSecret}, credentialsSecret); err != nil {
+			return CredentialRequest{}, fmt.Errorf("failed to get registry credentials secret %s/%s: %w", namespace, config.CredentialsSecret, err)
+		}
+		request.Credentials = credentialsSecret.Data
+	case config.ServiceAccount != "":
+		audience := config.Audience
+		if audience == "" {
+			audience = config.TokenURL
+		}
+
+		token, err := r.serviceAccountToken(ctx, namespace, config.ServiceAccount, audience)
+		if err != nil {
+			return CredentialRequest{}, err
+		}
+		request.SubjectToken = token
+	default:
+		return CredentialRequest{}, errors.New("registry credentials require either a credentials secret or a service account")
+	}
+
+	return request, nil
+}
+
+func (r *CredentialRefresher) serviceAccountToken(ctx context.Context, namespace, serviceAccountName, audience string) (string, error) {
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      serviceAccountName,
+		},
+	}
+	tokenRequest := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			Audiences:         []string{audience},
+			ExpirationSeconds: tools.PtrTo(int64(serviceAccountTokenLifetime.Seconds())),
+		},
+	}
+
+	if err := r.k8sClient.SubResource("token").Create(ctx, serviceAccount, tokenRequest); err != nil {
+		return "", fmt.Errorf("failed to request a token for service account %s/%s: %w", namespace, serviceAccountName, err)
+	}
+
+	return tokenRequest.Status.Token, nil
+}
+
+func (r *CredentialRefresher) storeRotatedCredentials(ctx context.Context, namespace, secretName string, rotated map[string][]byte) error {
+	if secretName == "" || len(rotated) == 0 {
+		return nil
+	}
+
+	credentialsSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      secretName,
+		},
+	}
+	_, err := controllerutil.CreateOrPatch(ctx, r.k8sClient, credentialsSecret, func() error {
+		if credentialsSecret.Data == nil {
+			credentialsSecret.Data = map[string][]byte{}
+		}
+		for key, value := range rotated {
+			credentialsSecret.Data[key] = value
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store the rotated credentials in secret %s/%s: %w", namespace, secretName, err)
+	}
+
+	return nil
+}
+
+func (r *CredentialRefresher) writeSecret(
+	ctx context.Context,
+	secret *corev1.Secret,
+	registry, configHash string,
+	credentials RegistryCredentials,
+	refreshAt time.Time,
+) error {
+	auth := credentials.Auth
+	auth.Auth = base64.StdEncoding.EncodeToString([]byte(auth.Username + ":" + auth.Password))
+	dockerConfig, err := json.Marshal(DockerRegistryConfig{
+		Auths: map[string]DockerRegistryAuth{registry: auth},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal docker registry config: %w", err)
+	}
+
+	_, err = controllerutil.CreateOrPatch(ctx, r.k8sClient, secret, func() error {
+		if secret.CreationTimestamp.IsZero() {
+			secret.Type = corev1.SecretTypeDockerConfigJson
+		}
+		if secret.Type != corev1.SecretTypeDockerConfigJson {
+			return fmt.Errorf("registry secret %s/%s is of type %s, expected %s", secret.Namespace, secret.Name, secret.Type, corev1.SecretTypeDockerConfigJson)
+		}
+
+		if secret.Annotations == nil {
+			secret.Annotations = map[string]string{}
+		}
+		secret.Annotations[CredentialsConfigAnnotation] = configHash
+		secret.Annotations[CredentialsRefreshAtAnnotation] = refreshAt.UTC().Format(time.RFC3339)
+		secret.Annotations[CredentialsExpireAtAnnotation] = credentials.ExpiresAt.UTC().Format(time.RFC3339)
+		secret.Data = map[string][]byte{
+			corev1.DockerConfigJsonKey: dockerConfig,
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write registry credentials to secret %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+
+	return nil
+}
+
+func hashConfig(config RegistryCredentialsConfig) (string, error) {
+	content, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal registry credentials config: %w", err)
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(content)), nil
+}
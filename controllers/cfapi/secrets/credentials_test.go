@@ -0,0 +1,164 @@
+package secrets_test
+
+import (
+	"time"
+
+	"github.com/kyma-project/cfapi/controllers/cfapi/secrets"
+	"github.com/kyma-project/cfapi/tests/helpers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("CredentialRefresher", func() {
+	var (
+		standIn   *helpers.OAuth2StandIn
+		refresher *secrets.CredentialRefresher
+		config    secrets.RegistryCredentialsConfig
+		refreshAt time.Time
+		refreshed bool
+		err       error
+	)
+
+	BeforeEach(func() {
+		standIn = helpers.NewOAuth2StandIn("my-client", "my-secret")
+		DeferCleanup(standIn.Close)
+
+		refresher = secrets.NewCredentialRefresher(adminClient, map[string]secrets.CredentialProvider{
+			secrets.CredentialProviderOAuth2: secrets.NewOAuth2CredentialProvider(helpers.NewStandInHTTPClient(standIn.Server)),
+		})
+
+		helpers.EnsureCreate(adminClient, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testNamespace,
+				Name:      "idp-credentials",
+			},
+			StringData: map[string]string{
+				"clientID":     "my-client",
+				"clientSecret": "my-secret",
+			},
+		})
+
+		config = secrets.RegistryCredentialsConfig{
+			Provider:          secrets.CredentialProviderOAuth2,
+			Registry:          "my-registry.com",
+			TokenURL:          "https://my-idp.com/oauth/token",
+			CredentialsSecret: "idp-credentials",
+		}
+	})
+
+	JustBeforeEach(func() {
+		refreshAt, refreshed, err = refresher.Refresh(ctx, testNamespace, "registry-secret", config)
+	})
+
+	It("writes the short-lived credentials to the registry secret", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(refreshed).To(BeTrue())
+		Expect(refreshAt).To(BeTemporally("~", time.Now().Add(40*time.Minute), 5*time.Second))
+
+		registryConfig, err := secrets.NewDocker(adminClient).GetRegistryConfig(ctx, testNamespace, "registry-secret")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(registryConfig.Auths).To(HaveKeyWithValue("my-registry.com", MatchFields(IgnoreExtras, Fields{
+			"Username": Equal("oauth2accesstoken"),
+			"Password": Equal(helpers.OAuth2StandInAccessToken),
+		})))
+
+		secret := &corev1.Secret{}
+		Expect(adminClient.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: "registry-secret"}, secret)).To(Succeed())
+		Expect(secret.Type).To(Equal(corev1.SecretTypeDockerConfigJson))
+		Expect(secret.Annotations).To(HaveKey(secrets.CredentialsRefreshAtAnnotation))
+		Expect(secret.Annotations).To(HaveKey(secrets.CredentialsExpireAtAnnotation))
+		Expect(secret.Annotations).To(HaveKey(secrets.CredentialsConfigAnnotation))
+	})
+
+	When("the credentials are not due for refresh", func() {
+		var previousRefreshAt time.Time
+
+		BeforeEach(func() {
+			var err error
+			previousRefreshAt, _, err = refresher.Refresh(ctx, testNamespace, "registry-secret", config)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("keeps them", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refreshed).To(BeFalse())
+			Expect(refreshAt).To(BeTemporally("~", previousRefreshAt, time.Second))
+			Expect(standIn.Requests()).To(BeEquivalentTo(1))
+		})
+
+		When("the config changes", func() {
+			BeforeEach(func() {
+				config.Scopes = []string{"registry:push"}
+			})
+
+			It("refreshes them", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(refreshed).To(BeTrue())
+				Expect(standIn.Requests()).To(BeEquivalentTo(2))
+			})
+		})
+	})
+
+	When("the credentials secret holds a refresh token", func() {
+		BeforeEach(func() {
+			helpers.EnsureCreate(adminClient, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: testNamespace,
+					Name:      "refresh-token",
+				},
+				StringData: map[string]string{
+					"refreshToken": helpers.OAuth2StandInRefreshToken,
+				},
+			})
+			config.CredentialsSecret = "refresh-token"
+		})
+
+		It("stores the rotated refresh token", func() {
+			Expect(err).NotTo(HaveOccurred())
+
+			secret := &corev1.Secret{}
+			Expect(adminClient.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: "refresh-token"}, secret)).To(Succeed())
+			Expect(secret.Data).To(HaveKeyWithValue("refreshToken", []byte(helpers.OAuth2StandInRotatedRefreshToken)))
+		})
+	})
+
+	When("the registry secret is not a docker config secret", func() {
+		BeforeEach(func() {
+			helpers.EnsureCreate(adminClient, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: testNamespace,
+					Name:      "registry-secret",
+				},
+				Type: corev1.SecretTypeOpaque,
+			})
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("is of type Opaque")))
+		})
+	})
+
+	When("the provider is unknown", func() {
+		BeforeEach(func() {
+			config.Provider = "unknown"
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring(`unknown registry credential provider "unknown"`)))
+		})
+	})
+
+	When("neither a credentials secret nor a service account is given", func() {
+		BeforeEach(func() {
+			config.CredentialsSecret = ""
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("require either a credentials secret or a service account")))
+		})
+	})
+})
@@ -2,8 +2,12 @@ package secrets
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -17,6 +21,54 @@ type DockerRegistryConfig struct {
 type DockerRegistryAuth struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	// Auth is the base64 encoded `username:password`, as written by `docker login`
+	Auth string `json:"auth,omitempty"`
+	// IdentityToken is an OAuth refresh token used instead of a password
+	IdentityToken string `json:"identitytoken,omitempty"`
+}
+
+// Registries returns the registries of the config in lexical order
+func (c DockerRegistryConfig) Registries() []string {
+	return slices.Sorted(maps.Keys(c.Auths))
+}
+
+// Registry returns the key of the registry in the config that matches the
+// given registry URL, ignoring the URL scheme and trailing slashes
+func (c DockerRegistryConfig) Registry(registryURL string) (string, bool) {
+	for _, registry := range c.Registries() {
+		if normalizeRegistry(registry) == normalizeRegistry(registryURL) {
+			return registry, true
+		}
+	}
+	return "", false
+}
+
+// Credentials returns the credentials for an image repository or registry,
+// e.g. `my-registry.com/korifi/` or `https://my-registry.com`. Credentials are
+// matched by the registry host
+func (c DockerRegistryConfig) Credentials(repository string) (DockerRegistryAuth, bool) {
+	if registry, ok := c.Registry(repository); ok {
+		return c.Auths[registry], true
+	}
+
+	for _, registry := range c.Registries() {
+		if registryHost(registry) == registryHost(repository) {
+			return c.Auths[registry], true
+		}
+	}
+
+	return DockerRegistryAuth{}, false
+}
+
+func registryHost(repository string) string {
+	host, _, _ := strings.Cut(normalizeRegistry(repository), "/")
+	return host
+}
+
+func normalizeRegistry(registryURL string) string {
+	registryURL = strings.TrimPrefix(registryURL, "https://")
+	registryURL = strings.TrimPrefix(registryURL, "http://")
+	return strings.TrimSuffix(registryURL, "/")
 }
 
 type Docker struct {
@@ -29,6 +81,9 @@ func NewDocker(k8sClient client.Client) *Docker {
 	}
 }
 
+// GetRegistryConfig reads the registry credentials of a `kubernetes.io/dockerconfigjson`
+// secret or a legacy `kubernetes.io/dockercfg` one. Credentials given as `auth`
+// are decoded into the username and password
 func (d *Docker) GetRegistryConfig(ctx context.Context, secretNamespace string, secretName string) (DockerRegistryConfig, error) {
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
@@ -42,11 +97,43 @@ func (d *Docker) GetRegistryConfig(ctx context.Context, secretNamespace string,
 		return DockerRegistryConfig{}, fmt.Errorf("failed to get docker registry secret %s/%s: %w", secretNamespace, secretName, err)
 	}
 
-	config := &DockerRegistryConfig{}
-	err = json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], config)
+	config := DockerRegistryConfig{}
+	if legacyConfig, ok := secret.Data[corev1.DockerConfigKey]; ok && secret.Data[corev1.DockerConfigJsonKey] == nil {
+		// the legacy format has no `auths` wrapper
+		err = json.Unmarshal(legacyConfig, &config.Auths)
+	} else {
+		err = json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config)
+	}
 	if err != nil {
 		return DockerRegistryConfig{}, fmt.Errorf("failed to unmarshal docker registry config from secret %s/%s: %w", secretNamespace, secretName, err)
 	}
 
-	return *config, nil
+	for registry, auth := range config.Auths {
+		config.Auths[registry], err = decodeAuth(auth)
+		if err != nil {
+			return DockerRegistryConfig{}, fmt.Errorf("invalid credentials for registry %s in secret %s/%s: %w", registry, secretNamespace, secretName, err)
+		}
+	}
+
+	return config, nil
+}
+
+func decodeAuth(auth DockerRegistryAuth) (DockerRegistryAuth, error) {
+	if auth.Auth == "" || auth.Username != "" {
+		return auth, nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+	if err != nil {
+		return DockerRegistryAuth{}, fmt.Errorf("failed to decode auth: %w", err)
+	}
+
+	username, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return DockerRegistryAuth{}, fmt.Errorf("auth is not of the form username:password")
+	}
+
+	auth.Username = username
+	auth.Password = password
+	return auth, nil
 }
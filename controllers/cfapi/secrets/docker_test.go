@@ -1,6 +1,7 @@
 package secrets_test
 
 import (
+	"encoding/base64"
 	"encoding/json"
 
 	"github.com/kyma-project/cfapi/controllers/cfapi/secrets"
@@ -64,33 +65,128 @@ var _ = Describe("Docker", func() {
 		}))
 	})
 
-	When("getting the secret fails", func() {
+	It("returns the registries in lexical order", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Registries()).To(Equal([]string{"my-server.com"}))
+	})
+
+	It("matches registries ignoring the scheme and trailing slashes", func() {
+		Expect(err).NotTo(HaveOccurred())
+		registry, ok := config.Registry("https://my-server.com/")
+		Expect(ok).To(BeTrue())
+		Expect(registry).To(Equal("my-server.com"))
+
+		_, ok = config.Registry("other-server.com")
+		Expect(ok).To(BeFalse())
+	})
+
+	When("the credentials are specified as auth", func() {
 		BeforeEach(func() {
-			secretName = "does-not-exist"
+			patchSecretData(secretName, corev1.DockerConfigJsonKey, `{"auths":{"my-server.com":{"auth":"`+
+				base64.StdEncoding.EncodeToString([]byte("my-user:my:password"))+`"}}}`)
+		})
+
+		It("decodes the username and password", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Auths).To(HaveKeyWithValue("my-server.com", secrets.DockerRegistryAuth{
+				Username: "my-user",
+				Password: "my:password",
+				Auth:     base64.StdEncoding.EncodeToString([]byte("my-user:my:password")),
+			}))
+		})
+	})
+
+	When("the auth is not base64 encoded", func() {
+		BeforeEach(func() {
+			patchSecretData(secretName, corev1.DockerConfigJsonKey, `{"auths":{"my-server.com":{"auth":"not-base64!"}}}`)
 		})
 
 		It("returns an error", func() {
-			Expect(err).To(MatchError(ContainSubstring("failed to get docker registry secret")))
+			Expect(err).To(MatchError(ContainSubstring("invalid credentials for registry my-server.com")))
 		})
 	})
 
-	When("the secret contains invalid data", func() {
+	When("the auth does not contain a password", func() {
 		BeforeEach(func() {
-			secret := &corev1.Secret{
+			patchSecretData(secretName, corev1.DockerConfigJsonKey, `{"auths":{"my-server.com":{"auth":"`+
+				base64.StdEncoding.EncodeToString([]byte("my-user"))+`"}}}`)
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("auth is not of the form username:password")))
+		})
+	})
+
+	When("the credentials are specified as identity token", func() {
+		BeforeEach(func() {
+			patchSecretData(secretName, corev1.DockerConfigJsonKey, `{"auths":{"my-server.com":{"username":"<token>","identitytoken":"my-token"}}}`)
+		})
+
+		It("returns the identity token", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Auths).To(HaveKeyWithValue("my-server.com", secrets.DockerRegistryAuth{
+				Username:      "<token>",
+				IdentityToken: "my-token",
+			}))
+		})
+	})
+
+	When("the secret is in the legacy dockercfg format", func() {
+		BeforeEach(func() {
+			secretName = "legacy-docker-secret"
+			helpers.EnsureCreate(adminClient, &corev1.Secret{
 				ObjectMeta: metav1.ObjectMeta{
 					Namespace: testNamespace,
 					Name:      secretName,
 				},
-			}
-			Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(secret), secret)).To(Succeed())
-
-			helpers.EnsurePatch(adminClient, secret, func(s *corev1.Secret) {
-				s.Data[corev1.DockerConfigJsonKey] = []byte("invalid-json")
+				Data: map[string][]byte{
+					corev1.DockerConfigKey: []byte(`{"my-legacy-server.com":{"auth":"` +
+						base64.StdEncoding.EncodeToString([]byte("legacy-user:legacy-password")) + `"}}`),
+				},
 			})
 		})
 
+		It("returns the config", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Registries()).To(Equal([]string{"my-legacy-server.com"}))
+			Expect(config.Auths["my-legacy-server.com"].Username).To(Equal("legacy-user"))
+			Expect(config.Auths["my-legacy-server.com"].Password).To(Equal("legacy-password"))
+		})
+	})
+
+	When("getting the secret fails", func() {
+		BeforeEach(func() {
+			secretName = "does-not-exist"
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("failed to get docker registry secret")))
+		})
+	})
+
+	When("the secret contains invalid data", func() {
+		BeforeEach(func() {
+			patchSecretData(secretName, corev1.DockerConfigJsonKey, "invalid-json")
+		})
+
 		It("returns an error", func() {
 			Expect(err).To(MatchError(ContainSubstring("failed to unmarshal docker registry config")))
 		})
 	})
 })
+
+func patchSecretData(secretName, key, value string) {
+	GinkgoHelper()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testNamespace,
+			Name:      secretName,
+		},
+	}
+	Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(secret), secret)).To(Succeed())
+
+	helpers.EnsurePatch(adminClient, secret, func(s *corev1.Secret) {
+		s.Data[key] = []byte(value)
+	})
+}
@@ -0,0 +1,46 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const dockerHubRegistry = "index.docker.io"
+
+// ResolveDigest returns the digest of the manifest an image reference such as
+// `paketobuildpacks/java`, `my-registry.com/stacks/run:jammy` or
+// `my-registry.com/stacks/run@sha256:...` refers to. Images of registries the
+// config has no credentials for are resolved anonymously
+func (c *RegistryClient) ResolveDigest(ctx context.Context, config DockerRegistryConfig, image string) (string, error) {
+	repository, reference := SplitImageReference(image)
+	if strings.HasPrefix(reference, "sha256:") {
+		return reference, nil
+	}
+
+	return c.ManifestDigest(ctx, config, repository, reference)
+}
+
+// SplitImageReference splits an image reference into its repository,
+// including the registry, and its digest or tag. Docker Hub images get their
+// registry, and official ones their `library/` namespace, like the container
+// runtime does
+func SplitImageReference(image string) (string, string) {
+	repository, reference, isDigest := strings.Cut(image, "@")
+	if !isDigest {
+		reference = "latest"
+		if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+			repository, reference = repository[:i], repository[i+1:]
+		}
+	}
+
+	registry, name, hasRegistry := strings.Cut(repository, "/")
+	if !hasRegistry || !strings.ContainsAny(registry, ".:") && registry != "localhost" {
+		registry, name = dockerHubRegistry, repository
+	}
+	if registry == dockerHubRegistry && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+
+	return fmt.Sprintf("%s/%s", registry, name), reference
+}
@@ -0,0 +1,126 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// CredentialProviderOAuth2 is the name of the OAuth2CredentialProvider
+	CredentialProviderOAuth2 = "oauth2"
+
+	// defaultOAuth2Username is the username registries such as Google
+	// Artifact Registry expect together with an OAuth2 access token
+	defaultOAuth2Username = "oauth2accesstoken"
+	// defaultTokenLifetime is assumed when the token endpoint does not
+	// report the lifetime of a token
+	defaultTokenLifetime = time.Hour
+
+	tokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+	jwtTokenType           = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// OAuth2CredentialProvider obtains an access token from an OAuth2 token
+// endpoint and uses it as registry password. It uses the client credentials
+// grant for `clientID` and `clientSecret`, the refresh token grant for a
+// `refreshToken` and the token exchange grant for a workload identity token
+type OAuth2CredentialProvider struct {
+	httpClient *http.Client
+}
+
+func NewOAuth2CredentialProvider(httpClient *http.Client) *OAuth2CredentialProvider {
+	return &OAuth2CredentialProvider{
+		httpClient: httpClient,
+	}
+}
+
+type oauth2TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (p *OAuth2CredentialProvider) GetCredentials(ctx context.Context, request CredentialRequest) (RegistryCredentials, error) {
+	clientID := string(request.Credentials["clientID"])
+	clientSecret := string(request.Credentials["clientSecret"])
+	refreshToken := string(request.Credentials["refreshToken"])
+
+	form := url.Values{}
+	switch {
+	case clientID != "" && clientSecret != "":
+		form.Set("grant_type", "client_credentials")
+	case refreshToken != "":
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", refreshToken)
+		if clientID != "" {
+			form.Set("client_id", clientID)
+		}
+	case request.SubjectToken != "":
+		form.Set("grant_type", tokenExchangeGrantType)
+		form.Set("subject_token", request.SubjectToken)
+		form.Set("subject_token_type", jwtTokenType)
+	default:
+		return RegistryCredentials{}, errors.New("no credentials to exchange, expected clientID and clientSecret, a refreshToken or a service account token")
+	}
+	if len(request.Scopes) > 0 {
+		form.Set("scope", strings.Join(request.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, request.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return RegistryCredentials{}, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if form.Get("grant_type") == "client_credentials" {
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	}
+
+	issuedAt := time.Now()
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return RegistryCredentials{}, fmt.Errorf("failed to request a token from %s: %w", request.TokenURL, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return RegistryCredentials{}, statusError(http.MethodPost, request.TokenURL, resp)
+	}
+
+	token := oauth2TokenResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return RegistryCredentials{}, fmt.Errorf("failed to decode token response of %s: %w", request.TokenURL, err)
+	}
+	if token.AccessToken == "" {
+		return RegistryCredentials{}, fmt.Errorf("token response of %s does not contain an access token", request.TokenURL)
+	}
+
+	lifetime := defaultTokenLifetime
+	if token.ExpiresIn > 0 {
+		lifetime = time.Duration(token.ExpiresIn) * time.Second
+	}
+
+	username := request.Username
+	if username == "" {
+		username = defaultOAuth2Username
+	}
+
+	credentials := RegistryCredentials{
+		Auth: DockerRegistryAuth{
+			Username: username,
+			Password: token.AccessToken,
+		},
+		ExpiresAt: issuedAt.Add(lifetime),
+	}
+	if refreshToken != "" && token.RefreshToken != "" && token.RefreshToken != refreshToken {
+		credentials.RotatedCredentials = map[string][]byte{"refreshToken": []byte(token.RefreshToken)}
+	}
+
+	return credentials, nil
+}
@@ -0,0 +1,110 @@
+package secrets_test
+
+import (
+	"time"
+
+	"github.com/kyma-project/cfapi/controllers/cfapi/secrets"
+	"github.com/kyma-project/cfapi/tests/helpers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OAuth2CredentialProvider", func() {
+	var (
+		standIn     *helpers.OAuth2StandIn
+		provider    *secrets.OAuth2CredentialProvider
+		request     secrets.CredentialRequest
+		credentials secrets.RegistryCredentials
+		err         error
+	)
+
+	BeforeEach(func() {
+		standIn = helpers.NewOAuth2StandIn("my-client", "my-secret")
+		DeferCleanup(standIn.Close)
+
+		provider = secrets.NewOAuth2CredentialProvider(helpers.NewStandInHTTPClient(standIn.Server))
+		request = secrets.CredentialRequest{
+			TokenURL: "https://my-idp.com/oauth/token",
+			Credentials: map[string][]byte{
+				"clientID":     []byte("my-client"),
+				"clientSecret": []byte("my-secret"),
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		credentials, err = provider.GetCredentials(ctx, request)
+	})
+
+	It("returns the access token as password", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(credentials.Auth).To(Equal(secrets.DockerRegistryAuth{
+			Username: "oauth2accesstoken",
+			Password: helpers.OAuth2StandInAccessToken,
+		}))
+		Expect(credentials.ExpiresAt).To(BeTemporally("~", time.Now().Add(helpers.OAuth2StandInExpiresIn*time.Second), 5*time.Second))
+		Expect(credentials.RotatedCredentials).To(BeEmpty())
+	})
+
+	When("a username is given", func() {
+		BeforeEach(func() {
+			request.Username = "my-user"
+		})
+
+		It("uses it", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(credentials.Auth.Username).To(Equal("my-user"))
+		})
+	})
+
+	When("the client credentials are wrong", func() {
+		BeforeEach(func() {
+			request.Credentials["clientSecret"] = []byte("wrong-secret")
+		})
+
+		It("returns the HTTP error of the token endpoint", func() {
+			Expect(err).To(MatchError(SatisfyAll(
+				ContainSubstring("POST https://my-idp.com/oauth/token returned 401 Unauthorized"),
+				ContainSubstring("invalid_client"),
+			)))
+		})
+	})
+
+	When("the credentials are a refresh token", func() {
+		BeforeEach(func() {
+			request.Credentials = map[string][]byte{
+				"refreshToken": []byte(helpers.OAuth2StandInRefreshToken),
+			}
+		})
+
+		It("returns the rotated refresh token", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(credentials.Auth.Password).To(Equal(helpers.OAuth2StandInAccessToken))
+			Expect(credentials.RotatedCredentials).To(Equal(map[string][]byte{
+				"refreshToken": []byte(helpers.OAuth2StandInRotatedRefreshToken),
+			}))
+		})
+	})
+
+	When("a service account token is given", func() {
+		BeforeEach(func() {
+			request.Credentials = nil
+			request.SubjectToken = helpers.OAuth2StandInSubjectToken
+		})
+
+		It("exchanges the token", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(credentials.Auth.Password).To(Equal(helpers.OAuth2StandInAccessToken))
+		})
+	})
+
+	When("there are no credentials", func() {
+		BeforeEach(func() {
+			request.Credentials = nil
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("no credentials to exchange")))
+		})
+	})
+})
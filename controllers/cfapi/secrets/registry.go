@@ -0,0 +1,309 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// RegistryCheckRepository is the repository a blob upload is started in
+	// to check that images can be pushed under a repository prefix
+	RegistryCheckRepository = "cfapi-registry-check"
+
+	maxErrorBodySize = 512
+)
+
+// registryClient talks to the distribution API of registries, answering
+// their authentication challenges with the credentials of a docker config
+type registryClient struct {
+	httpClient *http.Client
+}
+
+type RegistryChecker struct {
+	registryClient
+}
+
+func NewRegistryChecker(httpClient *http.Client) *RegistryChecker {
+	return &RegistryChecker{
+		registryClient: registryClient{httpClient: httpClient},
+	}
+}
+
+// Check verifies that the registry serves the distribution API at `/v2/` and
+// accepts the credentials of the config. When repositories are given, pushing
+// to each of them is checked by starting a blob upload, which is cancelled
+// right away so that nothing is stored in the registry
+func (c *RegistryChecker) Check(ctx context.Context, config DockerRegistryConfig, registryURL string, pushRepositories ...string) error {
+	endpoint := registryEndpoint(registryURL)
+	err := c.do(ctx, authFor(config, registryURL), http.MethodGet, endpoint.JoinPath("v2/").String(), "", http.StatusOK)
+	if err != nil {
+		return fmt.Errorf("failed to access registry %s: %w", registryURL, err)
+	}
+
+	for _, repository := range pushRepositories {
+		if err := c.checkPush(ctx, config, repository); err != nil {
+			return fmt.Errorf("failed to push to repository %s: %w", repository, err)
+		}
+	}
+
+	return nil
+}
+
+func (c *RegistryChecker) checkPush(ctx context.Context, config DockerRegistryConfig, repository string) error {
+	name, err := repositoryName(repository)
+	if err != nil {
+		return err
+	}
+
+	endpoint := registryEndpoint(repository)
+	auth := authFor(config, repository)
+	scope := "repository:" + name + ":pull,push"
+
+	uploadURL := endpoint.JoinPath("v2", name, "blobs", "uploads/").String()
+	resp, err := c.authorizedDo(ctx, auth, http.MethodPost, uploadURL, scope, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		return statusError(http.MethodPost, uploadURL, resp)
+	}
+
+	location, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.Header.Get("Location") == "" {
+		return fmt.Errorf("POST %s did not return a valid upload location", uploadURL)
+	}
+
+	// cancelling is best effort, abandoned uploads are purged by the registry
+	_ = c.do(ctx, auth, http.MethodDelete, location.String(), scope, http.StatusNoContent)
+
+	return nil
+}
+
+func (c *registryClient) do(ctx context.Context, auth DockerRegistryAuth, method, requestURL, scope string, expectedStatus int) error {
+	resp, err := c.authorizedDo(ctx, auth, method, requestURL, scope, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != expectedStatus {
+		return statusError(method, requestURL, resp)
+	}
+
+	return nil
+}
+
+// authorizedDo sends the request anonymously first and answers the
+// authentication challenge of the registry, if any. Registries either ask for
+// basic auth or for a bearer token of their token service
+func (c *registryClient) authorizedDo(ctx context.Context, auth DockerRegistryAuth, method, requestURL, scope string, header http.Header) (*http.Response, error) {
+	resp, err := c.send(ctx, method, requestURL, withHeader(header, nil))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	authScheme, params := parseChallenge(resp.Header.Get("WWW-Authenticate"))
+	switch strings.ToLower(authScheme) {
+	case "basic":
+		return c.send(ctx, method, requestURL, withHeader(header, func(req *http.Request) {
+			req.SetBasicAuth(auth.Username, auth.Password)
+		}))
+	case "bearer":
+		if scope == "" {
+			scope = params["scope"]
+		}
+		token, err := c.getToken(ctx, auth, params["realm"], params["service"], scope)
+		if err != nil {
+			return nil, err
+		}
+		return c.send(ctx, method, requestURL, withHeader(header, func(req *http.Request) {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}))
+	default:
+		return nil, fmt.Errorf("%s %s returned %s with unsupported authentication challenge %q", method, requestURL, resp.Status, resp.Header.Get("WWW-Authenticate"))
+	}
+}
+
+type tokenResponse struct {
+	Token       string `json:"token"`
+	AccessToken string `json:"access_token"`
+}
+
+// getToken gets a bearer token from the token service of the registry. An
+// identity token is exchanged with an OAuth2 refresh token grant, as done by
+// docker, username and password are sent as basic auth
+func (c *registryClient) getToken(ctx context.Context, auth DockerRegistryAuth, realm, service, scope string) (string, error) {
+	if realm == "" {
+		return "", errors.New("the bearer authentication challenge of the registry does not specify a realm")
+	}
+
+	var resp *http.Response
+	var err error
+	if auth.IdentityToken != "" {
+		form := url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {auth.IdentityToken},
+			"client_id":     {"cfapi"},
+			"service":       {service},
+			"scope":         {scope},
+		}
+		resp, err = c.send(ctx, http.MethodPost, realm, func(req *http.Request) {
+			body := form.Encode()
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Body = io.NopCloser(strings.NewReader(body))
+			req.ContentLength = int64(len(body))
+		})
+	} else {
+		tokenURL, parseErr := url.Parse(realm)
+		if parseErr != nil {
+			return "", fmt.Errorf("failed to parse token realm %q: %w", realm, parseErr)
+		}
+		query := tokenURL.Query()
+		query.Set("service", service)
+		if scope != "" {
+			query.Set("scope", scope)
+		}
+		tokenURL.RawQuery = query.Encode()
+
+		resp, err = c.send(ctx, http.MethodGet, tokenURL.String(), func(req *http.Request) {
+			if auth.Username != "" {
+				req.SetBasicAuth(auth.Username, auth.Password)
+			}
+		})
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get a registry token from %s: %w", realm, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get a registry token: %w", statusError(resp.Request.Method, realm, resp))
+	}
+
+	token := tokenResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode the registry token from %s: %w", realm, err)
+	}
+
+	if token.Token != "" {
+		return token.Token, nil
+	}
+	if token.AccessToken != "" {
+		return token.AccessToken, nil
+	}
+
+	return "", fmt.Errorf("the token service %s did not return a token", realm)
+}
+
+func (c *registryClient) send(ctx context.Context, method, requestURL string, prepare func(*http.Request)) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if prepare != nil {
+		prepare(req)
+	}
+
+	return c.httpClient.Do(req)
+}
+
+// withHeader returns a request preparation setting the given header before
+// running prepare, if any
+func withHeader(header http.Header, prepare func(*http.Request)) func(*http.Request) {
+	return func(req *http.Request) {
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		if prepare != nil {
+			prepare(req)
+		}
+	}
+}
+
+func statusError(method, requestURL string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return fmt.Errorf("%s %s returned %s", method, requestURL, resp.Status)
+	}
+
+	return fmt.Errorf("%s %s returned %s: %s", method, requestURL, resp.Status, strings.TrimSpace(string(body)))
+}
+
+// registryEndpoint returns the base URL of the distribution API of a registry.
+// Registries are accessed with https unless the registry URL explicitly uses
+// http or, like image builders do, the registry is an in-cluster `.local` host
+// or localhost. Docker Hub is served from `registry-1.docker.io`
+func registryEndpoint(registryURL string) *url.URL {
+	host := registryHost(registryURL)
+
+	scheme := "https"
+	if strings.HasPrefix(registryURL, "http://") || isInsecureHost(host) {
+		scheme = "http"
+	}
+
+	if host == "index.docker.io" || host == "docker.io" {
+		host = "registry-1.docker.io"
+	}
+
+	return &url.URL{Scheme: scheme, Host: host}
+}
+
+func isInsecureHost(host string) bool {
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+
+	return strings.HasSuffix(hostname, ".local") || hostname == "localhost" || hostname == "127.0.0.1"
+}
+
+func authFor(config DockerRegistryConfig, repository string) DockerRegistryAuth {
+	auth, _ := config.Credentials(repository)
+	return auth
+}
+
+// parseChallenge parses a `WWW-Authenticate` header such as
+// `Bearer realm="https://auth.docker.io/token",service="registry.docker.io"`.
+// Quoted values may contain commas, e.g. in `scope="repository:a:pull,push"`
+func parseChallenge(header string) (string, map[string]string) {
+	authScheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := map[string]string{}
+
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				params[key] = value[1:]
+				break
+			}
+			params[key] = value[1 : end+1]
+			rest = strings.TrimPrefix(strings.TrimSpace(value[end+2:]), ",")
+			continue
+		}
+
+		params[key], rest, _ = strings.Cut(value, ",")
+	}
+
+	return authScheme, params
+}
@@ -0,0 +1,194 @@
+package secrets_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/kyma-project/cfapi/controllers/cfapi/secrets"
+	"github.com/kyma-project/cfapi/tests/helpers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RegistryChecker", func() {
+	var (
+		registryServer   *httptest.Server
+		checker          *secrets.RegistryChecker
+		config           secrets.DockerRegistryConfig
+		registryURL      string
+		pushRepositories []string
+		err              error
+	)
+
+	BeforeEach(func() {
+		registryServer = helpers.NewRegistryStandIn("my-user", "my-password")
+		DeferCleanup(registryServer.Close)
+
+		checker = secrets.NewRegistryChecker(helpers.NewStandInHTTPClient(registryServer))
+		config = secrets.DockerRegistryConfig{
+			Auths: map[string]secrets.DockerRegistryAuth{
+				"https://my-registry.com/": {
+					Username: "my-user",
+					Password: "my-password",
+				},
+			},
+		}
+		registryURL = "https://my-registry.com/"
+		pushRepositories = nil
+	})
+
+	JustBeforeEach(func() {
+		err = checker.Check(ctx, config, registryURL, pushRepositories...)
+	})
+
+	It("succeeds", func() {
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	When("the credentials are wrong", func() {
+		BeforeEach(func() {
+			config.Auths["https://my-registry.com/"] = secrets.DockerRegistryAuth{
+				Username: "my-user",
+				Password: "wrong-password",
+			}
+		})
+
+		It("returns the HTTP error of the token service", func() {
+			Expect(err).To(MatchError(SatisfyAll(
+				ContainSubstring("failed to access registry https://my-registry.com/"),
+				ContainSubstring("401 Unauthorized"),
+				ContainSubstring("incorrect username or password"),
+			)))
+		})
+	})
+
+	When("the config does not contain credentials for the registry", func() {
+		BeforeEach(func() {
+			config = secrets.DockerRegistryConfig{}
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("401 Unauthorized")))
+		})
+	})
+
+	When("the credentials are an identity token", func() {
+		BeforeEach(func() {
+			config.Auths["https://my-registry.com/"] = secrets.DockerRegistryAuth{
+				Username:      "<token>",
+				IdentityToken: helpers.RegistryStandInIdentityToken,
+			}
+		})
+
+		It("exchanges the identity token for a registry token", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	When("push repositories are given", func() {
+		BeforeEach(func() {
+			pushRepositories = []string{"my-registry.com/korifi/" + secrets.RegistryCheckRepository, "my-registry.com/cfapi/kpack-builder"}
+		})
+
+		It("succeeds", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		When("pushing to a repository is denied", func() {
+			BeforeEach(func() {
+				pushRepositories = append(pushRepositories, "my-registry.com/"+helpers.RegistryStandInDeniedPrefix+"kpack-builder")
+			})
+
+			It("returns the HTTP error of the registry", func() {
+				Expect(err).To(MatchError(SatisfyAll(
+					ContainSubstring("failed to push to repository my-registry.com/denied/kpack-builder"),
+					ContainSubstring("403 Forbidden"),
+					ContainSubstring("DENIED"),
+				)))
+			})
+		})
+
+		When("a repository does not specify a name", func() {
+			BeforeEach(func() {
+				pushRepositories = []string{"my-registry.com"}
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(MatchError(ContainSubstring("does not specify a registry and a name")))
+			})
+		})
+	})
+
+	When("the registry uses basic authentication", func() {
+		BeforeEach(func() {
+			registryServer = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if username, password, ok := r.BasicAuth(); !ok || username != "my-user" || password != "my-password" {
+					w.Header().Set("WWW-Authenticate", `Basic realm="my-registry"`)
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			DeferCleanup(registryServer.Close)
+
+			checker = secrets.NewRegistryChecker(helpers.NewStandInHTTPClient(registryServer))
+		})
+
+		It("succeeds", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		When("the credentials are wrong", func() {
+			BeforeEach(func() {
+				config.Auths["https://my-registry.com/"] = secrets.DockerRegistryAuth{
+					Username: "my-user",
+					Password: "wrong-password",
+				}
+			})
+
+			It("returns the HTTP error", func() {
+				Expect(err).To(MatchError(ContainSubstring("GET https://my-registry.com/v2/ returned 401 Unauthorized")))
+			})
+		})
+	})
+
+	When("the registry is an in-cluster registry", func() {
+		BeforeEach(func() {
+			registryServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if username, password, ok := r.BasicAuth(); !ok || username != "my-user" || password != "my-password" {
+					w.Header().Set("WWW-Authenticate", `Basic realm="my-registry"`)
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			DeferCleanup(registryServer.Close)
+
+			checker = secrets.NewRegistryChecker(helpers.NewStandInHTTPClient(registryServer))
+			registryURL = "dockerregistry.kyma-system.svc.cluster.local:5000"
+			config.Auths = map[string]secrets.DockerRegistryAuth{
+				registryURL: {
+					Username: "my-user",
+					Password: "my-password",
+				},
+			}
+		})
+
+		It("talks plain HTTP to the registry", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	When("the registry does not serve the distribution API", func() {
+		BeforeEach(func() {
+			registryServer = httptest.NewTLSServer(http.NotFoundHandler())
+			DeferCleanup(registryServer.Close)
+
+			checker = secrets.NewRegistryChecker(helpers.NewStandInHTTPClient(registryServer))
+		})
+
+		It("returns the HTTP error", func() {
+			Expect(err).To(MatchError(ContainSubstring("GET https://my-registry.com/v2/ returned 404 Not Found")))
+		})
+	})
+})
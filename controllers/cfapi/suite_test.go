@@ -2,7 +2,16 @@ package cfapi_test
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
 	"path/filepath"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,11 +22,13 @@ import (
 	"github.com/kyma-project/cfapi/controllers/installable"
 	"github.com/kyma-project/cfapi/controllers/installable/fake"
 	"github.com/kyma-project/cfapi/controllers/kyma"
+	"github.com/kyma-project/cfapi/controllers/proxy"
 	"github.com/kyma-project/cfapi/tests/helpers"
 	"github.com/kyma-project/cfapi/tools"
 	kymaistiov1alpha2 "github.com/kyma-project/istio/operator/api/v1alpha2"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	buildv1alpha2 "github.com/pivotal/kpack/pkg/apis/build/v1alpha2"
 	istiov1alpha3 "istio.io/api/networking/v1alpha3"
 	istiov1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
 	istioclient "istio.io/client-go/pkg/clientset/versioned"
@@ -31,6 +42,7 @@ import (
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -48,8 +60,37 @@ var (
 
 	firstToUninstall  *fake.Installable
 	secondToUninstall *fake.Installable
+
+	apiProbe *fakeAPIProbe
 )
 
+type fakeAPIProbe struct {
+	mu        sync.Mutex
+	err       error
+	addresses []string
+	hosts     []string
+}
+
+func (p *fakeAPIProbe) probe(_ context.Context, address, host string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.addresses = append(p.addresses, address)
+	p.hosts = append(p.hosts, host)
+	return p.err
+}
+
+func (p *fakeAPIProbe) setErr(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+func (p *fakeAPIProbe) probed() ([]string, []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.addresses), slices.Clone(p.hosts)
+}
+
 func TestNetworkingControllers(t *testing.T) {
 	SetDefaultEventuallyTimeout(10 * time.Second)
 	SetDefaultEventuallyPollingInterval(250 * time.Millisecond)
@@ -72,6 +113,8 @@ var _ = BeforeEach(func() {
 			filepath.Join("..", "..", "tests", "dependencies", "vendor", "kyma-docker-registry"),
 			filepath.Join("..", "..", "tests", "dependencies", "vendor", "istio-kyma"),
 			filepath.Join("..", "..", "tests", "dependencies", "vendor", "istio", "manifests", "charts", "base", "files"),
+			filepath.Join("..", "..", "module-data", "vendor", "gateway-api", "standard-install.yaml"),
+			filepath.Join("..", "..", "module-data", "vendor", "kpack", "release-0.17.1.yaml"),
 		},
 		ErrorIfCRDPathMissing: true,
 	}
@@ -82,6 +125,8 @@ var _ = BeforeEach(func() {
 	Expect(v1alpha1.AddToScheme(scheme.Scheme)).To(Succeed())
 	Expect(istiov1beta1.AddToScheme(scheme.Scheme)).To(Succeed())
 	Expect(kymaistiov1alpha2.AddToScheme(testEnv.Scheme)).To(Succeed())
+	Expect(gatewayv1.Install(scheme.Scheme)).To(Succeed())
+	Expect(buildv1alpha2.AddToScheme(scheme.Scheme)).To(Succeed())
 
 	k8sManager = helpers.NewK8sManager(testEnv, filepath.Join("config", "rbac", "role.yaml"))
 
@@ -109,6 +154,21 @@ var _ = BeforeEach(func() {
 		},
 	})).To(Succeed())
 
+	Expect(adminClient.Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: cfAPINamespace,
+			Name:      kyma.InternalContainerRegistrySecretName,
+		},
+		Type: corev1.SecretTypeDockerConfigJson,
+		StringData: map[string]string{
+			"pushRegAddr": "dockerregistry.kyma-system.svc.cluster.local:5000",
+			"pullRegAddr": "localhost:32137",
+			corev1.DockerConfigJsonKey: `{"auths":{` +
+				`"dockerregistry.kyma-system.svc.cluster.local:5000":{"username":"kyma-user","password":"kyma-password"},` +
+				`"localhost:32137":{"username":"kyma-user","password":"kyma-password"}}}`,
+		},
+	})).To(Succeed())
+
 	Expect(adminClient.Create(ctx, &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: "kyma-system",
@@ -178,12 +238,22 @@ var _ = BeforeEach(func() {
 	firstToUninstall = new(fake.Installable)
 	secondToUninstall = new(fake.Installable)
 
-	kymaClient := kyma.NewClient(adminClient)
+	oidcServer := helpers.NewOIDCStandIn("uaa.cf.eu12.hana.ondemand.com")
+	DeferCleanup(oidcServer.Close)
+
+	registryServer := helpers.NewRegistryStandIn("registry-user", "registry-password")
+	DeferCleanup(registryServer.Close)
+
+	kymaClient := kyma.NewClient(adminClient, helpers.NewStandInHTTPClient(oidcServer))
+	apiProbe = &fakeAPIProbe{}
 	err = cfapi.NewReconciler(
 		k8sManager.GetClient(),
 		k8sManager.GetScheme(),
 		kymaClient,
 		secrets.NewDocker(adminClient),
+		secrets.NewRegistryChecker(helpers.NewStandInHTTPClient(registryServer)),
+		cfapi.NewGatewayMigrator(k8sManager.GetClient(), apiProbe.probe, 500*time.Millisecond),
+		proxy.NewSettings(),
 		k8sManager.GetEventRecorder("cfapi"),
 		ctrl.Log.WithName("controllers").WithName("cfapi"),
 		100*time.Millisecond,
@@ -200,3 +270,23 @@ var _ = AfterEach(func() {
 	stopClientCache()
 	Expect(testEnv.Stop()).To(Succeed())
 })
+
+func selfSignedCertificate() string {
+	GinkgoHelper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "Corporate CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}))
+}
@@ -0,0 +1,91 @@
+package cfapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const defaultTrustedCABundleKey = "ca.crt"
+
+// computeTrustedCA returns the source of `spec.trustedCABundle` together with
+// the hash of the bundle, which the installables use to roll out the
+// components whenever the bundle changes
+func (r *Reconciler) computeTrustedCA(ctx context.Context, cfAPI *v1alpha1.CFAPI) (*v1alpha1.TrustedCA, error) {
+	if cfAPI.Spec.TrustedCABundle == nil {
+		return nil, nil
+	}
+
+	key := cfAPI.Spec.TrustedCABundle.Key
+	if key == "" {
+		key = defaultTrustedCABundleKey
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := r.k8sClient.Get(ctx, client.ObjectKey{Namespace: cfAPI.Namespace, Name: cfAPI.Spec.TrustedCABundle.Name}, configMap); err != nil {
+		return nil, fmt.Errorf("failed to get trusted CA bundle %s: %w", cfAPI.Spec.TrustedCABundle.Name, err)
+	}
+
+	bundle, ok := configMap.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("trusted CA bundle %s does not contain key %s", configMap.Name, key)
+	}
+	if err := validateCABundle(bundle); err != nil {
+		return nil, fmt.Errorf("trusted CA bundle %s is invalid: %w", configMap.Name, err)
+	}
+
+	return &v1alpha1.TrustedCA{
+		Namespace: configMap.Namespace,
+		Name:      configMap.Name,
+		Key:       key,
+		Hash:      fmt.Sprintf("%x", sha256.Sum256([]byte(bundle))),
+	}, nil
+}
+
+// validateCABundle checks that the bundle consists of PEM encoded certificates
+func validateCABundle(bundle string) error {
+	rest := []byte(bundle)
+	certificates := 0
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return fmt.Errorf("unexpected PEM block of type %s", block.Type)
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		certificates++
+	}
+
+	if certificates == 0 {
+		return fmt.Errorf("no PEM encoded certificates found")
+	}
+	return nil
+}
+
+// enqueueTrustedCABundleCFAPIs triggers a reconcile of the CFAPIs whose
+// trusted CA bundle has changed
+func (r *Reconciler) enqueueTrustedCABundleCFAPIs(ctx context.Context, obj client.Object) []reconcile.Request {
+	requests := []reconcile.Request{}
+	for _, request := range r.enqueueCFAPIs(ctx, obj) {
+		cfAPI := &v1alpha1.CFAPI{}
+		if err := r.k8sClient.Get(ctx, request.NamespacedName, cfAPI); err != nil {
+			continue
+		}
+		if cfAPI.Spec.TrustedCABundle != nil && cfAPI.Namespace == obj.GetNamespace() && cfAPI.Spec.TrustedCABundle.Name == obj.GetName() {
+			requests = append(requests, request)
+		}
+	}
+	return requests
+}
@@ -0,0 +1,297 @@
+package cfroles
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"strings"
+
+	korifiv1alpha1 "code.cloudfoundry.org/korifi/controllers/api/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/google/uuid"
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/installable"
+	"github.com/kyma-project/cfapi/tools/k8s"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	RoleSyncLabel = "cfapi.kyma-project.io/role-sync"
+	CFRoleLabel   = "cfapi.kyma-project.io/cf-role"
+	roleGUIDLabel = "cloudfoundry.org/role-guid"
+)
+
+type Reconciler struct {
+	k8sClient     client.Client
+	eventRecorder events.EventRecorder
+}
+
+func NewReconciler(
+	k8sClient client.Client,
+	eventRecorder events.EventRecorder,
+	log logr.Logger,
+) *k8s.PatchingReconciler[v1alpha1.CFAPI] {
+	return k8s.NewPatchingReconciler(log, k8sClient, &Reconciler{
+		k8sClient:     k8sClient,
+		eventRecorder: eventRecorder,
+	})
+}
+
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) *builder.Builder {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("cfroles").
+		For(&v1alpha1.CFAPI{}).
+		Watches(&rbacv1.ClusterRoleBinding{}, handler.EnqueueRequestsFromMapFunc(r.enqueueCFAPIs)).
+		Watches(&rbacv1.RoleBinding{}, handler.EnqueueRequestsFromMapFunc(r.enqueueCFAPIs)).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.enqueueCFAPIs))
+}
+
+func (r *Reconciler) enqueueCFAPIs(ctx context.Context, _ client.Object) []reconcile.Request {
+	cfAPIs := &v1alpha1.CFAPIList{}
+	if err := r.k8sClient.List(ctx, cfAPIs); err != nil {
+		logr.FromContextOrDiscard(ctx).Error(err, "failed to list CFAPIs")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, cfAPI := range cfAPIs.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&cfAPI)})
+	}
+	return requests
+}
+
+type roleAssignment struct {
+	namespace string
+	cfRole    string
+	subject   rbacv1.Subject
+}
+
+func (a roleAssignment) key() string {
+	return a.cfRole + "::" + a.subject.Kind + "/" + a.subject.Name
+}
+
+func (a roleAssignment) roleBindingName() string {
+	return fmt.Sprintf("cfapi-%x", sha256.Sum256([]byte(a.key())))
+}
+
+func (r *Reconciler) ReconcileResource(ctx context.Context, cfAPI *v1alpha1.CFAPI) (ctrl.Result, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	rootNamespace := cfAPI.Status.InstallationConfig.RootNamespace
+	// the role bindings of a deleted CFAPI are deleted by its uninstall
+	if rootNamespace == "" || !cfAPI.DeletionTimestamp.IsZero() {
+		log.Info("cfapi is not installed, skipping cf roles sync")
+		return ctrl.Result{}, nil
+	}
+
+	eventRecorder := installable.NewCFAPIEventRecorder(r.eventRecorder, cfAPI)
+
+	desired := map[string]roleAssignment{}
+	if !cfAPI.Spec.DisableRoleSync {
+		assignments, err := r.desiredAssignments(ctx, cfAPI.Status.InstallationConfig, roleMappings(cfAPI), eventRecorder)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		for _, a := range assignments {
+			desired[a.namespace+"/"+a.roleBindingName()] = a
+		}
+	}
+
+	existing := &rbacv1.RoleBindingList{}
+	if err := r.k8sClient.List(ctx, existing, client.MatchingLabels{RoleSyncLabel: "true"}); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list synced role bindings: %w", err)
+	}
+
+	for _, roleBinding := range existing.Items {
+		// Korifi copies the bindings of propagated roles, labels included, to
+		// the org and space namespaces and deletes the copies along with them
+		if roleBinding.Labels[korifiv1alpha1.PropagatedFromLabel] != "" {
+			continue
+		}
+
+		if _, ok := desired[roleBinding.Namespace+"/"+roleBinding.Name]; ok {
+			delete(desired, roleBinding.Namespace+"/"+roleBinding.Name)
+			continue
+		}
+
+		if err := r.k8sClient.Delete(ctx, &roleBinding); client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to delete role binding %s/%s: %w", roleBinding.Namespace, roleBinding.Name, err)
+		}
+		eventRecorder.Event(installable.EventNormal, "CFRoleRevoked", fmt.Sprintf(
+			"Revoked CF role %s from %s in namespace %s", roleBinding.Labels[CFRoleLabel], describeSubjects(roleBinding.Subjects), roleBinding.Namespace,
+		))
+	}
+
+	for _, assignment := range desired {
+		err := r.k8sClient.Create(ctx, toRoleBinding(assignment))
+		if k8serrors.IsAlreadyExists(err) {
+			continue
+		}
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to create role binding for CF role %s: %w", assignment.cfRole, err)
+		}
+		eventRecorder.Event(installable.EventNormal, "CFRoleAssigned", fmt.Sprintf(
+			"Assigned CF role %s to %s %s in namespace %s", assignment.cfRole, assignment.subject.Kind, assignment.subject.Name, assignment.namespace,
+		))
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func roleMappings(cfAPI *v1alpha1.CFAPI) []v1alpha1.RoleMapping {
+	if len(cfAPI.Spec.RoleMappings) > 0 {
+		return cfAPI.Spec.RoleMappings
+	}
+	return DefaultRoleMappings
+}
+
+func (r *Reconciler) desiredAssignments(
+	ctx context.Context,
+	config v1alpha1.InstallationConfig,
+	mappings []v1alpha1.RoleMapping,
+	eventRecorder installable.EventRecorder,
+) ([]roleAssignment, error) {
+	clusterRoleBindings := &rbacv1.ClusterRoleBindingList{}
+	if err := r.k8sClient.List(ctx, clusterRoleBindings); err != nil {
+		return nil, fmt.Errorf("failed to list cluster role bindings: %w", err)
+	}
+
+	roleBindings := &rbacv1.RoleBindingList{}
+	if err := r.k8sClient.List(ctx, roleBindings); err != nil {
+		return nil, fmt.Errorf("failed to list role bindings: %w", err)
+	}
+
+	namespaces := &corev1.NamespaceList{}
+	if err := r.k8sClient.List(ctx, namespaces); err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	namespaceLabels := map[string]map[string]string{}
+	for _, ns := range namespaces.Items {
+		namespaceLabels[ns.Name] = ns.Labels
+	}
+
+	rootNamespace := config.RootNamespace
+	toCFSubjects := func(subjects []rbacv1.Subject) []rbacv1.Subject {
+		return cfSubjects(subjects, config.OIDCUsernamePrefix, config.OIDCGroupsPrefix)
+	}
+
+	assignments := []roleAssignment{}
+	for _, mapping := range mappings {
+		role, ok := cfRoles[mapping.CFRole]
+		if !ok || role.scope != mapping.Scope || mapping.CFRole == cfUserRole {
+			eventRecorder.Event(installable.EventWarning, "InvalidRoleMapping", fmt.Sprintf(
+				"Role mapping of cluster role %s to CF role %s is not valid for scope %s", mapping.ClusterRole, mapping.CFRole, mapping.Scope,
+			))
+			continue
+		}
+
+		if mapping.Scope == v1alpha1.RoleMappingScopeCluster {
+			for _, crb := range clusterRoleBindings.Items {
+				if !refersTo(crb.RoleRef, mapping.ClusterRole) {
+					continue
+				}
+				for _, subject := range toCFSubjects(crb.Subjects) {
+					assignments = append(assignments,
+						roleAssignment{namespace: rootNamespace, cfRole: mapping.CFRole, subject: subject},
+						roleAssignment{namespace: rootNamespace, cfRole: cfUserRole, subject: subject},
+					)
+				}
+			}
+			continue
+		}
+
+		for _, rb := range roleBindings.Items {
+			if !refersTo(rb.RoleRef, mapping.ClusterRole) || rb.Labels[RoleSyncLabel] != "" || rb.Labels[korifiv1alpha1.PropagatedFromLabel] != "" {
+				continue
+			}
+
+			labels := namespaceLabels[rb.Namespace]
+			orgNamespace, isOrgOrSpace := labels[korifiv1alpha1.CFOrgGUIDKey]
+			_, isSpace := labels[korifiv1alpha1.SpaceGUIDLabelKey]
+			if !isOrgOrSpace || isSpace != (mapping.Scope == v1alpha1.RoleMappingScopeSpace) {
+				continue
+			}
+
+			for _, subject := range toCFSubjects(rb.Subjects) {
+				assignments = append(assignments,
+					roleAssignment{namespace: rb.Namespace, cfRole: mapping.CFRole, subject: subject},
+					roleAssignment{namespace: rootNamespace, cfRole: cfUserRole, subject: subject},
+				)
+				if isSpace {
+					assignments = append(assignments, roleAssignment{namespace: orgNamespace, cfRole: "organization_user", subject: subject})
+				}
+			}
+		}
+	}
+
+	return assignments, nil
+}
+
+func refersTo(roleRef rbacv1.RoleRef, clusterRole string) bool {
+	return roleRef.Kind == "ClusterRole" && roleRef.Name == clusterRole
+}
+
+// cfSubjects converts Kubernetes user and group subjects to the names they
+// have in the tokens of the OIDC issuer. Service accounts are not mapped.
+func cfSubjects(subjects []rbacv1.Subject, usernamePrefix, groupsPrefix string) []rbacv1.Subject {
+	result := []rbacv1.Subject{}
+	for _, subject := range subjects {
+		switch subject.Kind {
+		case rbacv1.UserKind:
+			result = append(result, rbacv1.Subject{APIGroup: rbacv1.GroupName, Kind: rbacv1.UserKind, Name: withPrefix(usernamePrefix, subject.Name)})
+		case rbacv1.GroupKind:
+			result = append(result, rbacv1.Subject{APIGroup: rbacv1.GroupName, Kind: rbacv1.GroupKind, Name: withPrefix(groupsPrefix, subject.Name)})
+		}
+	}
+	return result
+}
+
+func withPrefix(prefix, name string) string {
+	if strings.HasPrefix(name, prefix) {
+		return name
+	}
+	return prefix + name
+}
+
+func toRoleBinding(assignment roleAssignment) *rbacv1.RoleBinding {
+	role := cfRoles[assignment.cfRole]
+	return &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: assignment.namespace,
+			Name:      assignment.roleBindingName(),
+			Labels: map[string]string{
+				RoleSyncLabel: "true",
+				CFRoleLabel:   assignment.cfRole,
+				roleGUIDLabel: uuid.NewSHA1(uuid.NameSpaceOID, []byte(assignment.namespace+"/"+assignment.key())).String(),
+			},
+			Annotations: map[string]string{
+				korifiv1alpha1.PropagateRoleBindingAnnotation: strconv.FormatBool(role.propagate),
+			},
+		},
+		Subjects: []rbacv1.Subject{assignment.subject},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     role.clusterRole,
+		},
+	}
+}
+
+func describeSubjects(subjects []rbacv1.Subject) string {
+	names := []string{}
+	for _, s := range subjects {
+		names = append(names, s.Kind+" "+s.Name)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,266 @@
+package cfroles_test
+
+import (
+	korifiv1alpha1 "code.cloudfoundry.org/korifi/controllers/api/v1alpha1"
+	"github.com/google/uuid"
+	v1alpha1 "github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/cfroles"
+	"github.com/kyma-project/cfapi/controllers/installable"
+	"github.com/kyma-project/cfapi/controllers/installable/fake"
+	. "github.com/kyma-project/cfapi/tests/helpers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	"github.com/onsi/gomega/types"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("CF Roles Sync", func() {
+	var (
+		cfAPI         *v1alpha1.CFAPI
+		rootNamespace string
+		orgNamespace  string
+		spaceNs       string
+		auditorCRB    *rbacv1.ClusterRoleBinding
+	)
+
+	syncedRoleBindings := func(g Gomega, namespace string) []rbacv1.RoleBinding {
+		roleBindings := &rbacv1.RoleBindingList{}
+		g.Expect(adminClient.List(ctx, roleBindings,
+			client.InNamespace(namespace),
+			client.MatchingLabels{cfroles.RoleSyncLabel: "true"},
+		)).To(Succeed())
+		return roleBindings.Items
+	}
+
+	roleBindingFor := func(clusterRole string, subject rbacv1.Subject) types.GomegaMatcher {
+		return MatchFields(IgnoreExtras, Fields{
+			"RoleRef":  MatchFields(IgnoreExtras, Fields{"Name": Equal(clusterRole)}),
+			"Subjects": ConsistOf(subject),
+		})
+	}
+
+	BeforeEach(func() {
+		rootNamespace = uuid.NewString()
+		EnsureCreate(adminClient, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: rootNamespace},
+		})
+
+		orgNamespace = uuid.NewString()
+		EnsureCreate(adminClient, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   orgNamespace,
+				Labels: map[string]string{"korifi.cloudfoundry.org/org-guid": orgNamespace},
+			},
+		})
+
+		spaceNs = uuid.NewString()
+		EnsureCreate(adminClient, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: spaceNs,
+				Labels: map[string]string{
+					"korifi.cloudfoundry.org/org-guid":   orgNamespace,
+					"korifi.cloudfoundry.org/space-guid": spaceNs,
+				},
+			},
+		})
+
+		auditorCRB = &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: uuid.NewString()},
+			Subjects: []rbacv1.Subject{{
+				APIGroup: rbacv1.GroupName,
+				Kind:     rbacv1.UserKind,
+				Name:     "auditor@sap.com",
+			}},
+			RoleRef: rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "view"},
+		}
+		EnsureCreate(adminClient, auditorCRB)
+
+		EnsureCreate(adminClient, &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Namespace: spaceNs, Name: uuid.NewString()},
+			Subjects: []rbacv1.Subject{{
+				APIGroup: rbacv1.GroupName,
+				Kind:     rbacv1.GroupKind,
+				Name:     "developers",
+			}},
+			RoleRef: rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "admin"},
+		})
+
+		cfAPI = &v1alpha1.CFAPI{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      uuid.NewString(),
+				Namespace: cfAPINamespace,
+			},
+		}
+		EnsureCreate(adminClient, cfAPI)
+
+		cfAPI.Status.InstallationConfig = v1alpha1.InstallationConfig{
+			RootNamespace:      rootNamespace,
+			OIDCUsernamePrefix: "sap.ids:",
+			OIDCGroupsPrefix:   "sap.ids.groups:",
+		}
+		Expect(adminClient.Status().Update(ctx, cfAPI)).To(Succeed())
+	})
+
+	It("assigns CF roles to subjects of mapped cluster role bindings", func() {
+		auditor := rbacv1.Subject{APIGroup: rbacv1.GroupName, Kind: rbacv1.UserKind, Name: "sap.ids:auditor@sap.com"}
+		Eventually(func(g Gomega) {
+			g.Expect(syncedRoleBindings(g, rootNamespace)).To(ContainElements(
+				roleBindingFor("korifi-controllers-global-auditor", auditor),
+				roleBindingFor("korifi-controllers-root-namespace-user", auditor),
+			))
+		}).Should(Succeed())
+	})
+
+	It("assigns CF roles to subjects of mapped space role bindings", func() {
+		developers := rbacv1.Subject{APIGroup: rbacv1.GroupName, Kind: rbacv1.GroupKind, Name: "sap.ids.groups:developers"}
+		Eventually(func(g Gomega) {
+			g.Expect(syncedRoleBindings(g, spaceNs)).To(ConsistOf(
+				roleBindingFor("korifi-controllers-space-developer", developers),
+			))
+			g.Expect(syncedRoleBindings(g, orgNamespace)).To(ConsistOf(
+				roleBindingFor("korifi-controllers-organization-user", developers),
+			))
+			g.Expect(syncedRoleBindings(g, rootNamespace)).To(ContainElement(
+				roleBindingFor("korifi-controllers-root-namespace-user", developers),
+			))
+		}).Should(Succeed())
+	})
+
+	When("the cluster role binding is deleted", func() {
+		BeforeEach(func() {
+			Eventually(func(g Gomega) {
+				g.Expect(syncedRoleBindings(g, rootNamespace)).To(ContainElement(
+					MatchFields(IgnoreExtras, Fields{
+						"RoleRef": MatchFields(IgnoreExtras, Fields{"Name": Equal("korifi-controllers-global-auditor")}),
+					}),
+				))
+			}).Should(Succeed())
+
+			EnsureDelete(adminClient, auditorCRB)
+		})
+
+		It("revokes the CF roles", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(syncedRoleBindings(g, rootNamespace)).NotTo(ContainElement(
+					MatchFields(IgnoreExtras, Fields{
+						"RoleRef": MatchFields(IgnoreExtras, Fields{"Name": Equal("korifi-controllers-global-auditor")}),
+					}),
+				))
+			}).Should(Succeed())
+		})
+	})
+
+	When("Korifi has propagated a synced role binding to a space", func() {
+		var propagated *rbacv1.RoleBinding
+
+		BeforeEach(func() {
+			var auditorBinding rbacv1.RoleBinding
+			Eventually(func(g Gomega) {
+				bindings := syncedRoleBindings(g, rootNamespace)
+				g.Expect(bindings).To(ContainElement(
+					MatchFields(IgnoreExtras, Fields{
+						"RoleRef": MatchFields(IgnoreExtras, Fields{"Name": Equal("korifi-controllers-global-auditor")}),
+					}), &auditorBinding,
+				))
+			}).Should(Succeed())
+
+			labels := map[string]string{korifiv1alpha1.PropagatedFromLabel: rootNamespace}
+			for key, value := range auditorBinding.Labels {
+				labels[key] = value
+			}
+			propagated = &rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   spaceNs,
+					Name:        auditorBinding.Name,
+					Labels:      labels,
+					Annotations: auditorBinding.Annotations,
+				},
+				Subjects: auditorBinding.Subjects,
+				RoleRef:  auditorBinding.RoleRef,
+			}
+			EnsureCreate(adminClient, propagated)
+		})
+
+		It("leaves the copy to Korifi", func() {
+			Consistently(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(propagated), &rbacv1.RoleBinding{})).To(Succeed())
+			}).Should(Succeed())
+		})
+	})
+
+	When("custom role mappings are specified", func() {
+		BeforeEach(func() {
+			EnsurePatch(adminClient, cfAPI, func(c *v1alpha1.CFAPI) {
+				c.Spec.RoleMappings = []v1alpha1.RoleMapping{{
+					ClusterRole: "admin",
+					Scope:       v1alpha1.RoleMappingScopeSpace,
+					CFRole:      "space_manager",
+				}}
+			})
+		})
+
+		It("uses them instead of the default ones", func() {
+			developers := rbacv1.Subject{APIGroup: rbacv1.GroupName, Kind: rbacv1.GroupKind, Name: "sap.ids.groups:developers"}
+			Eventually(func(g Gomega) {
+				g.Expect(syncedRoleBindings(g, spaceNs)).To(ConsistOf(
+					roleBindingFor("korifi-controllers-space-manager", developers),
+				))
+				g.Expect(syncedRoleBindings(g, rootNamespace)).NotTo(ContainElement(
+					MatchFields(IgnoreExtras, Fields{
+						"RoleRef": MatchFields(IgnoreExtras, Fields{"Name": Equal("korifi-controllers-global-auditor")}),
+					}),
+				))
+			}).Should(Succeed())
+		})
+	})
+
+	When("role sync is disabled", func() {
+		BeforeEach(func() {
+			Eventually(func(g Gomega) {
+				g.Expect(syncedRoleBindings(g, spaceNs)).NotTo(BeEmpty())
+			}).Should(Succeed())
+
+			EnsurePatch(adminClient, cfAPI, func(c *v1alpha1.CFAPI) {
+				c.Spec.DisableRoleSync = true
+			})
+		})
+
+		It("removes all synced role bindings", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(syncedRoleBindings(g, rootNamespace)).To(BeEmpty())
+				g.Expect(syncedRoleBindings(g, orgNamespace)).To(BeEmpty())
+				g.Expect(syncedRoleBindings(g, spaceNs)).To(BeEmpty())
+			}).Should(Succeed())
+		})
+	})
+
+	When("the CFAPI is uninstalled", func() {
+		BeforeEach(func() {
+			Eventually(func(g Gomega) {
+				g.Expect(syncedRoleBindings(g, spaceNs)).NotTo(BeEmpty())
+			}).Should(Succeed())
+
+			// the finalizer of the CFAPI controller keeps the CFAPI while it is uninstalled
+			EnsurePatch(adminClient, cfAPI, func(c *v1alpha1.CFAPI) {
+				c.Finalizers = []string{"cfapi.kyma-project.io/finalizer"}
+			})
+			Expect(adminClient.Delete(ctx, cfAPI)).To(Succeed())
+		})
+
+		It("deletes all synced role bindings", func() {
+			result, err := cfroles.NewRoleBindings(adminClient).Uninstall(ctx, cfAPI.Status.InstallationConfig, new(fake.EventRecorder))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.State).To(Equal(installable.ResultStateSuccess))
+
+			Eventually(func(g Gomega) {
+				g.Expect(syncedRoleBindings(g, rootNamespace)).To(BeEmpty())
+				g.Expect(syncedRoleBindings(g, orgNamespace)).To(BeEmpty())
+				g.Expect(syncedRoleBindings(g, spaceNs)).To(BeEmpty())
+			}).Should(Succeed())
+		})
+	})
+})
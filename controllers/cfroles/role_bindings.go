@@ -0,0 +1,53 @@
+package cfroles
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/installable"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RoleBindings deletes the role bindings of the synced CF roles when the
+// CFAPI is deleted. The reconciler stops syncing roles once the CFAPI is
+// being deleted, and the bindings live in namespaces other than the one of
+// the CFAPI, so they cannot be owned by it
+type RoleBindings struct {
+	k8sClient client.Client
+}
+
+func NewRoleBindings(k8sClient client.Client) *RoleBindings {
+	return &RoleBindings{
+		k8sClient: k8sClient,
+	}
+}
+
+func (b *RoleBindings) Name() string {
+	return "CF Role Bindings Installable"
+}
+
+func (b *RoleBindings) Install(ctx context.Context, config v1alpha1.InstallationConfig, eventRecorder installable.EventRecorder) (installable.Result, error) {
+	panic("not supported")
+}
+
+func (b *RoleBindings) Uninstall(ctx context.Context, config v1alpha1.InstallationConfig, eventRecorder installable.EventRecorder) (installable.Result, error) {
+	roleBindings := &rbacv1.RoleBindingList{}
+	if err := b.k8sClient.List(ctx, roleBindings, client.MatchingLabels{RoleSyncLabel: "true"}); err != nil {
+		eventRecorder.Event(installable.EventWarning, "InstallableFailed", fmt.Sprintf("Uninstalling %s failed", b.Name()))
+		return installable.Result{}, fmt.Errorf("failed to list synced role bindings: %w", err)
+	}
+
+	for _, roleBinding := range roleBindings.Items {
+		if err := client.IgnoreNotFound(b.k8sClient.Delete(ctx, &roleBinding)); err != nil {
+			eventRecorder.Event(installable.EventWarning, "InstallableFailed", fmt.Sprintf("Uninstalling %s failed", b.Name()))
+			return installable.Result{}, fmt.Errorf("failed to delete role binding %s/%s: %w", roleBinding.Namespace, roleBinding.Name, err)
+		}
+	}
+
+	return installable.Result{
+		State:   installable.ResultStateSuccess,
+		Message: "CF role bindings deleted successfully",
+	}, nil
+}
@@ -0,0 +1,42 @@
+package cfroles
+
+import (
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+)
+
+const cfUserRole = "cf_user"
+
+type cfRole struct {
+	clusterRole string
+	scope       string
+	propagate   bool
+}
+
+// cfRoles mirrors the role mappings configured in the Korifi API
+var cfRoles = map[string]cfRole{
+	"admin":                        {clusterRole: "korifi-controllers-admin", scope: v1alpha1.RoleMappingScopeCluster, propagate: true},
+	"admin_read_only":              {clusterRole: "korifi-controllers-admin-read-only", scope: v1alpha1.RoleMappingScopeCluster, propagate: true},
+	"global_auditor":               {clusterRole: "korifi-controllers-global-auditor", scope: v1alpha1.RoleMappingScopeCluster, propagate: true},
+	"organization_auditor":         {clusterRole: "korifi-controllers-organization-auditor", scope: v1alpha1.RoleMappingScopeOrganization},
+	"organization_billing_manager": {clusterRole: "korifi-controllers-organization-billing-manager", scope: v1alpha1.RoleMappingScopeOrganization},
+	"organization_manager":         {clusterRole: "korifi-controllers-organization-manager", scope: v1alpha1.RoleMappingScopeOrganization, propagate: true},
+	"organization_user":            {clusterRole: "korifi-controllers-organization-user", scope: v1alpha1.RoleMappingScopeOrganization},
+	"space_auditor":                {clusterRole: "korifi-controllers-space-auditor", scope: v1alpha1.RoleMappingScopeSpace},
+	"space_developer":              {clusterRole: "korifi-controllers-space-developer", scope: v1alpha1.RoleMappingScopeSpace},
+	"space_manager":                {clusterRole: "korifi-controllers-space-manager", scope: v1alpha1.RoleMappingScopeSpace},
+	"space_supporter":              {clusterRole: "korifi-controllers-space-supporter", scope: v1alpha1.RoleMappingScopeSpace},
+	cfUserRole:                     {clusterRole: "korifi-controllers-root-namespace-user", scope: v1alpha1.RoleMappingScopeCluster},
+}
+
+var DefaultRoleMappings = []v1alpha1.RoleMapping{
+	{
+		ClusterRole: "view",
+		Scope:       v1alpha1.RoleMappingScopeCluster,
+		CFRole:      "global_auditor",
+	},
+	{
+		ClusterRole: "admin",
+		Scope:       v1alpha1.RoleMappingScopeSpace,
+		CFRole:      "space_developer",
+	},
+}
@@ -13,12 +13,15 @@ import (
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/postrender"
 	"helm.sh/helm/v3/pkg/release"
 )
 
 type HelmResult struct {
 	ReleaseStatus release.Status
 	Message       string
+	// Manifest holds the post-rendered resources of the release
+	Manifest string
 }
 
 type Client struct{}
@@ -27,7 +30,7 @@ func NewClient() *Client {
 	return &Client{}
 }
 
-func (c *Client) Apply(ctx context.Context, chartPath string, releaseNamespace string, releaseName string, values map[string]any) (HelmResult, error) {
+func (c *Client) Apply(ctx context.Context, chartPath string, releaseNamespace string, releaseName string, values map[string]any, postRenderer postrender.PostRenderer) (HelmResult, error) {
 	log := logr.FromContextOrDiscard(ctx).WithName("helm").WithValues("chart", releaseName)
 
 	chart, err := loader.Load(chartPath)
@@ -42,7 +45,7 @@ func (c *Client) Apply(ctx context.Context, chartPath string, releaseNamespace s
 	}
 
 	if latestRelease == nil {
-		return c.install(ctx, chart, releaseNamespace, releaseName, values)
+		return c.install(ctx, chart, releaseNamespace, releaseName, values, postRenderer)
 	}
 
 	if latestRelease.Info.Status.IsPending() {
@@ -50,6 +53,7 @@ func (c *Client) Apply(ctx context.Context, chartPath string, releaseNamespace s
 		return HelmResult{
 			ReleaseStatus: latestRelease.Info.Status,
 			Message:       "operation pending",
+			Manifest:      latestRelease.Manifest,
 		}, nil
 	}
 
@@ -63,10 +67,11 @@ func (c *Client) Apply(ctx context.Context, chartPath string, releaseNamespace s
 		log.Info("helm chart does not need update")
 		return HelmResult{
 			ReleaseStatus: latestRelease.Info.Status,
+			Manifest:      latestRelease.Manifest,
 		}, nil
 	}
 
-	return c.upgrade(ctx, chart, releaseNamespace, releaseName, values)
+	return c.upgrade(ctx, chart, releaseNamespace, releaseName, values, postRenderer)
 }
 
 func (c *Client) Uninstall(ctx context.Context, releaseNamespace string, releaseName string) (HelmResult, error) {
@@ -170,7 +175,7 @@ func getLatestReleases(releaseNamespace string, releaseName string) (*release.Re
 	return versions[0], nil
 }
 
-func (c *Client) install(ctx context.Context, installedChart *chart.Chart, releaseNamespace string, releaseName string, values map[string]any) (HelmResult, error) {
+func (c *Client) install(ctx context.Context, installedChart *chart.Chart, releaseNamespace string, releaseName string, values map[string]any, postRenderer postrender.PostRenderer) (HelmResult, error) {
 	log := logr.FromContextOrDiscard(ctx).WithName("helm-install").WithValues("chart", releaseName)
 	log.Info("starting install")
 
@@ -183,6 +188,7 @@ func (c *Client) install(ctx context.Context, installedChart *chart.Chart, relea
 	installAction.Namespace = releaseNamespace
 	installAction.CreateNamespace = true
 	installAction.ReleaseName = releaseName
+	installAction.PostRenderer = postRenderer
 
 	rel, err := installAction.Run(installedChart, values)
 	if err != nil {
@@ -194,10 +200,11 @@ func (c *Client) install(ctx context.Context, installedChart *chart.Chart, relea
 
 	return HelmResult{
 		ReleaseStatus: rel.Info.Status,
+		Manifest:      rel.Manifest,
 	}, nil
 }
 
-func (c *Client) upgrade(ctx context.Context, upgradedChart *chart.Chart, releaseNamespace string, releaseName string, values map[string]any) (HelmResult, error) {
+func (c *Client) upgrade(ctx context.Context, upgradedChart *chart.Chart, releaseNamespace string, releaseName string, values map[string]any, postRenderer postrender.PostRenderer) (HelmResult, error) {
 	log := logr.FromContextOrDiscard(ctx).WithName("helm-upgrade").WithValues("chart", releaseName)
 	log.Info("starting upgrade")
 
@@ -209,6 +216,7 @@ func (c *Client) upgrade(ctx context.Context, upgradedChart *chart.Chart, releas
 	upgradeAction := action.NewUpgrade(actionConfig)
 	upgradeAction.Namespace = releaseNamespace
 	upgradeAction.Install = true
+	upgradeAction.PostRenderer = postRenderer
 
 	rel, err := upgradeAction.Run(releaseName, upgradedChart, values)
 	if err != nil {
@@ -220,6 +228,7 @@ func (c *Client) upgrade(ctx context.Context, upgradedChart *chart.Chart, releas
 
 	return HelmResult{
 		ReleaseStatus: rel.Info.Status,
+		Manifest:      rel.Manifest,
 	}, nil
 }
 
@@ -0,0 +1,132 @@
+package helm
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"helm.sh/helm/v3/pkg/postrender"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	yamlUtil "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+type configMapConfig struct {
+	configMapName string
+	dataKey       string
+	values        map[string]any
+}
+
+// SetConfigMapConfig returns a post renderer that sets the given top level
+// keys of the YAML config stored under dataKey in the named ConfigMap. It
+// covers settings a chart reads from a single value for several components.
+// Returns nil when no values are given
+func SetConfigMapConfig(configMapName, dataKey string, values map[string]any) postrender.PostRenderer {
+	if len(values) == 0 {
+		return nil
+	}
+
+	return &configMapConfig{
+		configMapName: configMapName,
+		dataKey:       dataKey,
+		values:        values,
+	}
+}
+
+func (c *configMapConfig) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	modifiedManifests := &bytes.Buffer{}
+
+	reader := yamlUtil.NewYAMLReader(bufio.NewReader(renderedManifests))
+	for {
+		doc, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return modifiedManifests, nil
+			}
+			return nil, fmt.Errorf("invalid YAML doc: %w", err)
+		}
+
+		doc, err = c.setConfig(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		modifiedManifests.WriteString("---\n")
+		modifiedManifests.Write(doc)
+	}
+}
+
+func (c *configMapConfig) setConfig(doc []byte) ([]byte, error) {
+	object := metav1.PartialObjectMetadata{}
+	if err := yaml.Unmarshal(doc, &object); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rendered manifest: %w", err)
+	}
+
+	if object.APIVersion != "v1" || object.Kind != "ConfigMap" || object.Name != c.configMapName {
+		return doc, nil
+	}
+
+	configMap := corev1.ConfigMap{}
+	if err := yaml.Unmarshal(doc, &configMap); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config map %s: %w", c.configMapName, err)
+	}
+
+	configData, ok := configMap.Data[c.dataKey]
+	if !ok {
+		return nil, fmt.Errorf("config map %s does not contain key %s", c.configMapName, c.dataKey)
+	}
+
+	config := map[string]any{}
+	if err := yaml.Unmarshal([]byte(configData), &config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s of config map %s: %w", c.dataKey, c.configMapName, err)
+	}
+
+	for key, value := range c.values {
+		config[key] = value
+	}
+
+	modifiedConfig, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s of config map %s: %w", c.dataKey, c.configMapName, err)
+	}
+	configMap.Data[c.dataKey] = string(modifiedConfig)
+
+	return yaml.Marshal(configMap)
+}
+
+type chain []postrender.PostRenderer
+
+// Chain returns a post renderer running the given post renderers in order.
+// Nil post renderers are skipped, nil is returned when none is left
+func Chain(postRenderers ...postrender.PostRenderer) postrender.PostRenderer {
+	nonNil := chain{}
+	for _, postRenderer := range postRenderers {
+		if postRenderer != nil {
+			nonNil = append(nonNil, postRenderer)
+		}
+	}
+
+	switch len(nonNil) {
+	case 0:
+		return nil
+	case 1:
+		return nonNil[0]
+	default:
+		return nonNil
+	}
+}
+
+func (c chain) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	var err error
+	for _, postRenderer := range c {
+		renderedManifests, err = postRenderer.Run(renderedManifests)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return renderedManifests, nil
+}
@@ -0,0 +1,59 @@
+package helm
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"helm.sh/helm/v3/pkg/postrender"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	yamlUtil "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+type kindsFilter struct {
+	excludedKinds []schema.GroupKind
+}
+
+// ExcludeKinds returns a post renderer that removes all resources of the
+// given kinds from the rendered manifests. Returns nil when no kinds are given
+func ExcludeKinds(kinds ...schema.GroupKind) postrender.PostRenderer {
+	if len(kinds) == 0 {
+		return nil
+	}
+
+	return &kindsFilter{excludedKinds: kinds}
+}
+
+func (f *kindsFilter) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	modifiedManifests := &bytes.Buffer{}
+
+	reader := yamlUtil.NewYAMLReader(bufio.NewReader(renderedManifests))
+	for {
+		doc, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return modifiedManifests, nil
+			}
+			return nil, fmt.Errorf("invalid YAML doc: %w", err)
+		}
+
+		typeMeta := struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+		}{}
+		if err := yaml.Unmarshal(doc, &typeMeta); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal rendered manifest: %w", err)
+		}
+
+		if slices.Contains(f.excludedKinds, schema.FromAPIVersionAndKind(typeMeta.APIVersion, typeMeta.Kind).GroupKind()) {
+			continue
+		}
+
+		modifiedManifests.WriteString("---\n")
+		modifiedManifests.Write(doc)
+	}
+}
@@ -0,0 +1,123 @@
+package helm
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/kyma-project/cfapi/controllers/highavailability"
+	"github.com/kyma-project/cfapi/controllers/imagemirror"
+	"github.com/kyma-project/cfapi/controllers/proxy"
+	"github.com/kyma-project/cfapi/controllers/trustedca"
+	"helm.sh/helm/v3/pkg/postrender"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	yamlUtil "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// objectsTransformer modifies the rendered resources in place and returns
+// additional resources to render along with them
+type objectsTransformer struct {
+	transform func(objs []*unstructured.Unstructured) []*unstructured.Unstructured
+}
+
+// eachObject returns a transform modifying every rendered resource in place
+func eachObject(transform func(obj *unstructured.Unstructured)) func([]*unstructured.Unstructured) []*unstructured.Unstructured {
+	return func(objs []*unstructured.Unstructured) []*unstructured.Unstructured {
+		for _, obj := range objs {
+			transform(obj)
+		}
+		return nil
+	}
+}
+
+// RewriteImages returns a post renderer that points the images of the
+// rendered resources to the mirror. Returns nil when no mirror is given
+func RewriteImages(mirror *imagemirror.Mirror) postrender.PostRenderer {
+	if mirror == nil {
+		return nil
+	}
+
+	return &objectsTransformer{transform: eachObject(func(obj *unstructured.Unstructured) {
+		mirror.Rewrite(obj)
+	})}
+}
+
+// InjectTrustedCA returns a post renderer that adds the trusted CA bundle to
+// the rendered workloads of a release in the given namespace. Returns nil
+// when no injector is given
+func InjectTrustedCA(injector *trustedca.Injector, namespace string) postrender.PostRenderer {
+	if injector == nil {
+		return nil
+	}
+
+	return &objectsTransformer{transform: eachObject(func(obj *unstructured.Unstructured) {
+		injector.Inject(obj, namespace)
+	})}
+}
+
+// InjectProxy returns a post renderer that sets the proxy variables in the
+// rendered workloads of a release in the given namespace. Returns nil when no
+// injector is given
+func InjectProxy(injector *proxy.Injector, namespace string) postrender.PostRenderer {
+	if injector == nil {
+		return nil
+	}
+
+	return &objectsTransformer{transform: eachObject(func(obj *unstructured.Unstructured) {
+		injector.Inject(obj, namespace)
+	})}
+}
+
+// AddHighAvailability returns a post renderer that spreads the replicas of
+// the rendered deployments of a release in the given namespace and adds
+// their PodDisruptionBudgets. Returns nil when no patcher is given
+func AddHighAvailability(patcher *highavailability.Patcher, namespace string) postrender.PostRenderer {
+	if patcher == nil {
+		return nil
+	}
+
+	return &objectsTransformer{transform: func(objs []*unstructured.Unstructured) []*unstructured.Unstructured {
+		return patcher.Patch(objs, namespace)
+	}}
+}
+
+func (t *objectsTransformer) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	objs := []*unstructured.Unstructured{}
+
+	reader := yamlUtil.NewYAMLReader(bufio.NewReader(renderedManifests))
+	for {
+		doc, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("invalid YAML doc: %w", err)
+		}
+
+		obj := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(doc, &obj.Object); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal rendered manifest: %w", err)
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		objs = append(objs, obj)
+	}
+
+	objs = append(objs, t.transform(objs)...)
+
+	modifiedManifests := &bytes.Buffer{}
+	for _, obj := range objs {
+		doc, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal rendered manifest %s/%s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+
+		modifiedManifests.WriteString("---\n")
+		modifiedManifests.Write(doc)
+	}
+	return modifiedManifests, nil
+}
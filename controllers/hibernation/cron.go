@@ -0,0 +1,211 @@
+package hibernation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearchSteps bounds the search for the next or previous activation of a
+// schedule. Every step skips at least a minute, most skip a whole hour, day
+// or month, so that schedules matching once a year are found well within
+// the limit, while the search for schedules that never match, such as
+// `0 0 31 2 *`, ends
+const maxSearchSteps = 100000
+
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = cronField{name: "minute", min: 0, max: 59}
+	hourField   = cronField{name: "hour", min: 0, max: 23}
+	domField    = cronField{name: "day of month", min: 1, max: 31}
+	monthField  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is Sunday as well as 0
+	dowField = cronField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// Schedule is a parsed cron expression with the five standard fields
+// minute, hour, day of month, month and day of week
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar record unrestricted days. As in cron, a day
+	// matches either of the day fields if both are restricted
+	domStar, dowStar bool
+	location         *time.Location
+}
+
+// ParseCron parses a cron expression evaluated in the given location. Fields
+// are `*`, values, ranges (`1-5`) and steps (`*/15`, `0-30/10`), separated
+// by commas. Months and days of week may be given by their English
+// abbreviations, e.g. `jan` or `mon-fri`
+func ParseCron(expr string, location *time.Location) (*Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields, got %d", expr, len(fields))
+	}
+
+	schedule := &Schedule{location: location}
+	var err error
+	for i, target := range []struct {
+		field cronField
+		bits  *uint64
+	}{
+		{minuteField, &schedule.minute},
+		{hourField, &schedule.hour},
+		{domField, &schedule.dom},
+		{monthField, &schedule.month},
+		{dowField, &schedule.dow},
+	} {
+		*target.bits, err = parseField(fields[i], target.field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+		}
+	}
+
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow |= 1
+	}
+	schedule.domStar = strings.HasPrefix(fields[2], "*")
+	schedule.dowStar = strings.HasPrefix(fields[4], "*")
+
+	return schedule, nil
+}
+
+func parseField(value string, field cronField) (uint64, error) {
+	var bits uint64
+	for item := range strings.SplitSeq(value, ",") {
+		itemBits, err := parseItem(item, field)
+		if err != nil {
+			return 0, err
+		}
+		bits |= itemBits
+	}
+	return bits, nil
+}
+
+// parseItem parses a single `*`, value, range or step of a field
+func parseItem(item string, field cronField) (uint64, error) {
+	rangeExpr, stepExpr, hasStep := strings.Cut(item, "/")
+
+	step := 1
+	if hasStep {
+		var err error
+		step, err = strconv.Atoi(stepExpr)
+		if err != nil || step <= 0 {
+			return 0, fmt.Errorf("invalid step %q of %s", stepExpr, field.name)
+		}
+	}
+
+	first, last := field.min, field.max
+	if rangeExpr != "*" {
+		firstExpr, lastExpr, isRange := strings.Cut(rangeExpr, "-")
+		var err error
+		first, err = parseValue(firstExpr, field)
+		if err != nil {
+			return 0, err
+		}
+		last = first
+		if isRange {
+			if last, err = parseValue(lastExpr, field); err != nil {
+				return 0, err
+			}
+		} else if hasStep {
+			last = field.max
+		}
+		if first > last {
+			return 0, fmt.Errorf("invalid range %q of %s", rangeExpr, field.name)
+		}
+	}
+
+	var bits uint64
+	for value := first; value <= last; value += step {
+		bits |= 1 << value
+	}
+	return bits, nil
+}
+
+func parseValue(expr string, field cronField) (int, error) {
+	if value, ok := field.names[strings.ToLower(expr)]; ok {
+		return value, nil
+	}
+
+	value, err := strconv.Atoi(expr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", field.name, expr)
+	}
+	if value < field.min || value > field.max {
+		return 0, fmt.Errorf("%s %d is out of range %d-%d", field.name, value, field.min, field.max)
+	}
+	return value, nil
+}
+
+// Next returns the first activation of the schedule after the given time, or
+// the zero time if the schedule never activates
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.In(s.location).Truncate(time.Minute).Add(time.Minute)
+
+	for range maxSearchSteps {
+		year, month, day := t.Date()
+		switch {
+		case !has(s.month, int(month)):
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, s.location)
+		case !s.matchesDay(t):
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, s.location)
+		case !has(s.hour, t.Hour()):
+			t = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, s.location)
+		case !has(s.minute, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// Prev returns the last activation of the schedule at or before the given
+// time, or the zero time if the schedule never activates
+func (s *Schedule) Prev(t time.Time) time.Time {
+	t = t.In(s.location).Truncate(time.Minute)
+
+	for range maxSearchSteps {
+		year, month, day := t.Date()
+		switch {
+		case !has(s.month, int(month)):
+			t = time.Date(year, month, 1, 0, 0, 0, 0, s.location).Add(-time.Minute)
+		case !s.matchesDay(t):
+			t = time.Date(year, month, day, 0, 0, 0, 0, s.location).Add(-time.Minute)
+		case !has(s.hour, t.Hour()):
+			t = time.Date(year, month, day, t.Hour(), 0, 0, 0, s.location).Add(-time.Minute)
+		case !has(s.minute, t.Minute()):
+			t = t.Add(-time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+func (s *Schedule) matchesDay(t time.Time) bool {
+	domMatches := has(s.dom, t.Day())
+	dowMatches := has(s.dow, int(t.Weekday()))
+	if s.domStar || s.dowStar {
+		return domMatches && dowMatches
+	}
+	return domMatches || dowMatches
+}
+
+func has(bits uint64, value int) bool {
+	return bits&(1<<value) != 0
+}
@@ -0,0 +1,72 @@
+package hibernation_test
+
+import (
+	"time"
+
+	"github.com/kyma-project/cfapi/controllers/hibernation"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cron", func() {
+	// a Wednesday
+	now := time.Date(2026, time.March, 18, 12, 30, 15, 0, time.UTC)
+
+	parse := func(expr string) *hibernation.Schedule {
+		schedule, err := hibernation.ParseCron(expr, time.UTC)
+		Expect(err).NotTo(HaveOccurred())
+		return schedule
+	}
+
+	DescribeTable("Next",
+		func(expr string, expected time.Time) {
+			Expect(parse(expr).Next(now)).To(Equal(expected))
+		},
+		Entry("every minute", "* * * * *", time.Date(2026, time.March, 18, 12, 31, 0, 0, time.UTC)),
+		Entry("later the same day", "0 20 * * *", time.Date(2026, time.March, 18, 20, 0, 0, 0, time.UTC)),
+		Entry("the next day", "0 7 * * *", time.Date(2026, time.March, 19, 7, 0, 0, 0, time.UTC)),
+		Entry("steps", "*/20 * * * *", time.Date(2026, time.March, 18, 12, 40, 0, 0, time.UTC)),
+		Entry("ranges with steps", "10-30/10 9-11 * * *", time.Date(2026, time.March, 19, 9, 10, 0, 0, time.UTC)),
+		Entry("lists", "0 6,18 * * *", time.Date(2026, time.March, 18, 18, 0, 0, 0, time.UTC)),
+		Entry("named days of week", "0 7 * * sat,sun", time.Date(2026, time.March, 21, 7, 0, 0, 0, time.UTC)),
+		Entry("Sunday as 7", "0 7 * * 7", time.Date(2026, time.March, 22, 7, 0, 0, 0, time.UTC)),
+		Entry("named months", "0 0 1 jan *", time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)),
+		Entry("either day field when both are restricted", "0 0 1 * mon", time.Date(2026, time.March, 23, 0, 0, 0, 0, time.UTC)),
+		Entry("leap days", "0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)),
+		Entry("days that never come", "0 0 31 2 *", time.Time{}),
+	)
+
+	DescribeTable("Prev",
+		func(expr string, expected time.Time) {
+			Expect(parse(expr).Prev(now)).To(Equal(expected))
+		},
+		Entry("every minute", "* * * * *", time.Date(2026, time.March, 18, 12, 30, 0, 0, time.UTC)),
+		Entry("earlier the same day", "0 7 * * *", time.Date(2026, time.March, 18, 7, 0, 0, 0, time.UTC)),
+		Entry("the day before", "0 20 * * *", time.Date(2026, time.March, 17, 20, 0, 0, 0, time.UTC)),
+		Entry("named days of week", "0 20 * * mon-fri", time.Date(2026, time.March, 17, 20, 0, 0, 0, time.UTC)),
+		Entry("the week before", "0 20 * * sun", time.Date(2026, time.March, 15, 20, 0, 0, 0, time.UTC)),
+		Entry("the year before", "0 0 1 dec *", time.Date(2025, time.December, 1, 0, 0, 0, 0, time.UTC)),
+		Entry("days that never come", "0 0 30 feb *", time.Time{}),
+	)
+
+	It("evaluates the schedule in its location", func() {
+		berlin, err := time.LoadLocation("Europe/Berlin")
+		Expect(err).NotTo(HaveOccurred())
+		schedule, err := hibernation.ParseCron("0 20 * * *", berlin)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(schedule.Next(now)).To(BeTemporally("==", time.Date(2026, time.March, 18, 19, 0, 0, 0, time.UTC)))
+	})
+
+	DescribeTable("invalid expressions",
+		func(expr, message string) {
+			_, err := hibernation.ParseCron(expr, time.UTC)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("too few fields", "0 20 * *", "expected 5 fields, got 4"),
+		Entry("values out of range", "60 20 * * *", "minute 60 is out of range 0-59"),
+		Entry("unknown names", "0 20 * * monday", `invalid day of week "monday"`),
+		Entry("reversed ranges", "0 20-7 * * *", `invalid range "20-7" of hour`),
+		Entry("zero steps", "*/0 * * * *", `invalid step "0" of minute`),
+	)
+})
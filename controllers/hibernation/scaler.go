@@ -0,0 +1,190 @@
+package hibernation
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/kyma-project/cfapi/controllers/installable"
+	"github.com/kyma-project/cfapi/tools"
+	"github.com/kyma-project/cfapi/tools/k8s"
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ReplicasAnnotation holds the replicas a workload had before it was
+	// scaled to zero, restored on resume
+	ReplicasAnnotation = "cfapi.kyma-project.io/hibernated-replicas"
+
+	// AppWorkloadLabel marks the stateful sets Korifi runs CF app processes in
+	AppWorkloadLabel = "korifi.cloudfoundry.org/appworkload-guid"
+
+	// OperatorComponentLabel marks the deployment of the operator, which has
+	// to keep running to resume the platform
+	OperatorComponentLabel = "app.kubernetes.io/component"
+	OperatorComponent      = "cfapi-operator.kyma-project.io"
+
+	helmReleaseAnnotation = "meta.helm.sh/release-name"
+)
+
+// Scaler scales the deployments of the platform and the workloads of CF apps
+// to zero and back
+type Scaler struct {
+	k8sClient  client.Client
+	namespaces []string
+}
+
+// NewScaler returns a scaler of the deployments the installables have
+// created in the given namespaces
+func NewScaler(k8sClient client.Client, namespaces ...string) *Scaler {
+	return &Scaler{
+		k8sClient:  k8sClient,
+		namespaces: namespaces,
+	}
+}
+
+// ScaleDown scales the deployments and, if apps are included, the app
+// workloads to zero and returns whether all their pods are gone. App
+// workloads are only scaled once the pods of the Korifi controllers, which
+// would scale them up again, are gone
+func (s *Scaler) ScaleDown(ctx context.Context, includeApps bool) (bool, error) {
+	deployments, err := s.deployments(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	stopped := true
+	for i := range deployments {
+		deployment := &deployments[i]
+		if err := s.scaleDown(ctx, deployment, &deployment.Spec.Replicas); err != nil {
+			return false, fmt.Errorf("failed to scale down deployment %s/%s: %w", deployment.Namespace, deployment.Name, err)
+		}
+		stopped = stopped && deployment.Status.Replicas == 0
+	}
+	if !stopped || !includeApps {
+		return stopped, nil
+	}
+
+	statefulSets, err := s.appStatefulSets(ctx)
+	if err != nil {
+		return false, err
+	}
+	for i := range statefulSets {
+		statefulSet := &statefulSets[i]
+		if err := s.scaleDown(ctx, statefulSet, &statefulSet.Spec.Replicas); err != nil {
+			return false, fmt.Errorf("failed to scale down app workload %s/%s: %w", statefulSet.Namespace, statefulSet.Name, err)
+		}
+		stopped = stopped && statefulSet.Status.Replicas == 0
+	}
+
+	return stopped, nil
+}
+
+// ScaleUp restores the replicas of the deployments and app workloads scaled
+// down before
+func (s *Scaler) ScaleUp(ctx context.Context) error {
+	deployments, err := s.deployments(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range deployments {
+		deployment := &deployments[i]
+		if err := s.scaleUp(ctx, deployment, &deployment.Spec.Replicas); err != nil {
+			return fmt.Errorf("failed to scale up deployment %s/%s: %w", deployment.Namespace, deployment.Name, err)
+		}
+	}
+
+	statefulSets, err := s.appStatefulSets(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range statefulSets {
+		statefulSet := &statefulSets[i]
+		if err := s.scaleUp(ctx, statefulSet, &statefulSet.Spec.Replicas); err != nil {
+			return fmt.Errorf("failed to scale up app workload %s/%s: %w", statefulSet.Namespace, statefulSet.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// scaleDown records the replicas of a workload and sets them to zero. The
+// recorded replicas are kept when the workload has been scaled up again by
+// someone else in the meantime
+func (s *Scaler) scaleDown(ctx context.Context, obj client.Object, replicas **int32) error {
+	_, recorded := obj.GetAnnotations()[ReplicasAnnotation]
+	if recorded && *replicas != nil && **replicas == 0 {
+		return nil
+	}
+
+	current := int32(1)
+	if *replicas != nil {
+		current = **replicas
+	}
+
+	return k8s.PatchResource(ctx, s.k8sClient, obj, func() {
+		if !recorded {
+			annotations := obj.GetAnnotations()
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			annotations[ReplicasAnnotation] = strconv.Itoa(int(current))
+			obj.SetAnnotations(annotations)
+		}
+		*replicas = tools.PtrTo(int32(0))
+	})
+}
+
+// scaleUp sets the replicas of a workload to the recorded ones
+func (s *Scaler) scaleUp(ctx context.Context, obj client.Object, replicas **int32) error {
+	recorded, ok := obj.GetAnnotations()[ReplicasAnnotation]
+	if !ok {
+		return nil
+	}
+
+	return k8s.PatchResource(ctx, s.k8sClient, obj, func() {
+		if previous, err := strconv.ParseInt(recorded, 10, 32); err == nil {
+			*replicas = tools.PtrTo(int32(previous))
+		}
+		annotations := obj.GetAnnotations()
+		delete(annotations, ReplicasAnnotation)
+		obj.SetAnnotations(annotations)
+	})
+}
+
+func (s *Scaler) deployments(ctx context.Context) ([]appsv1.Deployment, error) {
+	deployments := []appsv1.Deployment{}
+	for _, namespace := range s.namespaces {
+		deploymentList := &appsv1.DeploymentList{}
+		if err := s.k8sClient.List(ctx, deploymentList, client.InNamespace(namespace)); err != nil {
+			return nil, fmt.Errorf("failed to list deployments in namespace %s: %w", namespace, err)
+		}
+		for _, deployment := range deploymentList.Items {
+			if isInstalled(deployment) {
+				deployments = append(deployments, deployment)
+			}
+		}
+	}
+	return deployments, nil
+}
+
+// isInstalled matches the deployments of the helm releases and the yaml
+// installables of the operator. The deployment of the operator itself is
+// never scaled, as nothing would be left to resume the platform
+func isInstalled(deployment appsv1.Deployment) bool {
+	if deployment.Labels[OperatorComponentLabel] == OperatorComponent {
+		return false
+	}
+
+	return deployment.Labels[installable.InstalledByLabel] == installable.InstalledByValue ||
+		deployment.Annotations[helmReleaseAnnotation] != ""
+}
+
+func (s *Scaler) appStatefulSets(ctx context.Context) ([]appsv1.StatefulSet, error) {
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := s.k8sClient.List(ctx, statefulSets, client.HasLabels{AppWorkloadLabel}); err != nil {
+		return nil, fmt.Errorf("failed to list app workloads: %w", err)
+	}
+	return statefulSets.Items, nil
+}
@@ -0,0 +1,179 @@
+package hibernation_test
+
+import (
+	"github.com/google/uuid"
+	"github.com/kyma-project/cfapi/controllers/hibernation"
+	"github.com/kyma-project/cfapi/controllers/installable"
+	"github.com/kyma-project/cfapi/tests/helpers"
+	"github.com/kyma-project/cfapi/tools"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Scaler", func() {
+	var (
+		namespace string
+		scaler    *hibernation.Scaler
+
+		operator  *appsv1.Deployment
+		helmChart *appsv1.Deployment
+		yaml      *appsv1.Deployment
+		foreign   *appsv1.Deployment
+		app       *appsv1.StatefulSet
+	)
+
+	newDeployment := func(labels, annotations map[string]string) *appsv1.Deployment {
+		podLabels := map[string]string{"app": uuid.NewString()}
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   namespace,
+				Name:        uuid.NewString(),
+				Labels:      labels,
+				Annotations: annotations,
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: tools.PtrTo(int32(2)),
+				Selector: &metav1.LabelSelector{MatchLabels: podLabels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: podLabels},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "main", Image: "main"}},
+					},
+				},
+			},
+		}
+		helpers.EnsureCreate(adminClient, deployment)
+		return deployment
+	}
+
+	replicasOf := func(obj client.Object) func(Gomega) *int32 {
+		return func(g Gomega) *int32 {
+			g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(obj), obj)).To(Succeed())
+			switch workload := obj.(type) {
+			case *appsv1.Deployment:
+				return workload.Spec.Replicas
+			case *appsv1.StatefulSet:
+				return workload.Spec.Replicas
+			}
+			return nil
+		}
+	}
+
+	BeforeEach(func() {
+		startTestEnv()
+
+		namespace = uuid.NewString()
+		helpers.EnsureCreate(adminClient, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: namespace},
+		})
+		scaler = hibernation.NewScaler(adminClient, namespace)
+
+		operator = newDeployment(map[string]string{
+			hibernation.OperatorComponentLabel: hibernation.OperatorComponent,
+			installable.InstalledByLabel:       installable.InstalledByValue,
+		}, nil)
+		helmChart = newDeployment(nil, map[string]string{"meta.helm.sh/release-name": "korifi"})
+		yaml = newDeployment(map[string]string{installable.InstalledByLabel: installable.InstalledByValue}, nil)
+		foreign = newDeployment(nil, nil)
+
+		podLabels := map[string]string{"app": uuid.NewString()}
+		app = &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      uuid.NewString(),
+				Labels:    map[string]string{hibernation.AppWorkloadLabel: uuid.NewString()},
+			},
+			Spec: appsv1.StatefulSetSpec{
+				Replicas: tools.PtrTo(int32(3)),
+				Selector: &metav1.LabelSelector{MatchLabels: podLabels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: podLabels},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "app", Image: "app"}},
+					},
+				},
+			},
+		}
+		helpers.EnsureCreate(adminClient, app)
+	})
+
+	Describe("ScaleDown", func() {
+		var (
+			includeApps bool
+			stopped     bool
+			err         error
+		)
+
+		BeforeEach(func() {
+			includeApps = false
+		})
+
+		JustBeforeEach(func() {
+			stopped, err = scaler.ScaleDown(ctx, includeApps)
+		})
+
+		It("scales the installed deployments to zero", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stopped).To(BeTrue())
+
+			for _, deployment := range []*appsv1.Deployment{helmChart, yaml} {
+				Eventually(replicasOf(deployment)).Should(PointTo(BeEquivalentTo(0)))
+				Expect(deployment.Annotations).To(HaveKeyWithValue(hibernation.ReplicasAnnotation, "2"))
+			}
+		})
+
+		It("keeps the operator and deployments of others running", func() {
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, deployment := range []*appsv1.Deployment{operator, foreign} {
+				Consistently(replicasOf(deployment)).Should(PointTo(BeEquivalentTo(2)))
+				Expect(deployment.Annotations).NotTo(HaveKey(hibernation.ReplicasAnnotation))
+			}
+		})
+
+		It("keeps the apps running", func() {
+			Consistently(replicasOf(app)).Should(PointTo(BeEquivalentTo(3)))
+		})
+
+		When("apps are included", func() {
+			BeforeEach(func() {
+				includeApps = true
+			})
+
+			It("scales the apps to zero", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(stopped).To(BeTrue())
+
+				Eventually(replicasOf(app)).Should(PointTo(BeEquivalentTo(0)))
+				Expect(app.Annotations).To(HaveKeyWithValue(hibernation.ReplicasAnnotation, "3"))
+			})
+		})
+	})
+
+	Describe("ScaleUp", func() {
+		BeforeEach(func() {
+			_, err := scaler.ScaleDown(ctx, true)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(replicasOf(app)).Should(PointTo(BeEquivalentTo(0)))
+		})
+
+		JustBeforeEach(func() {
+			Expect(scaler.ScaleUp(ctx)).To(Succeed())
+		})
+
+		It("restores the recorded replicas", func() {
+			for _, deployment := range []*appsv1.Deployment{helmChart, yaml} {
+				Eventually(replicasOf(deployment)).Should(PointTo(BeEquivalentTo(2)))
+				Expect(deployment.Annotations).NotTo(HaveKey(hibernation.ReplicasAnnotation))
+			}
+
+			Eventually(replicasOf(app)).Should(PointTo(BeEquivalentTo(3)))
+			Expect(app.Annotations).NotTo(HaveKey(hibernation.ReplicasAnnotation))
+		})
+	})
+})
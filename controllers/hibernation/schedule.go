@@ -0,0 +1,56 @@
+package hibernation
+
+import (
+	"fmt"
+	"time"
+	// time zones are loaded from the binary, as the operator image has none
+	_ "time/tzdata"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+)
+
+// Desired returns whether the platform is to be hibernated at the given time
+// according to `spec.hibernation`, and when the schedule next changes that.
+// The schedule hibernates the platform while its last hibernation time is
+// more recent than its last resume time. No next transition is returned
+// while the platform is hibernated manually
+func Desired(hibernation *v1alpha1.Hibernation, now time.Time) (bool, *time.Time, error) {
+	if hibernation == nil {
+		return false, nil, nil
+	}
+	if hibernation.Schedule == nil {
+		return hibernation.Hibernated, nil, nil
+	}
+
+	location := time.UTC
+	if hibernation.Schedule.TimeZone != "" {
+		var err error
+		location, err = time.LoadLocation(hibernation.Schedule.TimeZone)
+		if err != nil {
+			return false, nil, fmt.Errorf("invalid hibernation time zone %s: %w", hibernation.Schedule.TimeZone, err)
+		}
+	}
+
+	hibernateSchedule, err := ParseCron(hibernation.Schedule.Hibernate, location)
+	if err != nil {
+		return false, nil, fmt.Errorf("invalid hibernation schedule: %w", err)
+	}
+	resumeSchedule, err := ParseCron(hibernation.Schedule.Resume, location)
+	if err != nil {
+		return false, nil, fmt.Errorf("invalid resume schedule: %w", err)
+	}
+
+	if hibernation.Hibernated {
+		return true, nil, nil
+	}
+
+	scheduled := hibernateSchedule.Prev(now).After(resumeSchedule.Prev(now))
+	next := hibernateSchedule.Next(now)
+	if scheduled {
+		next = resumeSchedule.Next(now)
+	}
+	if next.IsZero() {
+		return scheduled, nil, nil
+	}
+	return scheduled, &next, nil
+}
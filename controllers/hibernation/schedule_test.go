@@ -0,0 +1,134 @@
+package hibernation_test
+
+import (
+	"time"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/hibernation"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+)
+
+var _ = Describe("Desired", func() {
+	var (
+		spec *v1alpha1.Hibernation
+		now  time.Time
+
+		hibernated bool
+		next       *time.Time
+		err        error
+	)
+
+	BeforeEach(func() {
+		spec = &v1alpha1.Hibernation{
+			Schedule: &v1alpha1.HibernationSchedule{
+				Hibernate: "0 20 * * mon-fri",
+				Resume:    "0 7 * * mon-fri",
+			},
+		}
+		// a Wednesday
+		now = time.Date(2026, time.March, 18, 12, 0, 0, 0, time.UTC)
+	})
+
+	JustBeforeEach(func() {
+		hibernated, next, err = hibernation.Desired(spec, now)
+	})
+
+	It("keeps the platform running during the day", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hibernated).To(BeFalse())
+		Expect(next).To(PointTo(Equal(time.Date(2026, time.March, 18, 20, 0, 0, 0, time.UTC))))
+	})
+
+	When("it is night", func() {
+		BeforeEach(func() {
+			now = time.Date(2026, time.March, 18, 23, 0, 0, 0, time.UTC)
+		})
+
+		It("hibernates the platform until the morning", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hibernated).To(BeTrue())
+			Expect(next).To(PointTo(Equal(time.Date(2026, time.March, 19, 7, 0, 0, 0, time.UTC))))
+		})
+	})
+
+	When("it is the weekend", func() {
+		BeforeEach(func() {
+			now = time.Date(2026, time.March, 21, 12, 0, 0, 0, time.UTC)
+		})
+
+		It("hibernates the platform until Monday", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hibernated).To(BeTrue())
+			Expect(next).To(PointTo(Equal(time.Date(2026, time.March, 23, 7, 0, 0, 0, time.UTC))))
+		})
+	})
+
+	When("the schedule has a time zone", func() {
+		BeforeEach(func() {
+			spec.Schedule.TimeZone = "Asia/Tokyo"
+		})
+
+		It("evaluates the schedule in the time zone", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hibernated).To(BeTrue())
+			Expect(next).To(PointTo(BeTemporally("==", time.Date(2026, time.March, 18, 22, 0, 0, 0, time.UTC))))
+		})
+	})
+
+	When("the platform is hibernated manually", func() {
+		BeforeEach(func() {
+			spec.Hibernated = true
+		})
+
+		It("hibernates the platform regardless of the schedule", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hibernated).To(BeTrue())
+			Expect(next).To(BeNil())
+		})
+	})
+
+	When("there is no schedule", func() {
+		BeforeEach(func() {
+			spec = &v1alpha1.Hibernation{}
+		})
+
+		It("keeps the platform running", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hibernated).To(BeFalse())
+			Expect(next).To(BeNil())
+		})
+	})
+
+	When("hibernation is not configured", func() {
+		BeforeEach(func() {
+			spec = nil
+		})
+
+		It("keeps the platform running", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hibernated).To(BeFalse())
+		})
+	})
+
+	When("the time zone is unknown", func() {
+		BeforeEach(func() {
+			spec.Schedule.TimeZone = "Mars/Olympus_Mons"
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("invalid hibernation time zone Mars/Olympus_Mons")))
+		})
+	})
+
+	When("a cron expression is invalid", func() {
+		BeforeEach(func() {
+			spec.Schedule.Resume = "0 7 * *"
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("invalid resume schedule")))
+		})
+	})
+})
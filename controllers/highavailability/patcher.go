@@ -0,0 +1,184 @@
+package highavailability
+
+import (
+	"slices"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Label marks the deployments patched for high availability and the
+// PodDisruptionBudgets created for them
+const Label = "cfapi.kyma-project.io/high-availability"
+
+// singleReplicaDeployments cannot run more than one replica. The kpack
+// controller does not elect a leader and the local registry keeps its images
+// in the pod
+var singleReplicaDeployments = []types.NamespacedName{
+	{Namespace: "kpack", Name: "kpack-controller"},
+	{Namespace: "cfapi-system", Name: "cfapi-registry"},
+}
+
+// Patcher runs the deployments of the installed components with several
+// replicas spread across nodes and zones
+type Patcher struct {
+	namespaces        []string
+	replicas          int32
+	whenUnsatisfiable string
+}
+
+// NewPatcher returns the patcher of the installation config for deployments
+// in the given namespaces, or nil if high availability is not configured. A
+// nil patcher leaves deployments as they are
+func NewPatcher(config v1alpha1.InstallationConfig, namespaces ...string) *Patcher {
+	if config.HighAvailability == nil {
+		return nil
+	}
+
+	whenUnsatisfiable := "ScheduleAnyway"
+	if config.HighAvailability.ZoneSpread == v1alpha1.ZoneSpreadRequired {
+		whenUnsatisfiable = "DoNotSchedule"
+	}
+
+	return &Patcher{
+		namespaces:        namespaces,
+		replicas:          config.HighAvailability.Replicas,
+		whenUnsatisfiable: whenUnsatisfiable,
+	}
+}
+
+// Patch sets the replicas, topology spread and anti-affinity of the
+// deployments among the objects and returns the PodDisruptionBudgets of the
+// patched deployments that are not already among the objects. Objects
+// without a namespace are taken to be in the given default namespace, like
+// the resources of a helm release
+func (p *Patcher) Patch(objects []*unstructured.Unstructured, defaultNamespace string) []*unstructured.Unstructured {
+	if p == nil {
+		return nil
+	}
+
+	budgets := []*unstructured.Unstructured{}
+	for _, obj := range objects {
+		if !p.isPatched(obj, defaultNamespace) {
+			continue
+		}
+
+		selector, ok := nestedMap(obj.Object, "spec", "selector", "matchLabels")
+		if !ok {
+			continue
+		}
+		podSpec, ok := nestedMap(obj.Object, "spec", "template", "spec")
+		if !ok {
+			continue
+		}
+
+		obj.Object["spec"].(map[string]any)["replicas"] = int64(p.replicas)
+		setLabel(obj)
+		podSpec["topologySpreadConstraints"] = []any{map[string]any{
+			"maxSkew":           int64(1),
+			"topologyKey":       corev1.LabelTopologyZone,
+			"whenUnsatisfiable": p.whenUnsatisfiable,
+			"labelSelector":     map[string]any{"matchLabels": selector},
+		}}
+		affinity, ok := podSpec["affinity"].(map[string]any)
+		if !ok {
+			affinity = map[string]any{}
+			podSpec["affinity"] = affinity
+		}
+		affinity["podAntiAffinity"] = map[string]any{
+			"preferredDuringSchedulingIgnoredDuringExecution": []any{map[string]any{
+				"weight": int64(100),
+				"podAffinityTerm": map[string]any{
+					"topologyKey":   corev1.LabelHostname,
+					"labelSelector": map[string]any{"matchLabels": selector},
+				},
+			}},
+		}
+
+		namespace := obj.GetNamespace()
+		if namespace == "" {
+			namespace = defaultNamespace
+		}
+		if !hasBudget(objects, namespace, defaultNamespace, selector) {
+			budgets = append(budgets, PodDisruptionBudget(obj, namespace, selector))
+		}
+	}
+
+	return budgets
+}
+
+func (p *Patcher) isPatched(obj *unstructured.Unstructured, defaultNamespace string) bool {
+	if obj.GetKind() != "Deployment" {
+		return false
+	}
+
+	namespace := obj.GetNamespace()
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+	return slices.Contains(p.namespaces, namespace) &&
+		!slices.Contains(singleReplicaDeployments, types.NamespacedName{Namespace: namespace, Name: obj.GetName()})
+}
+
+// PodDisruptionBudget returns the budget keeping all but one replica of a
+// deployment running during voluntary disruptions, such as node drains
+func PodDisruptionBudget(deployment *unstructured.Unstructured, namespace string, selector map[string]any) *unstructured.Unstructured {
+	budget := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "policy/v1",
+		"kind":       "PodDisruptionBudget",
+		"metadata": map[string]any{
+			"namespace": namespace,
+			"name":      deployment.GetName(),
+		},
+		"spec": map[string]any{
+			"maxUnavailable": int64(1),
+			"selector":       map[string]any{"matchLabels": runtime.DeepCopyJSONValue(selector)},
+		},
+	}}
+	setLabel(budget)
+	return budget
+}
+
+// hasBudget returns whether the objects contain a PodDisruptionBudget of the
+// pods with the given labels, e.g. one rendered by the chart of a component
+func hasBudget(objects []*unstructured.Unstructured, namespace, defaultNamespace string, selector map[string]any) bool {
+	return slices.ContainsFunc(objects, func(obj *unstructured.Unstructured) bool {
+		if obj.GetKind() != "PodDisruptionBudget" {
+			return false
+		}
+
+		budgetNamespace := obj.GetNamespace()
+		if budgetNamespace == "" {
+			budgetNamespace = defaultNamespace
+		}
+		budgetSelector, _, _ := unstructured.NestedMap(obj.Object, "spec", "selector", "matchLabels")
+		return budgetNamespace == namespace && equality.Semantic.DeepEqual(budgetSelector, selector)
+	})
+}
+
+func setLabel(obj *unstructured.Unstructured) {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[Label] = "true"
+	obj.SetLabels(labels)
+}
+
+// nestedMap returns the map at the given path without copying it, so that it
+// can be modified in place
+func nestedMap(obj map[string]any, path ...string) (map[string]any, bool) {
+	for _, field := range path {
+		next, ok := obj[field].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		obj = next
+	}
+
+	return obj, true
+}
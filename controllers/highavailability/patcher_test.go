@@ -0,0 +1,187 @@
+package highavailability_test
+
+import (
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/highavailability"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("Patcher", func() {
+	var (
+		config     v1alpha1.InstallationConfig
+		deployment *unstructured.Unstructured
+		objects    []*unstructured.Unstructured
+		budgets    []*unstructured.Unstructured
+	)
+
+	newDeployment := func(namespace, name string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]any{
+				"name":      name,
+				"namespace": namespace,
+			},
+			"spec": map[string]any{
+				"replicas": int64(1),
+				"selector": map[string]any{
+					"matchLabels": map[string]any{"app": name},
+				},
+				"template": map[string]any{
+					"spec": map[string]any{
+						"affinity": map[string]any{
+							"nodeAffinity": map[string]any{"foo": "bar"},
+						},
+						"containers": []any{
+							map[string]any{"name": name, "image": name + ":latest"},
+						},
+					},
+				},
+			},
+		}}
+	}
+
+	BeforeEach(func() {
+		config = v1alpha1.InstallationConfig{
+			HighAvailability: &v1alpha1.HighAvailability{
+				Replicas:   3,
+				ZoneSpread: v1alpha1.ZoneSpreadPreferred,
+			},
+		}
+
+		deployment = newDeployment("korifi", "korifi-api-deployment")
+		objects = []*unstructured.Unstructured{deployment}
+	})
+
+	JustBeforeEach(func() {
+		budgets = highavailability.NewPatcher(config, "korifi", "kpack").Patch(objects, "")
+	})
+
+	It("sets the replicas of the deployment", func() {
+		replicas, _, _ := unstructured.NestedInt64(deployment.Object, "spec", "replicas")
+		Expect(replicas).To(BeEquivalentTo(3))
+		Expect(deployment.GetLabels()).To(HaveKeyWithValue(highavailability.Label, "true"))
+	})
+
+	It("preferably spreads the replicas across zones", func() {
+		constraints, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "topologySpreadConstraints")
+		Expect(constraints).To(ConsistOf(And(
+			HaveKeyWithValue("topologyKey", "topology.kubernetes.io/zone"),
+			HaveKeyWithValue("whenUnsatisfiable", "ScheduleAnyway"),
+			HaveKeyWithValue("labelSelector", HaveKeyWithValue("matchLabels", HaveKeyWithValue("app", "korifi-api-deployment"))),
+		)))
+	})
+
+	It("preferably spreads the replicas across nodes, keeping the other affinities", func() {
+		affinity, _, _ := unstructured.NestedMap(deployment.Object, "spec", "template", "spec", "affinity")
+		Expect(affinity).To(HaveKey("nodeAffinity"))
+
+		terms, _, _ := unstructured.NestedSlice(affinity, "podAntiAffinity", "preferredDuringSchedulingIgnoredDuringExecution")
+		Expect(terms).To(ConsistOf(HaveKeyWithValue("podAffinityTerm", And(
+			HaveKeyWithValue("topologyKey", "kubernetes.io/hostname"),
+			HaveKeyWithValue("labelSelector", HaveKeyWithValue("matchLabels", HaveKeyWithValue("app", "korifi-api-deployment"))),
+		))))
+	})
+
+	It("returns a PodDisruptionBudget of the deployment", func() {
+		Expect(budgets).To(HaveLen(1))
+		budget := budgets[0]
+		Expect(budget.GetKind()).To(Equal("PodDisruptionBudget"))
+		Expect(budget.GetNamespace()).To(Equal("korifi"))
+		Expect(budget.GetName()).To(Equal("korifi-api-deployment"))
+		Expect(budget.GetLabels()).To(HaveKeyWithValue(highavailability.Label, "true"))
+
+		maxUnavailable, _, _ := unstructured.NestedInt64(budget.Object, "spec", "maxUnavailable")
+		Expect(maxUnavailable).To(BeEquivalentTo(1))
+		selector, _, _ := unstructured.NestedStringMap(budget.Object, "spec", "selector", "matchLabels")
+		Expect(selector).To(Equal(map[string]string{"app": "korifi-api-deployment"}))
+	})
+
+	When("the replicas must be spread across zones", func() {
+		BeforeEach(func() {
+			config.HighAvailability.ZoneSpread = v1alpha1.ZoneSpreadRequired
+		})
+
+		It("does not schedule replicas skewing the zones", func() {
+			constraints, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "topologySpreadConstraints")
+			Expect(constraints).To(ConsistOf(HaveKeyWithValue("whenUnsatisfiable", "DoNotSchedule")))
+		})
+	})
+
+	When("the objects already contain a PodDisruptionBudget of the deployment", func() {
+		BeforeEach(func() {
+			objects = append(objects, &unstructured.Unstructured{Object: map[string]any{
+				"apiVersion": "policy/v1",
+				"kind":       "PodDisruptionBudget",
+				"metadata": map[string]any{
+					"name":      "chart-budget",
+					"namespace": "korifi",
+				},
+				"spec": map[string]any{
+					"selector": map[string]any{
+						"matchLabels": map[string]any{"app": "korifi-api-deployment"},
+					},
+				},
+			}})
+		})
+
+		It("does not return another one", func() {
+			Expect(budgets).To(BeEmpty())
+		})
+	})
+
+	When("the deployment has no namespace", func() {
+		JustBeforeEach(func() {
+			deployment = newDeployment("", "btp-service-broker")
+			budgets = highavailability.NewPatcher(config, "cfapi-system").Patch([]*unstructured.Unstructured{deployment}, "cfapi-system")
+		})
+
+		It("takes it to be in the default namespace", func() {
+			replicas, _, _ := unstructured.NestedInt64(deployment.Object, "spec", "replicas")
+			Expect(replicas).To(BeEquivalentTo(3))
+			Expect(budgets).To(ConsistOf(WithTransform(func(obj *unstructured.Unstructured) string {
+				return obj.GetNamespace()
+			}, Equal("cfapi-system"))))
+		})
+	})
+
+	When("the deployment cannot run more than one replica", func() {
+		BeforeEach(func() {
+			deployment = newDeployment("kpack", "kpack-controller")
+			objects = []*unstructured.Unstructured{deployment}
+		})
+
+		It("leaves it as it is", func() {
+			replicas, _, _ := unstructured.NestedInt64(deployment.Object, "spec", "replicas")
+			Expect(replicas).To(BeEquivalentTo(1))
+			Expect(budgets).To(BeEmpty())
+		})
+	})
+
+	When("the deployment is in another namespace", func() {
+		BeforeEach(func() {
+			deployment = newDeployment("other", "my-deployment")
+			objects = []*unstructured.Unstructured{deployment}
+		})
+
+		It("leaves it as it is", func() {
+			replicas, _, _ := unstructured.NestedInt64(deployment.Object, "spec", "replicas")
+			Expect(replicas).To(BeEquivalentTo(1))
+			Expect(budgets).To(BeEmpty())
+		})
+	})
+
+	When("high availability is not configured", func() {
+		BeforeEach(func() {
+			config.HighAvailability = nil
+		})
+
+		It("leaves the deployment as it is", func() {
+			replicas, _, _ := unstructured.NestedInt64(deployment.Object, "spec", "replicas")
+			Expect(replicas).To(BeEquivalentTo(1))
+			Expect(budgets).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package highavailability_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestHighAvailability(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "High Availability Suite")
+}
@@ -0,0 +1,254 @@
+package imagemirror
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const dockerHub = "docker.io"
+
+// publicRegistries are the registries whose images are expected to be
+// mirrored. Image references of other registries outside of `image` fields
+// are only rewritten when a rewrite matches, as they are hard to tell apart
+// from other host names
+var publicRegistries = []string{
+	dockerHub,
+	"ghcr.io",
+	"gcr.io",
+	"quay.io",
+	"registry.k8s.io",
+	"k8s.gcr.io",
+	"mcr.microsoft.com",
+	"public.ecr.aws",
+}
+
+// Mirror rewrites the image references of objects to mirror registries
+type Mirror struct {
+	rewrites    []v1alpha1.ImageRewrite
+	pullSecrets []string
+}
+
+// NewMirror returns the mirror of the installation config, or nil if no
+// rewrites are configured. A nil mirror leaves images as they are
+func NewMirror(config v1alpha1.InstallationConfig) *Mirror {
+	if len(config.ImageRewrites) == 0 {
+		return nil
+	}
+
+	rewrites := []v1alpha1.ImageRewrite{}
+	for _, rewrite := range config.ImageRewrites {
+		rewrites = append(rewrites, v1alpha1.ImageRewrite{From: canonicalPrefix(rewrite.From), To: rewrite.To})
+	}
+	slices.SortStableFunc(rewrites, func(r1, r2 v1alpha1.ImageRewrite) int {
+		return cmp.Compare(len(r2.From), len(r1.From))
+	})
+
+	return &Mirror{
+		rewrites:    rewrites,
+		pullSecrets: config.ImagePullSecrets,
+	}
+}
+
+// RewriteImage returns the image reference with the longest matching prefix
+// rewritten, and whether the image is pulled from a mirror
+func (m *Mirror) RewriteImage(image string) (string, bool) {
+	if m == nil {
+		return image, false
+	}
+
+	for _, rewrite := range m.rewrites {
+		if strings.HasPrefix(image, rewrite.To) {
+			return image, true
+		}
+	}
+
+	canonicalImage := canonical(image)
+	for _, rewrite := range m.rewrites {
+		if rest, ok := strings.CutPrefix(canonicalImage, rewrite.From); ok {
+			return rewrite.To + rest, true
+		}
+	}
+
+	return image, false
+}
+
+// Rewrite rewrites the image references in the spec of an object and adds
+// the pull secrets to the pods pulling from a mirror. It returns the images
+// which are not pulled from a mirror: the values of `image` fields and the
+// references to public registries elsewhere that no rewrite matches
+func (m *Mirror) Rewrite(obj *unstructured.Unstructured) []string {
+	unmirrored := []string{}
+	if m == nil || obj.GetKind() == "CustomResourceDefinition" {
+		return unmirrored
+	}
+
+	for key, value := range obj.Object {
+		if key == "apiVersion" || key == "kind" || key == "metadata" || key == "status" {
+			continue
+		}
+		obj.Object[key] = m.rewriteValue(key, value, &unmirrored)
+	}
+
+	for _, podSpec := range podSpecs(obj) {
+		m.addPullSecrets(podSpec)
+	}
+
+	slices.Sort(unmirrored)
+	return slices.Compact(unmirrored)
+}
+
+func (m *Mirror) rewriteValue(key string, value any, unmirrored *[]string) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		for k, v := range typed {
+			typed[k] = m.rewriteValue(k, v, unmirrored)
+		}
+		return typed
+	case []any:
+		for i, v := range typed {
+			typed[i] = m.rewriteValue(key, v, unmirrored)
+		}
+		return typed
+	case string:
+		return m.rewriteString(key, typed, unmirrored)
+	default:
+		return value
+	}
+}
+
+func (m *Mirror) rewriteString(key, value string, unmirrored *[]string) string {
+	if key != "image" && !hasRegistry(value) {
+		return value
+	}
+
+	rewritten, mirrored := m.RewriteImage(value)
+	if !mirrored && value != "" && (key == "image" || isPublic(value)) {
+		*unmirrored = append(*unmirrored, value)
+	}
+
+	return rewritten
+}
+
+// addPullSecrets adds the pull secrets to a pod spec with containers pulling
+// from a mirror
+func (m *Mirror) addPullSecrets(podSpec map[string]any) {
+	if m == nil || len(m.pullSecrets) == 0 || !m.pullsFromMirror(podSpec) {
+		return
+	}
+
+	pullSecrets, _, _ := unstructured.NestedSlice(podSpec, "imagePullSecrets")
+	for _, secretName := range m.pullSecrets {
+		if !slices.ContainsFunc(pullSecrets, func(pullSecret any) bool {
+			ref, ok := pullSecret.(map[string]any)
+			return ok && ref["name"] == secretName
+		}) {
+			pullSecrets = append(pullSecrets, map[string]any{"name": secretName})
+		}
+	}
+	podSpec["imagePullSecrets"] = pullSecrets
+}
+
+func (m *Mirror) pullsFromMirror(podSpec map[string]any) bool {
+	for _, field := range []string{"initContainers", "containers", "ephemeralContainers"} {
+		containers, _, _ := unstructured.NestedSlice(podSpec, field)
+		for _, container := range containers {
+			image, _, _ := unstructured.NestedString(container.(map[string]any), "image")
+			for _, rewrite := range m.rewrites {
+				if strings.HasPrefix(image, rewrite.To) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// podSpecs returns the pod specs of pods and of the templates of workloads
+func podSpecs(obj *unstructured.Unstructured) []map[string]any {
+	paths := [][]string{{"spec", "template", "spec"}, {"spec", "jobTemplate", "spec", "template", "spec"}}
+	if obj.GetKind() == "Pod" {
+		paths = [][]string{{"spec"}}
+	}
+
+	podSpecs := []map[string]any{}
+	for _, path := range paths {
+		if podSpec, ok := nestedMap(obj.Object, path...); ok && podSpec["containers"] != nil {
+			podSpecs = append(podSpecs, podSpec)
+		}
+	}
+
+	return podSpecs
+}
+
+// nestedMap returns the map at the given path without copying it, unlike
+// `unstructured.NestedMap`, so that it can be modified in place
+func nestedMap(obj map[string]any, path ...string) (map[string]any, bool) {
+	for _, field := range path {
+		next, ok := obj[field].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		obj = next
+	}
+
+	return obj, true
+}
+
+// canonical returns the full form of an image reference, with the registry
+// of Docker Hub images and the `library/` namespace of official ones
+func canonical(image string) string {
+	if !strings.Contains(image, "/") {
+		return dockerHub + "/library/" + image
+	}
+
+	image = canonicalPrefix(image)
+	registry, rest, _ := strings.Cut(image, "/")
+	if registry == dockerHub && !strings.Contains(rest, "/") {
+		return dockerHub + "/library/" + rest
+	}
+
+	return image
+}
+
+// canonicalPrefix returns the full form of the registry of an image reference
+// or prefix. Docker Hub has several names and is the default registry
+func canonicalPrefix(prefix string) string {
+	registry, rest, hasRegistry := strings.Cut(prefix, "/")
+	if !isHost(registry) {
+		return dockerHub + "/" + prefix
+	}
+
+	if registry == "index.docker.io" || registry == "registry-1.docker.io" {
+		registry = dockerHub
+	}
+	if !hasRegistry {
+		return registry
+	}
+
+	return registry + "/" + rest
+}
+
+// hasRegistry returns whether a value looks like an image reference with an
+// explicit registry, such as `ghcr.io/org/image:tag`
+func hasRegistry(value string) bool {
+	if strings.ContainsAny(value, " \t\n\"'=") || strings.Contains(value, "://") {
+		return false
+	}
+
+	registry, rest, ok := strings.Cut(value, "/")
+	return ok && rest != "" && isHost(registry)
+}
+
+func isHost(registry string) bool {
+	return strings.ContainsAny(registry, ".:") || registry == "localhost"
+}
+
+func isPublic(image string) bool {
+	registry, _, _ := strings.Cut(canonical(image), "/")
+	return slices.Contains(publicRegistries, registry) || strings.HasSuffix(registry, ".gcr.io") || strings.HasSuffix(registry, ".pkg.dev")
+}
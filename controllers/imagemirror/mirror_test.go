@@ -0,0 +1,167 @@
+package imagemirror_test
+
+import (
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/imagemirror"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("Mirror", func() {
+	var (
+		config v1alpha1.InstallationConfig
+		mirror *imagemirror.Mirror
+	)
+
+	BeforeEach(func() {
+		config = v1alpha1.InstallationConfig{
+			ImageRewrites: []v1alpha1.ImageRewrite{
+				{From: "ghcr.io/", To: "mirror.example.com/ghcr/"},
+				{From: "ghcr.io/kyma-project/", To: "mirror.example.com/kyma/"},
+				{From: "docker.io/", To: "mirror.example.com/docker/"},
+			},
+			ImagePullSecrets: []string{"mirror-secret"},
+		}
+	})
+
+	JustBeforeEach(func() {
+		mirror = imagemirror.NewMirror(config)
+	})
+
+	Describe("RewriteImage", func() {
+		DescribeTable("rewrites image references",
+			func(image, expectedImage string, expectedMirrored bool) {
+				rewritten, mirrored := mirror.RewriteImage(image)
+				Expect(rewritten).To(Equal(expectedImage))
+				Expect(mirrored).To(Equal(expectedMirrored))
+			},
+			Entry("matching prefix", "ghcr.io/foo/bar:1.0", "mirror.example.com/ghcr/foo/bar:1.0", true),
+			Entry("longest matching prefix", "ghcr.io/kyma-project/cfapi:1.0", "mirror.example.com/kyma/cfapi:1.0", true),
+			Entry("official docker hub image", "nginx:1.25", "mirror.example.com/docker/library/nginx:1.25", true),
+			Entry("docker hub image", "paketobuildpacks/java@sha256:abc", "mirror.example.com/docker/paketobuildpacks/java@sha256:abc", true),
+			Entry("docker hub alias", "index.docker.io/paketobuildpacks/java", "mirror.example.com/docker/paketobuildpacks/java", true),
+			Entry("already mirrored image", "mirror.example.com/ghcr/foo/bar", "mirror.example.com/ghcr/foo/bar", true),
+			Entry("no matching prefix", "quay.io/foo/bar", "quay.io/foo/bar", false),
+		)
+
+		When("no rewrites are configured", func() {
+			BeforeEach(func() {
+				config = v1alpha1.InstallationConfig{}
+			})
+
+			It("leaves images unchanged", func() {
+				Expect(mirror).To(BeNil())
+				rewritten, mirrored := mirror.RewriteImage("ghcr.io/foo/bar")
+				Expect(rewritten).To(Equal("ghcr.io/foo/bar"))
+				Expect(mirrored).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("Rewrite", func() {
+		var (
+			obj        *unstructured.Unstructured
+			unmirrored []string
+		)
+
+		BeforeEach(func() {
+			obj = &unstructured.Unstructured{Object: map[string]any{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata": map[string]any{
+					"name":        "my-deployment",
+					"annotations": map[string]any{"source": "ghcr.io/foo/source"},
+				},
+				"spec": map[string]any{
+					"template": map[string]any{
+						"spec": map[string]any{
+							"initContainers": []any{
+								map[string]any{"name": "init", "image": "busybox"},
+							},
+							"containers": []any{
+								map[string]any{
+									"name":  "main",
+									"image": "ghcr.io/foo/main:1.0",
+									"args":  []any{"--helper-image", "ghcr.io/foo/helper:1.0", "--url", "https://ghcr.io/foo"},
+								},
+								map[string]any{"name": "sidecar", "image": "quay.io/foo/sidecar"},
+							},
+						},
+					},
+				},
+			}}
+		})
+
+		JustBeforeEach(func() {
+			unmirrored = mirror.Rewrite(obj)
+		})
+
+		It("rewrites the images of the spec", func() {
+			containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+			Expect(containers[0]).To(HaveKeyWithValue("image", "mirror.example.com/ghcr/foo/main:1.0"))
+			Expect(containers[0]).To(HaveKeyWithValue("args", []any{"--helper-image", "mirror.example.com/ghcr/foo/helper:1.0", "--url", "https://ghcr.io/foo"}))
+
+			initContainers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "initContainers")
+			Expect(initContainers[0]).To(HaveKeyWithValue("image", "mirror.example.com/docker/library/busybox"))
+		})
+
+		It("leaves the metadata unchanged", func() {
+			Expect(obj.GetAnnotations()).To(HaveKeyWithValue("source", "ghcr.io/foo/source"))
+		})
+
+		It("adds the pull secrets to the pod template", func() {
+			pullSecrets, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "imagePullSecrets")
+			Expect(pullSecrets).To(ConsistOf(map[string]any{"name": "mirror-secret"}))
+		})
+
+		It("returns the images no rewrite matches", func() {
+			Expect(unmirrored).To(ConsistOf("quay.io/foo/sidecar"))
+		})
+
+		When("the pod template already references the pull secret", func() {
+			BeforeEach(func() {
+				Expect(unstructured.SetNestedSlice(obj.Object, []any{map[string]any{"name": "mirror-secret"}}, "spec", "template", "spec", "imagePullSecrets")).To(Succeed())
+			})
+
+			It("does not add it again", func() {
+				pullSecrets, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "imagePullSecrets")
+				Expect(pullSecrets).To(HaveLen(1))
+			})
+		})
+
+		When("the object holds images outside of image fields", func() {
+			BeforeEach(func() {
+				obj = &unstructured.Unstructured{Object: map[string]any{
+					"apiVersion": "v1",
+					"kind":       "ConfigMap",
+					"metadata":   map[string]any{"name": "build-init-image"},
+					"data": map[string]any{
+						"image":    "gcr.io/foo/build-init",
+						"registry": "my-registry.example.com/foo/bar",
+					},
+				}}
+			})
+
+			It("reports the public images which are not mirrored", func() {
+				Expect(obj.Object["data"]).To(Equal(map[string]any{
+					"image":    "gcr.io/foo/build-init",
+					"registry": "my-registry.example.com/foo/bar",
+				}))
+				Expect(unmirrored).To(ConsistOf("gcr.io/foo/build-init"))
+			})
+		})
+
+		When("no rewrites are configured", func() {
+			BeforeEach(func() {
+				config = v1alpha1.InstallationConfig{}
+			})
+
+			It("leaves the object unchanged", func() {
+				Expect(unmirrored).To(BeEmpty())
+				containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+				Expect(containers[0]).To(HaveKeyWithValue("image", "ghcr.io/foo/main:1.0"))
+			})
+		})
+	})
+})
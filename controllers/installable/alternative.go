@@ -0,0 +1,44 @@
+package installable
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+)
+
+// Alternative installs one of two installables, depending on the predicate.
+// Unlike Conditional it does not uninstall the installable which is not
+// chosen, which allows alternatives to manage the same resources
+type Alternative struct {
+	predicate Predicate
+	whenTrue  Installable
+	whenFalse Installable
+}
+
+func NewAlternative(predicate Predicate, whenTrue, whenFalse Installable) *Alternative {
+	return &Alternative{
+		predicate: predicate,
+		whenTrue:  whenTrue,
+		whenFalse: whenFalse,
+	}
+}
+
+func (a *Alternative) Name() string {
+	return fmt.Sprintf("Alternative Installable: %s | %s", a.whenTrue.Name(), a.whenFalse.Name())
+}
+
+func (a *Alternative) Install(ctx context.Context, config v1alpha1.InstallationConfig, eventRecorder EventRecorder) (Result, error) {
+	return a.choose(ctx, config).Install(ctx, config, eventRecorder)
+}
+
+func (a *Alternative) Uninstall(ctx context.Context, config v1alpha1.InstallationConfig, eventRecorder EventRecorder) (Result, error) {
+	return a.choose(ctx, config).Uninstall(ctx, config, eventRecorder)
+}
+
+func (a *Alternative) choose(ctx context.Context, config v1alpha1.InstallationConfig) Installable {
+	if a.predicate(ctx, config) {
+		return a.whenTrue
+	}
+	return a.whenFalse
+}
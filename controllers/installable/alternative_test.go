@@ -0,0 +1,92 @@
+package installable_test
+
+import (
+	"context"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/installable"
+	"github.com/kyma-project/cfapi/controllers/installable/fake"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Alternative Installable", func() {
+	var (
+		predicate installable.Predicate
+		config    v1alpha1.InstallationConfig
+		whenTrue  *fake.Installable
+		whenFalse *fake.Installable
+
+		result     installable.Result
+		installErr error
+	)
+
+	BeforeEach(func() {
+		config = v1alpha1.InstallationConfig{
+			RootNamespace: "my-root-ns",
+		}
+
+		whenTrue = new(fake.Installable)
+		whenFalse = new(fake.Installable)
+
+		predicate = func(ctx context.Context, config v1alpha1.InstallationConfig) bool {
+			return true
+		}
+
+		whenTrue.InstallReturns(installable.Result{State: installable.ResultStateSuccess, Message: "true-install"}, nil)
+		whenTrue.UninstallReturns(installable.Result{State: installable.ResultStateSuccess, Message: "true-uninstall"}, nil)
+		whenFalse.InstallReturns(installable.Result{State: installable.ResultStateSuccess, Message: "false-install"}, nil)
+		whenFalse.UninstallReturns(installable.Result{State: installable.ResultStateSuccess, Message: "false-uninstall"}, nil)
+	})
+
+	Describe("Install", func() {
+		JustBeforeEach(func() {
+			result, installErr = installable.NewAlternative(predicate, whenTrue, whenFalse).Install(ctx, config, eventRecorder)
+		})
+
+		It("installs the first installable", func() {
+			Expect(installErr).NotTo(HaveOccurred())
+			Expect(whenTrue.InstallCallCount()).To(Equal(1))
+			_, actualConfig, _ := whenTrue.InstallArgsForCall(0)
+			Expect(actualConfig).To(Equal(config))
+			Expect(result.Message).To(Equal("true-install"))
+		})
+
+		It("does not touch the second installable", func() {
+			Expect(whenFalse.InstallCallCount()).To(BeZero())
+			Expect(whenFalse.UninstallCallCount()).To(BeZero())
+		})
+
+		When("the condition is not met", func() {
+			BeforeEach(func() {
+				predicate = func(ctx context.Context, config v1alpha1.InstallationConfig) bool {
+					return false
+				}
+			})
+
+			It("installs the second installable", func() {
+				Expect(installErr).NotTo(HaveOccurred())
+				Expect(whenFalse.InstallCallCount()).To(Equal(1))
+				Expect(result.Message).To(Equal("false-install"))
+			})
+
+			It("does not uninstall the first installable", func() {
+				Expect(whenTrue.InstallCallCount()).To(BeZero())
+				Expect(whenTrue.UninstallCallCount()).To(BeZero())
+			})
+		})
+	})
+
+	Describe("Uninstall", func() {
+		JustBeforeEach(func() {
+			result, installErr = installable.NewAlternative(predicate, whenTrue, whenFalse).Uninstall(ctx, config, eventRecorder)
+		})
+
+		It("uninstalls the chosen installable", func() {
+			Expect(installErr).NotTo(HaveOccurred())
+			Expect(whenTrue.UninstallCallCount()).To(Equal(1))
+			Expect(whenFalse.UninstallCallCount()).To(BeZero())
+			Expect(result.Message).To(Equal("true-uninstall"))
+		})
+	})
+})
@@ -3,16 +3,23 @@ package installable
 import (
 	"context"
 	"fmt"
+	"maps"
 	"reflect"
 
 	"github.com/go-logr/logr"
 	"github.com/kyma-project/cfapi/api/v1alpha1"
 	"github.com/kyma-project/cfapi/controllers/helm"
+	"github.com/kyma-project/cfapi/controllers/highavailability"
+	"github.com/kyma-project/cfapi/controllers/imagemirror"
+	"github.com/kyma-project/cfapi/controllers/proxy"
+	"github.com/kyma-project/cfapi/controllers/trustedca"
+	"helm.sh/helm/v3/pkg/postrender"
 	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 type HelmClient interface {
-	Apply(ctx context.Context, chartPath, namespace, name string, values map[string]any) (helm.HelmResult, error)
+	Apply(ctx context.Context, chartPath, namespace, name string, values map[string]any, postRenderer postrender.PostRenderer) (helm.HelmResult, error)
 	Uninstall(ctx context.Context, namespace, name string) (helm.HelmResult, error)
 }
 
@@ -22,21 +29,37 @@ type HelmValuesProvider interface {
 	GetValues(ctx context.Context, config v1alpha1.InstallationConfig) (map[string]any, error)
 }
 
+// HelmPostRendererProvider is optionally implemented by values providers
+// which need to modify rendered resources beyond what the chart values cover
+type HelmPostRendererProvider interface {
+	GetPostRenderer(config v1alpha1.InstallationConfig) postrender.PostRenderer
+}
+
+// KindsFilter returns the kinds of rendered chart resources which must not be
+// applied with the given installation config
+type KindsFilter func(config v1alpha1.InstallationConfig) []schema.GroupKind
+
 type HelmChart struct {
 	chartPath      string
 	namespace      string
 	name           string
 	valuesProvider HelmValuesProvider
+	kindsFilter    KindsFilter
 	helmClient     HelmClient
 }
 
 func NewHelmChart(chartPath string, namespace, name string, valuesProvider HelmValuesProvider, helmClient HelmClient) *HelmChart {
+	return NewFilteredHelmChart(chartPath, namespace, name, valuesProvider, nil, helmClient)
+}
+
+func NewFilteredHelmChart(chartPath string, namespace, name string, valuesProvider HelmValuesProvider, kindsFilter KindsFilter, helmClient HelmClient) *HelmChart {
 	return &HelmChart{
 		helmClient:     helmClient,
 		chartPath:      chartPath,
 		namespace:      namespace,
 		name:           name,
 		valuesProvider: valuesProvider,
+		kindsFilter:    kindsFilter,
 	}
 }
 
@@ -55,7 +78,40 @@ func (h *HelmChart) Install(ctx context.Context, config v1alpha1.InstallationCon
 		}, nil
 	}
 
-	helmResult, err := h.helmClient.Apply(ctx, h.chartPath, h.namespace, h.name, values)
+	var excludedKinds []schema.GroupKind
+	if h.kindsFilter != nil {
+		excludedKinds = h.kindsFilter(config)
+	}
+
+	mirror := imagemirror.NewMirror(config)
+	postRenderer := helm.ExcludeKinds(excludedKinds...)
+	if postRendererProvider, ok := h.valuesProvider.(HelmPostRendererProvider); ok {
+		postRenderer = helm.Chain(postRenderer, postRendererProvider.GetPostRenderer(config))
+	}
+	trustedCA := trustedca.NewInjector(config, SystemNamespaces...)
+	proxyInjector := proxy.NewInjector(config, SystemNamespaces...)
+	haPatcher := highavailability.NewPatcher(config, SystemNamespaces...)
+	postRenderer = helm.Chain(
+		postRenderer,
+		helm.RewriteImages(mirror),
+		helm.InjectTrustedCA(trustedCA, h.namespace),
+		helm.InjectProxy(proxyInjector, h.namespace),
+		helm.AddHighAvailability(haPatcher, h.namespace),
+	)
+	if mirror != nil {
+		values = withImageMirror(values, config)
+	}
+	if trustedCA != nil {
+		values = withTrustedCA(values, config)
+	}
+	if proxyInjector != nil {
+		values = withProxy(values, config)
+	}
+	if haPatcher != nil {
+		values = withHighAvailability(values, config)
+	}
+
+	helmResult, err := h.helmClient.Apply(ctx, h.chartPath, h.namespace, h.name, values, postRenderer)
 	if err != nil {
 		log.Error(err, "failed to apply chart")
 		return Result{
@@ -65,27 +121,107 @@ func (h *HelmChart) Install(ctx context.Context, config v1alpha1.InstallationCon
 	}
 	eventRecorder.Event(EventNormal, "HelmChartApplied", fmt.Sprintf("Helm chart %s applied with status %s", h.name, helmResult.ReleaseStatus))
 
+	unmirroredImages, err := unmirroredImagesOf(mirror, helmResult.Manifest)
+	if err != nil {
+		log.Error(err, "failed to check the images of the release")
+	}
+
 	switch helmResult.ReleaseStatus {
 	case release.StatusDeployed:
 		eventRecorder.Event(EventNormal, "HelmChartDeployed", fmt.Sprintf("Helm chart %s deployed successfully", h.name))
 		return Result{
-			State: ResultStateSuccess,
+			State:            ResultStateSuccess,
+			UnmirroredImages: unmirroredImages,
 		}, nil
 	case release.StatusFailed:
 		eventRecorder.Event(EventWarning, "HelmChartDeploymentFailed", fmt.Sprintf("Helm chart %s failed to deploy: %s", h.name, helmResult.Message))
 		return Result{
-			State:   ResultStateFailed,
-			Message: helmResult.Message,
+			State:            ResultStateFailed,
+			Message:          helmResult.Message,
+			UnmirroredImages: unmirroredImages,
 		}, nil
 	default:
 		eventRecorder.Event(EventNormal, "HelmChartDeploying", fmt.Sprintf("Helm chart %s is being deployed", h.name))
 		return Result{
-			State:   ResultStateInProgress,
-			Message: fmt.Sprintf("helm chart %s is in status %s: %s", h.name, helmResult.ReleaseStatus, helmResult.Message),
+			State:            ResultStateInProgress,
+			Message:          fmt.Sprintf("helm chart %s is in status %s: %s", h.name, helmResult.ReleaseStatus, helmResult.Message),
+			UnmirroredImages: unmirroredImages,
 		}, nil
 	}
 }
 
+// withImageMirror adds the image mirror to the values. The chart does not
+// use it, but helm only upgrades a release when its values or its chart
+// change, so the rewritten images are rolled out whenever the mirror changes
+func withImageMirror(values map[string]any, config v1alpha1.InstallationConfig) map[string]any {
+	rewrites := []any{}
+	for _, rewrite := range config.ImageRewrites {
+		rewrites = append(rewrites, map[string]any{"from": rewrite.From, "to": rewrite.To})
+	}
+	pullSecrets := []any{}
+	for _, pullSecret := range config.ImagePullSecrets {
+		pullSecrets = append(pullSecrets, pullSecret)
+	}
+
+	values = maps.Clone(values)
+	values["cfapiImageMirror"] = map[string]any{
+		"rewrites":    rewrites,
+		"pullSecrets": pullSecrets,
+	}
+	return values
+}
+
+// withTrustedCA adds the hash of the trusted CA bundle to the values, so that
+// the release is upgraded and its workloads are rolled out whenever the
+// bundle changes
+func withTrustedCA(values map[string]any, config v1alpha1.InstallationConfig) map[string]any {
+	values = maps.Clone(values)
+	values["cfapiTrustedCA"] = map[string]any{
+		"hash": config.TrustedCA.Hash,
+	}
+	return values
+}
+
+// withProxy adds the proxy to the values, so that the release is upgraded
+// and its workloads are rolled out whenever the proxy changes
+func withProxy(values map[string]any, config v1alpha1.InstallationConfig) map[string]any {
+	values = maps.Clone(values)
+	values["cfapiProxy"] = map[string]any{
+		"httpProxy":  config.Proxy.HTTPProxy,
+		"httpsProxy": config.Proxy.HTTPSProxy,
+		"noProxy":    config.Proxy.NoProxy,
+	}
+	return values
+}
+
+// withHighAvailability adds the high availability settings to the values, so
+// that the release is upgraded whenever they change
+func withHighAvailability(values map[string]any, config v1alpha1.InstallationConfig) map[string]any {
+	values = maps.Clone(values)
+	values["cfapiHighAvailability"] = map[string]any{
+		"replicas":   config.HighAvailability.Replicas,
+		"zoneSpread": config.HighAvailability.ZoneSpread,
+	}
+	return values
+}
+
+// unmirroredImagesOf returns the images of the post-rendered resources of a
+// release which are not pulled from the mirror. The release manifest is
+// checked rather than the rendering itself, as releases are not rendered again
+// while their values are unchanged
+func unmirroredImagesOf(mirror *imagemirror.Mirror, manifest string) ([]string, error) {
+	if mirror == nil || manifest == "" {
+		return nil, nil
+	}
+
+	objects, err := parseToUnstructuredObjects(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return rewriteImages(mirror, objects), nil
+}
+
 func (h *HelmChart) Uninstall(ctx context.Context, config v1alpha1.InstallationConfig, eventRecorder EventRecorder) (Result, error) {
 	log := logr.FromContextOrDiscard(ctx).WithName("helm").WithValues("chart", h.name)
 
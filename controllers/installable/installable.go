@@ -6,6 +6,10 @@ import (
 	"github.com/kyma-project/cfapi/api/v1alpha1"
 )
 
+// SystemNamespaces are the namespaces of the workloads of the installed
+// components
+var SystemNamespaces = []string{"cfapi-system", "korifi", "kpack"}
+
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
 //counterfeiter:generate -o fake -fake-name Installable . Installable
 type Installable interface {
@@ -17,6 +21,12 @@ type Installable interface {
 type Result struct {
 	State   ResultState
 	Message string
+	// SharedObjects lists the objects of other managers the installable
+	// encountered, together with what it decided to do with them
+	SharedObjects []SharedObject
+	// UnmirroredImages lists the images of the installable which are not
+	// pulled from the image mirror, if one is configured
+	UnmirroredImages []string
 }
 
 type ResultState int
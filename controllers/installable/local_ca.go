@@ -0,0 +1,255 @@
+package installable
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	LocalCASecretName = "cfapi-local-ca"
+
+	caValidity          = 10 * 365 * 24 * time.Hour
+	certificateValidity = 365 * 24 * time.Hour
+	renewBefore         = 30 * 24 * time.Hour
+)
+
+type certificateRequest struct {
+	secretName string
+	dnsNames   []string
+}
+
+// LocalCA issues the certificates Korifi needs from a CA managed by the
+// operator. It replaces the gardener cert management on local clusters
+type LocalCA struct {
+	k8sClient       client.Client
+	caNamespace     string
+	korifiNamespace string
+}
+
+func NewLocalCA(k8sClient client.Client, caNamespace, korifiNamespace string) *LocalCA {
+	return &LocalCA{
+		k8sClient:       k8sClient,
+		caNamespace:     caNamespace,
+		korifiNamespace: korifiNamespace,
+	}
+}
+
+func (l *LocalCA) Name() string {
+	return "Local CA Installable"
+}
+
+func (l *LocalCA) Install(ctx context.Context, config v1alpha1.InstallationConfig, eventRecorder EventRecorder) (Result, error) {
+	ca, err := l.ensureCA(ctx)
+	if err != nil {
+		eventRecorder.Event(EventWarning, "InstallableFailed", fmt.Sprintf("Installable %s failed", l.Name()))
+		return Result{}, fmt.Errorf("failed to ensure the local CA: %w", err)
+	}
+
+	for _, request := range l.certificateRequests(config) {
+		if err := l.ensureCertificate(ctx, ca, request); err != nil {
+			eventRecorder.Event(EventWarning, "InstallableFailed", fmt.Sprintf("Installable %s failed", l.Name()))
+			return Result{}, fmt.Errorf("failed to ensure certificate %s: %w", request.secretName, err)
+		}
+	}
+
+	eventRecorder.Event(EventNormal, "InstallableDeployed", fmt.Sprintf("Installable %s deployed", l.Name()))
+	return Result{
+		State:   ResultStateSuccess,
+		Message: "Local certificates issued successfully",
+	}, nil
+}
+
+func (l *LocalCA) Uninstall(ctx context.Context, config v1alpha1.InstallationConfig, eventRecorder EventRecorder) (Result, error) {
+	secrets := []client.Object{l.secret(l.caNamespace, LocalCASecretName)}
+	for _, request := range l.certificateRequests(config) {
+		secrets = append(secrets, l.secret(l.korifiNamespace, request.secretName))
+	}
+
+	for _, secret := range secrets {
+		if err := client.IgnoreNotFound(l.k8sClient.Delete(ctx, secret)); err != nil {
+			eventRecorder.Event(EventWarning, "InstallableFailed", fmt.Sprintf("Uninstalling %s failed", l.Name()))
+			return Result{}, fmt.Errorf("failed to delete secret %s: %w", secret.GetName(), err)
+		}
+	}
+
+	return Result{
+		State:   ResultStateSuccess,
+		Message: "Local certificates deleted successfully",
+	}, nil
+}
+
+func (l *LocalCA) certificateRequests(config v1alpha1.InstallationConfig) []certificateRequest {
+	return []certificateRequest{
+		{secretName: "korifi-api-ingress-cert", dnsNames: []string{"cfapi." + config.CFDomain}},
+		{secretName: "korifi-workloads-ingress-cert", dnsNames: []string{"*.apps." + config.CFDomain}},
+		{secretName: "korifi-api-internal-cert", dnsNames: []string{
+			"korifi-api-svc." + l.korifiNamespace + ".svc.cluster.local",
+		}},
+		{secretName: "korifi-controllers-webhook-cert", dnsNames: []string{
+			"korifi-controllers-webhook-service." + l.korifiNamespace + ".svc",
+			"korifi-controllers-webhook-service." + l.korifiNamespace + ".svc.cluster.local",
+		}},
+	}
+}
+
+func (l *LocalCA) secret(namespace, name string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+	}
+}
+
+func (l *LocalCA) ensureCA(ctx context.Context) (tls.Certificate, error) {
+	caSecret := l.secret(l.caNamespace, LocalCASecretName)
+	err := l.k8sClient.Get(ctx, client.ObjectKeyFromObject(caSecret), caSecret)
+	if client.IgnoreNotFound(err) != nil {
+		return tls.Certificate{}, err
+	}
+
+	if err == nil {
+		ca, parseErr := parseKeyPair(caSecret)
+		if parseErr == nil && !expiresSoon(ca.Leaf) {
+			return ca, nil
+		}
+	}
+
+	certPEM, keyPEM, err := generateCertificate(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: "cfapi-local-ca"},
+		NotAfter:              time.Now().Add(caValidity),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}, nil)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	if _, err = controllerutil.CreateOrPatch(ctx, l.k8sClient, caSecret, func() error {
+		caSecret.Type = corev1.SecretTypeTLS
+		caSecret.Data = map[string][]byte{
+			corev1.TLSCertKey:       certPEM,
+			corev1.TLSPrivateKeyKey: keyPEM,
+			"ca.crt":                certPEM,
+		}
+		return nil
+	}); err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return parseKeyPair(caSecret)
+}
+
+func (l *LocalCA) ensureCertificate(ctx context.Context, ca tls.Certificate, request certificateRequest) error {
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Leaf.Raw})
+
+	certSecret := l.secret(l.korifiNamespace, request.secretName)
+	err := l.k8sClient.Get(ctx, client.ObjectKeyFromObject(certSecret), certSecret)
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	if err == nil && isValidCertificate(certSecret, ca, caPEM, request.dnsNames) {
+		return nil
+	}
+
+	certPEM, keyPEM, err := generateCertificate(&x509.Certificate{
+		Subject:     pkix.Name{CommonName: request.dnsNames[0]},
+		DNSNames:    request.dnsNames,
+		NotAfter:    time.Now().Add(certificateValidity),
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, &ca)
+	if err != nil {
+		return err
+	}
+
+	_, err = controllerutil.CreateOrPatch(ctx, l.k8sClient, certSecret, func() error {
+		certSecret.Type = corev1.SecretTypeTLS
+		certSecret.Data = map[string][]byte{
+			corev1.TLSCertKey:       certPEM,
+			corev1.TLSPrivateKeyKey: keyPEM,
+			"ca.crt":                caPEM,
+		}
+		return nil
+	})
+	return err
+}
+
+func isValidCertificate(secret *corev1.Secret, ca tls.Certificate, caPEM []byte, dnsNames []string) bool {
+	if !bytes.Equal(secret.Data["ca.crt"], caPEM) {
+		return false
+	}
+
+	cert, err := parseKeyPair(secret)
+	if err != nil || expiresSoon(cert.Leaf) {
+		return false
+	}
+
+	if cert.Leaf.CheckSignatureFrom(ca.Leaf) != nil {
+		return false
+	}
+
+	return slices.Equal(cert.Leaf.DNSNames, dnsNames)
+}
+
+func expiresSoon(cert *x509.Certificate) bool {
+	return time.Now().Add(renewBefore).After(cert.NotAfter)
+}
+
+func parseKeyPair(secret *corev1.Secret) (tls.Certificate, error) {
+	return tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+}
+
+// generateCertificate creates a key pair for the template, signed by the
+// given CA or self-signed when no CA is given
+func generateCertificate(template *x509.Certificate, ca *tls.Certificate) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate private key: %w", err)
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+	template.SerialNumber = serialNumber
+	template.NotBefore = time.Now().Add(-time.Hour)
+
+	parent, signer := template, any(key)
+	if ca != nil {
+		parent, signer = ca.Leaf, ca.PrivateKey
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal private key: %w", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		nil
+}
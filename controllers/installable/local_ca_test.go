@@ -0,0 +1,141 @@
+package installable_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/installable"
+	"github.com/kyma-project/cfapi/tools/k8s"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("LocalCA", func() {
+	var (
+		localCA *installable.LocalCA
+		config  v1alpha1.InstallationConfig
+
+		result     installable.Result
+		installErr error
+	)
+
+	getCertificate := func(name string) *x509.Certificate {
+		GinkgoHelper()
+
+		secret := &corev1.Secret{}
+		Expect(adminClient.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: name}, secret)).To(Succeed())
+		keyPair, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+		Expect(err).NotTo(HaveOccurred())
+
+		caPool := x509.NewCertPool()
+		Expect(caPool.AppendCertsFromPEM(secret.Data["ca.crt"])).To(BeTrue())
+		_, err = keyPair.Leaf.Verify(x509.VerifyOptions{Roots: caPool})
+		Expect(err).NotTo(HaveOccurred())
+
+		return keyPair.Leaf
+	}
+
+	BeforeEach(func() {
+		config = v1alpha1.InstallationConfig{
+			CFDomain: "127.0.0.1.nip.io",
+		}
+		localCA = installable.NewLocalCA(adminClient, testNamespace, testNamespace)
+	})
+
+	JustBeforeEach(func() {
+		result, installErr = localCA.Install(ctx, config, eventRecorder)
+	})
+
+	It("succeeds", func() {
+		Expect(installErr).NotTo(HaveOccurred())
+		Expect(result).To(Equal(installable.Result{
+			State:   installable.ResultStateSuccess,
+			Message: "Local certificates issued successfully",
+		}))
+	})
+
+	It("issues the korifi certificates from the local CA", func() {
+		Expect(getCertificate("korifi-api-ingress-cert").DNSNames).To(ConsistOf("cfapi.127.0.0.1.nip.io"))
+		Expect(getCertificate("korifi-workloads-ingress-cert").DNSNames).To(ConsistOf("*.apps.127.0.0.1.nip.io"))
+		Expect(getCertificate("korifi-api-internal-cert").DNSNames).To(ConsistOf(
+			"korifi-api-svc." + testNamespace + ".svc.cluster.local",
+		))
+		Expect(getCertificate("korifi-controllers-webhook-cert").DNSNames).To(ConsistOf(
+			"korifi-controllers-webhook-service."+testNamespace+".svc",
+			"korifi-controllers-webhook-service."+testNamespace+".svc.cluster.local",
+		))
+	})
+
+	When("installed again", func() {
+		var serialNumber string
+
+		JustBeforeEach(func() {
+			serialNumber = getCertificate("korifi-api-ingress-cert").SerialNumber.String()
+
+			result, installErr = localCA.Install(ctx, config, eventRecorder)
+			Expect(installErr).NotTo(HaveOccurred())
+		})
+
+		It("keeps the existing certificates", func() {
+			Expect(getCertificate("korifi-api-ingress-cert").SerialNumber.String()).To(Equal(serialNumber))
+		})
+
+		When("the CF domain changes", func() {
+			JustBeforeEach(func() {
+				config.CFDomain = "10.0.0.1.sslip.io"
+				result, installErr = localCA.Install(ctx, config, eventRecorder)
+				Expect(installErr).NotTo(HaveOccurred())
+			})
+
+			It("reissues the ingress certificates", func() {
+				Expect(getCertificate("korifi-api-ingress-cert").DNSNames).To(ConsistOf("cfapi.10.0.0.1.sslip.io"))
+				Expect(getCertificate("korifi-workloads-ingress-cert").DNSNames).To(ConsistOf("*.apps.10.0.0.1.sslip.io"))
+			})
+		})
+	})
+
+	When("the CA is replaced", func() {
+		JustBeforeEach(func() {
+			caSecret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: testNamespace,
+					Name:      installable.LocalCASecretName,
+				},
+			}
+			Expect(k8s.Patch(ctx, adminClient, caSecret, func() {
+				caSecret.Data = map[string][]byte{}
+			})).To(Succeed())
+
+			result, installErr = localCA.Install(ctx, config, eventRecorder)
+			Expect(installErr).NotTo(HaveOccurred())
+		})
+
+		It("reissues the certificates from the new CA", func() {
+			caSecret := &corev1.Secret{}
+			Expect(adminClient.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: installable.LocalCASecretName}, caSecret)).To(Succeed())
+
+			certSecret := &corev1.Secret{}
+			Expect(adminClient.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: "korifi-api-ingress-cert"}, certSecret)).To(Succeed())
+			Expect(certSecret.Data["ca.crt"]).To(Equal(caSecret.Data[corev1.TLSCertKey]))
+			getCertificate("korifi-api-ingress-cert")
+		})
+	})
+
+	When("uninstalled", func() {
+		JustBeforeEach(func() {
+			result, installErr = localCA.Uninstall(ctx, config, eventRecorder)
+			Expect(installErr).NotTo(HaveOccurred())
+		})
+
+		It("deletes the CA and the certificates", func() {
+			for _, name := range []string{installable.LocalCASecretName, "korifi-api-ingress-cert", "korifi-controllers-webhook-cert"} {
+				err := adminClient.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: name}, &corev1.Secret{})
+				Expect(err).To(MatchError(ContainSubstring("not found")))
+			}
+		})
+	})
+})
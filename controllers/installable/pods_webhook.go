@@ -0,0 +1,219 @@
+package installable
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"slices"
+	"time"
+
+	korifiv1alpha1 "code.cloudfoundry.org/korifi/controllers/api/v1alpha1"
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/tools"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// PodsWebhookName is the mutating webhook configuration of the pods in CF
+	// spaces
+	PodsWebhookName = "cfapi-pods"
+	// PodsWebhookCertSecretName is the secret of the serving certificate of
+	// the webhook, which the operator serves
+	PodsWebhookCertSecretName = "cfapi-pods-webhook-cert"
+	// PodsWebhookPath is the path the operator serves the webhook at
+	PodsWebhookPath = "/mutate-pods"
+
+	// the webhook registered by earlier versions, which only rewrote images
+	legacyImagesWebhookName           = "cfapi-images"
+	legacyImagesWebhookCertSecretName = "cfapi-images-webhook-cert"
+)
+
+// PodsWebhook registers the webhook of the operator mutating the app, task
+// and build pods in CF spaces. It points their images to the address the
+// nodes pull from, when images are pushed to an in-cluster registry address
+// the nodes cannot resolve, mounts the trusted CA bundle into them and sets
+// the proxy variables of builds. The webhook is only registered while a pull
+// address, a trusted CA bundle or a proxy is configured
+type PodsWebhook struct {
+	k8sClient   client.Client
+	namespace   string
+	serviceName string
+}
+
+func NewPodsWebhook(k8sClient client.Client, namespace, serviceName string) *PodsWebhook {
+	return &PodsWebhook{
+		k8sClient:   k8sClient,
+		namespace:   namespace,
+		serviceName: serviceName,
+	}
+}
+
+func (w *PodsWebhook) Name() string {
+	return "Pods Webhook Installable"
+}
+
+func (w *PodsWebhook) Install(ctx context.Context, config v1alpha1.InstallationConfig, eventRecorder EventRecorder) (Result, error) {
+	if config.ContainerRegistryPullURL == "" && config.TrustedCA == nil && config.Proxy == nil {
+		return w.Uninstall(ctx, config, eventRecorder)
+	}
+
+	if err := w.deleteLegacyWebhook(ctx); err != nil {
+		eventRecorder.Event(EventWarning, "InstallableFailed", fmt.Sprintf("Installable %s failed", w.Name()))
+		return Result{}, fmt.Errorf("failed to delete the legacy pods webhook: %w", err)
+	}
+
+	caPEM, err := w.ensureCertificate(ctx)
+	if err != nil {
+		eventRecorder.Event(EventWarning, "InstallableFailed", fmt.Sprintf("Installable %s failed", w.Name()))
+		return Result{}, fmt.Errorf("failed to ensure the serving certificate of the pods webhook: %w", err)
+	}
+
+	webhookConfig := &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: PodsWebhookName},
+	}
+	if _, err := controllerutil.CreateOrPatch(ctx, w.k8sClient, webhookConfig, func() error {
+		webhookConfig.Webhooks = []admissionregistrationv1.MutatingWebhook{{
+			Name: "pods.cfapi.kyma-project.io",
+			ClientConfig: admissionregistrationv1.WebhookClientConfig{
+				Service: &admissionregistrationv1.ServiceReference{
+					Namespace: w.namespace,
+					Name:      w.serviceName,
+					Path:      tools.PtrTo(PodsWebhookPath),
+				},
+				CABundle: caPEM,
+			},
+			Rules: []admissionregistrationv1.RuleWithOperations{{
+				Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create},
+				Rule: admissionregistrationv1.Rule{
+					APIGroups:   []string{""},
+					APIVersions: []string{"v1"},
+					Resources:   []string{"pods"},
+				},
+			}},
+			// app, task and build pods run in the space namespaces
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{
+					Key:      korifiv1alpha1.SpaceGUIDLabelKey,
+					Operator: metav1.LabelSelectorOpExists,
+				}},
+			},
+			FailurePolicy:           tools.PtrTo(admissionregistrationv1.Fail),
+			SideEffects:             tools.PtrTo(admissionregistrationv1.SideEffectClassNone),
+			AdmissionReviewVersions: []string{"v1"},
+			TimeoutSeconds:          tools.PtrTo(int32(10)),
+		}}
+		return nil
+	}); err != nil {
+		eventRecorder.Event(EventWarning, "InstallableFailed", fmt.Sprintf("Installable %s failed", w.Name()))
+		return Result{}, fmt.Errorf("failed to register the pods webhook: %w", err)
+	}
+
+	eventRecorder.Event(EventNormal, "InstallableDeployed", fmt.Sprintf("Installable %s deployed", w.Name()))
+	return Result{
+		State:   ResultStateSuccess,
+		Message: "Pods webhook registered successfully",
+	}, nil
+}
+
+// ensureCertificate issues the self-signed serving certificate of the webhook
+// unless a valid one exists and returns it as the CA bundle of the webhook
+func (w *PodsWebhook) ensureCertificate(ctx context.Context) ([]byte, error) {
+	dnsNames := []string{
+		w.serviceName + "." + w.namespace + ".svc",
+		w.serviceName + "." + w.namespace + ".svc.cluster.local",
+	}
+
+	certSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: w.namespace,
+			Name:      PodsWebhookCertSecretName,
+		},
+	}
+	err := w.k8sClient.Get(ctx, client.ObjectKeyFromObject(certSecret), certSecret)
+	if client.IgnoreNotFound(err) != nil {
+		return nil, err
+	}
+
+	if err == nil {
+		cert, parseErr := parseKeyPair(certSecret)
+		if parseErr == nil && !expiresSoon(cert.Leaf) && slices.Equal(cert.Leaf.DNSNames, dnsNames) {
+			return certSecret.Data[corev1.TLSCertKey], nil
+		}
+	}
+
+	certPEM, keyPEM, err := generateCertificate(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:              dnsNames,
+		NotAfter:              time.Now().Add(certificateValidity),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = controllerutil.CreateOrPatch(ctx, w.k8sClient, certSecret, func() error {
+		certSecret.Type = corev1.SecretTypeTLS
+		certSecret.Data = map[string][]byte{
+			corev1.TLSCertKey:       certPEM,
+			corev1.TLSPrivateKeyKey: keyPEM,
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return certPEM, nil
+}
+
+func (w *PodsWebhook) Uninstall(ctx context.Context, config v1alpha1.InstallationConfig, eventRecorder EventRecorder) (Result, error) {
+	if err := w.deleteLegacyWebhook(ctx); err != nil {
+		eventRecorder.Event(EventWarning, "InstallableFailed", fmt.Sprintf("Uninstalling %s failed", w.Name()))
+		return Result{}, fmt.Errorf("failed to delete the legacy images webhook: %w", err)
+	}
+
+	for _, obj := range []client.Object{
+		&admissionregistrationv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: PodsWebhookName},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: w.namespace, Name: PodsWebhookCertSecretName},
+		},
+	} {
+		if err := client.IgnoreNotFound(w.k8sClient.Delete(ctx, obj)); err != nil {
+			eventRecorder.Event(EventWarning, "InstallableFailed", fmt.Sprintf("Uninstalling %s failed", w.Name()))
+			return Result{}, fmt.Errorf("failed to delete %s: %w", obj.GetName(), err)
+		}
+	}
+
+	return Result{
+		State:   ResultStateSuccess,
+		Message: "Pods webhook deleted successfully",
+	}, nil
+}
+
+// deleteLegacyWebhook deletes the images webhook registered by earlier
+// versions, whose path the operator no longer serves
+func (w *PodsWebhook) deleteLegacyWebhook(ctx context.Context) error {
+	for _, obj := range []client.Object{
+		&admissionregistrationv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: legacyImagesWebhookName},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: w.namespace, Name: legacyImagesWebhookCertSecretName},
+		},
+	} {
+		if err := client.IgnoreNotFound(w.k8sClient.Delete(ctx, obj)); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", obj.GetName(), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package installable_test
+
+import (
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/installable"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("PodsWebhook", func() {
+	var (
+		podsWebhook *installable.PodsWebhook
+		config      v1alpha1.InstallationConfig
+
+		result     installable.Result
+		installErr error
+	)
+
+	getWebhookConfig := func() (*admissionregistrationv1.MutatingWebhookConfiguration, error) {
+		webhookConfig := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		err := adminClient.Get(ctx, client.ObjectKey{Name: installable.PodsWebhookName}, webhookConfig)
+		return webhookConfig, err
+	}
+
+	BeforeEach(func() {
+		config = v1alpha1.InstallationConfig{
+			ContainerRegistryURL:     "dockerregistry.kyma-system.svc.cluster.local:5000",
+			ContainerRegistryPullURL: "localhost:32137",
+		}
+		podsWebhook = installable.NewPodsWebhook(adminClient, testNamespace, "webhook-service")
+	})
+
+	JustBeforeEach(func() {
+		result, installErr = podsWebhook.Install(ctx, config, eventRecorder)
+	})
+
+	AfterEach(func() {
+		_, err := podsWebhook.Uninstall(ctx, config, eventRecorder)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("registers the webhook with the serving certificate", func() {
+		Expect(installErr).NotTo(HaveOccurred())
+		Expect(result).To(Equal(installable.Result{
+			State:   installable.ResultStateSuccess,
+			Message: "Pods webhook registered successfully",
+		}))
+
+		certSecret := &corev1.Secret{}
+		Expect(adminClient.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: installable.PodsWebhookCertSecretName}, certSecret)).To(Succeed())
+
+		webhookConfig, err := getWebhookConfig()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(webhookConfig.Webhooks).To(HaveLen(1))
+		Expect(webhookConfig.Webhooks[0].ClientConfig.CABundle).To(Equal(certSecret.Data[corev1.TLSCertKey]))
+		Expect(webhookConfig.Webhooks[0].ClientConfig.Service.Namespace).To(Equal(testNamespace))
+		Expect(*webhookConfig.Webhooks[0].ClientConfig.Service.Path).To(Equal(installable.PodsWebhookPath))
+	})
+
+	When("the webhook of an earlier version is registered", func() {
+		BeforeEach(func() {
+			Expect(adminClient.Create(ctx, &admissionregistrationv1.MutatingWebhookConfiguration{
+				ObjectMeta: metav1.ObjectMeta{Name: "cfapi-images"},
+			})).To(Succeed())
+		})
+
+		It("deletes it", func() {
+			Expect(installErr).NotTo(HaveOccurred())
+
+			err := adminClient.Get(ctx, client.ObjectKey{Name: "cfapi-images"}, &admissionregistrationv1.MutatingWebhookConfiguration{})
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	When("only a trusted CA bundle is configured", func() {
+		BeforeEach(func() {
+			config.ContainerRegistryPullURL = ""
+			config.TrustedCA = &v1alpha1.TrustedCA{}
+		})
+
+		It("registers the webhook", func() {
+			Expect(installErr).NotTo(HaveOccurred())
+
+			_, err := getWebhookConfig()
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	When("only a proxy is configured", func() {
+		BeforeEach(func() {
+			config.ContainerRegistryPullURL = ""
+			config.Proxy = &v1alpha1.ProxyConfig{HTTPProxy: "http://proxy.example.com:3128"}
+		})
+
+		It("registers the webhook", func() {
+			Expect(installErr).NotTo(HaveOccurred())
+
+			_, err := getWebhookConfig()
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	When("neither a pull address, a trusted CA bundle nor a proxy is configured", func() {
+		BeforeEach(func() {
+			_, err := podsWebhook.Install(ctx, config, eventRecorder)
+			Expect(err).NotTo(HaveOccurred())
+
+			config.ContainerRegistryPullURL = ""
+		})
+
+		It("deletes the webhook", func() {
+			Expect(installErr).NotTo(HaveOccurred())
+
+			_, err := getWebhookConfig()
+			Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+})
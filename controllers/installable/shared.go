@@ -0,0 +1,200 @@
+package installable
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+const (
+	// InstalledByLabel marks the objects of yaml installables the operator
+	// created or adopted. Of shared installables, only those are updated and
+	// deleted by the operator
+	InstalledByLabel = "cfapi.kyma-project.io/installed-by"
+	InstalledByValue = "cfapi-operator"
+
+	// AdoptAnnotation allows the operator to take over an object that is
+	// managed by another manager
+	AdoptAnnotation = "cfapi.kyma-project.io/adopt"
+
+	// FieldOwner is the field manager of the objects applied by the operator
+	FieldOwner = "cfapi-operator"
+
+	gatewayAPIBundleVersionAnnotation = "gateway.networking.k8s.io/bundle-version"
+)
+
+// legacyFieldManagers are the field managers of objects created by operator
+// versions that did not label their objects yet
+var legacyFieldManagers = []string{FieldOwner, "manager"}
+
+type SharedObjectDecision string
+
+const (
+	// SharedObjectAdopted objects are taken over and from then on managed by the operator
+	SharedObjectAdopted SharedObjectDecision = "Adopted"
+	// SharedObjectSkipped objects are compatible and used as they are
+	SharedObjectSkipped SharedObjectDecision = "Skipped"
+	// SharedObjectRefused objects are incompatible and block the installation
+	SharedObjectRefused SharedObjectDecision = "Refused"
+)
+
+// SharedObject describes an object of an installable that already exists on
+// the cluster and is managed by someone else
+type SharedObject struct {
+	Kind     string
+	Name     string
+	Manager  string
+	Decision SharedObjectDecision
+	Reason   string
+}
+
+func (o SharedObject) String() string {
+	description := fmt.Sprintf("%s %s managed by %s", o.Kind, o.Name, o.Manager)
+	if o.Reason == "" {
+		return description
+	}
+	return fmt.Sprintf("%s (%s)", description, o.Reason)
+}
+
+func isInstalledByOperator(obj *unstructured.Unstructured) bool {
+	return obj.GetLabels()[InstalledByLabel] == InstalledByValue
+}
+
+func isMarkedForAdoption(obj *unstructured.Unstructured) bool {
+	return obj.GetAnnotations()[AdoptAnnotation] == "true"
+}
+
+// foreignManager returns the manager of an existing object that was not
+// created by the operator, or an empty string if the object is the operator's.
+// The manager is detected from the common labels and annotations of package
+// managers and GitOps tools, falling back to the field managers of the
+// object. Unlabelled objects of earlier operator versions are recognised by
+// the field manager of the operator, as controllers and webhooks write fields
+// of them as well
+func foreignManager(obj *unstructured.Unstructured) string {
+	if isInstalledByOperator(obj) {
+		return ""
+	}
+
+	labels, annotations := obj.GetLabels(), obj.GetAnnotations()
+	switch {
+	case annotations["meta.helm.sh/release-name"] != "":
+		return fmt.Sprintf("Helm release %s/%s", annotations["meta.helm.sh/release-namespace"], annotations["meta.helm.sh/release-name"])
+	case labels["app.kubernetes.io/managed-by"] == "Helm":
+		return "Helm"
+	case labels["argocd.argoproj.io/instance"] != "" || annotations["argocd.argoproj.io/tracking-id"] != "":
+		return "Argo CD"
+	case labels["kustomize.toolkit.fluxcd.io/name"] != "" || labels["helm.toolkit.fluxcd.io/name"] != "":
+		return "Flux"
+	case hasKeyPrefix(labels, "kapp.k14s.io/") || hasKeyPrefix(annotations, "kapp.k14s.io/"):
+		return "kapp"
+	}
+
+	managers := []string{}
+	for _, entry := range obj.GetManagedFields() {
+		if slices.Contains(legacyFieldManagers, entry.Manager) {
+			return ""
+		}
+		// status is written by controllers and the API server, not by installers
+		if entry.Subresource != "" || slices.Contains(managers, entry.Manager) {
+			continue
+		}
+		managers = append(managers, entry.Manager)
+	}
+
+	if len(managers) == 0 {
+		return "an unknown manager"
+	}
+	return strings.Join(managers, ", ")
+}
+
+func hasKeyPrefix(values map[string]string, prefix string) bool {
+	for key := range values {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCRDCompatibility verifies that an existing CRD can be used instead of
+// the desired one. It has to serve every version the desired CRD serves and
+// must not be of an older Gateway API bundle
+func checkCRDCompatibility(existing, desired *unstructured.Unstructured) error {
+	existingCRD, err := toCRD(existing)
+	if err != nil {
+		return err
+	}
+
+	desiredCRD, err := toCRD(desired)
+	if err != nil {
+		return err
+	}
+
+	missingVersions := []string{}
+	for _, desiredVersion := range servedVersions(desiredCRD) {
+		if !slices.Contains(servedVersions(existingCRD), desiredVersion) {
+			missingVersions = append(missingVersions, desiredVersion)
+		}
+	}
+	if len(missingVersions) > 0 {
+		return fmt.Errorf("versions %s are not served", strings.Join(missingVersions, ", "))
+	}
+
+	return checkBundleVersion(existingCRD.Annotations[gatewayAPIBundleVersionAnnotation], desiredCRD.Annotations[gatewayAPIBundleVersionAnnotation])
+}
+
+// checkAdoption verifies that replacing an existing CRD with the desired one
+// does not stop serving versions other users may store resources in
+func checkAdoption(existing, desired *unstructured.Unstructured) error {
+	return checkCRDCompatibility(desired, existing)
+}
+
+func checkBundleVersion(existingBundleVersion, desiredBundleVersion string) error {
+	if existingBundleVersion == "" || desiredBundleVersion == "" {
+		return nil
+	}
+
+	existingVersion, err := version.ParseGeneric(existingBundleVersion)
+	if err != nil {
+		return fmt.Errorf("invalid bundle version %q: %w", existingBundleVersion, err)
+	}
+
+	desiredVersion, err := version.ParseGeneric(desiredBundleVersion)
+	if err != nil {
+		return fmt.Errorf("invalid bundle version %q: %w", desiredBundleVersion, err)
+	}
+
+	if existingVersion.LessThan(desiredVersion.WithPatch(0)) {
+		return fmt.Errorf("bundle version %s is older than the required %s", existingBundleVersion, desiredBundleVersion)
+	}
+
+	return nil
+}
+
+func toCRD(obj *unstructured.Unstructured) (*apiextv1.CustomResourceDefinition, error) {
+	crd := &apiextv1.CustomResourceDefinition{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, crd); err != nil {
+		return nil, fmt.Errorf("failed to convert %s to a CRD: %w", obj.GetName(), err)
+	}
+	return crd, nil
+}
+
+func servedVersions(crd *apiextv1.CustomResourceDefinition) []string {
+	versions := []string{}
+	for _, v := range crd.Spec.Versions {
+		if v.Served {
+			versions = append(versions, v.Name)
+		}
+	}
+	return versions
+}
+
+func isCRD(obj *unstructured.Unstructured) bool {
+	return obj.GroupVersionKind().GroupKind() == apiextv1.Kind("CustomResourceDefinition")
+}
@@ -0,0 +1,324 @@
+package installable_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/installable"
+	"github.com/kyma-project/cfapi/tests/helpers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	corev1 "k8s.io/api/core/v1"
+	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Shared Yaml", func() {
+	var (
+		group      string
+		sharedYaml *installable.Yaml
+
+		result installable.Result
+		err    error
+	)
+
+	crdName := func() string {
+		return "widgets." + group
+	}
+
+	crdVersion := func(name string) apiextv1.CustomResourceDefinitionVersion {
+		return apiextv1.CustomResourceDefinitionVersion{
+			Name:    name,
+			Served:  true,
+			Storage: name == "v1",
+			Schema: &apiextv1.CustomResourceValidation{
+				OpenAPIV3Schema: &apiextv1.JSONSchemaProps{Type: "object"},
+			},
+		}
+	}
+
+	createForeignCRD := func(annotations map[string]string, versions ...string) {
+		crd := &apiextv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        crdName(),
+				Annotations: annotations,
+			},
+			Spec: apiextv1.CustomResourceDefinitionSpec{
+				Group: group,
+				Names: apiextv1.CustomResourceDefinitionNames{
+					Plural:   "widgets",
+					Singular: "widget",
+					Kind:     "Widget",
+					ListKind: "WidgetList",
+				},
+				Scope: apiextv1.ClusterScoped,
+			},
+		}
+		for _, version := range versions {
+			crd.Spec.Versions = append(crd.Spec.Versions, crdVersion(version))
+		}
+		// like kubectl does
+		Expect(adminClient.Create(ctx, crd, client.FieldOwner("kubectl-client-side-apply"))).To(Succeed())
+		Eventually(func(g Gomega) {
+			g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(crd), crd)).To(Succeed())
+		}).Should(Succeed())
+	}
+
+	getCRD := func() *apiextv1.CustomResourceDefinition {
+		crd := &apiextv1.CustomResourceDefinition{}
+		Expect(adminClient.Get(ctx, client.ObjectKey{Name: crdName()}, crd)).To(Succeed())
+		return crd
+	}
+
+	BeforeEach(func() {
+		group = uuid.NewString()[:8] + ".example.com"
+
+		yamlFile, fileErr := os.CreateTemp("", "")
+		Expect(fileErr).NotTo(HaveOccurred())
+		DeferCleanup(func() {
+			Expect(os.RemoveAll(yamlFile.Name())).To(Succeed())
+		})
+
+		_, fileErr = io.WriteString(yamlFile, fmt.Sprintf(`apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.%[1]s
+spec:
+  group: %[1]s
+  names:
+    kind: Widget
+    listKind: WidgetList
+    plural: widgets
+    singular: widget
+  scope: Cluster
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: shared-map
+  namespace: %[2]s
+`, group, testNamespace))
+		Expect(fileErr).NotTo(HaveOccurred())
+
+		sharedYaml = installable.NewSharedYaml(adminClient, yamlFile.Name(), "shared")
+	})
+
+	Describe("Install", func() {
+		JustBeforeEach(func() {
+			result, err = sharedYaml.Install(ctx, v1alpha1.InstallationConfig{}, eventRecorder)
+		})
+
+		It("creates the objects labelled as installed by the operator", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.State).To(Equal(installable.ResultStateSuccess))
+			Expect(result.SharedObjects).To(BeEmpty())
+			Expect(getCRD().Labels).To(HaveKeyWithValue(installable.InstalledByLabel, installable.InstalledByValue))
+		})
+
+		When("a compatible CRD is managed by another manager", func() {
+			BeforeEach(func() {
+				createForeignCRD(map[string]string{"meta.helm.sh/release-name": "widgets", "meta.helm.sh/release-namespace": "default"}, "v1alpha1", "v1")
+			})
+
+			It("uses it as it is", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.State).To(Equal(installable.ResultStateSuccess))
+				Expect(result.SharedObjects).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+					"Kind":     Equal("CustomResourceDefinition"),
+					"Name":     Equal(crdName()),
+					"Manager":  Equal("Helm release default/widgets"),
+					"Decision": Equal(installable.SharedObjectSkipped),
+				})))
+
+				crd := getCRD()
+				Expect(crd.Labels).NotTo(HaveKey(installable.InstalledByLabel))
+				Expect(crd.Spec.Versions).To(HaveLen(2))
+			})
+		})
+
+		When("an incompatible CRD is managed by another manager", func() {
+			BeforeEach(func() {
+				createForeignCRD(map[string]string{"meta.helm.sh/release-name": "widgets"}, "v1alpha1")
+			})
+
+			It("refuses to install", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.State).To(Equal(installable.ResultStateFailed))
+				Expect(result.Message).To(ContainSubstring("versions v1 are not served"))
+				Expect(result.SharedObjects).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+					"Decision": Equal(installable.SharedObjectRefused),
+				})))
+			})
+
+			It("does not apply any object", func() {
+				Expect(getCRD().Labels).NotTo(HaveKey(installable.InstalledByLabel))
+				getErr := adminClient.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: "shared-map"}, &corev1.ConfigMap{})
+				Expect(k8serrors.IsNotFound(getErr)).To(BeTrue())
+			})
+		})
+
+		When("a CRD of another manager is marked for adoption", func() {
+			BeforeEach(func() {
+				createForeignCRD(map[string]string{"meta.helm.sh/release-name": "widgets", installable.AdoptAnnotation: "true"}, "v1")
+			})
+
+			It("takes it over", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.State).To(Equal(installable.ResultStateSuccess))
+				Expect(result.SharedObjects).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+					"Decision": Equal(installable.SharedObjectAdopted),
+				})))
+				Expect(getCRD().Labels).To(HaveKeyWithValue(installable.InstalledByLabel, installable.InstalledByValue))
+			})
+		})
+
+		When("adopting a CRD of another manager would stop serving a version", func() {
+			BeforeEach(func() {
+				createForeignCRD(map[string]string{"meta.helm.sh/release-name": "widgets", installable.AdoptAnnotation: "true"}, "v1alpha1", "v1")
+			})
+
+			It("refuses to adopt it", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.State).To(Equal(installable.ResultStateFailed))
+				Expect(result.SharedObjects).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+					"Decision": Equal(installable.SharedObjectRefused),
+					"Reason":   ContainSubstring("cannot be adopted: versions v1alpha1 are not served"),
+				})))
+				Expect(getCRD().Labels).NotTo(HaveKey(installable.InstalledByLabel))
+			})
+		})
+
+		When("an object of an earlier operator version is not labelled", func() {
+			BeforeEach(func() {
+				configMap := &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: testNamespace,
+						Name:      "shared-map",
+					},
+				}
+				Expect(adminClient.Create(ctx, configMap, client.FieldOwner(installable.FieldOwner))).To(Succeed())
+
+				// controllers and webhooks write fields of the objects as well
+				configMap.Annotations = map[string]string{"touched-by": "controller"}
+				Expect(adminClient.Update(ctx, configMap, client.FieldOwner("kube-controller-manager"))).To(Succeed())
+			})
+
+			It("takes it over as its own", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.SharedObjects).To(BeEmpty())
+
+				configMap := &corev1.ConfigMap{}
+				Expect(adminClient.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: "shared-map"}, configMap)).To(Succeed())
+				Expect(configMap.Labels).To(HaveKeyWithValue(installable.InstalledByLabel, installable.InstalledByValue))
+			})
+		})
+
+		When("a CRD was applied with kubectl", func() {
+			BeforeEach(func() {
+				createForeignCRD(nil, "v1")
+			})
+
+			It("treats it as managed by another manager", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.SharedObjects).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+					"Kind":     Equal("CustomResourceDefinition"),
+					"Manager":  Equal("kubectl-client-side-apply"),
+					"Decision": Equal(installable.SharedObjectSkipped),
+				})))
+				Expect(getCRD().Labels).NotTo(HaveKey(installable.InstalledByLabel))
+			})
+		})
+
+		When("a non CRD object is managed by another manager", func() {
+			BeforeEach(func() {
+				helpers.EnsureCreate(adminClient, &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: testNamespace,
+						Name:      "shared-map",
+						Labels:    map[string]string{"argocd.argoproj.io/instance": "shared"},
+						Annotations: map[string]string{
+							"owner": "someone-else",
+						},
+					},
+				})
+			})
+
+			It("leaves it untouched", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.SharedObjects).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+					"Kind":     Equal("ConfigMap"),
+					"Manager":  Equal("Argo CD"),
+					"Decision": Equal(installable.SharedObjectSkipped),
+				})))
+
+				configMap := &corev1.ConfigMap{}
+				Expect(adminClient.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: "shared-map"}, configMap)).To(Succeed())
+				Expect(configMap.Labels).NotTo(HaveKey(installable.InstalledByLabel))
+				Expect(configMap.Annotations).To(HaveKeyWithValue("owner", "someone-else"))
+			})
+		})
+	})
+
+	Describe("Uninstall", func() {
+		JustBeforeEach(func() {
+			result, err = sharedYaml.Uninstall(ctx, v1alpha1.InstallationConfig{}, eventRecorder)
+		})
+
+		When("the objects were installed by the operator", func() {
+			BeforeEach(func() {
+				result, err = sharedYaml.Install(ctx, v1alpha1.InstallationConfig{}, eventRecorder)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("deletes them", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.State).To(Equal(installable.ResultStateInProgress))
+			})
+		})
+
+		When("a CRD was applied with kubectl", func() {
+			BeforeEach(func() {
+				createForeignCRD(nil, "v1")
+			})
+
+			It("does not delete it", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(getCRD().DeletionTimestamp).To(BeNil())
+			})
+		})
+
+		When("the objects are managed by another manager", func() {
+			BeforeEach(func() {
+				createForeignCRD(map[string]string{"meta.helm.sh/release-name": "widgets"}, "v1")
+				helpers.EnsureCreate(adminClient, &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: testNamespace,
+						Name:      "shared-map",
+						Labels:    map[string]string{"argocd.argoproj.io/instance": "shared"},
+					},
+				})
+			})
+
+			It("does not delete them", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.State).To(Equal(installable.ResultStateSuccess))
+
+				Expect(getCRD().DeletionTimestamp).To(BeNil())
+				Expect(adminClient.Get(ctx, client.ObjectKey{Namespace: testNamespace, Name: "shared-map"}, &corev1.ConfigMap{})).To(Succeed())
+			})
+		})
+	})
+})
@@ -0,0 +1,121 @@
+package installable
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/trustedca"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// TrustedCA copies the trusted CA bundle to the namespaces of the installed
+// components, where their workloads mount it. It has to be installed before
+// the components, so that they are rolled out with the current bundle
+type TrustedCA struct {
+	k8sClient  client.Client
+	namespaces []string
+}
+
+func NewTrustedCA(k8sClient client.Client, namespaces ...string) *TrustedCA {
+	return &TrustedCA{
+		k8sClient:  k8sClient,
+		namespaces: namespaces,
+	}
+}
+
+func (t *TrustedCA) Name() string {
+	return "Trusted CA Installable"
+}
+
+func (t *TrustedCA) Install(ctx context.Context, config v1alpha1.InstallationConfig, eventRecorder EventRecorder) (Result, error) {
+	if config.TrustedCA == nil {
+		return t.Uninstall(ctx, config, eventRecorder)
+	}
+
+	source := &corev1.ConfigMap{}
+	err := t.k8sClient.Get(ctx, client.ObjectKey{Namespace: config.TrustedCA.Namespace, Name: config.TrustedCA.Name}, source)
+	if err != nil {
+		eventRecorder.Event(EventWarning, "InstallableFailed", fmt.Sprintf("Installable %s failed", t.Name()))
+		return Result{}, fmt.Errorf("failed to get trusted CA bundle %s/%s: %w", config.TrustedCA.Namespace, config.TrustedCA.Name, err)
+	}
+
+	missingNamespaces := []string{}
+	for _, namespace := range t.namespaces {
+		if err := t.k8sClient.Get(ctx, client.ObjectKey{Name: namespace}, &corev1.Namespace{}); err != nil {
+			if k8serrors.IsNotFound(err) {
+				missingNamespaces = append(missingNamespaces, namespace)
+				continue
+			}
+			return Result{}, fmt.Errorf("failed to get namespace %s: %w", namespace, err)
+		}
+
+		if err := t.ensureCopy(ctx, namespace, source.Data[config.TrustedCA.Key], config.TrustedCA.Hash); err != nil {
+			eventRecorder.Event(EventWarning, "InstallableFailed", fmt.Sprintf("Installable %s failed", t.Name()))
+			return Result{}, err
+		}
+	}
+
+	// the namespaces are created by the components installed later on, whose
+	// pods wait for the bundle to be copied
+	if len(missingNamespaces) > 0 {
+		return Result{
+			State:   ResultStateInProgress,
+			Message: fmt.Sprintf("waiting for namespaces %s to copy the trusted CA bundle to", strings.Join(missingNamespaces, ", ")),
+		}, nil
+	}
+
+	eventRecorder.Event(EventNormal, "InstallableDeployed", fmt.Sprintf("Installable %s deployed", t.Name()))
+	return Result{
+		State:   ResultStateSuccess,
+		Message: "Trusted CA bundle copied successfully",
+	}, nil
+}
+
+func (t *TrustedCA) ensureCopy(ctx context.Context, namespace, bundle, hash string) error {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      trustedca.ConfigMapName,
+		},
+	}
+
+	_, err := controllerutil.CreateOrPatch(ctx, t.k8sClient, configMap, func() error {
+		if configMap.Annotations == nil {
+			configMap.Annotations = map[string]string{}
+		}
+		configMap.Annotations[trustedca.HashAnnotation] = hash
+		configMap.Data = map[string]string{trustedca.BundleKey: bundle}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy the trusted CA bundle to namespace %s: %w", namespace, err)
+	}
+
+	return nil
+}
+
+func (t *TrustedCA) Uninstall(ctx context.Context, config v1alpha1.InstallationConfig, eventRecorder EventRecorder) (Result, error) {
+	for _, namespace := range t.namespaces {
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      trustedca.ConfigMapName,
+			},
+		}
+		if err := client.IgnoreNotFound(t.k8sClient.Delete(ctx, configMap)); err != nil {
+			eventRecorder.Event(EventWarning, "InstallableFailed", fmt.Sprintf("Uninstalling %s failed", t.Name()))
+			return Result{}, fmt.Errorf("failed to delete the trusted CA bundle in namespace %s: %w", namespace, err)
+		}
+	}
+
+	return Result{
+		State:   ResultStateSuccess,
+		Message: "Trusted CA bundle deleted successfully",
+	}, nil
+}
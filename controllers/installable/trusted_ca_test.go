@@ -0,0 +1,116 @@
+package installable_test
+
+import (
+	"github.com/google/uuid"
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/installable"
+	"github.com/kyma-project/cfapi/controllers/trustedca"
+	. "github.com/kyma-project/cfapi/tests/helpers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("TrustedCA", func() {
+	var (
+		trustedCA        *installable.TrustedCA
+		config           v1alpha1.InstallationConfig
+		missingNamespace string
+
+		result     installable.Result
+		installErr error
+	)
+
+	getCopy := func(namespace string) (*corev1.ConfigMap, error) {
+		configMap := &corev1.ConfigMap{}
+		err := adminClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: trustedca.ConfigMapName}, configMap)
+		return configMap, err
+	}
+
+	BeforeEach(func() {
+		EnsureCreate(adminClient, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: testNamespace,
+				Name:      "corporate-ca",
+			},
+			Data: map[string]string{"bundle.pem": "my-bundle"},
+		})
+
+		config = v1alpha1.InstallationConfig{
+			TrustedCA: &v1alpha1.TrustedCA{
+				Namespace: testNamespace,
+				Name:      "corporate-ca",
+				Key:       "bundle.pem",
+				Hash:      "abc123",
+			},
+		}
+		missingNamespace = uuid.NewString()
+		trustedCA = installable.NewTrustedCA(adminClient, testNamespace)
+	})
+
+	JustBeforeEach(func() {
+		result, installErr = trustedCA.Install(ctx, config, eventRecorder)
+	})
+
+	It("copies the bundle to the namespaces", func() {
+		Expect(installErr).NotTo(HaveOccurred())
+		Expect(result.State).To(Equal(installable.ResultStateSuccess))
+
+		configMap, err := getCopy(testNamespace)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(configMap.Data).To(Equal(map[string]string{trustedca.BundleKey: "my-bundle"}))
+		Expect(configMap.Annotations).To(HaveKeyWithValue(trustedca.HashAnnotation, "abc123"))
+	})
+
+	When("a namespace does not exist yet", func() {
+		BeforeEach(func() {
+			trustedCA = installable.NewTrustedCA(adminClient, testNamespace, missingNamespace)
+		})
+
+		It("copies the bundle to the existing namespaces and waits for the others", func() {
+			Expect(installErr).NotTo(HaveOccurred())
+			Expect(result).To(Equal(installable.Result{
+				State:   installable.ResultStateInProgress,
+				Message: "waiting for namespaces " + missingNamespace + " to copy the trusted CA bundle to",
+			}))
+
+			_, err := getCopy(testNamespace)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	When("the bundle is removed from the config", func() {
+		JustBeforeEach(func() {
+			config.TrustedCA = nil
+			result, installErr = trustedCA.Install(ctx, config, eventRecorder)
+		})
+
+		It("deletes the copies", func() {
+			Expect(installErr).NotTo(HaveOccurred())
+			Expect(result.State).To(Equal(installable.ResultStateSuccess))
+
+			Eventually(func(g Gomega) {
+				_, err := getCopy(testNamespace)
+				g.Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+			}).Should(Succeed())
+		})
+	})
+
+	When("uninstalled", func() {
+		JustBeforeEach(func() {
+			result, installErr = trustedCA.Uninstall(ctx, config, eventRecorder)
+		})
+
+		It("deletes the copies", func() {
+			Expect(installErr).NotTo(HaveOccurred())
+
+			Eventually(func(g Gomega) {
+				_, err := getCopy(testNamespace)
+				g.Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+			}).Should(Succeed())
+		})
+	})
+})
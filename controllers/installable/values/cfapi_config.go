@@ -14,6 +14,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const oidcGroupsClaim = "groups"
+
 type CFAPIConfig struct {
 	k8sClient client.Client
 }
@@ -25,24 +27,44 @@ func NewCFAPIConfig(client client.Client) *CFAPIConfig {
 }
 
 func (k *CFAPIConfig) GetValues(ctx context.Context, config v1alpha1.InstallationConfig) (map[string]any, error) {
-	korifiIngressHost, err := k.getKorifiIngressHost(ctx, config.KorifiIngressService)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get korifi ingress host: %w", err)
+	korifiIngressHost := config.IngressHost
+	switch {
+	case config.GatewayCutoverAddress != "":
+		// the cutover gateway serves the CF API while the gateway type is switched
+		korifiIngressHost = config.GatewayCutoverAddress
+	// the kyma gateway is exposed and resolved by kyma itself
+	case config.GatewayType != v1alpha1.GatewayTypeIstioNative && (config.IngressMode == "" || config.IngressMode == v1alpha1.IngressModeLoadBalancer):
+		var err error
+		korifiIngressHost, err = k.getKorifiIngressHost(ctx, config.KorifiIngressNamespace, config.KorifiIngressService)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get korifi ingress host: %w", err)
+		}
 	}
 
 	return map[string]any{
-		"cfDomain":          config.CFDomain,
-		"korifiIngressHost": korifiIngressHost,
-		"uaaUrl":            config.UAAURL,
-		"rootNamespace":     config.RootNamespace,
-		"cfapiAdmins":       slices.Collect(it.Map(slices.Values(config.CFAdmins), withOIDCPrefix)),
+		"profile":                   config.Profile,
+		"cfDomain":                  config.CFDomain,
+		"korifiIngressHost":         korifiIngressHost,
+		"gatewayType":               config.GatewayType,
+		"istioGateway":              config.IstioGateway,
+		"istioAppsGateway":          config.IstioAppsGateway,
+		"useSelfSignedCertificates": config.UseSelfSignedCertificates,
+		"uaaUrl":                    config.UAAURL,
+		"rootNamespace":             config.RootNamespace,
+		"cfapiAdmins":               slices.Collect(it.Map(slices.Values(config.CFAdmins), withPrefix(config.OIDCUsernamePrefix))),
+		"cfapiAdminGroups":          slices.Collect(it.Map(slices.Values(config.CFAdminGroups), withPrefix(config.OIDCGroupsPrefix))),
+		"oidc": map[string]any{
+			"usernamePrefix": config.OIDCUsernamePrefix,
+			"groupsClaim":    oidcGroupsClaim,
+			"groupsPrefix":   config.OIDCGroupsPrefix,
+		},
 	}, nil
 }
 
-func (k *CFAPIConfig) getKorifiIngressHost(ctx context.Context, korifiIngressServiceName string) (string, error) {
+func (k *CFAPIConfig) getKorifiIngressHost(ctx context.Context, korifiIngressServiceNamespace, korifiIngressServiceName string) (string, error) {
 	korifiIngressService := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Namespace: "cfapi-system",
+			Namespace: korifiIngressServiceNamespace,
 			Name:      korifiIngressServiceName,
 		},
 	}
@@ -64,10 +86,11 @@ func (k *CFAPIConfig) getKorifiIngressHost(ctx context.Context, korifiIngressSer
 	return hostname, nil
 }
 
-func withOIDCPrefix(user string) any {
-	if !strings.HasPrefix(user, "sap.ids:") {
-		return "sap.ids:" + user
+func withPrefix(prefix string) func(string) any {
+	return func(name string) any {
+		if !strings.HasPrefix(name, prefix) {
+			return prefix + name
+		}
+		return name
 	}
-
-	return user
 }
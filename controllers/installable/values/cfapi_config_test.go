@@ -23,11 +23,15 @@ var _ = Describe("CFAPI Config", func() {
 
 	BeforeEach(func() {
 		instCfg = v1alpha1.InstallationConfig{
-			KorifiIngressService: "contour-envoy",
-			CFDomain:             "korifi.example.com",
-			UAAURL:               "https://uaa.example.com",
-			RootNamespace:        "my-root-ns",
-			CFAdmins:             []string{"cf-admin@example.com"},
+			KorifiIngressService:   "contour-envoy",
+			KorifiIngressNamespace: "cfapi-system",
+			CFDomain:               "korifi.example.com",
+			UAAURL:                 "https://uaa.example.com",
+			OIDCUsernamePrefix:     "sap.ids:",
+			OIDCGroupsPrefix:       "sap.ids.groups:",
+			RootNamespace:          "my-root-ns",
+			CFAdmins:               []string{"cf-admin@example.com"},
+			CFAdminGroups:          []string{"cf-admin-group"},
 		}
 
 		ingressService = &corev1.Service{
@@ -62,11 +66,20 @@ var _ = Describe("CFAPI Config", func() {
 	It("returns helm values", func() {
 		Expect(getValuesErr).NotTo(HaveOccurred())
 		Expect(helmValues).To(MatchAllKeys(Keys{
+			"profile":           Equal(""),
+			"gatewayType":       Equal(""),
+			"istioGateway":      Equal(""),
 			"cfDomain":          Equal("korifi.example.com"),
 			"korifiIngressHost": Equal("contour-enoy"),
 			"uaaUrl":            Equal("https://uaa.example.com"),
 			"rootNamespace":     Equal("my-root-ns"),
 			"cfapiAdmins":       ConsistOf(Equal("sap.ids:cf-admin@example.com")),
+			"cfapiAdminGroups":  ConsistOf(Equal("sap.ids.groups:cf-admin-group")),
+			"oidc": MatchAllKeys(Keys{
+				"usernamePrefix": Equal("sap.ids:"),
+				"groupsClaim":    Equal("groups"),
+				"groupsPrefix":   Equal("sap.ids.groups:"),
+			}),
 		}))
 	})
 
@@ -83,6 +96,19 @@ var _ = Describe("CFAPI Config", func() {
 		})
 	})
 
+	When("the admin group is prefixed with sap.ids.groups", func() {
+		BeforeEach(func() {
+			instCfg.CFAdminGroups = []string{"sap.ids.groups:cf-admin-group"}
+		})
+
+		It("does not add the prefix again", func() {
+			Expect(getValuesErr).NotTo(HaveOccurred())
+			Expect(helmValues).To(MatchKeys(IgnoreExtras, Keys{
+				"cfapiAdminGroups": ConsistOf(Equal("sap.ids.groups:cf-admin-group")),
+			}))
+		})
+	})
+
 	When("the korifi ingress service has an IP instead of a hostname", func() {
 		BeforeEach(func() {
 			Expect(k8s.Patch(ctx, adminClient, ingressService, func() {
@@ -102,6 +128,103 @@ var _ = Describe("CFAPI Config", func() {
 		})
 	})
 
+	When("the korifi ingress service is provided by an external gateway", func() {
+		BeforeEach(func() {
+			externalService := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: testNamepace,
+					Name:      "envoy-korifi",
+				},
+				Spec: corev1.ServiceSpec{
+					Type: corev1.ServiceTypeLoadBalancer,
+					Ports: []corev1.ServicePort{{
+						Port: 443,
+					}},
+				},
+			}
+			helpers.EnsureCreate(adminClient, externalService)
+			Expect(k8s.Patch(ctx, adminClient, externalService, func() {
+				externalService.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{
+					Hostname: "envoy-korifi.example.com",
+				}}
+			})).To(Succeed())
+
+			instCfg.GatewayType = v1alpha1.GatewayTypeExternal
+			instCfg.KorifiIngressNamespace = testNamepace
+			instCfg.KorifiIngressService = "envoy-korifi"
+		})
+
+		It("returns its host as the korifi ingress host", func() {
+			Expect(getValuesErr).NotTo(HaveOccurred())
+			Expect(helmValues).To(MatchKeys(IgnoreExtras, Keys{
+				"korifiIngressHost": Equal("envoy-korifi.example.com"),
+			}))
+		})
+	})
+
+	When("the ingress mode is NodePort", func() {
+		BeforeEach(func() {
+			instCfg.IngressMode = v1alpha1.IngressModeNodePort
+			instCfg.IngressHost = "node.example.com"
+			instCfg.KorifiIngressService = "non-existent-service"
+		})
+
+		It("returns the configured host as the korifi ingress host", func() {
+			Expect(getValuesErr).NotTo(HaveOccurred())
+			Expect(helmValues).To(MatchKeys(IgnoreExtras, Keys{
+				"korifiIngressHost": Equal("node.example.com"),
+			}))
+		})
+	})
+
+	When("the ingress mode is Static", func() {
+		BeforeEach(func() {
+			instCfg.IngressMode = v1alpha1.IngressModeStatic
+			instCfg.IngressHost = "10.0.0.1"
+		})
+
+		It("returns the configured host as the korifi ingress host", func() {
+			Expect(getValuesErr).NotTo(HaveOccurred())
+			Expect(helmValues).To(MatchKeys(IgnoreExtras, Keys{
+				"korifiIngressHost": Equal("10.0.0.1"),
+			}))
+		})
+	})
+
+	When("the gateway type is being migrated to a cutover gateway", func() {
+		BeforeEach(func() {
+			instCfg.GatewayCutoverAddress = "10.0.0.2"
+			instCfg.KorifiIngressService = "non-existent-service"
+		})
+
+		It("returns the cutover address as the korifi ingress host", func() {
+			Expect(getValuesErr).NotTo(HaveOccurred())
+			Expect(helmValues).To(MatchKeys(IgnoreExtras, Keys{
+				"korifiIngressHost": Equal("10.0.0.2"),
+			}))
+		})
+	})
+
+	When("the gateway type is istio-native", func() {
+		BeforeEach(func() {
+			instCfg.GatewayType = v1alpha1.GatewayTypeIstioNative
+			instCfg.IstioGateway = "kyma-system/kyma-gateway"
+			instCfg.IstioAppsGateway = "cfapi-system/cfapi-apps"
+			instCfg.KorifiIngressService = ""
+			instCfg.KorifiIngressNamespace = ""
+		})
+
+		It("does not look up the korifi ingress host", func() {
+			Expect(getValuesErr).NotTo(HaveOccurred())
+			Expect(helmValues).To(MatchKeys(IgnoreExtras, Keys{
+				"gatewayType":       Equal("istio-native"),
+				"istioGateway":      Equal("kyma-system/kyma-gateway"),
+				"istioAppsGateway":  Equal("cfapi-system/cfapi-apps"),
+				"korifiIngressHost": BeEmpty(),
+			}))
+		})
+	})
+
 	When("the korifi ingress service does not exist", func() {
 		BeforeEach(func() {
 			instCfg.KorifiIngressService = "non-existent-service"
@@ -1,15 +1,54 @@
 package values
 
-var ContourValues = Override{
-	"gatewayAPI": map[string]any{
-		"manageCRDs": false,
-	},
-	"configInline": map[string]any{
-		"gateway": map[string]any{
-			"gatewayRef": map[string]any{
-				"name":      "korifi",
-				"namespace": "cfapi-system",
+import (
+	"context"
+	"maps"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+)
+
+type Contour struct{}
+
+func NewContour() *Contour {
+	return &Contour{}
+}
+
+func (c *Contour) GetValues(ctx context.Context, config v1alpha1.InstallationConfig) (map[string]any, error) {
+	values := map[string]any{
+		"gatewayAPI": map[string]any{
+			"manageCRDs": false,
+		},
+		"configInline": map[string]any{
+			"gateway": map[string]any{
+				"gatewayRef": map[string]any{
+					"name":      contourGateway(config),
+					"namespace": "cfapi-system",
+				},
 			},
 		},
-	},
+	}
+
+	if config.IngressMode == v1alpha1.IngressModeNodePort {
+		maps.Copy(values, map[string]any{
+			"envoy": map[string]any{
+				"service": map[string]any{
+					"type": "NodePort",
+					"nodePorts": map[string]any{
+						"https": config.IngressPort,
+					},
+				},
+			},
+		})
+	}
+
+	return values, nil
+}
+
+// contourGateway returns the gateway served by contour. Contour only serves a
+// single gateway, which is the cutover gateway while migrating to contour
+func contourGateway(config v1alpha1.InstallationConfig) string {
+	if config.PreviousGatewayType != "" && config.GatewayClassName != v1alpha1.GatewayTypeContour {
+		return v1alpha1.CutoverGatewayName
+	}
+	return "korifi"
 }
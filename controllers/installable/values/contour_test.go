@@ -0,0 +1,108 @@
+package values_test
+
+import (
+	v1alpha1 "github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/installable/values"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+)
+
+var _ = Describe("Contour", func() {
+	var (
+		instCfg    v1alpha1.InstallationConfig
+		helmValues map[string]any
+		err        error
+	)
+
+	BeforeEach(func() {
+		instCfg = v1alpha1.InstallationConfig{
+			IngressMode: v1alpha1.IngressModeLoadBalancer,
+			IngressPort: 443,
+		}
+	})
+
+	JustBeforeEach(func() {
+		helmValues, err = values.NewContour().GetValues(ctx, instCfg)
+	})
+
+	It("returns helm values", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(helmValues).To(MatchAllKeys(Keys{
+			"gatewayAPI": MatchAllKeys(Keys{
+				"manageCRDs": BeFalse(),
+			}),
+			"configInline": MatchAllKeys(Keys{
+				"gateway": MatchAllKeys(Keys{
+					"gatewayRef": MatchAllKeys(Keys{
+						"name":      Equal("korifi"),
+						"namespace": Equal("cfapi-system"),
+					}),
+				}),
+			}),
+		}))
+	})
+
+	When("migrating from contour", func() {
+		BeforeEach(func() {
+			instCfg.GatewayType = v1alpha1.GatewayTypeIstio
+			instCfg.PreviousGatewayType = v1alpha1.GatewayTypeContour
+			instCfg.GatewayClassName = "contour"
+		})
+
+		It("keeps serving the korifi gateway", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(helmValues).To(MatchKeys(IgnoreExtras, Keys{
+				"configInline": MatchAllKeys(Keys{
+					"gateway": MatchAllKeys(Keys{
+						"gatewayRef": MatchKeys(IgnoreExtras, Keys{
+							"name": Equal("korifi"),
+						}),
+					}),
+				}),
+			}))
+		})
+	})
+
+	When("migrating to contour", func() {
+		BeforeEach(func() {
+			instCfg.GatewayType = v1alpha1.GatewayTypeContour
+			instCfg.PreviousGatewayType = v1alpha1.GatewayTypeIstio
+			instCfg.GatewayClassName = "istio"
+		})
+
+		It("serves the cutover gateway", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(helmValues).To(MatchKeys(IgnoreExtras, Keys{
+				"configInline": MatchAllKeys(Keys{
+					"gateway": MatchAllKeys(Keys{
+						"gatewayRef": MatchKeys(IgnoreExtras, Keys{
+							"name": Equal(v1alpha1.CutoverGatewayName),
+						}),
+					}),
+				}),
+			}))
+		})
+	})
+
+	When("the ingress mode is NodePort", func() {
+		BeforeEach(func() {
+			instCfg.IngressMode = v1alpha1.IngressModeNodePort
+			instCfg.IngressPort = 30443
+		})
+
+		It("exposes envoy on the node port", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(helmValues).To(MatchKeys(IgnoreExtras, Keys{
+				"envoy": MatchAllKeys(Keys{
+					"service": MatchAllKeys(Keys{
+						"type": Equal("NodePort"),
+						"nodePorts": MatchAllKeys(Keys{
+							"https": Equal(int32(30443)),
+						}),
+					}),
+				}),
+			}))
+		})
+	})
+})
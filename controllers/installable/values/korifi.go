@@ -2,14 +2,28 @@ package values
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"slices"
 
+	korifiv1alpha1 "code.cloudfoundry.org/korifi/controllers/api/v1alpha1"
 	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/helm"
+	"helm.sh/helm/v3/pkg/postrender"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// TrustedCAVCAPApplicationKey is the key of the trusted CA bundle in the
+// VCAP_APPLICATION environment of CF apps and their builds
+const TrustedCAVCAPApplicationKey = "trusted_ca_certificates"
+
+// defaultDomainName is the CFDomain the chart creates for the default domain
+// of apps
+const defaultDomainName = "default-domain"
+
 type Korifi struct {
 	k8sClient        client.Client
 	releaseNamespace string
@@ -27,36 +41,134 @@ func (k *Korifi) GetValues(ctx context.Context, config v1alpha1.InstallationConf
 		return nil, fmt.Errorf("failed to ensure required certificate secrets: %w", err)
 	}
 
-	return map[string]any{
+	appsDomain, err := k.appsDomain(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the default domain of apps: %w", err)
+	}
+
+	values := map[string]any{
 		"systemNamespace":              "cfapi-system",
 		"adminUserName":                "cf-admin",
 		"generateInternalCertificates": false,
-		"containerRegistrySecrets":     []any{config.ContainerRegistrySecret},
-		"containerRepositoryPrefix":    config.ContainerRepositoryPrefix,
-		"defaultAppDomainName":         "apps." + config.CFDomain,
+		"containerRegistrySecrets":     registrySecrets(config),
+		"containerRepositoryPrefix":    config.DropletRepositoryPrefix,
+		"defaultAppDomainName":         appsDomain,
 		"api": map[string]any{
 			"apiServer": map[string]any{
-				"url": "cfapi." + config.CFDomain,
+				"url":  "cfapi." + config.CFDomain,
+				"port": config.IngressPort,
 			},
 			"uaaURL": config.UAAURL,
 		},
 		"kpackImageBuilder": map[string]any{
-			"builderRepository": config.BuilderRepository,
+			"builderRepository":  config.BuilderRepository,
+			"clusterBuilderName": clusterBuilderName(config),
+		},
+		"stagingRequirements": map[string]any{
+			"buildCacheMB": config.BuildCacheMB,
+			"memoryMB":     config.BuildMemoryMB,
+			"diskMB":       config.BuildDiskMB,
 		},
 		"networking": map[string]any{
 			"gatewayNamespace": "cfapi-system",
-			"gatewayClass":     config.GatewayType,
+			"gatewayClass":     config.GatewayClassName,
 		},
 		"experimental": map[string]any{
 			"managedServices": map[string]any{
 				"enabled": true,
 			},
 			"uaa": map[string]any{
-				"enabled": true,
+				"enabled": config.UAAURL != "",
 				"url":     config.UAAURL,
 			},
 		},
-	}, nil
+	}
+
+	if config.TrustedCA != nil {
+		bundle, err := k.trustedCABundle(ctx, config.TrustedCA)
+		if err != nil {
+			return nil, err
+		}
+		values["controllers"] = map[string]any{
+			"extraVCAPApplicationValues": map[string]any{
+				TrustedCAVCAPApplicationKey: bundle,
+			},
+		}
+	}
+
+	return values, nil
+}
+
+// trustedCABundle returns the trusted CA bundle as a quoted YAML string, as
+// the chart renders the extra VCAP_APPLICATION values into the controllers
+// config as they are
+func (k *Korifi) trustedCABundle(ctx context.Context, trustedCA *v1alpha1.TrustedCA) (string, error) {
+	source := &corev1.ConfigMap{}
+	if err := k.k8sClient.Get(ctx, client.ObjectKey{Namespace: trustedCA.Namespace, Name: trustedCA.Name}, source); err != nil {
+		return "", fmt.Errorf("failed to get trusted CA bundle %s/%s: %w", trustedCA.Namespace, trustedCA.Name, err)
+	}
+
+	quoted, err := json.Marshal(source.Data[trustedCA.Key])
+	if err != nil {
+		return "", fmt.Errorf("failed to quote trusted CA bundle: %w", err)
+	}
+	return string(quoted), nil
+}
+
+// GetPostRenderer sets the repository prefix of package images in the CF API
+// config, as the chart uses the droplets repository prefix for both the CF API
+// and the kpack image builder
+func (k *Korifi) GetPostRenderer(config v1alpha1.InstallationConfig) postrender.PostRenderer {
+	if config.PackageRepositoryPrefix == config.DropletRepositoryPrefix {
+		return nil
+	}
+
+	return helm.SetConfigMapConfig("korifi-api-config", "korifi_api_config.yaml", map[string]any{
+		"containerRepositoryPrefix": config.PackageRepositoryPrefix,
+	})
+}
+
+// registrySecrets returns the distinct registry secrets of package images,
+// droplet images and the builder image, and the pull secrets of the image
+// mirror, which the build pods need to pull the buildpacks and the stack
+func registrySecrets(config v1alpha1.InstallationConfig) []any {
+	secrets := []any{}
+	for _, secret := range append([]string{config.PackageRegistrySecret, config.DropletRegistrySecret, config.BuilderRegistrySecret}, config.ImagePullSecrets...) {
+		if !slices.Contains(secrets, any(secret)) {
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets
+}
+
+// clusterBuilderName returns the builder of the installation, or nothing for
+// the chart to render its own builder
+func clusterBuilderName(config v1alpha1.InstallationConfig) string {
+	if len(config.BuildpackOrder) == 0 {
+		return ""
+	}
+	return config.ClusterBuilderName
+}
+
+// appsDomain returns the default domain of CF apps. Apps get a subdomain of
+// their own, so that their hosts cannot collide with the CF API or other hosts
+// of the CF domain, which is the kyma domain with the istio-native gateway
+// type. Korifi neither allows renaming the default domain nor keeps its routes
+// when it is deleted, so istio-native installations that exposed apps directly
+// under the CF domain before keep doing so
+func (k *Korifi) appsDomain(ctx context.Context, config v1alpha1.InstallationConfig) (string, error) {
+	if config.GatewayType == v1alpha1.GatewayTypeIstioNative {
+		defaultDomain := &korifiv1alpha1.CFDomain{}
+		err := k.k8sClient.Get(ctx, client.ObjectKey{Namespace: config.RootNamespace, Name: defaultDomainName}, defaultDomain)
+		if client.IgnoreNotFound(err) != nil && !meta.IsNoMatchError(err) {
+			return "", err
+		}
+		if err == nil && defaultDomain.Spec.Name == config.CFDomain {
+			return config.CFDomain, nil
+		}
+	}
+
+	return "apps." + config.CFDomain, nil
 }
 
 func (k *Korifi) ensureCertificateSecrets(ctx context.Context) error {
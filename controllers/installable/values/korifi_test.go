@@ -1,6 +1,9 @@
 package values_test
 
 import (
+	"bytes"
+
+	korifiv1alpha1 "code.cloudfoundry.org/korifi/controllers/api/v1alpha1"
 	v1alpha1 "github.com/kyma-project/cfapi/api/v1alpha1"
 	"github.com/kyma-project/cfapi/controllers/installable/values"
 	"github.com/kyma-project/cfapi/tests/helpers"
@@ -26,9 +29,18 @@ var _ = Describe("Korifi", func() {
 			ContainerRegistryURL:      "my-registry.com",
 			ContainerRepositoryPrefix: "my-registry.com/",
 			BuilderRepository:         "my-registry.com/cfapi/kpack-builder",
+			PackageRegistrySecret:     "my-registry-secret",
+			PackageRepositoryPrefix:   "my-registry.com/",
+			DropletRegistrySecret:     "my-registry-secret",
+			DropletRepositoryPrefix:   "my-registry.com/",
+			BuilderRegistrySecret:     "my-registry-secret",
 			UAAURL:                    "https://uaa.example.com",
 			CFDomain:                  "korifi.example.com",
 			GatewayType:               "contour",
+			GatewayClassName:          "contour",
+			IngressPort:               443,
+			ClusterBuilderName:        "cf-kpack-cluster-builder",
+			BuildCacheMB:              2048,
 		}
 
 		korifi = values.NewKorifi(adminClient, testNamepace)
@@ -62,12 +74,19 @@ var _ = Describe("Korifi", func() {
 			"defaultAppDomainName":         Equal("apps.korifi.example.com"),
 			"api": MatchAllKeys(Keys{
 				"apiServer": MatchAllKeys(Keys{
-					"url": Equal("cfapi." + instCfg.CFDomain),
+					"url":  Equal("cfapi." + instCfg.CFDomain),
+					"port": Equal(int32(443)),
 				}),
 				"uaaURL": Equal(instCfg.UAAURL),
 			}),
 			"kpackImageBuilder": MatchAllKeys(Keys{
-				"builderRepository": Equal("my-registry.com/cfapi/kpack-builder"),
+				"builderRepository":  Equal("my-registry.com/cfapi/kpack-builder"),
+				"clusterBuilderName": BeEmpty(),
+			}),
+			"stagingRequirements": MatchAllKeys(Keys{
+				"buildCacheMB": Equal(int64(2048)),
+				"memoryMB":     Equal(int64(0)),
+				"diskMB":       Equal(int64(0)),
 			}),
 			"networking": MatchAllKeys(Keys{
 				"gatewayNamespace": Equal("cfapi-system"),
@@ -85,6 +104,138 @@ var _ = Describe("Korifi", func() {
 		}))
 	})
 
+	It("does not modify the rendered chart", func() {
+		Expect(korifi.GetPostRenderer(instCfg)).To(BeNil())
+	})
+
+	When("an image mirror with pull secrets is configured", func() {
+		BeforeEach(func() {
+			instCfg.ImageRewrites = []v1alpha1.ImageRewrite{{From: "docker.io/", To: "mirror.example.com/docker/"}}
+			instCfg.ImagePullSecrets = []string{"mirror-secret", "my-registry-secret"}
+		})
+
+		It("adds the pull secrets to the registry secrets", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(helmValues).To(MatchKeys(IgnoreExtras, Keys{
+				"containerRegistrySecrets": Equal([]any{"my-registry-secret", "mirror-secret"}),
+			}))
+		})
+	})
+
+	When("packages, droplets and the builder use separate registries", func() {
+		BeforeEach(func() {
+			instCfg.PackageRegistrySecret = "packages-secret"
+			instCfg.PackageRepositoryPrefix = "packages.com/korifi/"
+			instCfg.DropletRegistrySecret = "droplets-secret"
+			instCfg.DropletRepositoryPrefix = "droplets.com/korifi/"
+			instCfg.BuilderRegistrySecret = "packages-secret"
+			instCfg.BuilderRepository = "packages.com/cfapi/kpack-builder"
+		})
+
+		It("passes all registry secrets and the droplets repository prefix", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(helmValues).To(MatchKeys(IgnoreExtras, Keys{
+				"containerRegistrySecrets":  Equal([]any{"packages-secret", "droplets-secret"}),
+				"containerRepositoryPrefix": Equal("droplets.com/korifi/"),
+				"kpackImageBuilder": MatchKeys(IgnoreExtras, Keys{
+					"builderRepository": Equal("packages.com/cfapi/kpack-builder"),
+				}),
+			}))
+		})
+
+		It("sets the packages repository prefix in the CF API config", func() {
+			postRenderer := korifi.GetPostRenderer(instCfg)
+			Expect(postRenderer).NotTo(BeNil())
+
+			rendered, err := postRenderer.Run(bytes.NewBufferString(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: korifi-api-config
+data:
+  korifi_api_config.yaml: |
+    externalFQDN: cfapi.korifi.example.com
+    containerRepositoryPrefix: "droplets.com/korifi/"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: korifi-controllers-config
+data:
+  config.yaml: |
+    containerRepositoryPrefix: "droplets.com/korifi/"
+`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rendered.String()).To(SatisfyAll(
+				ContainSubstring("containerRepositoryPrefix: packages.com/korifi/"),
+				ContainSubstring("externalFQDN: cfapi.korifi.example.com"),
+				ContainSubstring(`containerRepositoryPrefix: "droplets.com/korifi/"`),
+			))
+		})
+	})
+
+	When("a custom builder and build resources are configured", func() {
+		BeforeEach(func() {
+			instCfg.ClusterBuilderName = "cfapi-cluster-builder"
+			instCfg.BuildpackOrder = []v1alpha1.BuildpackGroup{{Group: []v1alpha1.BuildpackRef{{ID: "paketo-buildpacks/java"}}}}
+			instCfg.BuildCacheMB = 4096
+			instCfg.BuildMemoryMB = 2000
+			instCfg.BuildDiskMB = 4000
+		})
+
+		It("uses the custom builder and requests the build resources", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(helmValues).To(MatchKeys(IgnoreExtras, Keys{
+				"kpackImageBuilder": MatchKeys(IgnoreExtras, Keys{
+					"clusterBuilderName": Equal("cfapi-cluster-builder"),
+				}),
+				"stagingRequirements": MatchAllKeys(Keys{
+					"buildCacheMB": Equal(int64(4096)),
+					"memoryMB":     Equal(int64(2000)),
+					"diskMB":       Equal(int64(4000)),
+				}),
+			}))
+		})
+	})
+
+	When("a trusted CA bundle is configured", func() {
+		BeforeEach(func() {
+			helpers.EnsureCreate(adminClient, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: testNamepace,
+					Name:      "corporate-ca",
+				},
+				Data: map[string]string{
+					"ca.crt": "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n",
+				},
+			})
+			instCfg.TrustedCA = &v1alpha1.TrustedCA{
+				Namespace: testNamepace,
+				Name:      "corporate-ca",
+				Key:       "ca.crt",
+				Hash:      "abc123",
+			}
+		})
+
+		It("adds the quoted bundle to the VCAP_APPLICATION of apps", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(helmValues).To(HaveKeyWithValue("controllers", MatchAllKeys(Keys{
+				"extraVCAPApplicationValues": MatchAllKeys(Keys{
+					values.TrustedCAVCAPApplicationKey: Equal(`"-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"`),
+				}),
+			})))
+		})
+
+		When("the bundle does not exist", func() {
+			BeforeEach(func() {
+				instCfg.TrustedCA.Name = "missing-ca"
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(MatchError(ContainSubstring("failed to get trusted CA bundle")))
+			})
+		})
+	})
+
 	When("a required cert secret does not exist", func() {
 		BeforeEach(func() {
 			certSecret := &corev1.Secret{
@@ -104,6 +255,7 @@ var _ = Describe("Korifi", func() {
 	When("the gatewy type is istio", func() {
 		BeforeEach(func() {
 			instCfg.GatewayType = "istio"
+			instCfg.GatewayClassName = "istio"
 		})
 
 		It("sets networking.gatewayClass accordingly", func() {
@@ -115,4 +267,74 @@ var _ = Describe("Korifi", func() {
 			}))
 		})
 	})
+
+	When("the gateway type is external", func() {
+		BeforeEach(func() {
+			instCfg.GatewayType = v1alpha1.GatewayTypeExternal
+			instCfg.GatewayClassName = "envoy-gateway"
+		})
+
+		It("uses the configured gateway class", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(helmValues).To(MatchKeys(IgnoreExtras, Keys{
+				"networking": MatchKeys(IgnoreExtras, Keys{
+					"gatewayClass": Equal("envoy-gateway"),
+				}),
+			}))
+		})
+	})
+
+	When("the gateway type is istio-native", func() {
+		BeforeEach(func() {
+			instCfg.GatewayType = v1alpha1.GatewayTypeIstioNative
+		})
+
+		It("exposes apps under the apps subdomain of the CF domain", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(helmValues).To(MatchKeys(IgnoreExtras, Keys{
+				"defaultAppDomainName": Equal("apps.korifi.example.com"),
+			}))
+		})
+
+		When("apps have been exposed directly under the CF domain before", func() {
+			BeforeEach(func() {
+				helpers.EnsureCreate(adminClient, &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{Name: instCfg.RootNamespace},
+				})
+				helpers.EnsureCreate(adminClient, &korifiv1alpha1.CFDomain{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: instCfg.RootNamespace,
+						Name:      "default-domain",
+					},
+					Spec: korifiv1alpha1.CFDomainSpec{
+						Name: "korifi.example.com",
+					},
+				})
+			})
+
+			It("keeps the default domain, which Korifi does not allow to rename", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(helmValues).To(MatchKeys(IgnoreExtras, Keys{
+					"defaultAppDomainName": Equal("korifi.example.com"),
+				}))
+			})
+		})
+	})
+
+	When("no UAA url is configured", func() {
+		BeforeEach(func() {
+			instCfg.UAAURL = ""
+		})
+
+		It("disables the experimental UAA support", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(helmValues).To(MatchKeys(IgnoreExtras, Keys{
+				"experimental": MatchKeys(IgnoreExtras, Keys{
+					"uaa": MatchKeys(IgnoreExtras, Keys{
+						"enabled": BeFalse(),
+					}),
+				}),
+			}))
+		})
+	})
 })
@@ -3,10 +3,10 @@ package values
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	certv1alpha1 "github.com/gardener/cert-management/pkg/apis/cert/v1alpha1"
 	"github.com/kyma-project/cfapi/api/v1alpha1"
-	"github.com/kyma-project/cfapi/controllers/kyma"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -26,36 +26,61 @@ func NewPrerequisites(k8sClient client.Client) *Prerequisites {
 func (p *Prerequisites) GetValues(ctx context.Context, config v1alpha1.InstallationConfig) (map[string]any, error) {
 	systemNamespace := "kyma-system"
 
-	if err := p.ensureSelfSignedIssuer(ctx, systemNamespace); err != nil {
-		return nil, fmt.Errorf("failed to get self seigned issuer: %w", err)
-	}
-
-	propagationEnabled := config.ContainerRegistrySecret != kyma.ContainerRegistrySecretName
-	if config.DisableContainerRegistrySecretPropagation {
-		propagationEnabled = false
-	}
-
-	propagationConfig := map[string]any{
-		"enabled": propagationEnabled,
-	}
-	if propagationEnabled {
-		propagationConfig["sourceNamespace"] = "cfapi-system"
-		propagationConfig["destinationNamespace"] = config.RootNamespace
+	// the local profile issues certificates from its own CA instead of the
+	// gardener cert management
+	if config.Profile != v1alpha1.ProfileLocal {
+		if err := p.ensureSelfSignedIssuer(ctx, systemNamespace); err != nil {
+			return nil, fmt.Errorf("failed to get self seigned issuer: %w", err)
+		}
 	}
 
 	return map[string]any{
+		"profile":                   config.Profile,
 		"systemNamespace":           systemNamespace,
 		"useSelfSignedCertificates": config.UseSelfSignedCertificates,
 		"selfSignedIssuer":          selfSignedIssuerName,
 		"cfDomain":                  config.CFDomain,
 		"gatewayType":               config.GatewayType,
-		"containerRegistrySecret": map[string]any{
-			"name":        config.ContainerRegistrySecret,
-			"propagation": propagationConfig,
-		},
+		"previousGatewayType":       config.PreviousGatewayType,
+		"clusterBuilder":            clusterBuilder(config),
 	}, nil
 }
 
+// clusterBuilder returns the kpack cluster builder configured in `spec.build`.
+// It is empty when the Korifi chart renders its default builder
+func clusterBuilder(config v1alpha1.InstallationConfig) map[string]any {
+	if len(config.BuildpackOrder) == 0 {
+		return map[string]any{}
+	}
+
+	buildpacks := []any{}
+	for _, buildpack := range config.Buildpacks {
+		buildpacks = append(buildpacks, map[string]any{"image": pinImage(config, buildpack)})
+	}
+
+	order := []any{}
+	for _, group := range config.BuildpackOrder {
+		refs := []any{}
+		for _, ref := range group.Group {
+			refs = append(refs, map[string]any{"id": ref.ID, "optional": ref.Optional})
+		}
+		order = append(order, map[string]any{"group": refs})
+	}
+
+	return map[string]any{
+		"name":          config.ClusterBuilderName,
+		"tag":           config.BuilderRepository,
+		"rootNamespace": config.RootNamespace,
+		"buildpacks":    buildpacks,
+		"stack": map[string]any{
+			"id":         config.Stack.ID,
+			"buildImage": pinImage(config, config.Stack.BuildImage),
+			"runImage":   pinImage(config, config.Stack.RunImage),
+		},
+		"order": order,
+	}
+}
+
 func (p *Prerequisites) ensureSelfSignedIssuer(ctx context.Context, systemNamespace string) error {
 	selfSignedIssuer := certv1alpha1.Issuer{
 		ObjectMeta: metav1.ObjectMeta{
@@ -65,3 +90,19 @@ func (p *Prerequisites) ensureSelfSignedIssuer(ctx context.Context, systemNamesp
 	}
 	return p.k8sClient.Get(ctx, client.ObjectKeyFromObject(&selfSignedIssuer), &selfSignedIssuer)
 }
+
+// pinImage replaces the tag of an image with the digest the build updates
+// resolved it to, as kpack does not notice when a tag is moved
+func pinImage(config v1alpha1.InstallationConfig, image string) string {
+	digest, ok := config.BuildImageDigests[image]
+	if !ok {
+		return image
+	}
+
+	repository, _, _ := strings.Cut(image, "@")
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository = repository[:i]
+	}
+
+	return repository + "@" + digest
+}
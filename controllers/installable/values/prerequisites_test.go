@@ -4,7 +4,6 @@ import (
 	certv1alpha1 "github.com/gardener/cert-management/pkg/apis/cert/v1alpha1"
 	v1alpha1 "github.com/kyma-project/cfapi/api/v1alpha1"
 	"github.com/kyma-project/cfapi/controllers/installable/values"
-	"github.com/kyma-project/cfapi/controllers/kyma"
 	"github.com/kyma-project/cfapi/tests/helpers"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -34,7 +33,6 @@ var _ = Describe("Prerequisites", func() {
 		instCfg = v1alpha1.InstallationConfig{
 			CFDomain:                  "korifi.example.com",
 			UseSelfSignedCertificates: true,
-			ContainerRegistrySecret:   kyma.ContainerRegistrySecretName,
 			RootNamespace:             "my-root-ns",
 			GatewayType:               "contour",
 		}
@@ -49,51 +47,72 @@ var _ = Describe("Prerequisites", func() {
 	It("returns helm values", func() {
 		Expect(err).NotTo(HaveOccurred())
 		Expect(helmValues).To(MatchAllKeys(Keys{
+			"profile":                   Equal(""),
 			"systemNamespace":           Equal("kyma-system"),
 			"cfDomain":                  Equal("korifi.example.com"),
 			"useSelfSignedCertificates": Equal(true),
 			"selfSignedIssuer":          Equal("cfapi-self-signed-issuer"),
 			"gatewayType":               Equal("contour"),
-			"containerRegistrySecret": MatchAllKeys(Keys{
-				"name": Equal(kyma.ContainerRegistrySecretName),
-				"propagation": MatchAllKeys(Keys{
-					"enabled": Equal(false),
-				}),
-			}),
+			"previousGatewayType":       Equal(""),
+			"clusterBuilder":            BeEmpty(),
 		}))
 	})
 
-	When("the container registry secret is not the kyma registry one", func() {
+	When("a custom builder is configured", func() {
 		BeforeEach(func() {
-			instCfg.ContainerRegistrySecret = "custom-registry-secret"
+			instCfg.ClusterBuilderName = "cfapi-cluster-builder"
+			instCfg.BuilderRepository = "my-registry.com/cfapi/kpack-builder"
+			instCfg.Buildpacks = []string{"paketobuildpacks/dotnet-core"}
+			instCfg.Stack = &v1alpha1.BuildStack{
+				ID:         "io.buildpacks.stacks.jammy",
+				BuildImage: "my-registry.com/build-jammy",
+				RunImage:   "my-registry.com/run-jammy",
+			}
+			instCfg.BuildpackOrder = []v1alpha1.BuildpackGroup{{Group: []v1alpha1.BuildpackRef{
+				{ID: "paketo-buildpacks/dotnet-core"},
+				{ID: "paketo-buildpacks/procfile", Optional: true},
+			}}}
 		})
 
-		It("returns helm values", func() {
+		It("returns the cluster builder", func() {
 			Expect(err).NotTo(HaveOccurred())
 			Expect(helmValues).To(MatchKeys(IgnoreExtras, Keys{
-				"containerRegistrySecret": MatchAllKeys(Keys{
-					"name": Equal("custom-registry-secret"),
-					"propagation": MatchAllKeys(Keys{
-						"enabled":              Equal(true),
-						"sourceNamespace":      Equal("cfapi-system"),
-						"destinationNamespace": Equal("my-root-ns"),
+				"clusterBuilder": MatchAllKeys(Keys{
+					"name":          Equal("cfapi-cluster-builder"),
+					"tag":           Equal("my-registry.com/cfapi/kpack-builder"),
+					"rootNamespace": Equal("my-root-ns"),
+					"buildpacks":    Equal([]any{map[string]any{"image": "paketobuildpacks/dotnet-core"}}),
+					"stack": Equal(map[string]any{
+						"id":         "io.buildpacks.stacks.jammy",
+						"buildImage": "my-registry.com/build-jammy",
+						"runImage":   "my-registry.com/run-jammy",
 					}),
+					"order": Equal([]any{map[string]any{"group": []any{
+						map[string]any{"id": "paketo-buildpacks/dotnet-core", "optional": false},
+						map[string]any{"id": "paketo-buildpacks/procfile", "optional": true},
+					}}}),
 				}),
 			}))
 		})
 
-		When("container registry secret propagation is disabled", func() {
+		When("the build updates resolved the images", func() {
 			BeforeEach(func() {
-				instCfg.DisableContainerRegistrySecretPropagation = true
+				instCfg.Buildpacks = []string{"paketobuildpacks/dotnet-core:1.2"}
+				instCfg.BuildImageDigests = map[string]string{
+					"paketobuildpacks/dotnet-core:1.2": "sha256:aaa",
+					"my-registry.com/run-jammy":        "sha256:ccc",
+				}
 			})
 
-			It("returns helm values", func() {
+			It("pins the images to their digests", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(helmValues).To(MatchKeys(IgnoreExtras, Keys{
-					"containerRegistrySecret": MatchAllKeys(Keys{
-						"name": Equal("custom-registry-secret"),
-						"propagation": MatchAllKeys(Keys{
-							"enabled": Equal(false),
+					"clusterBuilder": MatchKeys(IgnoreExtras, Keys{
+						"buildpacks": Equal([]any{map[string]any{"image": "paketobuildpacks/dotnet-core@sha256:aaa"}}),
+						"stack": Equal(map[string]any{
+							"id":         "io.buildpacks.stacks.jammy",
+							"buildImage": "my-registry.com/build-jammy",
+							"runImage":   "my-registry.com/run-jammy@sha256:ccc",
 						}),
 					}),
 				}))
@@ -101,6 +120,21 @@ var _ = Describe("Prerequisites", func() {
 		})
 	})
 
+	When("the gateway type is being migrated", func() {
+		BeforeEach(func() {
+			instCfg.GatewayType = v1alpha1.GatewayTypeIstio
+			instCfg.PreviousGatewayType = v1alpha1.GatewayTypeContour
+		})
+
+		It("returns the previous gateway type", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(helmValues).To(MatchKeys(IgnoreExtras, Keys{
+				"gatewayType":         Equal("istio"),
+				"previousGatewayType": Equal("contour"),
+			}))
+		})
+	})
+
 	When("the self-signed issuer does not exist", func() {
 		BeforeEach(func() {
 			selfSignedIssuer := &certv1alpha1.Issuer{
@@ -116,4 +150,23 @@ var _ = Describe("Prerequisites", func() {
 			Expect(err).To(MatchError(ContainSubstring("not found")))
 		})
 	})
+
+	When("the profile is local", func() {
+		BeforeEach(func() {
+			instCfg.Profile = v1alpha1.ProfileLocal
+			helpers.EnsureDelete(adminClient, &certv1alpha1.Issuer{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "kyma-system",
+					Name:      "cfapi-self-signed-issuer",
+				},
+			})
+		})
+
+		It("does not require the self-signed issuer", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(helmValues).To(MatchKeys(IgnoreExtras, Keys{
+				"profile": Equal(v1alpha1.ProfileLocal),
+			}))
+		})
+	})
 })
@@ -11,6 +11,7 @@ import (
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 
+	korifiv1alpha1 "code.cloudfoundry.org/korifi/controllers/api/v1alpha1"
 	certv1alpha1 "github.com/gardener/cert-management/pkg/apis/cert/v1alpha1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -48,6 +49,7 @@ var _ = BeforeEach(func() {
 		ErrorIfCRDPathMissing: true,
 		CRDDirectoryPaths: []string{
 			"../../../tests/dependencies/vendor/gardener-cert-manager",
+			"../../../module-data/vendor/korifi-chart/controllers/crds",
 		},
 	}
 
@@ -55,6 +57,7 @@ var _ = BeforeEach(func() {
 	Expect(err).NotTo(HaveOccurred())
 
 	Expect(certv1alpha1.AddToScheme(testEnv.Scheme)).To(Succeed())
+	Expect(korifiv1alpha1.AddToScheme(testEnv.Scheme)).To(Succeed())
 
 	adminClient, stopClientCache = helpers.NewCachedClient(testEnv.Config)
 
@@ -9,9 +9,15 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/highavailability"
+	"github.com/kyma-project/cfapi/controllers/imagemirror"
+	"github.com/kyma-project/cfapi/controllers/proxy"
+	"github.com/kyma-project/cfapi/controllers/trustedca"
+	policyv1 "k8s.io/api/policy/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -26,6 +32,7 @@ type Yaml struct {
 	k8sClient   client.Client
 	yamlGlob    string
 	displayName string
+	shared      bool
 }
 
 func NewYaml(k8sClient client.Client, yamlGlob string, displayName string) *Yaml {
@@ -36,6 +43,20 @@ func NewYaml(k8sClient client.Client, yamlGlob string, displayName string) *Yaml
 	}
 }
 
+// NewSharedYaml creates a Yaml installable for objects that other managers
+// may have installed already, such as cluster wide CRDs. Objects of other
+// managers are used as they are when compatible, adopted when annotated with
+// `cfapi.kyma-project.io/adopt: "true"` and block the installation otherwise.
+// Only objects created or adopted by the operator are updated and deleted
+func NewSharedYaml(k8sClient client.Client, yamlGlob string, displayName string) *Yaml {
+	return &Yaml{
+		k8sClient:   k8sClient,
+		yamlGlob:    yamlGlob,
+		displayName: displayName,
+		shared:      true,
+	}
+}
+
 func (y *Yaml) Name() string {
 	return fmt.Sprintf("Yaml Installable: %s", y.displayName)
 }
@@ -48,6 +69,47 @@ func (y *Yaml) Install(ctx context.Context, config v1alpha1.InstallationConfig,
 			Message: err.Error(),
 		}, nil
 	}
+	unmirroredImages := rewriteImages(imagemirror.NewMirror(config), objects)
+	trustedCA := trustedca.NewInjector(config, SystemNamespaces...)
+	proxyInjector := proxy.NewInjector(config, SystemNamespaces...)
+	for _, obj := range objects {
+		trustedCA.Inject(obj, "")
+		proxyInjector.Inject(obj, "")
+	}
+
+	sharedObjects := []SharedObject{}
+	if !y.shared {
+		for _, obj := range objects {
+			withInstalledByLabel(obj)
+		}
+	} else {
+		objects, sharedObjects, err = y.planSharedInstall(ctx, objects)
+		if err != nil {
+			eventRecorder.Event(EventWarning, "InstallableFailed", fmt.Sprintf("Installable %s failed", y.displayName))
+			return Result{}, err
+		}
+
+		if refused := refusedObjects(sharedObjects); len(refused) > 0 {
+			eventRecorder.Event(EventWarning, "SharedObjectsRefused", fmt.Sprintf("Installable %s conflicts with existing objects: %s", y.displayName, refused))
+			return Result{
+				State:            ResultStateFailed,
+				Message:          fmt.Sprintf("%s conflicts with existing objects: %s", y.displayName, refused),
+				SharedObjects:    sharedObjects,
+				UnmirroredImages: unmirroredImages,
+			}, nil
+		}
+	}
+
+	budgets := highavailability.NewPatcher(config, SystemNamespaces...).Patch(objects, "")
+	for _, budget := range budgets {
+		objects = append(objects, withInstalledByLabel(budget))
+	}
+	if config.HighAvailability == nil {
+		if err = y.deleteBudgets(ctx, objects); err != nil {
+			eventRecorder.Event(EventWarning, "InstallableFailed", fmt.Sprintf("Installable %s failed", y.displayName))
+			return Result{}, err
+		}
+	}
 
 	for _, obj := range objects {
 		err = y.createOrUpdate(ctx, obj)
@@ -57,13 +119,107 @@ func (y *Yaml) Install(ctx context.Context, config v1alpha1.InstallationConfig,
 		}
 	}
 
+	for _, sharedObject := range sharedObjects {
+		if sharedObject.Decision == SharedObjectAdopted {
+			eventRecorder.Event(EventNormal, "SharedObjectAdopted", fmt.Sprintf("Adopted %s", sharedObject))
+		}
+	}
+
 	eventRecorder.Event(EventNormal, "InstallableDeployed", fmt.Sprintf("Installable %s deployed", y.displayName))
 	return Result{
-		State:   ResultStateSuccess,
-		Message: fmt.Sprintf("%s installed successfully", y.displayName),
+		State:            ResultStateSuccess,
+		Message:          fmt.Sprintf("%s installed successfully", y.displayName),
+		SharedObjects:    sharedObjects,
+		UnmirroredImages: unmirroredImages,
 	}, nil
 }
 
+// planSharedInstall returns the objects to apply, labelled as installed by
+// the operator, along with the decisions taken for objects of other managers
+func (y *Yaml) planSharedInstall(ctx context.Context, objects []*unstructured.Unstructured) ([]*unstructured.Unstructured, []SharedObject, error) {
+	toApply := []*unstructured.Unstructured{}
+	sharedObjects := []SharedObject{}
+
+	for _, obj := range objects {
+		existing, err := y.getExisting(ctx, obj)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get existing object %s/%s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+
+		manager := ""
+		if existing != nil {
+			manager = foreignManager(existing)
+		}
+
+		if manager == "" {
+			toApply = append(toApply, withInstalledByLabel(obj))
+			continue
+		}
+
+		sharedObject := SharedObject{Kind: obj.GetKind(), Name: obj.GetName(), Manager: manager}
+		switch {
+		case isMarkedForAdoption(existing):
+			sharedObject.Decision = SharedObjectAdopted
+			if isCRD(obj) {
+				if err := checkAdoption(existing, obj); err != nil {
+					sharedObject.Decision = SharedObjectRefused
+					sharedObject.Reason = "cannot be adopted: " + err.Error()
+				}
+			}
+			if sharedObject.Decision == SharedObjectAdopted {
+				toApply = append(toApply, withInstalledByLabel(obj))
+			}
+		case isCRD(obj):
+			sharedObject.Decision = SharedObjectSkipped
+			if err := checkCRDCompatibility(existing, obj); err != nil {
+				sharedObject.Decision = SharedObjectRefused
+				sharedObject.Reason = "incompatible: " + err.Error()
+			}
+		default:
+			sharedObject.Decision = SharedObjectSkipped
+		}
+
+		sharedObjects = append(sharedObjects, sharedObject)
+	}
+
+	return toApply, sharedObjects, nil
+}
+
+func (y *Yaml) getExisting(ctx context.Context, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(obj.GroupVersionKind())
+	err := y.k8sClient.Get(ctx, client.ObjectKeyFromObject(obj), existing)
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return existing, nil
+}
+
+func withInstalledByLabel(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[InstalledByLabel] = InstalledByValue
+	obj.SetLabels(labels)
+
+	return obj
+}
+
+func refusedObjects(sharedObjects []SharedObject) string {
+	refused := []string{}
+	for _, sharedObject := range sharedObjects {
+		if sharedObject.Decision == SharedObjectRefused {
+			refused = append(refused, sharedObject.String())
+		}
+	}
+	return strings.Join(refused, "; ")
+}
+
 func (y *Yaml) Uninstall(ctx context.Context, config v1alpha1.InstallationConfig, eventRecorder EventRecorder) (Result, error) {
 	objects, err := globToUnstructuredObjects(y.yamlGlob)
 	if err != nil {
@@ -73,6 +229,11 @@ func (y *Yaml) Uninstall(ctx context.Context, config v1alpha1.InstallationConfig
 		}, nil
 	}
 
+	if err = y.deleteBudgets(ctx, objects); err != nil {
+		eventRecorder.Event(EventWarning, "InstallableFailed", fmt.Sprintf("Uninstalling %s failed", y.displayName))
+		return Result{}, err
+	}
+
 	allDeleted := true
 	for _, obj := range objects {
 		isGone, err := y.delete(ctx, obj)
@@ -108,7 +269,7 @@ func (y *Yaml) createOrUpdate(ctx context.Context, unstructuredObj *unstructured
 	err = y.k8sClient.Get(ctx, client.ObjectKeyFromObject(&partialObj), &partialObj)
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
-			return y.k8sClient.Create(ctx, unstructuredObj)
+			return y.k8sClient.Create(ctx, unstructuredObj, client.FieldOwner(FieldOwner))
 		}
 		return fmt.Errorf("failed to get existing object %s/%s: %w", partialObj.GetNamespace(), partialObj.GetName(), err)
 	}
@@ -118,7 +279,7 @@ func (y *Yaml) createOrUpdate(ctx context.Context, unstructuredObj *unstructured
 		return fmt.Errorf("failed to set resource version for %s/%s: %w", unstructuredObj.GetNamespace(), unstructuredObj.GetName(), err)
 	}
 
-	err = y.k8sClient.Update(ctx, unstructuredObj)
+	err = y.k8sClient.Update(ctx, unstructuredObj, client.FieldOwner(FieldOwner))
 	if err != nil {
 		return fmt.Errorf("failed to update existing object: %w", err)
 	}
@@ -127,6 +288,19 @@ func (y *Yaml) createOrUpdate(ctx context.Context, unstructuredObj *unstructured
 }
 
 func (y *Yaml) delete(ctx context.Context, unstructuredObj *unstructured.Unstructured) (bool, error) {
+	if y.shared {
+		existing, err := y.getExisting(ctx, unstructuredObj)
+		if err != nil {
+			return false, err
+		}
+
+		// only objects the operator installed or adopted are deleted, any
+		// other object may be used by others
+		if existing == nil || !(isInstalledByOperator(existing) || isMarkedForAdoption(existing)) {
+			return true, nil
+		}
+	}
+
 	err := y.k8sClient.Delete(ctx, unstructuredObj)
 	if k8serrors.IsNotFound(err) {
 		return true, nil
@@ -135,6 +309,46 @@ func (y *Yaml) delete(ctx context.Context, unstructuredObj *unstructured.Unstruc
 	return false, err
 }
 
+// deleteBudgets deletes the PodDisruptionBudgets created for the deployments
+// among the objects while high availability was configured
+func (y *Yaml) deleteBudgets(ctx context.Context, objects []*unstructured.Unstructured) error {
+	for _, obj := range objects {
+		if obj.GetKind() != "Deployment" {
+			continue
+		}
+
+		budget := &policyv1.PodDisruptionBudget{}
+		err := y.k8sClient.Get(ctx, client.ObjectKeyFromObject(obj), budget)
+		if k8serrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get the PodDisruptionBudget of %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+		}
+		if budget.Labels[highavailability.Label] != "true" {
+			continue
+		}
+
+		if err := y.k8sClient.Delete(ctx, budget); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete the PodDisruptionBudget of %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+
+	return nil
+}
+
+// rewriteImages points the images of the objects to the mirror and returns
+// the images which are not pulled from it
+func rewriteImages(mirror *imagemirror.Mirror, objects []*unstructured.Unstructured) []string {
+	unmirrored := []string{}
+	for _, obj := range objects {
+		unmirrored = append(unmirrored, mirror.Rewrite(obj)...)
+	}
+
+	slices.Sort(unmirrored)
+	return slices.Compact(unmirrored)
+}
+
 func globToUnstructuredObjects(yamlGlob string) ([]*unstructured.Unstructured, error) {
 	matchedFiles, err := filepath.Glob(yamlGlob)
 	if err != nil {
@@ -8,12 +8,16 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/highavailability"
 	"github.com/kyma-project/cfapi/controllers/installable"
 	"github.com/kyma-project/cfapi/tests/helpers"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -22,6 +26,7 @@ var _ = Describe("Yaml", func() {
 	Describe("Install File", func() {
 		var (
 			yamlContent string
+			config      v1alpha1.InstallationConfig
 
 			installResult installable.Result
 			installErr    error
@@ -29,6 +34,7 @@ var _ = Describe("Yaml", func() {
 
 		BeforeEach(func() {
 			yamlContent = ""
+			config = v1alpha1.InstallationConfig{}
 		})
 
 		JustBeforeEach(func() {
@@ -42,7 +48,7 @@ var _ = Describe("Yaml", func() {
 			Expect(err).NotTo(HaveOccurred())
 
 			installResult, installErr = installable.NewYaml(adminClient, yamlFile.Name(), "test-file").
-				Install(ctx, v1alpha1.InstallationConfig{}, eventRecorder)
+				Install(ctx, config, eventRecorder)
 		})
 
 		It("succeeds for empty yaml", func() {
@@ -102,6 +108,115 @@ metadata:
 			})
 		})
 
+		When("an image mirror is configured", func() {
+			BeforeEach(func() {
+				config.ImageRewrites = []v1alpha1.ImageRewrite{{From: "ghcr.io/", To: "mirror.example.com/ghcr/"}}
+				yamlContent = fmt.Sprintf(
+					`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: mirrored-image
+  namespace: %s
+data:
+  image: ghcr.io/foo/bar:1.0
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unmirrored-image
+  namespace: %s
+data:
+  image: quay.io/foo/bar:1.0`, testNamespace, testNamespace)
+			})
+
+			It("rewrites the images", func() {
+				Expect(installErr).NotTo(HaveOccurred())
+				Expect(installResult.State).To(Equal(installable.ResultStateSuccess))
+
+				m := &corev1.ConfigMap{}
+				Expect(adminClient.Get(ctx, client.ObjectKey{Name: "mirrored-image", Namespace: testNamespace}, m)).To(Succeed())
+				Expect(m.Data).To(HaveKeyWithValue("image", "mirror.example.com/ghcr/foo/bar:1.0"))
+			})
+
+			It("reports the images no rewrite matches", func() {
+				Expect(installResult.UnmirroredImages).To(ConsistOf("quay.io/foo/bar:1.0"))
+			})
+		})
+
+		When("high availability is configured", func() {
+			var budget *policyv1.PodDisruptionBudget
+
+			BeforeEach(func() {
+				Expect(client.IgnoreAlreadyExists(adminClient.Create(ctx, &corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{Name: "kpack"},
+				}))).To(Succeed())
+
+				config.HighAvailability = &v1alpha1.HighAvailability{Replicas: 2, ZoneSpread: v1alpha1.ZoneSpreadPreferred}
+				yamlContent = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kpack-webhook
+  namespace: kpack
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: kpack-webhook
+  template:
+    metadata:
+      labels:
+        app: kpack-webhook
+    spec:
+      containers:
+      - name: webhook
+        image: kpack/webhook:1.0`
+				budget = &policyv1.PodDisruptionBudget{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "kpack", Name: "kpack-webhook"},
+				}
+			})
+
+			It("spreads the replicas of the deployments and creates their PodDisruptionBudgets", func() {
+				Expect(installErr).NotTo(HaveOccurred())
+				Expect(installResult.State).To(Equal(installable.ResultStateSuccess))
+
+				deployment := &appsv1.Deployment{}
+				Expect(adminClient.Get(ctx, client.ObjectKey{Namespace: "kpack", Name: "kpack-webhook"}, deployment)).To(Succeed())
+				Expect(deployment.Spec.Replicas).To(PointTo(BeEquivalentTo(2)))
+				Expect(deployment.Spec.Template.Spec.TopologySpreadConstraints).To(HaveLen(1))
+
+				Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(budget), budget)).To(Succeed())
+				Expect(budget.Labels).To(HaveKeyWithValue(highavailability.Label, "true"))
+				Expect(budget.Spec.Selector.MatchLabels).To(Equal(map[string]string{"app": "kpack-webhook"}))
+			})
+
+			When("high availability is no longer configured", func() {
+				JustBeforeEach(func() {
+					Eventually(func() error {
+						return adminClient.Get(ctx, client.ObjectKeyFromObject(budget), budget)
+					}).Should(Succeed())
+
+					yamlFile, err := os.CreateTemp("", "")
+					Expect(err).NotTo(HaveOccurred())
+					DeferCleanup(func() {
+						Expect(os.RemoveAll(yamlFile.Name())).To(Succeed())
+					})
+					_, err = io.WriteString(yamlFile, yamlContent)
+					Expect(err).NotTo(HaveOccurred())
+
+					installResult, installErr = installable.NewYaml(adminClient, yamlFile.Name(), "test-file").
+						Install(ctx, v1alpha1.InstallationConfig{}, eventRecorder)
+				})
+
+				It("deletes the PodDisruptionBudgets", func() {
+					Expect(installErr).NotTo(HaveOccurred())
+					Expect(installResult.State).To(Equal(installable.ResultStateSuccess))
+
+					err := adminClient.Get(ctx, client.ObjectKeyFromObject(budget), budget)
+					Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+				})
+			})
+		})
+
 		When("the yaml is invalid", func() {
 			BeforeEach(func() {
 				yamlContent = "invalid-yaml"
@@ -1,6 +1,8 @@
 package kyma
 
 import (
+	"net/http"
+
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -9,13 +11,15 @@ type Client struct {
 	UAA               *UAA
 	Users             *Users
 	Gateway           *Gateway
+	Network           *Network
 }
 
-func NewClient(k8sClient client.Client) *Client {
+func NewClient(k8sClient client.Client, httpClient *http.Client) *Client {
 	return &Client{
 		ContainerRegistry: NewContainerRegistry(k8sClient),
-		UAA:               NewUAA(k8sClient),
+		UAA:               NewUAA(k8sClient, httpClient),
 		Users:             NewUsers(k8sClient),
 		Gateway:           NewGateway(k8sClient),
+		Network:           NewNetwork(k8sClient),
 	}
 }
@@ -4,12 +4,27 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/kyma-project/cfapi/api/v1alpha1"
 	"github.com/kyma-project/istio/operator/api/v1alpha2"
 	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	kymaGatewayNamespace = "kyma-system"
+	kymaGatewayName      = "kyma-gateway"
+	shootInfoNamespace   = "kube-system"
+	shootInfoName        = "shoot-info"
+
+	korifiGatewayNamespace = "cfapi-system"
+	appsGatewayName        = "cfapi-apps"
 )
 
 type Gateway struct {
@@ -30,24 +45,103 @@ func (g *Gateway) KorifiGatewayType(cfAPI *v1alpha1.CFAPI) string {
 }
 
 func (g *Gateway) Validate(ctx context.Context, cfAPI *v1alpha1.CFAPI) error {
-	if g.KorifiGatewayType(cfAPI) != v1alpha1.GatewayTypeContour && g.KorifiGatewayType(cfAPI) != v1alpha1.GatewayTypeIstio {
-		return fmt.Errorf("invalid gateway type: %s. Valid values are: %s, %s", cfAPI.Spec.GatewayType, v1alpha1.GatewayTypeContour, v1alpha1.GatewayTypeIstio)
+	gatewayType := g.KorifiGatewayType(cfAPI)
+	if !slices.Contains([]string{v1alpha1.GatewayTypeContour, v1alpha1.GatewayTypeIstio, v1alpha1.GatewayTypeIstioNative, v1alpha1.GatewayTypeExternal}, gatewayType) {
+		return fmt.Errorf("invalid gateway type: %s. Valid values are: %s, %s, %s, %s",
+			cfAPI.Spec.GatewayType, v1alpha1.GatewayTypeContour, v1alpha1.GatewayTypeIstio, v1alpha1.GatewayTypeIstioNative, v1alpha1.GatewayTypeExternal)
+	}
+
+	if err := validateIngress(cfAPI); err != nil {
+		return err
+	}
+
+	if cfAPI.Spec.Profile == v1alpha1.ProfileLocal && gatewayType == v1alpha1.GatewayTypeIstioNative {
+		return errors.New("the istio-native gateway type requires the kyma gateway and is not supported with the local profile")
+	}
+
+	if cfAPI.Spec.Profile == v1alpha1.ProfileLocal && cfAPI.Spec.Ingress == nil && gatewayType != v1alpha1.GatewayTypeContour {
+		return errors.New("the local profile exposes the gateway on a node port by default, which is only supported with the contour gateway type. Configure `spec.ingress` to use istio")
+	}
+
+	if gatewayType == v1alpha1.GatewayTypeExternal {
+		return g.validateExternalGateway(ctx, cfAPI)
 	}
 
-	if cfAPI.Spec.GatewayType == v1alpha1.GatewayTypeIstio {
+	if gatewayType == v1alpha1.GatewayTypeIstio {
 		aphaGWAPIEnabled, err := g.isAplhaGatewayAPIEnabled(ctx)
 		if err != nil {
 			return err
 		}
 
 		if !aphaGWAPIEnabled {
-			return errors.New("alpha gateway API feature is not enabled in istio. To fix this, enable the `experimental` channel on the istio module and set `spec.experimental.pilot.enableAlphaGatewayAPI` to `true` on the `kyma-system/default` Istio resource")
+			return errors.New("alpha gateway API feature is not enabled in istio. To fix this, enable the `experimental` channel on the istio module and set `spec.experimental.pilot.enableAlphaGatewayAPI` to `true` on the `kyma-system/default` Istio resource, or use the `istio-native` gateway type")
 		}
 	}
 
 	return nil
 }
 
+func validateIngress(cfAPI *v1alpha1.CFAPI) error {
+	if cfAPI.Spec.Ingress == nil {
+		return nil
+	}
+
+	switch ingressMode(cfAPI.Spec.Ingress) {
+	case "", v1alpha1.IngressModeLoadBalancer:
+		return nil
+	case v1alpha1.IngressModeNodePort:
+		if cfAPI.Spec.Ingress.Host == "" {
+			return errors.New("ingress host is required for ingress mode NodePort")
+		}
+		// istio and external implementations provision the gateway service themselves and do not allow pinning its node ports
+		if cfAPI.Spec.GatewayType != "" && cfAPI.Spec.GatewayType != v1alpha1.GatewayTypeContour {
+			return errors.New("ingress mode NodePort is only supported with the contour gateway type")
+		}
+		return nil
+	case v1alpha1.IngressModeStatic:
+		if cfAPI.Spec.Ingress.Host == "" {
+			return errors.New("ingress host is required for ingress mode Static")
+		}
+		// the kyma gateway is already exposed by kyma
+		if cfAPI.Spec.GatewayType == v1alpha1.GatewayTypeIstioNative {
+			return errors.New("ingress mode Static is not supported with the istio-native gateway type")
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid ingress mode: %s. Valid values are: %s, %s, %s", cfAPI.Spec.Ingress.Mode, v1alpha1.IngressModeLoadBalancer, v1alpha1.IngressModeNodePort, v1alpha1.IngressModeStatic)
+	}
+}
+
+// validateExternalGateway checks that the gateway of the `external` gateway
+// type is configured and that its GatewayClass has been accepted by the
+// controller of the gateway api implementation
+func (g *Gateway) validateExternalGateway(ctx context.Context, cfAPI *v1alpha1.CFAPI) error {
+	externalGateway := cfAPI.Spec.Gateway
+	if externalGateway == nil || externalGateway.ClassName == "" {
+		return errors.New("gateway class name is required for the external gateway type. Set `spec.gateway.className`")
+	}
+
+	if externalGateway.IngressService.Namespace == "" || externalGateway.IngressService.Name == "" {
+		return errors.New("gateway ingress service is required for the external gateway type. Set `spec.gateway.ingressService`")
+	}
+
+	gatewayClass := &gatewayv1.GatewayClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: externalGateway.ClassName,
+		},
+	}
+	if err := g.k8sClient.Get(ctx, client.ObjectKeyFromObject(gatewayClass), gatewayClass); err != nil {
+		return fmt.Errorf("failed to get gateway class %s: %w", externalGateway.ClassName, err)
+	}
+
+	accepted := meta.FindStatusCondition(gatewayClass.Status.Conditions, string(gatewayv1.GatewayClassConditionStatusAccepted))
+	if accepted == nil || accepted.Status != metav1.ConditionTrue {
+		return fmt.Errorf("gateway class %s is not accepted by controller %s", externalGateway.ClassName, gatewayClass.Spec.ControllerName)
+	}
+
+	return nil
+}
+
 func (g *Gateway) isAplhaGatewayAPIEnabled(ctx context.Context) (bool, error) {
 	istio := v1alpha2.Istio{
 		ObjectMeta: metav1.ObjectMeta{
@@ -68,28 +162,167 @@ func (g *Gateway) isAplhaGatewayAPIEnabled(ctx context.Context) (bool, error) {
 	return istio.Spec.Experimental.EnableAlphaGatewayAPI, nil
 }
 
-func (g *Gateway) KymaDomain(ctx context.Context) (string, error) {
+// KymaDomain resolves the kyma domain from the wildcard host of the kyma
+// gateway. When no gateway is configured and the default kyma gateway does not
+// provide a domain, the domain of the Gardener shoot is used. The
+// `istio-native` gateway type always requires the kyma gateway. Returns the
+// domain and a description of where it was resolved from
+func (g *Gateway) KymaDomain(ctx context.Context, cfAPI *v1alpha1.CFAPI) (string, string, error) {
+	if cfAPI.Spec.KymaGateway != nil || cfAPI.Spec.GatewayType == v1alpha1.GatewayTypeIstioNative {
+		namespace, name := g.KymaGateway(cfAPI)
+		domain, err := g.gatewayDomain(ctx, namespace, name)
+		if err != nil {
+			return "", "", err
+		}
+		return domain, gatewaySource(namespace, name), nil
+	}
+
+	domain, gatewayErr := g.gatewayDomain(ctx, kymaGatewayNamespace, kymaGatewayName)
+	if gatewayErr == nil {
+		return domain, gatewaySource(kymaGatewayNamespace, kymaGatewayName), nil
+	}
+
+	domain, shootInfoErr := g.shootDomain(ctx)
+	if shootInfoErr == nil {
+		return domain, fmt.Sprintf("ConfigMap %s/%s", shootInfoNamespace, shootInfoName), nil
+	}
+
+	return "", "", fmt.Errorf("failed to determine the kyma domain: %w", errors.Join(gatewayErr, shootInfoErr))
+}
+
+// AppsGateway returns the namespace and name of the istio gateway serving the
+// apps domain with the `istio-native` gateway type. It runs on the ingress
+// gateway of kyma next to the kyma gateway, whose wildcard certificate does
+// not cover the nested apps subdomain
+func (g *Gateway) AppsGateway() (string, string) {
+	return korifiGatewayNamespace, appsGatewayName
+}
+
+// KymaGateway returns the namespace and name of the configured kyma gateway,
+// defaulting to `kyma-system/kyma-gateway`
+func (g *Gateway) KymaGateway(cfAPI *v1alpha1.CFAPI) (string, string) {
+	if cfAPI.Spec.KymaGateway != nil {
+		return cfAPI.Spec.KymaGateway.Namespace, cfAPI.Spec.KymaGateway.Name
+	}
+	return kymaGatewayNamespace, kymaGatewayName
+}
+
+func (g *Gateway) gatewayDomain(ctx context.Context, namespace, name string) (string, error) {
 	istioGateway := &networkingv1beta1.Gateway{
 		ObjectMeta: metav1.ObjectMeta{
-			Namespace: "kyma-system",
-			Name:      "kyma-gateway",
+			Namespace: namespace,
+			Name:      name,
 		},
 	}
 	if err := g.k8sClient.Get(ctx, client.ObjectKeyFromObject(istioGateway), istioGateway); err != nil {
-		return "", fmt.Errorf("failed to get the kyma system gateway: %w", err)
+		return "", fmt.Errorf("failed to get the kyma gateway %s/%s: %w", namespace, name, err)
 	}
 
 	if len(istioGateway.Spec.Servers) == 0 {
-		return "", errors.New("failed to get the kyma gateway domain: gateway has no servers")
+		return "", fmt.Errorf("failed to get the kyma gateway domain: gateway %s/%s has no servers", namespace, name)
+	}
+
+	for _, server := range istioGateway.Spec.Servers {
+		for _, host := range server.GetHosts() {
+			// hosts may be scoped to a namespace, e.g. `*/*.kyma-domain.com`
+			host = host[strings.LastIndex(host, "/")+1:]
+			if domain, ok := strings.CutPrefix(host, "*."); ok && domain != "" {
+				return domain, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("failed to get the kyma gateway domain: gateway %s/%s has no wildcard host", namespace, name)
+}
+
+func (g *Gateway) shootDomain(ctx context.Context) (string, error) {
+	shootInfo := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: shootInfoNamespace,
+			Name:      shootInfoName,
+		},
+	}
+	if err := g.k8sClient.Get(ctx, client.ObjectKeyFromObject(shootInfo), shootInfo); err != nil {
+		return "", fmt.Errorf("failed to get the gardener shoot info: %w", err)
+	}
+
+	domain := shootInfo.Data["domain"]
+	if domain == "" {
+		return "", errors.New("gardener shoot info does not contain key 'domain'")
+	}
+
+	return domain, nil
+}
+
+func gatewaySource(namespace, name string) string {
+	return fmt.Sprintf("Gateway %s/%s", namespace, name)
+}
+
+// KorifiIngressService returns the namespace and name of the service exposing
+// the Korifi gateway
+func (g *Gateway) KorifiIngressService(cfAPI *v1alpha1.CFAPI) (string, string) {
+	switch cfAPI.Spec.GatewayType {
+	case v1alpha1.GatewayTypeIstio:
+		return korifiGatewayNamespace, "korifi-istio"
+	case v1alpha1.GatewayTypeIstioNative:
+		// traffic enters through the ingress gateway of kyma
+		return "", ""
+	case v1alpha1.GatewayTypeExternal:
+		if cfAPI.Spec.Gateway == nil {
+			return "", ""
+		}
+		return cfAPI.Spec.Gateway.IngressService.Namespace, cfAPI.Spec.Gateway.IngressService.Name
+	default:
+		return korifiGatewayNamespace, "contour-envoy"
+	}
+}
+
+// KorifiGatewayClass returns the GatewayClass of the Korifi gateway
+func (g *Gateway) KorifiGatewayClass(cfAPI *v1alpha1.CFAPI) string {
+	switch g.KorifiGatewayType(cfAPI) {
+	case v1alpha1.GatewayTypeExternal:
+		if cfAPI.Spec.Gateway == nil {
+			return ""
+		}
+		return cfAPI.Spec.Gateway.ClassName
+	case v1alpha1.GatewayTypeIstioNative:
+		// no Korifi gateway is deployed
+		return ""
+	default:
+		return g.KorifiGatewayType(cfAPI)
+	}
+}
+
+// Ingress returns the ingress mode, the host configured for it (empty in
+// `LoadBalancer` mode) and the external HTTPS port of the ingress gateway.
+// The `local` profile defaults to a node port on 127.0.0.1
+func (g *Gateway) Ingress(cfAPI *v1alpha1.CFAPI) (string, string, int32) {
+	// local clusters usually have no load balancers, the gateway is reachable
+	// via a node port mapped to the host
+	if cfAPI.Spec.Ingress == nil && cfAPI.Spec.Profile == v1alpha1.ProfileLocal {
+		return v1alpha1.IngressModeNodePort, "127.0.0.1", v1alpha1.DefaultIngressNodePort
+	}
+
+	if cfAPI.Spec.Ingress == nil || cfAPI.Spec.Ingress.Mode == "" || cfAPI.Spec.Ingress.Mode == v1alpha1.IngressModeLoadBalancer {
+		return v1alpha1.IngressModeLoadBalancer, "", 443
+	}
+
+	if cfAPI.Spec.Ingress.Mode == v1alpha1.IngressModeNodePort {
+		nodePort := cfAPI.Spec.Ingress.NodePort
+		if nodePort == 0 {
+			nodePort = v1alpha1.DefaultIngressNodePort
+		}
+		return v1alpha1.IngressModeNodePort, cfAPI.Spec.Ingress.Host, nodePort
 	}
 
-	wildCardDomain := istioGateway.Spec.Servers[0].Hosts[0]
-	return wildCardDomain[2:], nil
+	return ingressMode(cfAPI.Spec.Ingress), cfAPI.Spec.Ingress.Host, 443
 }
 
-func (g *Gateway) KorifiIngressService(cfAPI *v1alpha1.CFAPI) string {
-	if cfAPI.Spec.GatewayType == v1alpha1.GatewayTypeIstio {
-		return "korifi-istio"
+// ingressMode returns the mode of the ingress, accepting `static` as an alias
+// of `Static`
+func ingressMode(ingress *v1alpha1.Ingress) string {
+	if ingress.Mode == v1alpha1.IngressModeStaticAlias {
+		return v1alpha1.IngressModeStatic
 	}
-	return "contour-envoy"
+	return ingress.Mode
 }
@@ -1,6 +1,7 @@
 package kyma_test
 
 import (
+	"github.com/google/uuid"
 	"github.com/kyma-project/cfapi/api/v1alpha1"
 	"github.com/kyma-project/cfapi/controllers/kyma"
 	"github.com/kyma-project/cfapi/tests/helpers"
@@ -10,7 +11,10 @@ import (
 	. "github.com/onsi/gomega"
 	networkingv1alpha3 "istio.io/api/networking/v1alpha3"
 	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
 var _ = Describe("Gateway", func() {
@@ -112,6 +116,159 @@ var _ = Describe("Gateway", func() {
 			})
 		})
 
+		When("the gateway type is set to istio-native", func() {
+			BeforeEach(func() {
+				cfAPI.Spec.GatewayType = v1alpha1.GatewayTypeIstioNative
+			})
+
+			It("does not require the alpha gateway API", func() {
+				Expect(validateErr).NotTo(HaveOccurred())
+			})
+
+			When("the ingress mode is Static", func() {
+				BeforeEach(func() {
+					cfAPI.Spec.Ingress = &v1alpha1.Ingress{
+						Mode: v1alpha1.IngressModeStatic,
+						Host: "static.example.com",
+					}
+				})
+
+				It("returns validation error", func() {
+					Expect(validateErr).To(MatchError(ContainSubstring("not supported with the istio-native gateway type")))
+				})
+			})
+
+			When("the profile is local", func() {
+				BeforeEach(func() {
+					cfAPI.Spec.Profile = v1alpha1.ProfileLocal
+				})
+
+				It("returns validation error", func() {
+					Expect(validateErr).To(MatchError(ContainSubstring("not supported with the local profile")))
+				})
+			})
+		})
+
+		When("the gateway type is set to external", func() {
+			var gatewayClass *gatewayv1.GatewayClass
+
+			BeforeEach(func() {
+				gatewayClass = &gatewayv1.GatewayClass{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: uuid.NewString(),
+					},
+					Spec: gatewayv1.GatewayClassSpec{
+						ControllerName: "gateway.envoyproxy.io/gatewayclass-controller",
+					},
+				}
+				helpers.EnsureCreate(adminClient, gatewayClass)
+
+				cfAPI.Spec.GatewayType = v1alpha1.GatewayTypeExternal
+				cfAPI.Spec.Gateway = &v1alpha1.ExternalGateway{
+					ClassName: gatewayClass.Name,
+					IngressService: v1alpha1.NamespacedObjectReference{
+						Namespace: "envoy-gateway-system",
+						Name:      "envoy-korifi",
+					},
+				}
+			})
+
+			It("returns an error about the class not being accepted", func() {
+				Expect(validateErr).To(MatchError(ContainSubstring("is not accepted by controller gateway.envoyproxy.io/gatewayclass-controller")))
+			})
+
+			When("the gateway class is accepted", func() {
+				BeforeEach(func() {
+					Expect(k8s.Patch(ctx, adminClient, gatewayClass, func() {
+						meta.SetStatusCondition(&gatewayClass.Status.Conditions, metav1.Condition{
+							Type:   string(gatewayv1.GatewayClassConditionStatusAccepted),
+							Status: metav1.ConditionTrue,
+							Reason: string(gatewayv1.GatewayClassReasonAccepted),
+						})
+					})).To(Succeed())
+				})
+
+				It("succeeds", func() {
+					Expect(validateErr).NotTo(HaveOccurred())
+				})
+			})
+
+			When("the gateway class does not exist", func() {
+				BeforeEach(func() {
+					cfAPI.Spec.Gateway.ClassName = "non-existent"
+				})
+
+				It("returns validation error", func() {
+					Expect(validateErr).To(MatchError(ContainSubstring("failed to get gateway class non-existent")))
+				})
+			})
+
+			When("the gateway class name is not set", func() {
+				BeforeEach(func() {
+					cfAPI.Spec.Gateway.ClassName = ""
+				})
+
+				It("returns validation error", func() {
+					Expect(validateErr).To(MatchError(ContainSubstring("gateway class name is required")))
+				})
+			})
+
+			When("the ingress service is not set", func() {
+				BeforeEach(func() {
+					cfAPI.Spec.Gateway.IngressService = v1alpha1.NamespacedObjectReference{}
+				})
+
+				It("returns validation error", func() {
+					Expect(validateErr).To(MatchError(ContainSubstring("gateway ingress service is required")))
+				})
+			})
+		})
+
+		When("the ingress mode is NodePort", func() {
+			BeforeEach(func() {
+				cfAPI.Spec.Ingress = &v1alpha1.Ingress{
+					Mode: v1alpha1.IngressModeNodePort,
+					Host: "node.example.com",
+				}
+			})
+
+			It("succeeds", func() {
+				Expect(validateErr).NotTo(HaveOccurred())
+			})
+
+			When("the ingress host is not set", func() {
+				BeforeEach(func() {
+					cfAPI.Spec.Ingress.Host = ""
+				})
+
+				It("returns validation error", func() {
+					Expect(validateErr).To(MatchError(ContainSubstring("ingress host is required")))
+				})
+			})
+
+			When("the gateway type is istio", func() {
+				BeforeEach(func() {
+					cfAPI.Spec.GatewayType = v1alpha1.GatewayTypeIstio
+				})
+
+				It("returns validation error", func() {
+					Expect(validateErr).To(MatchError(ContainSubstring("only supported with the contour gateway type")))
+				})
+			})
+		})
+
+		When("the ingress mode is Static without a host", func() {
+			BeforeEach(func() {
+				cfAPI.Spec.Ingress = &v1alpha1.Ingress{
+					Mode: v1alpha1.IngressModeStatic,
+				}
+			})
+
+			It("returns validation error", func() {
+				Expect(validateErr).To(MatchError(ContainSubstring("ingress host is required")))
+			})
+		})
+
 		When("gateway type is invalid", func() {
 			BeforeEach(func() {
 				cfAPI.Spec.GatewayType = "invalid-gateway-type"
@@ -125,17 +282,39 @@ var _ = Describe("Gateway", func() {
 
 	Describe("KymaDomain", func() {
 		var (
-			kymaGateway *networkingv1beta1.Gateway
-			domain      string
-			domainErr   error
+			kymaGateway  *networkingv1beta1.Gateway
+			domain       string
+			domainSource string
+			domainErr    error
 		)
 
 		JustBeforeEach(func() {
-			domain, domainErr = gateway.KymaDomain(ctx)
+			domain, domainSource, domainErr = gateway.KymaDomain(ctx, cfAPI)
 		})
 
 		It("errors", func() {
-			Expect(domainErr).To(MatchError(ContainSubstring("failed to get the kyma system gateway")))
+			Expect(domainErr).To(MatchError(ContainSubstring("failed to get the kyma gateway kyma-system/kyma-gateway")))
+			Expect(domainErr).To(MatchError(ContainSubstring("failed to get the gardener shoot info")))
+		})
+
+		When("the gardener shoot info exists", func() {
+			BeforeEach(func() {
+				helpers.EnsureCreate(adminClient, &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: "kube-system",
+						Name:      "shoot-info",
+					},
+					Data: map[string]string{
+						"domain": "shoot-domain.com",
+					},
+				})
+			})
+
+			It("returns the shoot domain", func() {
+				Expect(domainErr).NotTo(HaveOccurred())
+				Expect(domain).To(Equal("shoot-domain.com"))
+				Expect(domainSource).To(Equal("ConfigMap kube-system/shoot-info"))
+			})
 		})
 
 		When("the kyma gateway exists", func() {
@@ -150,7 +329,7 @@ var _ = Describe("Gateway", func() {
 			})
 
 			It("errors", func() {
-				Expect(domainErr).To(MatchError(ContainSubstring("gateway has no servers")))
+				Expect(domainErr).To(MatchError(ContainSubstring("gateway kyma-system/kyma-gateway has no servers")))
 			})
 
 			When("the kyma gateway has servers", func() {
@@ -159,33 +338,132 @@ var _ = Describe("Gateway", func() {
 						kymaGateway.Spec.Servers = []*networkingv1alpha3.Server{
 							{
 								Port: &networkingv1alpha3.Port{
-									Name:     "https-8080",
+									Name:     "http-8080",
 									Number:   8080,
 									Protocol: "HTTP",
 								},
-								Hosts: []string{"*.kyma-domain.com"},
+								Hosts: []string{},
+							},
+							{
+								Port: &networkingv1alpha3.Port{
+									Name:     "https-8443",
+									Number:   8443,
+									Protocol: "HTTPS",
+								},
+								Hosts: []string{"api.kyma-domain.com", "*/*.kyma-domain.com"},
 							},
 						}
 					})).To(Succeed())
 				})
 
-				It("returns the kyma domain", func() {
+				It("returns the kyma domain from the wildcard host", func() {
 					Expect(domainErr).NotTo(HaveOccurred())
 					Expect(domain).To(Equal("kyma-domain.com"))
+					Expect(domainSource).To(Equal("Gateway kyma-system/kyma-gateway"))
+				})
+			})
+
+			When("the kyma gateway has no wildcard host", func() {
+				BeforeEach(func() {
+					Expect(k8s.Patch(ctx, adminClient, kymaGateway, func() {
+						kymaGateway.Spec.Servers = []*networkingv1alpha3.Server{{
+							Port: &networkingv1alpha3.Port{
+								Name:     "https-8443",
+								Number:   8443,
+								Protocol: "HTTPS",
+							},
+							Hosts: []string{"api.kyma-domain.com"},
+						}}
+					})).To(Succeed())
+				})
+
+				It("errors", func() {
+					Expect(domainErr).To(MatchError(ContainSubstring("gateway kyma-system/kyma-gateway has no wildcard host")))
+				})
+			})
+		})
+
+		When("the gateway type is istio-native", func() {
+			BeforeEach(func() {
+				cfAPI.Spec.GatewayType = v1alpha1.GatewayTypeIstioNative
+
+				helpers.EnsureCreate(adminClient, &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: "kube-system",
+						Name:      "shoot-info",
+					},
+					Data: map[string]string{
+						"domain": "shoot-domain.com",
+					},
+				})
+			})
+
+			It("does not fall back to the shoot domain", func() {
+				Expect(domainErr).To(MatchError(ContainSubstring("failed to get the kyma gateway kyma-system/kyma-gateway")))
+			})
+		})
+
+		When("a gateway is configured", func() {
+			BeforeEach(func() {
+				cfAPI.Spec.KymaGateway = &v1alpha1.NamespacedObjectReference{
+					Namespace: testNamespace,
+					Name:      "custom-gateway",
+				}
+
+				helpers.EnsureCreate(adminClient, &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: "kube-system",
+						Name:      "shoot-info",
+					},
+					Data: map[string]string{
+						"domain": "shoot-domain.com",
+					},
+				})
+			})
+
+			It("does not fall back to the shoot domain", func() {
+				Expect(domainErr).To(MatchError(ContainSubstring("failed to get the kyma gateway " + testNamespace + "/custom-gateway")))
+			})
+
+			When("the configured gateway exists", func() {
+				BeforeEach(func() {
+					helpers.EnsureCreate(adminClient, &networkingv1beta1.Gateway{
+						ObjectMeta: metav1.ObjectMeta{
+							Namespace: testNamespace,
+							Name:      "custom-gateway",
+						},
+						Spec: networkingv1alpha3.Gateway{
+							Servers: []*networkingv1alpha3.Server{{
+								Port: &networkingv1alpha3.Port{
+									Name:     "https-8443",
+									Number:   8443,
+									Protocol: "HTTPS",
+								},
+								Hosts: []string{"*.custom-domain.com"},
+							}},
+						},
+					})
+				})
+
+				It("returns the domain of the configured gateway", func() {
+					Expect(domainErr).NotTo(HaveOccurred())
+					Expect(domain).To(Equal("custom-domain.com"))
+					Expect(domainSource).To(Equal("Gateway " + testNamespace + "/custom-gateway"))
 				})
 			})
 		})
 	})
 
 	Describe("KorifiIngressService", func() {
-		var ingressHostname string
+		var ingressNamespace, ingressName string
 
 		JustBeforeEach(func() {
-			ingressHostname = gateway.KorifiIngressService(cfAPI)
+			ingressNamespace, ingressName = gateway.KorifiIngressService(cfAPI)
 		})
 
 		It("returns the contour service", func() {
-			Expect(ingressHostname).To(Equal("contour-envoy"))
+			Expect(ingressNamespace).To(Equal("cfapi-system"))
+			Expect(ingressName).To(Equal("contour-envoy"))
 		})
 
 		When("the gateway type is set to Istio", func() {
@@ -194,7 +472,138 @@ var _ = Describe("Gateway", func() {
 			})
 
 			It("returns the istio service", func() {
-				Expect(ingressHostname).To(Equal("korifi-istio"))
+				Expect(ingressNamespace).To(Equal("cfapi-system"))
+				Expect(ingressName).To(Equal("korifi-istio"))
+			})
+		})
+
+		When("the gateway type is set to istio-native", func() {
+			BeforeEach(func() {
+				cfAPI.Spec.GatewayType = v1alpha1.GatewayTypeIstioNative
+			})
+
+			It("returns no service", func() {
+				Expect(ingressNamespace).To(BeEmpty())
+				Expect(ingressName).To(BeEmpty())
+			})
+		})
+
+		When("the gateway type is set to external", func() {
+			BeforeEach(func() {
+				cfAPI.Spec.GatewayType = v1alpha1.GatewayTypeExternal
+				cfAPI.Spec.Gateway = &v1alpha1.ExternalGateway{
+					ClassName:      "envoy-gateway",
+					IngressService: v1alpha1.NamespacedObjectReference{Namespace: "envoy-gateway-system", Name: "envoy-korifi"},
+				}
+			})
+
+			It("returns the configured service", func() {
+				Expect(ingressNamespace).To(Equal("envoy-gateway-system"))
+				Expect(ingressName).To(Equal("envoy-korifi"))
+			})
+		})
+	})
+
+	Describe("KorifiGatewayClass", func() {
+		var gatewayClass string
+
+		JustBeforeEach(func() {
+			gatewayClass = gateway.KorifiGatewayClass(cfAPI)
+		})
+
+		It("returns the contour class", func() {
+			Expect(gatewayClass).To(Equal("contour"))
+		})
+
+		When("the gateway type is set to external", func() {
+			BeforeEach(func() {
+				cfAPI.Spec.GatewayType = v1alpha1.GatewayTypeExternal
+				cfAPI.Spec.Gateway = &v1alpha1.ExternalGateway{ClassName: "envoy-gateway"}
+			})
+
+			It("returns the configured class", func() {
+				Expect(gatewayClass).To(Equal("envoy-gateway"))
+			})
+		})
+	})
+
+	Describe("Ingress", func() {
+		var (
+			mode string
+			host string
+			port int32
+		)
+
+		JustBeforeEach(func() {
+			mode, host, port = gateway.Ingress(cfAPI)
+		})
+
+		It("defaults to LoadBalancer", func() {
+			Expect(mode).To(Equal(v1alpha1.IngressModeLoadBalancer))
+			Expect(host).To(BeEmpty())
+			Expect(port).To(BeEquivalentTo(443))
+		})
+
+		When("the ingress mode is NodePort", func() {
+			BeforeEach(func() {
+				cfAPI.Spec.Ingress = &v1alpha1.Ingress{
+					Mode: v1alpha1.IngressModeNodePort,
+					Host: "node.example.com",
+				}
+			})
+
+			It("returns the host and the default node port", func() {
+				Expect(mode).To(Equal(v1alpha1.IngressModeNodePort))
+				Expect(host).To(Equal("node.example.com"))
+				Expect(port).To(BeEquivalentTo(30443))
+			})
+
+			When("a node port is configured", func() {
+				BeforeEach(func() {
+					cfAPI.Spec.Ingress.NodePort = 31443
+				})
+
+				It("returns it", func() {
+					Expect(port).To(BeEquivalentTo(31443))
+				})
+			})
+		})
+
+		When("the ingress mode is Static", func() {
+			BeforeEach(func() {
+				cfAPI.Spec.Ingress = &v1alpha1.Ingress{
+					Mode: v1alpha1.IngressModeStatic,
+					Host: "10.0.0.1",
+				}
+			})
+
+			It("returns the host", func() {
+				Expect(mode).To(Equal(v1alpha1.IngressModeStatic))
+				Expect(host).To(Equal("10.0.0.1"))
+				Expect(port).To(BeEquivalentTo(443))
+			})
+
+			When("the mode is spelled in lower case", func() {
+				BeforeEach(func() {
+					cfAPI.Spec.Ingress.Mode = v1alpha1.IngressModeStaticAlias
+				})
+
+				It("is treated as Static", func() {
+					Expect(mode).To(Equal(v1alpha1.IngressModeStatic))
+					Expect(host).To(Equal("10.0.0.1"))
+				})
+			})
+		})
+
+		When("the profile is local", func() {
+			BeforeEach(func() {
+				cfAPI.Spec.Profile = v1alpha1.ProfileLocal
+			})
+
+			It("defaults to a node port on localhost", func() {
+				Expect(mode).To(Equal(v1alpha1.IngressModeNodePort))
+				Expect(host).To(Equal("127.0.0.1"))
+				Expect(port).To(BeEquivalentTo(30443))
 			})
 		})
 	})
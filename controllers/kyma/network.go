@@ -0,0 +1,120 @@
+package kyma
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// shootNetworkKeys are the keys of the node, pod and service networks in the
+// Gardener shoot info
+var shootNetworkKeys = []string{"nodeNetwork", "podNetwork", "serviceNetwork"}
+
+type Network struct {
+	k8sClient client.Client
+}
+
+func NewNetwork(k8sClient client.Client) *Network {
+	return &Network{
+		k8sClient: k8sClient,
+	}
+}
+
+// ClusterNetworks returns the CIDRs of the nodes, pods and services of the
+// cluster. They are taken from the Gardener `kube-system/shoot-info` config
+// map, falling back to the pod CIDRs and addresses of the nodes and the
+// service CIDRs of the cluster
+func (n *Network) ClusterNetworks(ctx context.Context) ([]string, error) {
+	shootInfo := &corev1.ConfigMap{}
+	err := n.k8sClient.Get(ctx, client.ObjectKey{Namespace: shootInfoNamespace, Name: shootInfoName}, shootInfo)
+	if client.IgnoreNotFound(err) != nil {
+		return nil, fmt.Errorf("failed to get the gardener shoot info: %w", err)
+	}
+
+	networks := []string{}
+	for _, key := range shootNetworkKeys {
+		networks = append(networks, splitList(shootInfo.Data[key])...)
+	}
+	if len(networks) > 0 {
+		return compact(networks), nil
+	}
+
+	nodeNetworks, err := n.nodeNetworks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	serviceNetworks, err := n.serviceNetworks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return compact(append(nodeNetworks, serviceNetworks...)), nil
+}
+
+func (n *Network) nodeNetworks(ctx context.Context) ([]string, error) {
+	nodes := &corev1.NodeList{}
+	if err := n.k8sClient.List(ctx, nodes); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	networks := []string{}
+	for _, node := range nodes.Items {
+		networks = append(networks, node.Spec.PodCIDRs...)
+		for _, address := range node.Status.Addresses {
+			if address.Type == corev1.NodeInternalIP {
+				networks = append(networks, address.Address)
+			}
+		}
+	}
+	return networks, nil
+}
+
+// serviceNetworks returns the service CIDRs of the cluster. Clusters without
+// the ServiceCIDR API only report the address of the API server service
+func (n *Network) serviceNetworks(ctx context.Context) ([]string, error) {
+	serviceCIDRs := &networkingv1.ServiceCIDRList{}
+	err := n.k8sClient.List(ctx, serviceCIDRs)
+	if err == nil {
+		networks := []string{}
+		for _, serviceCIDR := range serviceCIDRs.Items {
+			networks = append(networks, serviceCIDR.Spec.CIDRs...)
+		}
+		return networks, nil
+	}
+	if !meta.IsNoMatchError(err) {
+		return nil, fmt.Errorf("failed to list service CIDRs: %w", err)
+	}
+
+	apiServer := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: metav1.NamespaceDefault,
+			Name:      "kubernetes",
+		},
+	}
+	if err := n.k8sClient.Get(ctx, client.ObjectKeyFromObject(apiServer), apiServer); err != nil {
+		return nil, fmt.Errorf("failed to get the kubernetes service: %w", err)
+	}
+	return apiServer.Spec.ClusterIPs, nil
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func compact(items []string) []string {
+	slices.Sort(items)
+	return slices.Compact(items)
+}
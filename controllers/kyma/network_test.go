@@ -0,0 +1,77 @@
+package kyma_test
+
+import (
+	"github.com/google/uuid"
+	"github.com/kyma-project/cfapi/controllers/kyma"
+	"github.com/kyma-project/cfapi/tests/helpers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Network", func() {
+	var (
+		network     *kyma.Network
+		networks    []string
+		networksErr error
+	)
+
+	BeforeEach(func() {
+		network = kyma.NewNetwork(adminClient)
+
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: uuid.NewString(),
+			},
+			Spec: corev1.NodeSpec{
+				PodCIDRs: []string{"100.64.1.0/24"},
+			},
+		}
+		helpers.EnsureCreate(adminClient, node)
+		node.Status.Addresses = []corev1.NodeAddress{
+			{Type: corev1.NodeInternalIP, Address: "10.250.0.5"},
+			{Type: corev1.NodeHostName, Address: "node-1"},
+		}
+		Expect(adminClient.Status().Update(ctx, node)).To(Succeed())
+		Eventually(func(g Gomega) {
+			g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(node), node)).To(Succeed())
+			g.Expect(node.Status.Addresses).NotTo(BeEmpty())
+		}).Should(Succeed())
+	})
+
+	JustBeforeEach(func() {
+		networks, networksErr = network.ClusterNetworks(ctx)
+	})
+
+	It("returns the node and service networks of the cluster", func() {
+		Expect(networksErr).NotTo(HaveOccurred())
+		Expect(networks).To(ContainElements("100.64.1.0/24", "10.250.0.5"))
+		Expect(networks).NotTo(ContainElement("node-1"))
+		// the service CIDRs or the API server service address
+		Expect(len(networks)).To(BeNumerically(">", 2))
+	})
+
+	When("the gardener shoot info exists", func() {
+		BeforeEach(func() {
+			helpers.EnsureCreate(adminClient, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "kube-system",
+					Name:      "shoot-info",
+				},
+				Data: map[string]string{
+					"domain":         "shoot-domain.com",
+					"nodeNetwork":    "10.250.0.0/16",
+					"podNetwork":     "100.64.0.0/12",
+					"serviceNetwork": "100.104.0.0/13, fd00::/108",
+				},
+			})
+		})
+
+		It("returns the networks of the shoot", func() {
+			Expect(networksErr).NotTo(HaveOccurred())
+			Expect(networks).To(ConsistOf("10.250.0.0/16", "100.64.0.0/12", "100.104.0.0/13", "fd00::/108"))
+		})
+	})
+})
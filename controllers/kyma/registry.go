@@ -12,7 +12,13 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
-const ContainerRegistrySecretName = "dockerregistry-config-external"
+const (
+	ContainerRegistrySecretName = "dockerregistry-config-external"
+	// InternalContainerRegistrySecretName is the secret of the docker
+	// registry module holding its in-cluster push address and the address
+	// the nodes pull from. It exists whether external access is enabled or not
+	InternalContainerRegistrySecretName = "dockerregistry-config"
+)
 
 type ContainerRegistry struct {
 	k8sClient client.Client
@@ -25,34 +31,81 @@ func NewContainerRegistry(k8sClient client.Client) *ContainerRegistry {
 }
 
 func (k *ContainerRegistry) GetRegistrySecret(ctx context.Context, namespace string) (*corev1.Secret, error) {
+	secret, err := k.getSecret(ctx, namespace, ContainerRegistrySecretName)
+	if err != nil {
+		return nil, fmt.Errorf("could not get the kyma docker container registry external secret: %w. Make sure a docker registry resource exists and has its external access is enabled", err)
+	}
+
+	return secret, nil
+}
+
+func (k *ContainerRegistry) GetRegistryURL(ctx context.Context, namespace string) (string, error) {
+	registrySecret, err := k.GetRegistrySecret(ctx, namespace)
+	if err != nil {
+		return "", err
+	}
+	return secretValue(registrySecret, "pushRegAddr")
+}
+
+// GetInternalRegistrySecret returns the secret of the docker registry
+// module for in-cluster access, which does not require external access to be
+// enabled
+func (k *ContainerRegistry) GetInternalRegistrySecret(ctx context.Context, namespace string) (*corev1.Secret, error) {
+	secret, err := k.getSecret(ctx, namespace, InternalContainerRegistrySecretName)
+	if err != nil {
+		return nil, fmt.Errorf("could not get the kyma docker container registry secret: %w. Make sure a docker registry resource exists", err)
+	}
+
+	return secret, nil
+}
+
+// GetInternalRegistryURLs returns the in-cluster address images are pushed to
+// and the address the nodes pull them from, e.g.
+// `dockerregistry.kyma-system.svc.cluster.local:5000` and `localhost:32137`
+func (k *ContainerRegistry) GetInternalRegistryURLs(ctx context.Context, namespace string) (string, string, error) {
+	registrySecret, err := k.GetInternalRegistrySecret(ctx, namespace)
+	if err != nil {
+		return "", "", err
+	}
+
+	pushURL, err := secretValue(registrySecret, "pushRegAddr")
+	if err != nil {
+		return "", "", err
+	}
+
+	pullURL, err := secretValue(registrySecret, "pullRegAddr")
+	if err != nil {
+		return "", "", err
+	}
+
+	return pushURL, pullURL, nil
+}
+
+func (k *ContainerRegistry) getSecret(ctx context.Context, namespace, name string) (*corev1.Secret, error) {
 	if !k.dockerRegistryModuleIsEnabled(ctx) {
 		return nil, errors.New("dockerregistry kyma module is not enabled")
 	}
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: namespace,
-			Name:      ContainerRegistrySecretName,
+			Name:      name,
 		},
 	}
 
 	err := k.k8sClient.Get(ctx, client.ObjectKeyFromObject(secret), secret)
 	if err != nil {
-		return nil, fmt.Errorf("could not get the kyma docker container registry external secret: %w. Make sure a docker registry resource exists and has its external access is enabled", err)
+		return nil, err
 	}
 
 	return secret, nil
 }
 
-func (k *ContainerRegistry) GetRegistryURL(ctx context.Context, namespace string) (string, error) {
-	registrySecret, err := k.GetRegistrySecret(ctx, namespace)
-	if err != nil {
-		return "", err
-	}
-	registryURL, ok := registrySecret.Data["pushRegAddr"]
+func secretValue(secret *corev1.Secret, key string) (string, error) {
+	value, ok := secret.Data[key]
 	if !ok {
-		return "", fmt.Errorf("registry secret %s/%s is missing the pushRegAddr key", registrySecret.Namespace, registrySecret.Name)
+		return "", fmt.Errorf("registry secret %s/%s is missing the %s key", secret.Namespace, secret.Name, key)
 	}
-	return string(registryURL), nil
+	return string(value), nil
 }
 
 func (k *ContainerRegistry) dockerRegistryModuleIsEnabled(ctx context.Context) bool {
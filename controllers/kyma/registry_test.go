@@ -109,4 +109,52 @@ var _ = Describe("Kyma", func() {
 			})
 		})
 	})
+
+	Describe("GetInternalRegistryURLs", func() {
+		var (
+			pushURL    string
+			pullURL    string
+			err        error
+			secretData map[string]string
+		)
+
+		BeforeEach(func() {
+			_, err = envtest.InstallCRDs(testEnv.Config, envtest.CRDInstallOptions{
+				Paths: []string{filepath.Join("..", "..", "tests", "dependencies", "vendor", "kyma-docker-registry")},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			secretData = map[string]string{
+				"pushRegAddr": "dockerregistry.kyma-system.svc.cluster.local:5000",
+				"pullRegAddr": "localhost:32137",
+			}
+		})
+
+		JustBeforeEach(func() {
+			helpers.EnsureCreate(adminClient, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: testNamespace,
+					Name:      kyma.InternalContainerRegistrySecretName,
+				},
+				StringData: secretData,
+			})
+
+			pushURL, pullURL, err = kymaRegistry.GetInternalRegistryURLs(ctx, testNamespace)
+		})
+
+		It("returns the push and pull registry urls", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pushURL).To(Equal("dockerregistry.kyma-system.svc.cluster.local:5000"))
+			Expect(pullURL).To(Equal("localhost:32137"))
+		})
+
+		When("the pullRegAddr key is missing", func() {
+			BeforeEach(func() {
+				delete(secretData, "pullRegAddr")
+			})
+
+			It("returns an error", func() {
+				Expect(err).To(MatchError(ContainSubstring("pullRegAddr")))
+			})
+		})
+	})
 })
@@ -21,6 +21,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -51,6 +52,7 @@ var _ = BeforeEach(func() {
 		CRDDirectoryPaths: []string{
 			filepath.Join("..", "..", "tests", "dependencies", "vendor", "istio", "manifests", "charts", "base", "files"),
 			filepath.Join("..", "..", "tests", "dependencies", "vendor", "istio-kyma"),
+			filepath.Join("..", "..", "module-data", "vendor", "gateway-api", "standard-install.yaml"),
 		},
 		ErrorIfCRDPathMissing: true,
 	}
@@ -59,6 +61,7 @@ var _ = BeforeEach(func() {
 	Expect(err).NotTo(HaveOccurred())
 	Expect(networkingv1beta1.AddToScheme(testEnv.Scheme)).To(Succeed())
 	Expect(istiov1alpha2.AddToScheme(testEnv.Scheme)).To(Succeed())
+	Expect(gatewayv1.Install(testEnv.Scheme)).To(Succeed())
 
 	adminClient, stopClientCache = helpers.NewCachedClient(testEnv.Config)
 
@@ -2,8 +2,11 @@ package kyma
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
@@ -11,16 +14,32 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const oidcDiscoveryPath = "/.well-known/openid-configuration"
+
 type UAA struct {
-	k8sClient client.Client
+	k8sClient  client.Client
+	httpClient *http.Client
 }
 
-func NewUAA(k8sClient client.Client) *UAA {
+func NewUAA(k8sClient client.Client, httpClient *http.Client) *UAA {
 	return &UAA{
-		k8sClient: k8sClient,
+		k8sClient:  k8sClient,
+		httpClient: httpClient,
 	}
 }
 
+type oidcConfiguration struct {
+	Issuer  string `json:"issuer"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+type jwks struct {
+	Keys []json.RawMessage `json:"keys"`
+}
+
+// GetURL discovers the UAA of the subaccount. Candidate URLs are derived from
+// the token url of the btp service operator and the first one exposing a
+// valid OIDC discovery document is returned
 func (o *UAA) GetURL(ctx context.Context) (string, error) {
 	btpServiceOperatorSecret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
@@ -28,7 +47,7 @@ func (o *UAA) GetURL(ctx context.Context) (string, error) {
 			Name:      "sap-btp-service-operator",
 		},
 	}
-	err := o.k8sClient.Get(context.Background(), client.ObjectKeyFromObject(btpServiceOperatorSecret), btpServiceOperatorSecret)
+	err := o.k8sClient.Get(ctx, client.ObjectKeyFromObject(btpServiceOperatorSecret), btpServiceOperatorSecret)
 	if err != nil {
 		return "", fmt.Errorf("failed to get the btp service operator secret: %w. Make sure the btp operator kyma module is enbled", err)
 	}
@@ -38,15 +57,97 @@ func (o *UAA) GetURL(ctx context.Context) (string, error) {
 		return "", errors.New("btp service operator secret does not contain key 'tokenurl'")
 	}
 
-	return extractUaaURLFromTokenUrl(string(tokenURLBytes)), nil
+	candidates, err := uaaCandidates(string(tokenURLBytes))
+	if err != nil {
+		return "", err
+	}
+
+	validationErrs := []error{}
+	for _, candidate := range candidates {
+		validationErr := o.Validate(ctx, candidate)
+		if validationErr == nil {
+			return candidate, nil
+		}
+		validationErrs = append(validationErrs, validationErr)
+	}
+
+	return "", fmt.Errorf("failed to discover the UAA url from token url %q: %w", string(tokenURLBytes), errors.Join(validationErrs...))
+}
+
+// Validate checks that the UAA serves an OIDC discovery document with a
+// matching issuer and a reachable JWKS endpoint
+func (o *UAA) Validate(ctx context.Context, uaaURL string) error {
+	uaaURL = strings.TrimSuffix(uaaURL, "/")
+
+	config := oidcConfiguration{}
+	if err := o.getJSON(ctx, uaaURL+oidcDiscoveryPath, &config); err != nil {
+		return fmt.Errorf("failed to get the OIDC configuration of %s: %w", uaaURL, err)
+	}
+
+	if config.Issuer != uaaURL && !strings.HasPrefix(config.Issuer, uaaURL+"/") {
+		return fmt.Errorf("the issuer %q of %s does not match the UAA url", config.Issuer, uaaURL)
+	}
+
+	if config.JWKSURI == "" {
+		return fmt.Errorf("the OIDC configuration of %s does not contain a jwks_uri", uaaURL)
+	}
+
+	keys := jwks{}
+	if err := o.getJSON(ctx, config.JWKSURI, &keys); err != nil {
+		return fmt.Errorf("failed to get the JWKS of %s: %w", uaaURL, err)
+	}
+
+	if len(keys.Keys) == 0 {
+		return fmt.Errorf("the JWKS of %s does not contain any keys", uaaURL)
+	}
+
+	return nil
 }
 
-func extractUaaURLFromTokenUrl(tokenUrl string) string {
-	// input => https://worker1-q3zjpctt.authentication.eu12.hana.ondemand.com
-	// output => https://uaa.cf.eu12.hana.ondemand.com
+func (o *UAA) getJSON(ctx context.Context, url string, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
 
-	parts := strings.Split(tokenUrl, ".")
-	parts = parts[2:]
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned status %d", url, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// uaaCandidates derives the possible CF UAA urls from the subaccount token url
+// by prefixing every parent domain of the token url host with `uaa.cf.`, e.g.
+// https://worker1-q3zjpctt.authentication.eu12.hana.ondemand.com results in
+// https://uaa.cf.authentication.eu12.hana.ondemand.com,
+// https://uaa.cf.eu12.hana.ondemand.com, https://uaa.cf.hana.ondemand.com
+func uaaCandidates(tokenURL string) ([]string, error) {
+	parsedURL, err := url.Parse(tokenURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token url %q: %w", tokenURL, err)
+	}
+
+	labels := strings.Split(parsedURL.Hostname(), ".")
+	if len(labels) < 3 {
+		return nil, fmt.Errorf("token url %q does not contain a subaccount domain", tokenURL)
+	}
+
+	candidates := []string{}
+	for i := 1; i < len(labels)-1; i++ {
+		host := "uaa.cf." + strings.Join(labels[i:], ".")
+		if parsedURL.Port() != "" {
+			host = host + ":" + parsedURL.Port()
+		}
+		candidates = append(candidates, (&url.URL{Scheme: parsedURL.Scheme, Host: host}).String())
+	}
 
-	return "https://uaa.cf." + strings.Join(parts, ".")
+	return candidates, nil
 }
@@ -1,6 +1,9 @@
 package kyma_test
 
 import (
+	"net/http"
+	"net/http/httptest"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -12,53 +15,114 @@ import (
 
 var _ = Describe("UAA", func() {
 	var (
-		uaa    *kyma.UAA
-		uaaURL string
-		err    error
+		oidcServer *httptest.Server
+		uaa        *kyma.UAA
 	)
 
 	BeforeEach(func() {
-		uaa = kyma.NewUAA(adminClient)
-	})
+		oidcServer = helpers.NewOIDCStandIn("uaa.cf.eu12.hana.ondemand.com", "my-own.uaa.com")
+		DeferCleanup(oidcServer.Close)
 
-	JustBeforeEach(func() {
-		uaaURL, err = uaa.GetURL(ctx)
+		uaa = kyma.NewUAA(adminClient, helpers.NewStandInHTTPClient(oidcServer))
 	})
 
-	It("returns an error", func() {
-		Expect(err).To(MatchError(ContainSubstring("not found")))
+	Describe("GetURL", func() {
+		var (
+			uaaURL string
+			err    error
+		)
+
+		JustBeforeEach(func() {
+			uaaURL, err = uaa.GetURL(ctx)
+		})
+
+		It("returns an error", func() {
+			Expect(err).To(MatchError(ContainSubstring("not found")))
+		})
+
+		When("the btp service manager operator secret exists", func() {
+			var btpOperatorSecret *corev1.Secret
+
+			BeforeEach(func() {
+				btpOperatorSecret = &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: "kyma-system",
+						Name:      "sap-btp-service-operator",
+					},
+					StringData: map[string]string{
+						"tokenurl": "https://worker1-q3zjpctt.authentication.eu12.hana.ondemand.com",
+					},
+				}
+				helpers.EnsureCreate(adminClient, btpOperatorSecret)
+			})
+
+			It("discovers the uaa url", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(uaaURL).To(Equal("https://uaa.cf.eu12.hana.ondemand.com"))
+			})
+
+			When("the token url belongs to a landscape without a UAA", func() {
+				BeforeEach(func() {
+					helpers.EnsurePatch(adminClient, btpOperatorSecret, func(s *corev1.Secret) {
+						s.Data = map[string][]byte{
+							"tokenurl": []byte("https://worker1-q3zjpctt.auth.unknown.example.com"),
+						}
+					})
+				})
+
+				It("returns an error listing the validated candidates", func() {
+					Expect(err).To(MatchError(ContainSubstring("failed to discover the UAA url")))
+					Expect(err).To(MatchError(ContainSubstring("https://uaa.cf.unknown.example.com")))
+				})
+			})
+
+			When("the btp operator secret does not have a tokenurl key", func() {
+				BeforeEach(func() {
+					helpers.EnsurePatch(adminClient, btpOperatorSecret, func(s *corev1.Secret) {
+						s.Data = map[string][]byte{}
+					})
+				})
+
+				It("returns an error", func() {
+					Expect(err).To(MatchError(ContainSubstring("does not contain key")))
+				})
+			})
+		})
 	})
 
-	When("the btp service manager operator secret exists", func() {
-		var btpOperatorSecret *corev1.Secret
+	Describe("Validate", func() {
+		var uaaURL string
 
 		BeforeEach(func() {
-			btpOperatorSecret = &corev1.Secret{
-				ObjectMeta: metav1.ObjectMeta{
-					Namespace: "kyma-system",
-					Name:      "sap-btp-service-operator",
-				},
-				StringData: map[string]string{
-					"tokenurl": "https://worker1-q3zjpctt.authentication.eu12.hana.ondemand.com",
-				},
-			}
-			helpers.EnsureCreate(adminClient, btpOperatorSecret)
+			uaaURL = "https://my-own.uaa.com"
 		})
 
-		It("gets the uaa url", func() {
-			Expect(err).NotTo(HaveOccurred())
-			Expect(uaaURL).To(Equal("https://uaa.cf.eu12.hana.ondemand.com"))
+		It("succeeds", func() {
+			Expect(uaa.Validate(ctx, uaaURL)).To(Succeed())
 		})
 
-		When("the btp operator secret does not have a tokenurl key", func() {
+		When("the uaa does not serve an OIDC configuration", func() {
 			BeforeEach(func() {
-				helpers.EnsurePatch(adminClient, btpOperatorSecret, func(s *corev1.Secret) {
-					s.Data = map[string][]byte{}
-				})
+				uaaURL = "https://not-a.uaa.com"
+			})
+
+			It("returns an error", func() {
+				Expect(uaa.Validate(ctx, uaaURL)).To(MatchError(ContainSubstring("returned status 404")))
+			})
+		})
+
+		When("the issuer does not match the uaa url", func() {
+			BeforeEach(func() {
+				otherServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					_, _ = w.Write([]byte(`{"issuer":"https://other.uaa.com/oauth/token","jwks_uri":"https://other.uaa.com/token_keys"}`))
+				}))
+				DeferCleanup(otherServer.Close)
+
+				uaa = kyma.NewUAA(adminClient, helpers.NewStandInHTTPClient(otherServer))
 			})
 
 			It("returns an error", func() {
-				Expect(err).To(MatchError(ContainSubstring("does not contain key")))
+				Expect(uaa.Validate(ctx, uaaURL)).To(MatchError(ContainSubstring("does not match the UAA url")))
 			})
 		})
 	})
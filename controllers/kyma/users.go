@@ -8,6 +8,13 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const (
+	// UAAUserPrefix and UAAGroupPrefix are prepended to the UAA token user
+	// name and groups claims by the OIDC configuration of the cluster
+	UAAUserPrefix  = "sap.ids:"
+	UAAGroupPrefix = "sap.ids.groups:"
+)
+
 type Users struct {
 	k8sClient client.Client
 }
@@ -19,6 +26,14 @@ func NewUsers(k8sClient client.Client) *Users {
 }
 
 func (u *Users) GetClusterAdmins(ctx context.Context) ([]rbacv1.Subject, error) {
+	return u.getClusterAdminSubjects(ctx, rbacv1.UserKind)
+}
+
+func (u *Users) GetClusterAdminGroups(ctx context.Context) ([]rbacv1.Subject, error) {
+	return u.getClusterAdminSubjects(ctx, rbacv1.GroupKind)
+}
+
+func (u *Users) getClusterAdminSubjects(ctx context.Context, kind string) ([]rbacv1.Subject, error) {
 	subjects := []rbacv1.Subject{}
 
 	clusterRoleBindings := &rbacv1.ClusterRoleBindingList{}
@@ -33,7 +48,7 @@ func (u *Users) GetClusterAdmins(ctx context.Context) ([]rbacv1.Subject, error)
 		}
 
 		for _, subject := range crb.Subjects {
-			if subject.Kind != "User" {
+			if subject.Kind != kind {
 				continue
 			}
 			subjects = append(subjects, subject)
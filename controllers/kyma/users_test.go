@@ -12,62 +12,96 @@ import (
 )
 
 var _ = Describe("Users", func() {
-	var (
-		users  *kyma.Users
-		admins []rbacv1.Subject
-	)
+	var users *kyma.Users
 
 	BeforeEach(func() {
 		users = kyma.NewUsers(adminClient)
 	})
 
-	JustBeforeEach(func() {
-		var err error
-		admins, err = users.GetClusterAdmins(ctx)
-		Expect(err).NotTo(HaveOccurred())
-	})
-
-	It("returns an empty list", func() {
-		Expect(admins).To(BeEmpty())
-	})
-
-	When("there are admin users", func() {
-		BeforeEach(func() {
-			helpers.EnsureCreate(adminClient, &rbacv1.ClusterRoleBinding{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: uuid.NewString(),
+	createClusterAdminBinding := func() {
+		helpers.EnsureCreate(adminClient, &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: uuid.NewString(),
+			},
+			Subjects: []rbacv1.Subject{
+				{
+					Kind:      "ServiceAccount",
+					Name:      "admin-sa",
+					Namespace: testNamespace,
 				},
-				Subjects: []rbacv1.Subject{
-					{
-						Kind:      "ServiceAccount",
-						Name:      "admin-sa",
-						Namespace: testNamespace,
-					},
-					{
-						Kind:      "Group",
-						Name:      "admin-group",
-						Namespace: testNamespace,
-					},
-					{
-						Kind:      "User",
-						Name:      "admin-user",
-						Namespace: testNamespace,
-					},
+				{
+					Kind:      "Group",
+					Name:      "admin-group",
+					Namespace: testNamespace,
 				},
-				RoleRef: rbacv1.RoleRef{
-					Kind: "ClusterRole",
-					Name: "cluster-admin",
+				{
+					Kind:      "User",
+					Name:      "admin-user",
+					Namespace: testNamespace,
 				},
+			},
+			RoleRef: rbacv1.RoleRef{
+				Kind: "ClusterRole",
+				Name: "cluster-admin",
+			},
+		})
+	}
+
+	Describe("GetClusterAdmins", func() {
+		var admins []rbacv1.Subject
+
+		JustBeforeEach(func() {
+			var err error
+			admins, err = users.GetClusterAdmins(ctx)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns an empty list", func() {
+			Expect(admins).To(BeEmpty())
+		})
+
+		When("there are admin users", func() {
+			BeforeEach(func() {
+				createClusterAdminBinding()
 			})
+
+			It("returns user subjects only", func() {
+				Expect(admins).To(ConsistOf(rbacv1.Subject{
+					APIGroup:  "rbac.authorization.k8s.io",
+					Kind:      "User",
+					Name:      "admin-user",
+					Namespace: testNamespace,
+				}))
+			})
+		})
+	})
+
+	Describe("GetClusterAdminGroups", func() {
+		var adminGroups []rbacv1.Subject
+
+		JustBeforeEach(func() {
+			var err error
+			adminGroups, err = users.GetClusterAdminGroups(ctx)
+			Expect(err).NotTo(HaveOccurred())
 		})
 
-		It("returns user subjects only", func() {
-			Expect(admins).To(ConsistOf(rbacv1.Subject{
-				APIGroup:  "rbac.authorization.k8s.io",
-				Kind:      "User",
-				Name:      "admin-user",
-				Namespace: testNamespace,
-			}))
+		It("returns an empty list", func() {
+			Expect(adminGroups).To(BeEmpty())
+		})
+
+		When("there are admin groups", func() {
+			BeforeEach(func() {
+				createClusterAdminBinding()
+			})
+
+			It("returns group subjects only", func() {
+				Expect(adminGroups).To(ConsistOf(rbacv1.Subject{
+					APIGroup:  "rbac.authorization.k8s.io",
+					Kind:      "Group",
+					Name:      "admin-group",
+					Namespace: testNamespace,
+				}))
+			})
 		})
 	})
 })
@@ -0,0 +1,128 @@
+package proxy
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var workloadKinds = []string{"Deployment", "StatefulSet", "DaemonSet", "Job", "CronJob"}
+
+// EnvVars returns the proxy variables of the proxy config by name. Both the
+// upper and lower case variants are set, as tools disagree on which one they
+// read. Variables of empty settings are left out
+func EnvVars(config *v1alpha1.ProxyConfig) map[string]string {
+	envVars := map[string]string{}
+	if config == nil {
+		return envVars
+	}
+
+	for name, value := range map[string]string{
+		"HTTP_PROXY":  config.HTTPProxy,
+		"HTTPS_PROXY": config.HTTPSProxy,
+		"NO_PROXY":    config.NoProxy,
+	} {
+		if value != "" {
+			envVars[name] = value
+			envVars[strings.ToLower(name)] = value
+		}
+	}
+	return envVars
+}
+
+// Injector sets the proxy variables in the containers of the workloads of
+// the installed components
+type Injector struct {
+	namespaces []string
+	envVars    map[string]string
+}
+
+// NewInjector returns the injector of the installation config for workloads
+// in the given namespaces, or nil if no proxy is configured. A nil injector
+// leaves workloads as they are
+func NewInjector(config v1alpha1.InstallationConfig, namespaces ...string) *Injector {
+	if config.Proxy == nil {
+		return nil
+	}
+
+	return &Injector{
+		namespaces: namespaces,
+		envVars:    EnvVars(config.Proxy),
+	}
+}
+
+// Inject sets the proxy variables in the pod template of a workload. Objects
+// without a namespace are taken to be in the given default namespace, like
+// the resources of a helm release
+func (i *Injector) Inject(obj *unstructured.Unstructured, defaultNamespace string) {
+	if i == nil || !slices.Contains(workloadKinds, obj.GetKind()) {
+		return
+	}
+
+	namespace := obj.GetNamespace()
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+	if !slices.Contains(i.namespaces, namespace) {
+		return
+	}
+
+	podSpecPath := []string{"spec", "template", "spec"}
+	if obj.GetKind() == "CronJob" {
+		podSpecPath = []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	}
+	podSpec, ok := nestedMap(obj.Object, podSpecPath...)
+	if !ok {
+		return
+	}
+
+	names := make([]string, 0, len(i.envVars))
+	for name := range i.envVars {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _ := podSpec[field].([]any)
+		for _, container := range containers {
+			container, ok := container.(map[string]any)
+			if !ok {
+				continue
+			}
+
+			env, _, _ := unstructured.NestedSlice(container, "env")
+			for _, name := range names {
+				env = setNamed(env, map[string]any{"name": name, "value": i.envVars[name]})
+			}
+			container["env"] = env
+		}
+	}
+}
+
+// setNamed replaces the item with the name of the given item, or appends it
+func setNamed(items []any, item map[string]any) []any {
+	for i, existing := range items {
+		if existingItem, ok := existing.(map[string]any); ok && existingItem["name"] == item["name"] {
+			items[i] = item
+			return items
+		}
+	}
+
+	return append(items, item)
+}
+
+// nestedMap returns the map at the given path without copying it, so that it
+// can be modified in place
+func nestedMap(obj map[string]any, path ...string) (map[string]any, bool) {
+	for _, field := range path {
+		next, ok := obj[field].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		obj = next
+	}
+
+	return obj, true
+}
@@ -0,0 +1,99 @@
+package proxy_test
+
+import (
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/proxy"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("Injector", func() {
+	var (
+		config   v1alpha1.InstallationConfig
+		injector *proxy.Injector
+		obj      *unstructured.Unstructured
+	)
+
+	containerEnv := func() []any {
+		GinkgoHelper()
+
+		containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+		Expect(containers).To(HaveLen(1))
+		env, _, _ := unstructured.NestedSlice(containers[0].(map[string]any), "env")
+		return env
+	}
+
+	BeforeEach(func() {
+		config = v1alpha1.InstallationConfig{
+			Proxy: &v1alpha1.ProxyConfig{
+				HTTPProxy:  "http://proxy.example.com:3128",
+				HTTPSProxy: "http://proxy.example.com:3128",
+				NoProxy:    "localhost,.svc",
+			},
+		}
+
+		obj = &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]any{
+				"name":      "korifi-api-deployment",
+				"namespace": "korifi",
+			},
+			"spec": map[string]any{
+				"template": map[string]any{
+					"spec": map[string]any{
+						"containers": []any{
+							map[string]any{
+								"name":  "korifi-api",
+								"image": "korifi-api:latest",
+								"env": []any{
+									map[string]any{"name": "LOG_LEVEL", "value": "info"},
+									map[string]any{"name": "HTTP_PROXY", "value": "http://other.example.com"},
+								},
+							},
+						},
+					},
+				},
+			},
+		}}
+	})
+
+	JustBeforeEach(func() {
+		injector = proxy.NewInjector(config, "korifi", "kpack")
+		injector.Inject(obj, "")
+	})
+
+	It("sets the proxy variables of the containers", func() {
+		Expect(containerEnv()).To(ConsistOf(
+			map[string]any{"name": "LOG_LEVEL", "value": "info"},
+			map[string]any{"name": "HTTP_PROXY", "value": "http://proxy.example.com:3128"},
+			map[string]any{"name": "HTTPS_PROXY", "value": "http://proxy.example.com:3128"},
+			map[string]any{"name": "NO_PROXY", "value": "localhost,.svc"},
+			map[string]any{"name": "http_proxy", "value": "http://proxy.example.com:3128"},
+			map[string]any{"name": "https_proxy", "value": "http://proxy.example.com:3128"},
+			map[string]any{"name": "no_proxy", "value": "localhost,.svc"},
+		))
+	})
+
+	When("the workload is in another namespace", func() {
+		BeforeEach(func() {
+			obj.SetNamespace("default")
+		})
+
+		It("leaves the workload as it is", func() {
+			Expect(containerEnv()).To(HaveLen(2))
+		})
+	})
+
+	When("no proxy is configured", func() {
+		BeforeEach(func() {
+			config.Proxy = nil
+		})
+
+		It("leaves the workload as it is", func() {
+			Expect(injector).To(BeNil())
+			Expect(containerEnv()).To(HaveLen(2))
+		})
+	})
+})
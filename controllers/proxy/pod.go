@@ -0,0 +1,29 @@
+package proxy
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// InjectPod sets the proxy variables in all containers of a pod, like Inject
+// does for the pod templates of the installed components. Variables a
+// container already sets are left to it, so that an app can still set its
+// own proxy with `cf set-env`
+func InjectPod(pod *corev1.Pod, config *v1alpha1.ProxyConfig) {
+	envVars := EnvVars(config)
+	names := slices.Sorted(maps.Keys(envVars))
+
+	for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for i := range containers {
+			for _, name := range names {
+				if slices.ContainsFunc(containers[i].Env, func(e corev1.EnvVar) bool { return e.Name == name }) {
+					continue
+				}
+				containers[i].Env = append(containers[i].Env, corev1.EnvVar{Name: name, Value: envVars[name]})
+			}
+		}
+	}
+}
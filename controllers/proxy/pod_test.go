@@ -0,0 +1,65 @@
+package proxy_test
+
+import (
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/proxy"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("InjectPod", func() {
+	var (
+		config *v1alpha1.ProxyConfig
+		pod    *corev1.Pod
+	)
+
+	BeforeEach(func() {
+		config = &v1alpha1.ProxyConfig{
+			HTTPProxy:  "http://proxy.example.com:3128",
+			HTTPSProxy: "http://proxy.example.com:3128",
+			NoProxy:    "localhost,.svc",
+		}
+
+		pod = &corev1.Pod{
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{
+					Name:  "build",
+					Image: "kpack-builder:latest",
+					Env:   []corev1.EnvVar{{Name: "HTTP_PROXY", Value: "http://app.example.com"}},
+				}},
+				Containers: []corev1.Container{{
+					Name:  "completion",
+					Image: "kpack-completion:latest",
+				}},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		proxy.InjectPod(pod, config)
+	})
+
+	It("sets the proxy variables the containers do not set", func() {
+		Expect(pod.Spec.InitContainers[0].Env).To(ConsistOf(
+			corev1.EnvVar{Name: "HTTP_PROXY", Value: "http://app.example.com"},
+			corev1.EnvVar{Name: "HTTPS_PROXY", Value: "http://proxy.example.com:3128"},
+			corev1.EnvVar{Name: "NO_PROXY", Value: "localhost,.svc"},
+			corev1.EnvVar{Name: "http_proxy", Value: "http://proxy.example.com:3128"},
+			corev1.EnvVar{Name: "https_proxy", Value: "http://proxy.example.com:3128"},
+			corev1.EnvVar{Name: "no_proxy", Value: "localhost,.svc"},
+		))
+		Expect(pod.Spec.Containers[0].Env).To(HaveLen(6))
+	})
+
+	When("no proxy is configured", func() {
+		BeforeEach(func() {
+			config = nil
+		})
+
+		It("leaves the pod as it is", func() {
+			Expect(pod.Spec.InitContainers[0].Env).To(HaveLen(1))
+			Expect(pod.Spec.Containers[0].Env).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,86 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"golang.org/x/net/http/httpproxy"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Settings routes the outgoing requests of the operator through the proxy
+// of the installation config. Until a proxy is configured, the proxy
+// variables of the operator's environment are used
+type Settings struct {
+	mu        sync.RWMutex
+	proxyFunc func(*url.URL) (*url.URL, error)
+}
+
+func NewSettings() *Settings {
+	return &Settings{}
+}
+
+// Set switches the requests to the given proxy. A nil config falls back to
+// the proxy variables of the environment
+func (s *Settings) Set(config *v1alpha1.ProxyConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if config == nil {
+		s.proxyFunc = nil
+		return
+	}
+
+	s.proxyFunc = (&httpproxy.Config{
+		HTTPProxy:  config.HTTPProxy,
+		HTTPSProxy: config.HTTPSProxy,
+		NoProxy:    config.NoProxy,
+	}).ProxyFunc()
+}
+
+// Load sets the proxy of the installed CFAPI, so that the requests sent before
+// the CFAPI is reconciled after a restart of the operator already go through
+// the proxy
+func (s *Settings) Load(ctx context.Context, k8sReader client.Reader) error {
+	cfAPIs := &v1alpha1.CFAPIList{}
+	if err := k8sReader.List(ctx, cfAPIs); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to list CFAPIs: %w", err)
+	}
+
+	for _, cfAPI := range cfAPIs.Items {
+		if cfAPI.DeletionTimestamp.IsZero() && cfAPI.Status.InstallationConfig.RootNamespace != "" {
+			s.Set(cfAPI.Status.InstallationConfig.Proxy)
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Proxy returns the proxy of a request, as `http.Transport.Proxy` expects it
+func (s *Settings) Proxy(req *http.Request) (*url.URL, error) {
+	s.mu.RLock()
+	proxyFunc := s.proxyFunc
+	s.mu.RUnlock()
+
+	if proxyFunc == nil {
+		return http.ProxyFromEnvironment(req)
+	}
+	return proxyFunc(req.URL)
+}
+
+// Transport returns a transport like the default one that routes requests
+// through the current proxy
+func (s *Settings) Transport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = s.Proxy
+	return transport
+}
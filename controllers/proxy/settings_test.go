@@ -0,0 +1,116 @@
+package proxy_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/proxy"
+	"github.com/kyma-project/cfapi/tools/k8s/fake"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Settings", func() {
+	var settings *proxy.Settings
+
+	proxyOf := func(rawURL string) *url.URL {
+		GinkgoHelper()
+
+		req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+		Expect(err).NotTo(HaveOccurred())
+		proxyURL, err := settings.Proxy(req)
+		Expect(err).NotTo(HaveOccurred())
+		return proxyURL
+	}
+
+	BeforeEach(func() {
+		settings = proxy.NewSettings()
+		settings.Set(&v1alpha1.ProxyConfig{
+			HTTPProxy:  "http://proxy.example.com:3128",
+			HTTPSProxy: "http://secure-proxy.example.com:3128",
+			NoProxy:    ".svc,10.0.0.0/8,apps.example.com",
+		})
+	})
+
+	It("routes requests through the proxy", func() {
+		Expect(proxyOf("http://uaa.example.org")).To(Equal(&url.URL{Scheme: "http", Host: "proxy.example.com:3128"}))
+		Expect(proxyOf("https://uaa.example.org")).To(Equal(&url.URL{Scheme: "http", Host: "secure-proxy.example.com:3128"}))
+	})
+
+	It("bypasses the proxy for the excluded hosts", func() {
+		Expect(proxyOf("https://registry.kyma-system.svc:5000")).To(BeNil())
+		Expect(proxyOf("https://10.1.2.3")).To(BeNil())
+		Expect(proxyOf("https://my-app.apps.example.com")).To(BeNil())
+	})
+
+	It("uses the proxy of the transport", func() {
+		transport := settings.Transport()
+		req, err := http.NewRequest(http.MethodGet, "https://uaa.example.org", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		proxyURL, err := transport.Proxy(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(proxyURL.Host).To(Equal("secure-proxy.example.com:3128"))
+	})
+
+	When("the proxy is removed", func() {
+		BeforeEach(func() {
+			settings.Set(nil)
+		})
+
+		It("falls back to the environment", func() {
+			GinkgoT().Setenv("HTTPS_PROXY", "")
+			GinkgoT().Setenv("https_proxy", "")
+			Expect(proxyOf("https://uaa.example.org")).To(BeNil())
+		})
+	})
+
+	Describe("Load", func() {
+		var (
+			fakeClient *fake.Client
+			cfAPIs     []v1alpha1.CFAPI
+			loadErr    error
+		)
+
+		BeforeEach(func() {
+			settings = proxy.NewSettings()
+			cfAPIs = []v1alpha1.CFAPI{{
+				Status: v1alpha1.CFAPIStatus{
+					InstallationConfig: v1alpha1.InstallationConfig{
+						RootNamespace: "cf",
+						Proxy:         &v1alpha1.ProxyConfig{HTTPSProxy: "http://secure-proxy.example.com:3128"},
+					},
+				},
+			}}
+
+			fakeClient = new(fake.Client)
+			fakeClient.ListStub = func(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+				list.(*v1alpha1.CFAPIList).Items = cfAPIs
+				return nil
+			}
+		})
+
+		JustBeforeEach(func() {
+			loadErr = settings.Load(context.Background(), fakeClient)
+		})
+
+		It("sets the proxy of the installed CFAPI", func() {
+			Expect(loadErr).NotTo(HaveOccurred())
+			Expect(proxyOf("https://uaa.example.org")).To(Equal(&url.URL{Scheme: "http", Host: "secure-proxy.example.com:3128"}))
+		})
+
+		When("listing the CFAPIs fails", func() {
+			BeforeEach(func() {
+				fakeClient.ListReturns(errors.New("list-error"))
+			})
+
+			It("returns the error", func() {
+				Expect(loadErr).To(MatchError(ContainSubstring("list-error")))
+			})
+		})
+	})
+})
@@ -0,0 +1,223 @@
+package proxyenv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	korifiv1alpha1 "code.cloudfoundry.org/korifi/controllers/api/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/installable"
+	"github.com/kyma-project/cfapi/controllers/proxy"
+	"github.com/kyma-project/cfapi/tools/k8s"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// ManagedAnnotation holds the proxy variables the operator has set in an
+	// app environment secret, as a JSON object of their values
+	ManagedAnnotation = "cfapi.kyma-project.io/proxy-env"
+
+	// korifiPendingInterval is how often the reconciler checks whether Korifi
+	// has been installed
+	korifiPendingInterval = 30 * time.Second
+)
+
+var proxyVarNames = []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy"}
+
+// Reconciler sets the proxy variables in the environment of every CF app when
+// `spec.proxy.injectIntoApps` is set, so that the app containers reach the
+// internet through the proxy. Builds get the proxy variables from the pods
+// webhook regardless. Variables the app has set to other values are left to
+// the app, and the variables the reconciler has set are removed once the
+// injection is turned off.
+//
+// The CFApp kind only exists once Korifi is installed, which is why apps are
+// watched lazily from the first reconcile after the installation on
+type Reconciler struct {
+	k8sClient     client.Client
+	eventRecorder events.EventRecorder
+	appWatch      *k8s.LazyWatch
+}
+
+func NewReconciler(
+	k8sClient client.Client,
+	informers cache.Informers,
+	eventRecorder events.EventRecorder,
+	log logr.Logger,
+) *k8s.PatchingReconciler[v1alpha1.CFAPI] {
+	r := &Reconciler{
+		k8sClient:     k8sClient,
+		eventRecorder: eventRecorder,
+	}
+	r.appWatch = k8s.NewLazyWatch(informers, &korifiv1alpha1.CFApp{}, r.enqueueCFAPIs)
+
+	return k8s.NewPatchingReconciler(log, k8sClient, r)
+}
+
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) *builder.Builder {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("proxyenv").
+		For(&v1alpha1.CFAPI{}).
+		WatchesRawSource(r.appWatch.Source()).
+		Watches(
+			&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(r.enqueueCFAPIs),
+			builder.WithPredicates(predicate.NewPredicateFuncs(isAppSecret)),
+		)
+}
+
+// isAppSecret matches the secrets Korifi creates for apps, among them the
+// environment secrets, which are created after the app
+func isAppSecret(obj client.Object) bool {
+	_, ok := obj.GetLabels()[korifiv1alpha1.CFAppGUIDLabelKey]
+	return ok
+}
+
+func (r *Reconciler) enqueueCFAPIs(ctx context.Context, _ client.Object) []reconcile.Request {
+	cfAPIs := &v1alpha1.CFAPIList{}
+	if err := r.k8sClient.List(ctx, cfAPIs); err != nil {
+		logr.FromContextOrDiscard(ctx).Error(err, "failed to list CFAPIs")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, cfAPI := range cfAPIs.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&cfAPI)})
+	}
+	return requests
+}
+
+func (r *Reconciler) ReconcileResource(ctx context.Context, cfAPI *v1alpha1.CFAPI) (ctrl.Result, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	config := cfAPI.Status.InstallationConfig
+	if config.RootNamespace == "" || !cfAPI.DeletionTimestamp.IsZero() {
+		log.Info("cfapi is not installed, skipping proxy variables of apps")
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.appWatch.Start(ctx); err != nil {
+		if meta.IsNoMatchError(err) {
+			log.Info("waiting for Korifi to be installed")
+			return ctrl.Result{RequeueAfter: korifiPendingInterval}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	envVars := map[string]string{}
+	if config.Proxy != nil && config.Proxy.InjectIntoApps {
+		envVars = proxy.EnvVars(config.Proxy)
+	}
+
+	apps := &korifiv1alpha1.CFAppList{}
+	if err := r.k8sClient.List(ctx, apps); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list apps: %w", err)
+	}
+
+	updated := []string{}
+	for _, app := range apps.Items {
+		if app.Spec.EnvSecretName == "" || !app.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		envSecret := &corev1.Secret{}
+		err := r.k8sClient.Get(ctx, client.ObjectKey{Namespace: app.Namespace, Name: app.Spec.EnvSecretName}, envSecret)
+		if err != nil {
+			if client.IgnoreNotFound(err) == nil {
+				// reconciled again once Korifi has created the secret
+				continue
+			}
+			return ctrl.Result{}, fmt.Errorf("failed to get the environment of app %s/%s: %w", app.Namespace, app.Name, err)
+		}
+
+		changed, err := r.updateEnv(ctx, envSecret, envVars)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to update the proxy variables of app %s/%s: %w", app.Namespace, app.Name, err)
+		}
+		if changed {
+			updated = append(updated, app.Spec.DisplayName)
+		}
+	}
+
+	if len(updated) > 0 {
+		installable.NewCFAPIEventRecorder(r.eventRecorder, cfAPI).Event(installable.EventNormal, "ProxyEnvUpdated", fmt.Sprintf(
+			"Updated the proxy variables of apps %s. They apply once the apps are restarted",
+			strings.Join(updated, ", "),
+		))
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// updateEnv sets the proxy variables in an app environment secret and
+// returns whether it has changed. A variable is only set or removed while it
+// is missing or still has the value the operator has set
+func (r *Reconciler) updateEnv(ctx context.Context, envSecret *corev1.Secret, envVars map[string]string) (bool, error) {
+	managed := map[string]string{}
+	if annotation, ok := envSecret.Annotations[ManagedAnnotation]; ok {
+		if err := json.Unmarshal([]byte(annotation), &managed); err != nil {
+			managed = map[string]string{}
+		}
+	}
+
+	data := maps.Clone(envSecret.Data)
+	if data == nil {
+		data = map[string][]byte{}
+	}
+	newManaged := map[string]string{}
+	for _, name := range proxyVarNames {
+		current, isSet := data[name]
+		setValue, isManaged := managed[name]
+		if isSet && (!isManaged || string(current) != setValue) {
+			continue
+		}
+
+		value, desired := envVars[name]
+		if !desired {
+			delete(data, name)
+			continue
+		}
+		data[name] = []byte(value)
+		newManaged[name] = value
+	}
+
+	if maps.EqualFunc(data, envSecret.Data, func(v1, v2 []byte) bool { return string(v1) == string(v2) }) && maps.Equal(managed, newManaged) {
+		return false, nil
+	}
+
+	annotation, err := json.Marshal(newManaged)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal the managed proxy variables: %w", err)
+	}
+
+	err = k8s.PatchResource(ctx, r.k8sClient, envSecret, func() {
+		envSecret.Data = data
+		if envSecret.Annotations == nil {
+			envSecret.Annotations = map[string]string{}
+		}
+		if len(newManaged) == 0 {
+			delete(envSecret.Annotations, ManagedAnnotation)
+		} else {
+			envSecret.Annotations[ManagedAnnotation] = string(annotation)
+		}
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
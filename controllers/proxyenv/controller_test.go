@@ -0,0 +1,186 @@
+package proxyenv_test
+
+import (
+	korifiv1alpha1 "code.cloudfoundry.org/korifi/controllers/api/v1alpha1"
+	"github.com/google/uuid"
+	v1alpha1 "github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/proxyenv"
+	. "github.com/kyma-project/cfapi/tests/helpers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Proxy Env", func() {
+	var (
+		cfAPI       *v1alpha1.CFAPI
+		proxyConfig *v1alpha1.ProxyConfig
+		spaceNS     string
+		envSecret   *corev1.Secret
+	)
+
+	eventReasons := func(g Gomega) []string {
+		eventList := &eventsv1.EventList{}
+		g.Expect(adminClient.List(ctx, eventList, client.InNamespace(cfAPINamespace))).To(Succeed())
+
+		reasons := []string{}
+		for _, event := range eventList.Items {
+			reasons = append(reasons, event.Reason)
+		}
+		return reasons
+	}
+
+	getEnv := func(g Gomega) map[string]string {
+		g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(envSecret), envSecret)).To(Succeed())
+
+		env := map[string]string{}
+		for key, value := range envSecret.Data {
+			env[key] = string(value)
+		}
+		return env
+	}
+
+	BeforeEach(func() {
+		proxyConfig = &v1alpha1.ProxyConfig{
+			HTTPProxy:      "http://proxy.example.com:3128",
+			HTTPSProxy:     "http://proxy.example.com:3128",
+			NoProxy:        "localhost,.svc",
+			InjectIntoApps: true,
+		}
+
+		spaceNS = uuid.NewString()
+		EnsureCreate(adminClient, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: spaceNS,
+			},
+		})
+
+		app := &korifiv1alpha1.CFApp{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: spaceNS,
+				Name:      uuid.NewString(),
+			},
+			Spec: korifiv1alpha1.CFAppSpec{
+				DisplayName:   "my-app",
+				DesiredState:  korifiv1alpha1.StoppedState,
+				EnvSecretName: uuid.NewString(),
+				Lifecycle: korifiv1alpha1.Lifecycle{
+					Type: korifiv1alpha1.BuildpackLifecycle,
+				},
+			},
+		}
+		EnsureCreate(adminClient, app)
+
+		envSecret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: spaceNS,
+				Name:      app.Spec.EnvSecretName,
+				Labels:    map[string]string{korifiv1alpha1.CFAppGUIDLabelKey: app.Name},
+			},
+			StringData: map[string]string{
+				"LOG_LEVEL": "debug",
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		EnsureCreate(adminClient, envSecret)
+
+		cfAPI = &v1alpha1.CFAPI{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      uuid.NewString(),
+				Namespace: cfAPINamespace,
+			},
+		}
+		EnsureCreate(adminClient, cfAPI)
+
+		cfAPI.Status.InstallationConfig = v1alpha1.InstallationConfig{
+			RootNamespace: "cf",
+			Proxy:         proxyConfig,
+		}
+		Expect(adminClient.Status().Update(ctx, cfAPI)).To(Succeed())
+	})
+
+	It("sets the proxy variables in the app environment", func() {
+		Eventually(func(g Gomega) {
+			g.Expect(getEnv(g)).To(Equal(map[string]string{
+				"LOG_LEVEL":   "debug",
+				"HTTP_PROXY":  "http://proxy.example.com:3128",
+				"HTTPS_PROXY": "http://proxy.example.com:3128",
+				"NO_PROXY":    "localhost,.svc",
+				"http_proxy":  "http://proxy.example.com:3128",
+				"https_proxy": "http://proxy.example.com:3128",
+				"no_proxy":    "localhost,.svc",
+			}))
+			g.Expect(envSecret.Annotations).To(HaveKey(proxyenv.ManagedAnnotation))
+			g.Expect(eventReasons(g)).To(ContainElement("ProxyEnvUpdated"))
+		}).Should(Succeed())
+	})
+
+	When("the app sets a proxy variable itself", func() {
+		BeforeEach(func() {
+			envSecret.StringData["NO_PROXY"] = "internal.example.com"
+		})
+
+		It("keeps the value of the app", func() {
+			Eventually(func(g Gomega) {
+				env := getEnv(g)
+				g.Expect(env).To(HaveKeyWithValue("HTTP_PROXY", "http://proxy.example.com:3128"))
+				g.Expect(env).To(HaveKeyWithValue("NO_PROXY", "internal.example.com"))
+			}).Should(Succeed())
+		})
+	})
+
+	When("the proxy is not injected into apps", func() {
+		BeforeEach(func() {
+			proxyConfig.InjectIntoApps = false
+		})
+
+		It("leaves the app environment as it is", func() {
+			Consistently(func(g Gomega) {
+				g.Expect(getEnv(g)).To(Equal(map[string]string{"LOG_LEVEL": "debug"}))
+			}).Should(Succeed())
+		})
+	})
+
+	When("the injection into apps is turned off", func() {
+		JustBeforeEach(func() {
+			Eventually(func(g Gomega) {
+				g.Expect(getEnv(g)).To(HaveKey("HTTP_PROXY"))
+			}).Should(Succeed())
+
+			Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+			cfAPI.Status.InstallationConfig.Proxy.InjectIntoApps = false
+			Expect(adminClient.Status().Update(ctx, cfAPI)).To(Succeed())
+		})
+
+		It("removes the proxy variables from the app environment", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(getEnv(g)).To(Equal(map[string]string{"LOG_LEVEL": "debug"}))
+				g.Expect(envSecret.Annotations).NotTo(HaveKey(proxyenv.ManagedAnnotation))
+			}).Should(Succeed())
+		})
+	})
+
+	When("the proxy is removed", func() {
+		JustBeforeEach(func() {
+			Eventually(func(g Gomega) {
+				g.Expect(getEnv(g)).To(HaveKey("HTTP_PROXY"))
+			}).Should(Succeed())
+
+			Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+			cfAPI.Status.InstallationConfig.Proxy = nil
+			Expect(adminClient.Status().Update(ctx, cfAPI)).To(Succeed())
+		})
+
+		It("removes the proxy variables from the app environment", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(getEnv(g)).To(Equal(map[string]string{"LOG_LEVEL": "debug"}))
+				g.Expect(envSecret.Annotations).NotTo(HaveKey(proxyenv.ManagedAnnotation))
+			}).Should(Succeed())
+		})
+	})
+})
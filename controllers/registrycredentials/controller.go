@@ -0,0 +1,111 @@
+package registrycredentials
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/cfapi/secrets"
+	"github.com/kyma-project/cfapi/controllers/installable"
+	"github.com/kyma-project/cfapi/tools/k8s"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Reconciler keeps the short-lived credentials configured in
+// `containerRegistryCredentials` refreshed in the container registry secret.
+// It reads the spec rather than the installation config, as the installation
+// requires the registry secret to exist
+type Reconciler struct {
+	k8sClient     client.Client
+	eventRecorder events.EventRecorder
+	refresher     *secrets.CredentialRefresher
+}
+
+func NewReconciler(
+	k8sClient client.Client,
+	eventRecorder events.EventRecorder,
+	refresher *secrets.CredentialRefresher,
+	log logr.Logger,
+) *k8s.PatchingReconciler[v1alpha1.CFAPI] {
+	return k8s.NewPatchingReconciler(log, k8sClient, &Reconciler{
+		k8sClient:     k8sClient,
+		eventRecorder: eventRecorder,
+		refresher:     refresher,
+	})
+}
+
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) *builder.Builder {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("registrycredentials").
+		For(&v1alpha1.CFAPI{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.enqueueCFAPIs))
+}
+
+func (r *Reconciler) enqueueCFAPIs(ctx context.Context, obj client.Object) []reconcile.Request {
+	cfAPIs := &v1alpha1.CFAPIList{}
+	if err := r.k8sClient.List(ctx, cfAPIs, client.InNamespace(obj.GetNamespace())); err != nil {
+		logr.FromContextOrDiscard(ctx).Error(err, "failed to list CFAPIs")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, cfAPI := range cfAPIs.Items {
+		credentials := cfAPI.Spec.ContainerRegistryCredentials
+		if credentials == nil || !slices.Contains([]string{cfAPI.Spec.ContainerRegistrySecret, credentials.CredentialsSecret}, obj.GetName()) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&cfAPI)})
+	}
+	return requests
+}
+
+func (r *Reconciler) ReconcileResource(ctx context.Context, cfAPI *v1alpha1.CFAPI) (ctrl.Result, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	credentials := cfAPI.Spec.ContainerRegistryCredentials
+	if credentials == nil || !cfAPI.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	eventRecorder := installable.NewCFAPIEventRecorder(r.eventRecorder, cfAPI)
+
+	if cfAPI.Spec.ContainerRegistrySecret == "" {
+		eventRecorder.Event(installable.EventWarning, "RegistryCredentialsRefreshFailed",
+			"containerRegistryCredentials require containerRegistrySecret to be set")
+		return ctrl.Result{}, nil
+	}
+
+	refreshAt, refreshed, err := r.refresher.Refresh(ctx, cfAPI.Namespace, cfAPI.Spec.ContainerRegistrySecret, secrets.RegistryCredentialsConfig{
+		Provider:          credentials.Provider,
+		Registry:          credentials.Registry,
+		TokenURL:          credentials.TokenURL,
+		Scopes:            credentials.Scopes,
+		Username:          credentials.Username,
+		CredentialsSecret: credentials.CredentialsSecret,
+		ServiceAccount:    credentials.ServiceAccount,
+		Audience:          credentials.Audience,
+	})
+	if err != nil {
+		eventRecorder.Event(installable.EventWarning, "RegistryCredentialsRefreshFailed", err.Error())
+		return ctrl.Result{}, fmt.Errorf("failed to refresh registry credentials: %w", err)
+	}
+
+	if refreshed {
+		log.Info("refreshed registry credentials", "secret", cfAPI.Spec.ContainerRegistrySecret, "refreshAt", refreshAt)
+		eventRecorder.Event(installable.EventNormal, "RegistryCredentialsRefreshed", fmt.Sprintf(
+			"Refreshed the credentials for registry %s in secret %s, next refresh at %s",
+			credentials.Registry, cfAPI.Spec.ContainerRegistrySecret, refreshAt.UTC().Format(time.RFC3339),
+		))
+	}
+
+	return ctrl.Result{RequeueAfter: max(time.Until(refreshAt), time.Second)}, nil
+}
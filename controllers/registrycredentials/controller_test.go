@@ -0,0 +1,138 @@
+package registrycredentials_test
+
+import (
+	"github.com/google/uuid"
+	v1alpha1 "github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/cfapi/secrets"
+	. "github.com/kyma-project/cfapi/tests/helpers"
+	"github.com/kyma-project/cfapi/tools/k8s"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Registry Credentials Refresh", func() {
+	var (
+		cfAPI              *v1alpha1.CFAPI
+		registrySecretName string
+		credentialsSecret  *corev1.Secret
+	)
+
+	getRegistrySecret := func(g Gomega) *corev1.Secret {
+		secret := &corev1.Secret{}
+		g.Expect(adminClient.Get(ctx, client.ObjectKey{Namespace: cfAPINamespace, Name: registrySecretName}, secret)).To(Succeed())
+		return secret
+	}
+
+	eventReasons := func(g Gomega) []string {
+		eventList := &eventsv1.EventList{}
+		g.Expect(adminClient.List(ctx, eventList, client.InNamespace(cfAPINamespace))).To(Succeed())
+
+		reasons := []string{}
+		for _, event := range eventList.Items {
+			reasons = append(reasons, event.Reason)
+		}
+		return reasons
+	}
+
+	BeforeEach(func() {
+		registrySecretName = uuid.NewString()
+		credentialsSecret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: cfAPINamespace,
+				Name:      uuid.NewString(),
+			},
+			StringData: map[string]string{
+				"clientID":     "my-client",
+				"clientSecret": "my-secret",
+			},
+		}
+		EnsureCreate(adminClient, credentialsSecret)
+
+		cfAPI = &v1alpha1.CFAPI{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      uuid.NewString(),
+				Namespace: cfAPINamespace,
+			},
+			Spec: v1alpha1.CFAPISpec{
+				ContainerRegistrySecret: registrySecretName,
+				ContainerRegistryCredentials: &v1alpha1.ContainerRegistryCredentials{
+					Provider:          "oauth2",
+					Registry:          "my-registry.com",
+					TokenURL:          "https://my-idp.com/oauth/token",
+					CredentialsSecret: credentialsSecret.Name,
+				},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		EnsureCreate(adminClient, cfAPI)
+	})
+
+	It("writes short-lived credentials to the container registry secret", func() {
+		Eventually(func(g Gomega) {
+			secret := getRegistrySecret(g)
+			g.Expect(secret.Type).To(Equal(corev1.SecretTypeDockerConfigJson))
+			g.Expect(secret.Data[corev1.DockerConfigJsonKey]).To(ContainSubstring(OAuth2StandInAccessToken))
+			g.Expect(secret.Annotations).To(HaveKey(secrets.CredentialsRefreshAtAnnotation))
+			g.Expect(eventReasons(g)).To(ContainElement("RegistryCredentialsRefreshed"))
+		}).Should(Succeed())
+	})
+
+	When("the credentials are due for refresh", func() {
+		JustBeforeEach(func() {
+			var secret *corev1.Secret
+			Eventually(func(g Gomega) {
+				secret = getRegistrySecret(g)
+			}).Should(Succeed())
+
+			Expect(k8s.PatchResource(ctx, adminClient, secret, func() {
+				secret.Annotations[secrets.CredentialsRefreshAtAnnotation] = "2000-01-01T00:00:00Z"
+				secret.Data[corev1.DockerConfigJsonKey] = []byte(`{"auths":{}}`)
+			})).To(Succeed())
+		})
+
+		It("refreshes them", func() {
+			Eventually(func(g Gomega) {
+				secret := getRegistrySecret(g)
+				g.Expect(secret.Annotations[secrets.CredentialsRefreshAtAnnotation]).NotTo(Equal("2000-01-01T00:00:00Z"))
+				g.Expect(secret.Data[corev1.DockerConfigJsonKey]).To(ContainSubstring(OAuth2StandInAccessToken))
+			}).Should(Succeed())
+		})
+	})
+
+	When("the credentials are rejected", func() {
+		BeforeEach(func() {
+			Expect(k8s.PatchResource(ctx, adminClient, credentialsSecret, func() {
+				credentialsSecret.Data = map[string][]byte{
+					"clientID":     []byte("my-client"),
+					"clientSecret": []byte("wrong-secret"),
+				}
+			})).To(Succeed())
+		})
+
+		It("reports the failure", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(eventReasons(g)).To(ContainElement("RegistryCredentialsRefreshFailed"))
+			}).Should(Succeed())
+		})
+	})
+
+	When("no container registry credentials are configured", func() {
+		BeforeEach(func() {
+			cfAPI.Spec.ContainerRegistryCredentials = nil
+		})
+
+		It("does not create the registry secret", func() {
+			Consistently(func(g Gomega) {
+				err := adminClient.Get(ctx, client.ObjectKey{Namespace: cfAPINamespace, Name: registrySecretName}, &corev1.Secret{})
+				g.Expect(client.IgnoreNotFound(err)).To(Succeed())
+				g.Expect(err).To(HaveOccurred())
+			}).Should(Succeed())
+		})
+	})
+})
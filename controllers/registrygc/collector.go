@@ -0,0 +1,343 @@
+package registrygc
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	korifiv1alpha1 "code.cloudfoundry.org/korifi/controllers/api/v1alpha1"
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/cfapi/secrets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	ReasonOrphanedApp     = "OrphanedApp"
+	ReasonOutdatedDroplet = "OutdatedDroplet"
+
+	packagesSuffix = "-packages"
+	dropletsSuffix = "-droplets"
+	latestTag      = "latest"
+)
+
+// kpackBuildTag matches the tags kpack adds to every image it builds, e.g.
+// `b3.20250101.120000` for the third build
+var kpackBuildTag = regexp.MustCompile(`^b(\d+)\.`)
+
+type Settings struct {
+	DryRun             bool          `json:"dryRun"`
+	CollectDeletedApps bool          `json:"collectDeletedApps"`
+	GracePeriod        time.Duration `json:"gracePeriod"`
+	KeepDroplets       int           `json:"keepDroplets"`
+}
+
+// Deletion is a manifest deleted, or to be deleted in a dry run, together
+// with all tags referring to it
+type Deletion struct {
+	Repository string   `json:"repository"`
+	Digest     string   `json:"digest"`
+	Tags       []string `json:"tags,omitempty"`
+	Reason     string   `json:"reason"`
+}
+
+type Report struct {
+	DryRun bool `json:"dryRun"`
+	// OrphanedRepositories are the non-empty repositories of deleted apps
+	// with the time they were first found orphaned
+	OrphanedRepositories map[string]time.Time `json:"orphanedRepositories,omitempty"`
+	Deletions            []Deletion           `json:"deletions,omitempty"`
+	// Errors are failures on single repositories or manifests, which do not
+	// stop the collection
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Collector deletes the images Korifi pushes to `<prefix><appGUID>-packages`
+// and `<prefix><appGUID>-droplets` once their app has been deleted for the
+// grace period, and the outdated droplets of existing apps. The images of
+// deleted apps are only collected when the settings opt in, as other clusters
+// sharing the repository prefix push the images of their apps there as well
+type Collector struct {
+	k8sClient client.Client
+	docker    *secrets.Docker
+	registry  *secrets.RegistryClient
+}
+
+func NewCollector(k8sClient client.Client, docker *secrets.Docker, registry *secrets.RegistryClient) *Collector {
+	return &Collector{
+		k8sClient: k8sClient,
+		docker:    docker,
+		registry:  registry,
+	}
+}
+
+type collectionTarget struct {
+	secret string
+	prefix string
+	suffix string
+}
+
+type registryRepositories struct {
+	config       secrets.DockerRegistryConfig
+	repositories []string
+}
+
+// Collect lists the package and droplet repositories of the installation
+// config and deletes their garbage, unless the settings ask for a dry run.
+// orphanedSince holds the repositories found orphaned by previous runs
+func (c *Collector) Collect(
+	ctx context.Context,
+	namespace string,
+	config v1alpha1.InstallationConfig,
+	settings Settings,
+	orphanedSince map[string]time.Time,
+) (Report, error) {
+	currentDroplets, err := c.currentDroplets(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{
+		DryRun:               settings.DryRun,
+		OrphanedRepositories: map[string]time.Time{},
+	}
+
+	listed := map[string]registryRepositories{}
+	repositoryConfigs := map[string]secrets.DockerRegistryConfig{}
+	for _, target := range []collectionTarget{
+		{secret: config.PackageRegistrySecret, prefix: config.PackageRepositoryPrefix, suffix: packagesSuffix},
+		{secret: config.DropletRegistrySecret, prefix: config.DropletRepositoryPrefix, suffix: dropletsSuffix},
+	} {
+		registryURL := repositoryRegistry(target.prefix)
+		key := target.secret + "/" + registryURL
+		if _, ok := listed[key]; !ok {
+			registryConfig, err := c.docker.GetRegistryConfig(ctx, namespace, target.secret)
+			if err != nil {
+				return Report{}, err
+			}
+
+			repositories, err := c.registry.Repositories(ctx, registryConfig, registryURL)
+			if err != nil {
+				return Report{}, err
+			}
+			listed[key] = registryRepositories{config: registryConfig, repositories: repositories}
+		}
+
+		for _, name := range listed[key].repositories {
+			repository := registryURL + "/" + name
+			appGUID, ok := appOf(repository, target)
+			if !ok {
+				continue
+			}
+
+			repositoryConfigs[repository] = listed[key].config
+			currentDroplet, isLive := currentDroplets[appGUID]
+			switch {
+			case !isLive && !settings.CollectDeletedApps:
+				continue
+			case !isLive:
+				c.collectOrphan(ctx, &report, listed[key].config, repository, settings, orphanedSince)
+			case target.suffix == dropletsSuffix:
+				c.collectDroplets(ctx, &report, listed[key].config, repository, currentDroplet, settings)
+			}
+		}
+	}
+
+	if !settings.DryRun {
+		report.Deletions = c.delete(ctx, &report, repositoryConfigs)
+	}
+
+	return report, nil
+}
+
+// currentDroplets returns the GUIDs of all apps, mapped to the digest of
+// their current droplet, if any
+func (c *Collector) currentDroplets(ctx context.Context) (map[string]string, error) {
+	apps := &korifiv1alpha1.CFAppList{}
+	if err := c.k8sClient.List(ctx, apps); err != nil {
+		return nil, fmt.Errorf("failed to list apps: %w", err)
+	}
+
+	builds := &korifiv1alpha1.CFBuildList{}
+	if err := c.k8sClient.List(ctx, builds); err != nil {
+		return nil, fmt.Errorf("failed to list builds: %w", err)
+	}
+
+	dropletImages := map[string]string{}
+	for _, build := range builds.Items {
+		if build.Status.Droplet != nil {
+			dropletImages[build.Namespace+"/"+build.Name] = build.Status.Droplet.Registry.Image
+		}
+	}
+
+	currentDroplets := map[string]string{}
+	for _, app := range apps.Items {
+		_, digest, _ := strings.Cut(dropletImages[app.Namespace+"/"+app.Spec.CurrentDropletRef.Name], "@")
+		currentDroplets[app.Name] = digest
+	}
+
+	return currentDroplets, nil
+}
+
+func (c *Collector) collectOrphan(
+	ctx context.Context,
+	report *Report,
+	registryConfig secrets.DockerRegistryConfig,
+	repository string,
+	settings Settings,
+	orphanedSince map[string]time.Time,
+) {
+	manifests, err := c.manifests(ctx, registryConfig, repository)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return
+	}
+
+	// deleted images leave empty repositories behind in most registries
+	if len(manifests) == 0 {
+		return
+	}
+
+	since, ok := orphanedSince[repository]
+	if !ok {
+		since = time.Now().UTC()
+	}
+	report.OrphanedRepositories[repository] = since
+
+	if time.Since(since) < settings.GracePeriod {
+		return
+	}
+
+	for _, digest := range slices.Sorted(maps.Keys(manifests)) {
+		report.Deletions = append(report.Deletions, Deletion{
+			Repository: repository,
+			Digest:     digest,
+			Tags:       manifests[digest],
+			Reason:     ReasonOrphanedApp,
+		})
+	}
+}
+
+// collectDroplets keeps the droplets of the most recent kpack builds, the
+// `latest` image and the current droplet of the app. Images not tagged by a
+// kpack build are kept as well, as their age is unknown
+func (c *Collector) collectDroplets(
+	ctx context.Context,
+	report *Report,
+	registryConfig secrets.DockerRegistryConfig,
+	repository, currentDroplet string,
+	settings Settings,
+) {
+	manifests, err := c.manifests(ctx, registryConfig, repository)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return
+	}
+
+	buildNumbers := map[string]int{}
+	for digest, tags := range manifests {
+		for _, tag := range tags {
+			if buildNumber, ok := kpackBuildNumber(tag); ok {
+				buildNumbers[digest] = max(buildNumbers[digest], buildNumber)
+			}
+		}
+	}
+
+	digests := slices.SortedFunc(maps.Keys(buildNumbers), func(a, b string) int {
+		return buildNumbers[b] - buildNumbers[a]
+	})
+
+	for _, digest := range digests[min(settings.KeepDroplets, len(digests)):] {
+		if digest == currentDroplet || slices.Contains(manifests[digest], latestTag) {
+			continue
+		}
+		report.Deletions = append(report.Deletions, Deletion{
+			Repository: repository,
+			Digest:     digest,
+			Tags:       manifests[digest],
+			Reason:     ReasonOutdatedDroplet,
+		})
+	}
+}
+
+// manifests returns the digests of the manifests of a repository, each with
+// the tags referring to it
+func (c *Collector) manifests(ctx context.Context, registryConfig secrets.DockerRegistryConfig, repository string) (map[string][]string, error) {
+	tags, err := c.registry.Tags(ctx, registryConfig, repository)
+	if err != nil {
+		return nil, err
+	}
+
+	manifests := map[string][]string{}
+	for _, tag := range slices.Sorted(slices.Values(tags)) {
+		digest, err := c.registry.ManifestDigest(ctx, registryConfig, repository, tag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s:%s: %w", repository, tag, err)
+		}
+		manifests[digest] = append(manifests[digest], tag)
+	}
+
+	return manifests, nil
+}
+
+// delete deletes the manifests and returns the successful deletions. Failures
+// are added to the errors of the report
+func (c *Collector) delete(ctx context.Context, report *Report, repositoryConfigs map[string]secrets.DockerRegistryConfig) []Deletion {
+	deleted := []Deletion{}
+	for _, deletion := range report.Deletions {
+		if err := c.registry.DeleteManifest(ctx, repositoryConfigs[deletion.Repository], deletion.Repository, deletion.Digest); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("failed to delete %s@%s: %s", deletion.Repository, deletion.Digest, err))
+			continue
+		}
+		deleted = append(deleted, deletion)
+	}
+
+	return deleted
+}
+
+// appOf returns the GUID of the app a repository belongs to, if it is a
+// package or droplet repository under the prefix of the target
+func appOf(repository string, target collectionTarget) (string, bool) {
+	rest, ok := strings.CutPrefix(trimScheme(repository), trimScheme(target.prefix))
+	if !ok {
+		return "", false
+	}
+
+	appGUID, ok := strings.CutSuffix(rest, target.suffix)
+	if !ok || appGUID == "" || strings.Contains(appGUID, "/") {
+		return "", false
+	}
+
+	return appGUID, true
+}
+
+func kpackBuildNumber(tag string) (int, bool) {
+	match := kpackBuildTag.FindStringSubmatch(tag)
+	if match == nil {
+		return 0, false
+	}
+
+	buildNumber, err := strconv.Atoi(match[1])
+	return buildNumber, err == nil
+}
+
+// repositoryRegistry returns the registry of a repository prefix, keeping an
+// explicit URL scheme
+func repositoryRegistry(prefix string) string {
+	scheme := ""
+	if strings.HasPrefix(prefix, "http://") || strings.HasPrefix(prefix, "https://") {
+		scheme, prefix, _ = strings.Cut(prefix, "://")
+		scheme += "://"
+	}
+
+	registry, _, _ := strings.Cut(prefix, "/")
+	return scheme + registry
+}
+
+func trimScheme(repository string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(repository, "https://"), "http://")
+}
@@ -0,0 +1,210 @@
+package registrygc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/installable"
+	"github.com/kyma-project/cfapi/tools/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ReportConfigMapName is the config map in the CFAPI namespace holding the
+	// report of the last run
+	ReportConfigMapName = "cfapi-registry-gc"
+
+	ReportKey      = "report"
+	LastRunTimeKey = "lastRunTime"
+	SettingsKey    = "settings"
+
+	DefaultInterval     = 24 * time.Hour
+	DefaultGracePeriod  = 72 * time.Hour
+	DefaultKeepDroplets = 5
+)
+
+// Reconciler runs the registry garbage collection of a CFAPI periodically.
+// The time of the last run and the repositories found orphaned so far are
+// kept in the report config map, so that neither restarts of the operator nor
+// frequent reconciles of the CFAPI shorten the interval or the grace period
+type Reconciler struct {
+	k8sClient     client.Client
+	eventRecorder events.EventRecorder
+	collector     *Collector
+}
+
+func NewReconciler(
+	k8sClient client.Client,
+	eventRecorder events.EventRecorder,
+	collector *Collector,
+	log logr.Logger,
+) *k8s.PatchingReconciler[v1alpha1.CFAPI] {
+	return k8s.NewPatchingReconciler(log, k8sClient, &Reconciler{
+		k8sClient:     k8sClient,
+		eventRecorder: eventRecorder,
+		collector:     collector,
+	})
+}
+
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) *builder.Builder {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("registrygc").
+		For(&v1alpha1.CFAPI{})
+}
+
+func (r *Reconciler) ReconcileResource(ctx context.Context, cfAPI *v1alpha1.CFAPI) (ctrl.Result, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	gc := cfAPI.Spec.RegistryGarbageCollection
+	config := cfAPI.Status.InstallationConfig
+	if gc == nil || !gc.Enabled || config.RootNamespace == "" || !cfAPI.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	interval := DefaultInterval
+	if gc.Interval != nil {
+		interval = gc.Interval.Duration
+	}
+	settings := settingsOf(gc)
+
+	lastRun, report, err := r.loadReport(ctx, cfAPI.Namespace, settings)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if nextRun := lastRun.Add(interval); time.Now().Before(nextRun) {
+		return ctrl.Result{RequeueAfter: time.Until(nextRun)}, nil
+	}
+
+	eventRecorder := installable.NewCFAPIEventRecorder(r.eventRecorder, cfAPI)
+
+	log.Info("collecting registry garbage", "dryRun", settings.DryRun)
+	report, err = r.collector.Collect(ctx, cfAPI.Namespace, config, settings, report.OrphanedRepositories)
+	if err != nil {
+		recordFailure()
+		eventRecorder.Event(installable.EventWarning, "RegistryGarbageCollectionFailed", err.Error())
+		return ctrl.Result{}, fmt.Errorf("failed to collect registry garbage: %w", err)
+	}
+	recordRun(report)
+
+	if err := r.saveReport(ctx, cfAPI.Namespace, settings, report); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	eventRecorder.Event(installable.EventNormal, "RegistryGarbageCollected", summary(report))
+	if len(report.Errors) > 0 {
+		eventRecorder.Event(installable.EventWarning, "RegistryGarbageCollectionFailed", fmt.Sprintf(
+			"Registry garbage collection failed on %d repositories or manifests, see config map %s", len(report.Errors), ReportConfigMapName,
+		))
+	}
+
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+func settingsOf(gc *v1alpha1.RegistryGarbageCollection) Settings {
+	settings := Settings{
+		DryRun:             gc.DryRun,
+		CollectDeletedApps: gc.CollectDeletedApps,
+		GracePeriod:        DefaultGracePeriod,
+		KeepDroplets:       DefaultKeepDroplets,
+	}
+	if gc.GracePeriod != nil {
+		settings.GracePeriod = gc.GracePeriod.Duration
+	}
+	if gc.KeepDroplets != nil {
+		settings.KeepDroplets = int(*gc.KeepDroplets)
+	}
+
+	return settings
+}
+
+// loadReport returns the time and report of the last run. Runs with other
+// settings are ignored, so that changing the settings takes effect right away
+func (r *Reconciler) loadReport(ctx context.Context, namespace string, settings Settings) (time.Time, Report, error) {
+	configMap := &corev1.ConfigMap{}
+	err := r.k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ReportConfigMapName}, configMap)
+	if err != nil {
+		return time.Time{}, Report{}, client.IgnoreNotFound(err)
+	}
+
+	report := Report{}
+	if err := yaml.Unmarshal([]byte(configMap.Data[ReportKey]), &report); err != nil {
+		return time.Time{}, Report{}, fmt.Errorf("failed to unmarshal the report of config map %s/%s: %w", namespace, ReportConfigMapName, err)
+	}
+
+	lastRun, err := time.Parse(time.RFC3339, configMap.Data[LastRunTimeKey])
+	if err != nil {
+		return time.Time{}, report, nil
+	}
+
+	expectedSettings, err := json.Marshal(settings)
+	if err != nil {
+		return time.Time{}, Report{}, fmt.Errorf("failed to marshal registry garbage collection settings: %w", err)
+	}
+	if configMap.Data[SettingsKey] != string(expectedSettings) {
+		return time.Time{}, report, nil
+	}
+
+	return lastRun, report, nil
+}
+
+func (r *Reconciler) saveReport(ctx context.Context, namespace string, settings Settings, report Report) error {
+	reportYAML, err := yaml.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal registry garbage collection report: %w", err)
+	}
+
+	settingsJSON, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("failed to marshal registry garbage collection settings: %w", err)
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      ReportConfigMapName,
+		},
+	}
+	_, err = controllerutil.CreateOrPatch(ctx, r.k8sClient, configMap, func() error {
+		configMap.Data = map[string]string{
+			ReportKey:      string(reportYAML),
+			LastRunTimeKey: time.Now().UTC().Format(time.RFC3339),
+			SettingsKey:    string(settingsJSON),
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save the registry garbage collection report to config map %s/%s: %w", namespace, ReportConfigMapName, err)
+	}
+
+	return nil
+}
+
+func summary(report Report) string {
+	orphaned, outdated := 0, 0
+	for _, deletion := range report.Deletions {
+		if deletion.Reason == ReasonOrphanedApp {
+			orphaned++
+		} else {
+			outdated++
+		}
+	}
+
+	action := "Deleted"
+	if report.DryRun {
+		action = "Dry run: would delete"
+	}
+
+	return fmt.Sprintf("%s %d manifests of deleted apps and %d outdated droplets, %d repositories of deleted apps found, see config map %s",
+		action, orphaned, outdated, len(report.OrphanedRepositories), ReportConfigMapName)
+}
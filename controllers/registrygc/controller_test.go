@@ -0,0 +1,233 @@
+package registrygc_test
+
+import (
+	"time"
+
+	korifiv1alpha1 "code.cloudfoundry.org/korifi/controllers/api/v1alpha1"
+	"github.com/google/uuid"
+	v1alpha1 "github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/registrygc"
+	. "github.com/kyma-project/cfapi/tests/helpers"
+	"github.com/kyma-project/cfapi/tools"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+var _ = Describe("Registry Garbage Collection", func() {
+	var (
+		cfAPI          *v1alpha1.CFAPI
+		gcSpec         *v1alpha1.RegistryGarbageCollection
+		spaceNamespace string
+		liveAppGUID    string
+		deletedAppGUID string
+	)
+
+	getReport := func(g Gomega) registrygc.Report {
+		configMap := &corev1.ConfigMap{}
+		g.Expect(adminClient.Get(ctx, client.ObjectKey{Namespace: cfAPINamespace, Name: registrygc.ReportConfigMapName}, configMap)).To(Succeed())
+
+		report := registrygc.Report{}
+		g.Expect(yaml.Unmarshal([]byte(configMap.Data[registrygc.ReportKey]), &report)).To(Succeed())
+		return report
+	}
+
+	eventReasons := func(g Gomega) []string {
+		eventList := &eventsv1.EventList{}
+		g.Expect(adminClient.List(ctx, eventList, client.InNamespace(cfAPINamespace))).To(Succeed())
+
+		reasons := []string{}
+		for _, event := range eventList.Items {
+			reasons = append(reasons, event.Reason)
+		}
+		return reasons
+	}
+
+	BeforeEach(func() {
+		EnsureCreate(adminClient, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: cfAPINamespace,
+				Name:      "registry-secret",
+			},
+			Type: corev1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{
+				corev1.DockerConfigJsonKey: []byte(`{"auths":{"my-registry.com":{"username":"user","password":"password"}}}`),
+			},
+		})
+
+		spaceNamespace = uuid.NewString()
+		EnsureCreate(adminClient, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: spaceNamespace},
+		})
+
+		liveAppGUID = uuid.NewString()
+		deletedAppGUID = uuid.NewString()
+
+		build := &korifiv1alpha1.CFBuild{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: spaceNamespace,
+				Name:      uuid.NewString(),
+			},
+			Spec: korifiv1alpha1.CFBuildSpec{
+				PackageRef: corev1.LocalObjectReference{Name: "my-package"},
+				AppRef:     corev1.LocalObjectReference{Name: liveAppGUID},
+				Lifecycle:  korifiv1alpha1.Lifecycle{Type: "buildpack"},
+			},
+		}
+		EnsureCreate(adminClient, build)
+		build.Status.Droplet = &korifiv1alpha1.BuildDropletStatus{
+			Registry: korifiv1alpha1.Registry{Image: "my-registry.com/korifi/" + liveAppGUID + "-droplets@sha256:1"},
+		}
+		Expect(adminClient.Status().Update(ctx, build)).To(Succeed())
+
+		EnsureCreate(adminClient, &korifiv1alpha1.CFApp{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: spaceNamespace,
+				Name:      liveAppGUID,
+			},
+			Spec: korifiv1alpha1.CFAppSpec{
+				DisplayName:       "my-app",
+				DesiredState:      "STARTED",
+				Lifecycle:         korifiv1alpha1.Lifecycle{Type: "buildpack"},
+				CurrentDropletRef: corev1.LocalObjectReference{Name: build.Name},
+			},
+		})
+
+		liveDroplets := "korifi/" + liveAppGUID + "-droplets"
+		registry.AddImage(liveDroplets, "b1.20250101.120000", "sha256:1")
+		registry.AddImage(liveDroplets, "b2.20250102.120000", "sha256:2")
+		registry.AddImage(liveDroplets, "b3.20250103.120000", "sha256:3")
+		registry.AddImage(liveDroplets, "b4.20250104.120000", "sha256:4")
+		registry.AddImage(liveDroplets, "latest", "sha256:4")
+		registry.AddImage("korifi/"+liveAppGUID+"-packages", "package", "sha256:live-package")
+		registry.AddImage("korifi/"+deletedAppGUID+"-droplets", "latest", "sha256:deleted-droplet")
+		registry.AddImage("korifi/"+deletedAppGUID+"-packages", "package", "sha256:deleted-package")
+		registry.AddImage("other/"+deletedAppGUID+"-packages", "package", "sha256:other")
+
+		gcSpec = &v1alpha1.RegistryGarbageCollection{
+			Enabled:            true,
+			CollectDeletedApps: true,
+			GracePeriod:        &metav1.Duration{},
+			KeepDroplets:       tools.PtrTo(int32(2)),
+		}
+	})
+
+	JustBeforeEach(func() {
+		cfAPI = &v1alpha1.CFAPI{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      uuid.NewString(),
+				Namespace: cfAPINamespace,
+			},
+			Spec: v1alpha1.CFAPISpec{
+				RegistryGarbageCollection: gcSpec,
+			},
+		}
+		EnsureCreate(adminClient, cfAPI)
+
+		cfAPI.Status.InstallationConfig = v1alpha1.InstallationConfig{
+			RootNamespace:           "cf",
+			PackageRegistrySecret:   "registry-secret",
+			PackageRepositoryPrefix: "my-registry.com/korifi/",
+			DropletRegistrySecret:   "registry-secret",
+			DropletRepositoryPrefix: "my-registry.com/korifi/",
+		}
+		Expect(adminClient.Status().Update(ctx, cfAPI)).To(Succeed())
+	})
+
+	It("deletes the images of deleted apps and outdated droplets", func() {
+		Eventually(func(g Gomega) {
+			g.Expect(registry.Tags("korifi/" + deletedAppGUID + "-droplets")).To(BeEmpty())
+			g.Expect(registry.Tags("korifi/" + deletedAppGUID + "-packages")).To(BeEmpty())
+			g.Expect(registry.Tags("korifi/" + liveAppGUID + "-droplets")).To(ConsistOf(
+				"b1.20250101.120000", "b3.20250103.120000", "b4.20250104.120000", "latest",
+			))
+			g.Expect(registry.Tags("korifi/" + liveAppGUID + "-packages")).To(ConsistOf("package"))
+			g.Expect(registry.Tags("other/" + deletedAppGUID + "-packages")).To(ConsistOf("package"))
+
+			report := getReport(g)
+			g.Expect(report.DryRun).To(BeFalse())
+			g.Expect(report.Deletions).To(HaveLen(3))
+			g.Expect(report.OrphanedRepositories).To(HaveLen(2))
+			g.Expect(eventReasons(g)).To(ContainElement("RegistryGarbageCollected"))
+		}).Should(Succeed())
+	})
+
+	When("dry run is enabled", func() {
+		BeforeEach(func() {
+			gcSpec.DryRun = true
+		})
+
+		It("reports the deletions without deleting anything", func() {
+			Eventually(func(g Gomega) {
+				report := getReport(g)
+				g.Expect(report.DryRun).To(BeTrue())
+				g.Expect(report.Deletions).To(ConsistOf(
+					MatchFields(IgnoreExtras, Fields{"Digest": Equal("sha256:deleted-droplet"), "Reason": Equal(registrygc.ReasonOrphanedApp)}),
+					MatchFields(IgnoreExtras, Fields{"Digest": Equal("sha256:deleted-package"), "Reason": Equal(registrygc.ReasonOrphanedApp)}),
+					MatchFields(IgnoreExtras, Fields{"Digest": Equal("sha256:2"), "Reason": Equal(registrygc.ReasonOutdatedDroplet)}),
+				))
+			}).Should(Succeed())
+
+			Consistently(func(g Gomega) {
+				g.Expect(registry.Tags("korifi/" + deletedAppGUID + "-packages")).To(ConsistOf("package"))
+				g.Expect(registry.Tags("korifi/" + liveAppGUID + "-droplets")).To(HaveLen(5))
+			}).Should(Succeed())
+		})
+	})
+
+	When("the grace period has not passed", func() {
+		BeforeEach(func() {
+			gcSpec.GracePeriod = &metav1.Duration{Duration: time.Hour}
+		})
+
+		It("keeps the images of deleted apps", func() {
+			Eventually(func(g Gomega) {
+				report := getReport(g)
+				g.Expect(report.OrphanedRepositories).To(HaveLen(2))
+				g.Expect(report.Deletions).To(ConsistOf(
+					MatchFields(IgnoreExtras, Fields{"Reason": Equal(registrygc.ReasonOutdatedDroplet)}),
+				))
+			}).Should(Succeed())
+
+			Expect(registry.Tags("korifi/" + deletedAppGUID + "-packages")).To(ConsistOf("package"))
+		})
+	})
+
+	When("the images of deleted apps are not collected", func() {
+		BeforeEach(func() {
+			gcSpec.CollectDeletedApps = false
+		})
+
+		It("keeps them, as other clusters may own them, and collects the outdated droplets", func() {
+			Eventually(func(g Gomega) {
+				report := getReport(g)
+				g.Expect(report.OrphanedRepositories).To(BeEmpty())
+				g.Expect(report.Errors).To(BeEmpty())
+				g.Expect(report.Deletions).To(ConsistOf(
+					MatchFields(IgnoreExtras, Fields{"Reason": Equal(registrygc.ReasonOutdatedDroplet)}),
+				))
+			}).Should(Succeed())
+
+			Expect(registry.Tags("korifi/" + deletedAppGUID + "-packages")).To(ConsistOf("package"))
+		})
+	})
+
+	When("garbage collection is disabled", func() {
+		BeforeEach(func() {
+			gcSpec.Enabled = false
+		})
+
+		It("does not collect garbage", func() {
+			Consistently(func(g Gomega) {
+				err := adminClient.Get(ctx, client.ObjectKey{Namespace: cfAPINamespace, Name: registrygc.ReportConfigMapName}, &corev1.ConfigMap{})
+				g.Expect(err).To(HaveOccurred())
+				g.Expect(registry.Tags("korifi/" + deletedAppGUID + "-packages")).To(ConsistOf("package"))
+			}).Should(Succeed())
+		})
+	})
+})
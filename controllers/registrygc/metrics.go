@@ -0,0 +1,60 @@
+package registrygc
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	runsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cfapi_registry_gc_runs_total",
+		Help: "Number of registry garbage collection runs by result",
+	}, []string{"result"})
+	deletedManifestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cfapi_registry_gc_deleted_manifests_total",
+		Help: "Number of manifests deleted from the container registries by reason",
+	}, []string{"reason"})
+	candidateManifests = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cfapi_registry_gc_candidate_manifests",
+		Help: "Number of manifests the last registry garbage collection run deleted, or would have deleted in a dry run, by reason",
+	}, []string{"reason"})
+	orphanedRepositories = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "cfapi_registry_gc_orphaned_repositories",
+		Help: "Number of non-empty repositories of deleted apps found by the last registry garbage collection run",
+	})
+	errorsLastRun = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "cfapi_registry_gc_errors",
+		Help: "Number of repositories or manifests the last registry garbage collection run failed on",
+	})
+	lastRunTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "cfapi_registry_gc_last_run_timestamp_seconds",
+		Help: "Time of the last completed registry garbage collection run",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(runsTotal, deletedManifestsTotal, candidateManifests, orphanedRepositories, errorsLastRun, lastRunTimestamp)
+}
+
+func recordRun(report Report) {
+	runsTotal.WithLabelValues("success").Inc()
+
+	counts := map[string]int{ReasonOrphanedApp: 0, ReasonOutdatedDroplet: 0}
+	for _, deletion := range report.Deletions {
+		counts[deletion.Reason]++
+	}
+	for reason, count := range counts {
+		candidateManifests.WithLabelValues(reason).Set(float64(count))
+		if !report.DryRun {
+			deletedManifestsTotal.WithLabelValues(reason).Add(float64(count))
+		}
+	}
+
+	orphanedRepositories.Set(float64(len(report.OrphanedRepositories)))
+	errorsLastRun.Set(float64(len(report.Errors)))
+	lastRunTimestamp.SetToCurrentTime()
+}
+
+func recordFailure() {
+	runsTotal.WithLabelValues("failure").Inc()
+}
@@ -0,0 +1,337 @@
+package registrysecrets
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	korifiv1alpha1 "code.cloudfoundry.org/korifi/controllers/api/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/installable"
+	"github.com/kyma-project/cfapi/controllers/kyma"
+	"github.com/kyma-project/cfapi/tools/k8s"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// PropagatedLabel marks the copies of registry secrets managed by the
+	// operator. Korifi keeps the label when it copies the secrets further
+	PropagatedLabel = "cfapi.kyma-project.io/propagated-registry-secret"
+	// ContentHashAnnotation holds the hash of the type and data of the source
+	// secret a copy was last synced from
+	ContentHashAnnotation = "cfapi.kyma-project.io/content-hash"
+)
+
+// Reconciler keeps copies of the registry secrets of the CFAPI namespace in
+// the root namespace and in every org and space namespace, and copies of the
+// pull secrets of the image mirror in the system namespaces as well. Copies
+// are updated whenever the content of their source changes, e.g. on rotation,
+// and removed when propagation is disabled
+type Reconciler struct {
+	k8sClient     client.Client
+	eventRecorder events.EventRecorder
+}
+
+func NewReconciler(
+	k8sClient client.Client,
+	eventRecorder events.EventRecorder,
+	log logr.Logger,
+) *k8s.PatchingReconciler[v1alpha1.CFAPI] {
+	return k8s.NewPatchingReconciler(log, k8sClient, &Reconciler{
+		k8sClient:     k8sClient,
+		eventRecorder: eventRecorder,
+	})
+}
+
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) *builder.Builder {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("registrysecrets").
+		For(&v1alpha1.CFAPI{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.enqueueCFAPIs)).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.enqueueCFAPIs))
+}
+
+func (r *Reconciler) enqueueCFAPIs(ctx context.Context, obj client.Object) []reconcile.Request {
+	cfAPIs := &v1alpha1.CFAPIList{}
+	if err := r.k8sClient.List(ctx, cfAPIs); err != nil {
+		logr.FromContextOrDiscard(ctx).Error(err, "failed to list CFAPIs")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, cfAPI := range cfAPIs.Items {
+		if secret, ok := obj.(*corev1.Secret); ok && !isRelevantSecret(&cfAPI, secret) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&cfAPI)})
+	}
+	return requests
+}
+
+// isRelevantSecret filters the sources, their copies and any other secret
+// with the name of a source, which might have replaced a copy
+func isRelevantSecret(cfAPI *v1alpha1.CFAPI, secret *corev1.Secret) bool {
+	config := cfAPI.Status.InstallationConfig
+	return secret.Labels[PropagatedLabel] == "true" ||
+		slices.Contains(sourceSecretNames(config), secret.Name) ||
+		slices.Contains(config.ImagePullSecrets, secret.Name)
+}
+
+func (r *Reconciler) ReconcileResource(ctx context.Context, cfAPI *v1alpha1.CFAPI) (ctrl.Result, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	config := cfAPI.Status.InstallationConfig
+	if config.RootNamespace == "" || !cfAPI.DeletionTimestamp.IsZero() {
+		log.Info("cfapi is not installed, skipping registry secret propagation")
+		return ctrl.Result{}, nil
+	}
+
+	eventRecorder := installable.NewCFAPIEventRecorder(r.eventRecorder, cfAPI)
+
+	namespaces, err := r.listNamespaces(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	rootNamespaceExists := slices.Contains(cfNamespaces(namespaces, config.RootNamespace), config.RootNamespace)
+	propagateRegistrySecrets := !config.DisableContainerRegistrySecretPropagation
+
+	targets := map[string][]string{}
+	for _, secretName := range config.ImagePullSecrets {
+		targets[secretName] = mirrorPullSecretNamespaces(namespaces, config.RootNamespace)
+	}
+	if propagateRegistrySecrets && rootNamespaceExists {
+		for _, secretName := range sourceSecretNames(config) {
+			targets[secretName] = union(targets[secretName], cfNamespaces(namespaces, config.RootNamespace))
+		}
+	}
+
+	desired := map[string]bool{}
+	for _, secretName := range slices.Sorted(maps.Keys(targets)) {
+		for _, namespace := range targets[secretName] {
+			desired[namespace+"/"+secretName] = true
+		}
+
+		if err := r.propagate(ctx, cfAPI.Namespace, secretName, targets[secretName], eventRecorder); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if propagateRegistrySecrets && !rootNamespaceExists {
+		return ctrl.Result{}, k8s.NewNotReadyError().WithMessage(fmt.Sprintf("root namespace %s does not exist", config.RootNamespace)).WithRequeue()
+	}
+
+	return ctrl.Result{}, r.removeCopies(ctx, desired, eventRecorder)
+}
+
+// sourceSecretNames returns the registry secrets to propagate. The secrets
+// of the kyma docker registry module are provided in every namespace by the
+// module itself
+func sourceSecretNames(config v1alpha1.InstallationConfig) []string {
+	secretNames := []string{}
+	for _, secretName := range []string{config.PackageRegistrySecret, config.DropletRegistrySecret, config.BuilderRegistrySecret, config.ContainerRegistrySecret} {
+		if secretName == "" || secretName == kyma.ContainerRegistrySecretName || secretName == kyma.InternalContainerRegistrySecretName {
+			continue
+		}
+		if !slices.Contains(secretNames, secretName) {
+			secretNames = append(secretNames, secretName)
+		}
+	}
+	return secretNames
+}
+
+// listNamespaces returns the namespaces which are not being deleted
+func (r *Reconciler) listNamespaces(ctx context.Context) ([]corev1.Namespace, error) {
+	namespaces := &corev1.NamespaceList{}
+	if err := r.k8sClient.List(ctx, namespaces); err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	return slices.DeleteFunc(namespaces.Items, func(ns corev1.Namespace) bool {
+		return !ns.DeletionTimestamp.IsZero()
+	}), nil
+}
+
+// cfNamespaces returns the root namespace and the org and space namespaces,
+// which Korifi labels with the GUID of their org
+func cfNamespaces(namespaces []corev1.Namespace, rootNamespace string) []string {
+	names := []string{}
+	for _, ns := range namespaces {
+		if _, isOrgOrSpace := ns.Labels[korifiv1alpha1.CFOrgGUIDKey]; isOrgOrSpace || ns.Name == rootNamespace {
+			names = append(names, ns.Name)
+		}
+	}
+	return names
+}
+
+// mirrorPullSecretNamespaces returns the namespaces pulling images from the
+// image mirror: the namespaces of the installed components, as far as they
+// exist, and the CF namespaces, where apps are staged and run
+func mirrorPullSecretNamespaces(namespaces []corev1.Namespace, rootNamespace string) []string {
+	names := []string{}
+	for _, ns := range namespaces {
+		if slices.Contains(installable.SystemNamespaces, ns.Name) {
+			names = append(names, ns.Name)
+		}
+	}
+	return union(names, cfNamespaces(namespaces, rootNamespace))
+}
+
+func union(names, others []string) []string {
+	result := slices.Clone(names)
+	for _, name := range others {
+		if !slices.Contains(result, name) {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+func (r *Reconciler) propagate(ctx context.Context, sourceNamespace, secretName string, namespaces []string, eventRecorder installable.EventRecorder) error {
+	source := &corev1.Secret{}
+	err := r.k8sClient.Get(ctx, client.ObjectKey{Namespace: sourceNamespace, Name: secretName}, source)
+	if k8serrors.IsNotFound(err) {
+		eventRecorder.Event(installable.EventWarning, "RegistrySecretNotFound", fmt.Sprintf(
+			"Registry secret %s/%s does not exist and is not propagated", sourceNamespace, secretName,
+		))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get registry secret %s/%s: %w", sourceNamespace, secretName, err)
+	}
+
+	hash, err := contentHash(source)
+	if err != nil {
+		return err
+	}
+
+	created := []string{}
+	rotated := []string{}
+	for _, namespace := range namespaces {
+		if namespace == sourceNamespace {
+			continue
+		}
+
+		existing := &corev1.Secret{}
+		err := r.k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: secretName}, existing)
+		if client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to get registry secret %s/%s: %w", namespace, secretName, err)
+		}
+
+		switch {
+		case k8serrors.IsNotFound(err):
+			if err := r.k8sClient.Create(ctx, toCopy(source, namespace, hash)); client.IgnoreAlreadyExists(err) != nil {
+				return fmt.Errorf("failed to create registry secret %s/%s: %w", namespace, secretName, err)
+			}
+			created = append(created, namespace)
+		case existing.Annotations[ContentHashAnnotation] != hash || existing.Labels[PropagatedLabel] != "true":
+			if err := r.update(ctx, existing, source, hash); err != nil {
+				return err
+			}
+			rotated = append(rotated, namespace)
+		}
+	}
+
+	if len(created) > 0 {
+		eventRecorder.Event(installable.EventNormal, "RegistrySecretPropagated", fmt.Sprintf(
+			"Propagated registry secret %s to namespaces %s", secretName, strings.Join(created, ", "),
+		))
+	}
+	if len(rotated) > 0 {
+		eventRecorder.Event(installable.EventNormal, "RegistrySecretRotated", fmt.Sprintf(
+			"Updated registry secret %s in namespaces %s to its current content", secretName, strings.Join(rotated, ", "),
+		))
+	}
+
+	return nil
+}
+
+func (r *Reconciler) update(ctx context.Context, existing, source *corev1.Secret, hash string) error {
+	desired := toCopy(source, existing.Namespace, hash)
+
+	// the type of a secret is immutable
+	if existing.Type != desired.Type {
+		if err := r.k8sClient.Delete(ctx, existing); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete registry secret %s/%s: %w", existing.Namespace, existing.Name, err)
+		}
+		if err := r.k8sClient.Create(ctx, desired); err != nil {
+			return fmt.Errorf("failed to create registry secret %s/%s: %w", existing.Namespace, existing.Name, err)
+		}
+		return nil
+	}
+
+	if err := k8s.PatchResource(ctx, r.k8sClient, existing, func() {
+		existing.Labels = desired.Labels
+		existing.Annotations = desired.Annotations
+		existing.Data = desired.Data
+	}); err != nil {
+		return fmt.Errorf("failed to update registry secret %s/%s: %w", existing.Namespace, existing.Name, err)
+	}
+	return nil
+}
+
+func (r *Reconciler) removeCopies(ctx context.Context, desired map[string]bool, eventRecorder installable.EventRecorder) error {
+	copies := &corev1.SecretList{}
+	if err := r.k8sClient.List(ctx, copies, client.MatchingLabels{PropagatedLabel: "true"}); err != nil {
+		return fmt.Errorf("failed to list propagated registry secrets: %w", err)
+	}
+
+	removed := map[string][]string{}
+	for _, secret := range copies.Items {
+		if desired[secret.Namespace+"/"+secret.Name] {
+			continue
+		}
+
+		if err := r.k8sClient.Delete(ctx, &secret); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete registry secret %s/%s: %w", secret.Namespace, secret.Name, err)
+		}
+		removed[secret.Name] = append(removed[secret.Name], secret.Namespace)
+	}
+
+	for _, secretName := range slices.Sorted(maps.Keys(removed)) {
+		eventRecorder.Event(installable.EventNormal, "RegistrySecretRemoved", fmt.Sprintf(
+			"Removed registry secret %s from namespaces %s", secretName, strings.Join(removed[secretName], ", "),
+		))
+	}
+
+	return nil
+}
+
+func toCopy(source *corev1.Secret, namespace, hash string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   namespace,
+			Name:        source.Name,
+			Labels:      map[string]string{PropagatedLabel: "true"},
+			Annotations: map[string]string{ContentHashAnnotation: hash},
+		},
+		Type: source.Type,
+		Data: source.Data,
+	}
+}
+
+func contentHash(secret *corev1.Secret) (string, error) {
+	// map keys are marshalled in sorted order, so equal content results in
+	// the same hash
+	content, err := json.Marshal(map[string]any{
+		"type": secret.Type,
+		"data": secret.Data,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal registry secret %s/%s: %w", secret.Namespace, secret.Name, err)
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(content)), nil
+}
@@ -0,0 +1,268 @@
+package registrysecrets_test
+
+import (
+	"github.com/google/uuid"
+	v1alpha1 "github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/kyma"
+	"github.com/kyma-project/cfapi/controllers/registrysecrets"
+	. "github.com/kyma-project/cfapi/tests/helpers"
+	"github.com/kyma-project/cfapi/tools/k8s"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Registry Secret Propagation", func() {
+	var (
+		cfAPI           *v1alpha1.CFAPI
+		sourceSecret    *corev1.Secret
+		rootNamespace   string
+		orgNamespace    string
+		spaceNamespace  string
+		otherNamespace  string
+		installedConfig v1alpha1.InstallationConfig
+	)
+
+	getCopy := func(g Gomega, namespace string) *corev1.Secret {
+		secret := &corev1.Secret{}
+		g.Expect(adminClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: sourceSecret.Name}, secret)).To(Succeed())
+		return secret
+	}
+
+	expectNoCopy := func(g Gomega, namespace string) {
+		err := adminClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: sourceSecret.Name}, &corev1.Secret{})
+		g.Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+	}
+
+	eventReasons := func(g Gomega) []string {
+		eventList := &eventsv1.EventList{}
+		g.Expect(adminClient.List(ctx, eventList, client.InNamespace(cfAPINamespace))).To(Succeed())
+
+		reasons := []string{}
+		for _, event := range eventList.Items {
+			reasons = append(reasons, event.Reason)
+		}
+		return reasons
+	}
+
+	createNamespace := func(labels map[string]string) string {
+		name := uuid.NewString()
+		EnsureCreate(adminClient, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		})
+		return name
+	}
+
+	BeforeEach(func() {
+		rootNamespace = createNamespace(nil)
+		orgNamespace = createNamespace(map[string]string{"korifi.cloudfoundry.org/org-guid": "my-org"})
+		spaceNamespace = createNamespace(map[string]string{
+			"korifi.cloudfoundry.org/org-guid":   "my-org",
+			"korifi.cloudfoundry.org/space-guid": "my-space",
+		})
+		otherNamespace = createNamespace(nil)
+
+		sourceSecret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: cfAPINamespace,
+				Name:      uuid.NewString(),
+			},
+			Type: corev1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{
+				corev1.DockerConfigJsonKey: []byte(`{"auths":{"my-registry.com":{"username":"user","password":"password"}}}`),
+			},
+		}
+		EnsureCreate(adminClient, sourceSecret)
+
+		installedConfig = v1alpha1.InstallationConfig{
+			RootNamespace:           rootNamespace,
+			ContainerRegistrySecret: sourceSecret.Name,
+			PackageRegistrySecret:   sourceSecret.Name,
+			DropletRegistrySecret:   sourceSecret.Name,
+			BuilderRegistrySecret:   sourceSecret.Name,
+		}
+	})
+
+	JustBeforeEach(func() {
+		cfAPI = &v1alpha1.CFAPI{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      uuid.NewString(),
+				Namespace: cfAPINamespace,
+			},
+		}
+		EnsureCreate(adminClient, cfAPI)
+
+		cfAPI.Status.InstallationConfig = installedConfig
+		Expect(adminClient.Status().Update(ctx, cfAPI)).To(Succeed())
+	})
+
+	It("copies the registry secret to the root, org and space namespaces", func() {
+		Eventually(func(g Gomega) {
+			for _, namespace := range []string{rootNamespace, orgNamespace, spaceNamespace} {
+				secret := getCopy(g, namespace)
+				g.Expect(secret.Type).To(Equal(corev1.SecretTypeDockerConfigJson))
+				g.Expect(secret.Data).To(Equal(sourceSecret.Data))
+				g.Expect(secret.Labels).To(HaveKeyWithValue(registrysecrets.PropagatedLabel, "true"))
+				g.Expect(secret.Annotations).To(HaveKey(registrysecrets.ContentHashAnnotation))
+			}
+			g.Expect(eventReasons(g)).To(ContainElement("RegistrySecretPropagated"))
+		}).Should(Succeed())
+
+		Consistently(func(g Gomega) {
+			expectNoCopy(g, otherNamespace)
+		}).Should(Succeed())
+	})
+
+	When("the registry secret is rotated", func() {
+		var initialHash string
+
+		JustBeforeEach(func() {
+			Eventually(func(g Gomega) {
+				initialHash = getCopy(g, spaceNamespace).Annotations[registrysecrets.ContentHashAnnotation]
+				g.Expect(initialHash).NotTo(BeEmpty())
+			}).Should(Succeed())
+
+			Expect(k8s.PatchResource(ctx, adminClient, sourceSecret, func() {
+				sourceSecret.Data[corev1.DockerConfigJsonKey] = []byte(`{"auths":{"my-registry.com":{"username":"user","password":"rotated"}}}`)
+			})).To(Succeed())
+		})
+
+		It("updates the copies and reports the rotation", func() {
+			Eventually(func(g Gomega) {
+				for _, namespace := range []string{rootNamespace, orgNamespace, spaceNamespace} {
+					secret := getCopy(g, namespace)
+					g.Expect(secret.Data[corev1.DockerConfigJsonKey]).To(ContainSubstring("rotated"))
+					g.Expect(secret.Annotations[registrysecrets.ContentHashAnnotation]).NotTo(Equal(initialHash))
+				}
+				g.Expect(eventReasons(g)).To(ContainElement("RegistrySecretRotated"))
+			}).Should(Succeed())
+		})
+	})
+
+	When("a copy is modified", func() {
+		JustBeforeEach(func() {
+			var secret *corev1.Secret
+			Eventually(func(g Gomega) {
+				secret = getCopy(g, orgNamespace)
+			}).Should(Succeed())
+
+			Expect(k8s.PatchResource(ctx, adminClient, secret, func() {
+				secret.Data[corev1.DockerConfigJsonKey] = []byte(`{}`)
+				secret.Annotations[registrysecrets.ContentHashAnnotation] = "modified"
+			})).To(Succeed())
+		})
+
+		It("restores the content of the source", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(getCopy(g, orgNamespace).Data).To(Equal(sourceSecret.Data))
+			}).Should(Succeed())
+		})
+	})
+
+	When("a space is created", func() {
+		var newSpaceNamespace string
+
+		JustBeforeEach(func() {
+			Eventually(func(g Gomega) {
+				getCopy(g, spaceNamespace)
+			}).Should(Succeed())
+
+			newSpaceNamespace = createNamespace(map[string]string{
+				"korifi.cloudfoundry.org/org-guid":   "my-org",
+				"korifi.cloudfoundry.org/space-guid": "my-other-space",
+			})
+		})
+
+		It("copies the registry secret to its namespace", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(getCopy(g, newSpaceNamespace).Data).To(Equal(sourceSecret.Data))
+			}).Should(Succeed())
+		})
+	})
+
+	When("propagation is disabled", func() {
+		JustBeforeEach(func() {
+			Eventually(func(g Gomega) {
+				getCopy(g, spaceNamespace)
+			}).Should(Succeed())
+
+			Expect(k8s.Patch(ctx, adminClient, cfAPI, func() {
+				cfAPI.Status.InstallationConfig.DisableContainerRegistrySecretPropagation = true
+			})).To(Succeed())
+		})
+
+		It("removes the copies", func() {
+			Eventually(func(g Gomega) {
+				for _, namespace := range []string{rootNamespace, orgNamespace, spaceNamespace} {
+					expectNoCopy(g, namespace)
+				}
+				g.Expect(eventReasons(g)).To(ContainElement("RegistrySecretRemoved"))
+			}).Should(Succeed())
+		})
+
+		It("keeps the source secret", func() {
+			Consistently(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(sourceSecret), &corev1.Secret{})).To(Succeed())
+			}).Should(Succeed())
+		})
+	})
+
+	When("the registry secret is provided by the kyma docker registry module", func() {
+		BeforeEach(func() {
+			installedConfig.ContainerRegistrySecret = kyma.InternalContainerRegistrySecretName
+			installedConfig.PackageRegistrySecret = kyma.InternalContainerRegistrySecretName
+			installedConfig.DropletRegistrySecret = kyma.InternalContainerRegistrySecretName
+			installedConfig.BuilderRegistrySecret = kyma.InternalContainerRegistrySecretName
+		})
+
+		It("does not copy it", func() {
+			Consistently(func(g Gomega) {
+				err := adminClient.Get(ctx, client.ObjectKey{Namespace: rootNamespace, Name: kyma.InternalContainerRegistrySecretName}, &corev1.Secret{})
+				g.Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+			}).Should(Succeed())
+		})
+	})
+
+	When("the secret is a pull secret of the image mirror", func() {
+		BeforeEach(func() {
+			err := adminClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kpack"}})
+			Expect(client.IgnoreAlreadyExists(err)).To(Succeed())
+
+			installedConfig.ContainerRegistrySecret = kyma.InternalContainerRegistrySecretName
+			installedConfig.PackageRegistrySecret = kyma.InternalContainerRegistrySecretName
+			installedConfig.DropletRegistrySecret = kyma.InternalContainerRegistrySecretName
+			installedConfig.BuilderRegistrySecret = kyma.InternalContainerRegistrySecretName
+			installedConfig.ImagePullSecrets = []string{sourceSecret.Name}
+			installedConfig.DisableContainerRegistrySecretPropagation = true
+		})
+
+		It("copies it to the system namespaces and the CF namespaces", func() {
+			Eventually(func(g Gomega) {
+				for _, namespace := range []string{"kpack", rootNamespace, orgNamespace, spaceNamespace} {
+					g.Expect(getCopy(g, namespace).Data).To(Equal(sourceSecret.Data))
+				}
+			}).Should(Succeed())
+
+			Consistently(func(g Gomega) {
+				expectNoCopy(g, otherNamespace)
+			}).Should(Succeed())
+		})
+	})
+
+	When("the source secret does not exist", func() {
+		BeforeEach(func() {
+			installedConfig.DropletRegistrySecret = "missing-secret"
+		})
+
+		It("reports the missing secret and propagates the others", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(eventReasons(g)).To(ContainElement("RegistrySecretNotFound"))
+				g.Expect(getCopy(g, rootNamespace).Data).To(Equal(sourceSecret.Data))
+			}).Should(Succeed())
+		})
+	})
+})
@@ -0,0 +1,405 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/tools/k8s"
+	istiov1alpha3 "istio.io/api/networking/v1alpha3"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+const (
+	ControllerName = "cfapi.kyma-project.io/istio-native"
+
+	// korifiGatewayNamespace and korifiGatewayName identify the gateway
+	// Korifi attaches the HTTPRoutes of CF apps to
+	korifiGatewayNamespace = "cfapi-system"
+	korifiGatewayName      = "korifi"
+
+	// crdsPendingInterval is how often the reconciler checks whether the
+	// Gateway API and Istio CRDs have been installed
+	crdsPendingInterval = 30 * time.Second
+)
+
+// Reconciler translates the HTTPRoutes of CF apps to Istio VirtualServices
+// when the `istio-native` gateway type is used. Hosts of the apps domain are
+// bound to the apps gateway, other hosts to the kyma gateway. Routes for the
+// host of the CF API are rejected, as they would take over the CF API.
+//
+// The HTTPRoute kind only exists once the Gateway API is installed, and the
+// VirtualService kind only with Istio, which is why routes and virtual
+// services are watched lazily from the first reconcile of an `istio-native`
+// CFAPI on
+type Reconciler struct {
+	k8sClient           client.Client
+	scheme              *runtime.Scheme
+	routeWatch          *k8s.LazyWatch
+	virtualServiceWatch *k8s.LazyWatch
+}
+
+func NewReconciler(
+	k8sClient client.Client,
+	scheme *runtime.Scheme,
+	informers cache.Informers,
+	log logr.Logger,
+) *k8s.PatchingReconciler[v1alpha1.CFAPI] {
+	r := &Reconciler{
+		k8sClient: k8sClient,
+		scheme:    scheme,
+	}
+	r.routeWatch = k8s.NewLazyWatch(informers, &gatewayv1.HTTPRoute{}, r.enqueueCFAPIs)
+	r.virtualServiceWatch = k8s.NewLazyWatch(informers, &networkingv1beta1.VirtualService{}, r.enqueueCFAPIs)
+
+	return k8s.NewPatchingReconciler(log, k8sClient, r)
+}
+
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) *builder.Builder {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("routes").
+		For(&v1alpha1.CFAPI{}).
+		WatchesRawSource(r.routeWatch.Source()).
+		WatchesRawSource(r.virtualServiceWatch.Source())
+}
+
+func (r *Reconciler) enqueueCFAPIs(ctx context.Context, _ client.Object) []reconcile.Request {
+	cfAPIs := &v1alpha1.CFAPIList{}
+	if err := r.k8sClient.List(ctx, cfAPIs); err != nil {
+		logr.FromContextOrDiscard(ctx).Error(err, "failed to list CFAPIs")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, cfAPI := range cfAPIs.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&cfAPI)})
+	}
+	return requests
+}
+
+func (r *Reconciler) ReconcileResource(ctx context.Context, cfAPI *v1alpha1.CFAPI) (ctrl.Result, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	if !cfAPI.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	config := cfAPI.Status.InstallationConfig
+	if config.GatewayType != v1alpha1.GatewayTypeIstioNative || config.IstioGateway == "" {
+		if err := r.unexposeRoutes(ctx); err != nil && !meta.IsNoMatchError(err) {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if err := errors.Join(r.routeWatch.Start(ctx), r.virtualServiceWatch.Start(ctx)); err != nil {
+		if meta.IsNoMatchError(err) {
+			log.Info("waiting for the Gateway API and Istio to be installed")
+			return ctrl.Result{RequeueAfter: crdsPendingInterval}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	httpRoutes := &gatewayv1.HTTPRouteList{}
+	if err := r.k8sClient.List(ctx, httpRoutes); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list HTTPRoutes: %w", err)
+	}
+
+	for i := range httpRoutes.Items {
+		if err := r.exposeRoute(ctx, &httpRoutes.Items[i], config); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// exposeRoute creates the virtual service of a route attached to the korifi
+// gateway and marks the route as accepted
+func (r *Reconciler) exposeRoute(ctx context.Context, httpRoute *gatewayv1.HTTPRoute, config v1alpha1.InstallationConfig) error {
+	parentRef, ok := korifiParentRef(httpRoute)
+	if !ok || !httpRoute.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	if host, reserved := reservedHost(httpRoute, config); reserved {
+		return r.rejectRoute(ctx, httpRoute, parentRef, "Host "+host+" is reserved for the CF API")
+	}
+
+	gateways := routeGateways(httpRoute, config)
+	virtualService := &networkingv1beta1.VirtualService{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: httpRoute.Namespace,
+			Name:      httpRoute.Name,
+		},
+	}
+	_, err := controllerutil.CreateOrPatch(ctx, r.k8sClient, virtualService, func() error {
+		virtualService.Spec.Gateways = gateways
+		virtualService.Spec.Hosts = toHosts(httpRoute.Spec.Hostnames)
+		virtualService.Spec.Http = toHTTPRoutes(httpRoute)
+		return controllerutil.SetControllerReference(httpRoute, virtualService, r.scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create/patch virtual service %s/%s: %w", httpRoute.Namespace, httpRoute.Name, err)
+	}
+
+	if err := k8s.PatchResource(ctx, r.k8sClient, httpRoute, func() {
+		setAccepted(httpRoute, parentRef, metav1.ConditionTrue, gatewayv1.RouteReasonAccepted, "Route is exposed by istio gateway "+strings.Join(gateways, ", "))
+	}); err != nil {
+		return fmt.Errorf("failed to patch the status of HTTPRoute %s/%s: %w", httpRoute.Namespace, httpRoute.Name, err)
+	}
+
+	return nil
+}
+
+// rejectRoute deletes the virtual service of a route and marks the route as
+// not accepted
+func (r *Reconciler) rejectRoute(ctx context.Context, httpRoute *gatewayv1.HTTPRoute, parentRef gatewayv1.ParentReference, message string) error {
+	virtualService := &networkingv1beta1.VirtualService{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: httpRoute.Namespace,
+			Name:      httpRoute.Name,
+		},
+	}
+	if err := r.k8sClient.Delete(ctx, virtualService); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to delete virtual service %s/%s: %w", httpRoute.Namespace, httpRoute.Name, err)
+	}
+
+	if err := k8s.PatchResource(ctx, r.k8sClient, httpRoute, func() {
+		setAccepted(httpRoute, parentRef, metav1.ConditionFalse, gatewayv1.RouteReasonNotAllowedByListeners, message)
+	}); err != nil {
+		return fmt.Errorf("failed to patch the status of HTTPRoute %s/%s: %w", httpRoute.Namespace, httpRoute.Name, err)
+	}
+
+	return nil
+}
+
+// reservedHost returns the host of the route that is served by the CF API on
+// the kyma gateway, if any
+func reservedHost(httpRoute *gatewayv1.HTTPRoute, config v1alpha1.InstallationConfig) (string, bool) {
+	apiHost := "cfapi." + config.CFDomain
+	for _, hostname := range httpRoute.Spec.Hostnames {
+		if strings.EqualFold(string(hostname), apiHost) {
+			return string(hostname), true
+		}
+	}
+
+	return "", false
+}
+
+// routeGateways returns the gateways serving the hosts of a route: the apps
+// gateway for hosts of the apps domain and the kyma gateway for others, such
+// as custom domains
+func routeGateways(httpRoute *gatewayv1.HTTPRoute, config v1alpha1.InstallationConfig) []string {
+	appsDomainSuffix := ".apps." + config.CFDomain
+
+	gateways := []string{}
+	for _, hostname := range httpRoute.Spec.Hostnames {
+		gateway := config.IstioGateway
+		if config.IstioAppsGateway != "" && strings.HasSuffix(strings.ToLower(string(hostname)), strings.ToLower(appsDomainSuffix)) {
+			gateway = config.IstioAppsGateway
+		}
+		if !slices.Contains(gateways, gateway) {
+			gateways = append(gateways, gateway)
+		}
+	}
+
+	if len(gateways) == 0 {
+		gateways = append(gateways, config.IstioGateway)
+	}
+	return gateways
+}
+
+// unexposeRoutes removes the virtual services and the route status of the
+// routes exposed before the gateway type was switched away from
+// `istio-native`
+func (r *Reconciler) unexposeRoutes(ctx context.Context) error {
+	httpRoutes := &gatewayv1.HTTPRouteList{}
+	if err := r.k8sClient.List(ctx, httpRoutes); err != nil {
+		return fmt.Errorf("failed to list HTTPRoutes: %w", err)
+	}
+
+	for i := range httpRoutes.Items {
+		httpRoute := &httpRoutes.Items[i]
+		if !slices.ContainsFunc(httpRoute.Status.Parents, isOwnParentStatus) {
+			continue
+		}
+
+		virtualService := &networkingv1beta1.VirtualService{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: httpRoute.Namespace,
+				Name:      httpRoute.Name,
+			},
+		}
+		if err := r.k8sClient.Delete(ctx, virtualService); client.IgnoreNotFound(err) != nil && !meta.IsNoMatchError(err) {
+			return fmt.Errorf("failed to delete virtual service %s/%s: %w", httpRoute.Namespace, httpRoute.Name, err)
+		}
+
+		if err := k8s.PatchResource(ctx, r.k8sClient, httpRoute, func() {
+			httpRoute.Status.Parents = slices.DeleteFunc(httpRoute.Status.Parents, isOwnParentStatus)
+		}); err != nil {
+			return fmt.Errorf("failed to patch the status of HTTPRoute %s/%s: %w", httpRoute.Namespace, httpRoute.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func korifiParentRef(httpRoute *gatewayv1.HTTPRoute) (gatewayv1.ParentReference, bool) {
+	for _, parentRef := range httpRoute.Spec.ParentRefs {
+		namespace := httpRoute.Namespace
+		if parentRef.Namespace != nil {
+			namespace = string(*parentRef.Namespace)
+		}
+
+		if string(parentRef.Name) == korifiGatewayName && namespace == korifiGatewayNamespace {
+			return parentRef, true
+		}
+	}
+
+	return gatewayv1.ParentReference{}, false
+}
+
+func toHosts(hostnames []gatewayv1.Hostname) []string {
+	hosts := []string{}
+	for _, hostname := range hostnames {
+		hosts = append(hosts, string(hostname))
+	}
+	return hosts
+}
+
+func toHTTPRoutes(httpRoute *gatewayv1.HTTPRoute) []*istiov1alpha3.HTTPRoute {
+	httpRoutes := []*istiov1alpha3.HTTPRoute{}
+	for _, rule := range httpRoute.Spec.Rules {
+		destinations := toDestinations(httpRoute.Namespace, rule.BackendRefs)
+		if len(destinations) == 0 {
+			continue
+		}
+
+		httpRoutes = append(httpRoutes, &istiov1alpha3.HTTPRoute{
+			Match: toMatches(rule.Matches),
+			Route: destinations,
+		})
+	}
+
+	return httpRoutes
+}
+
+// toMatches translates the path matches of a rule. Gateway API path prefixes
+// match whole path segments only, so `/foo` matches `/foo` and `/foo/bar`,
+// but not `/foobar`
+func toMatches(matches []gatewayv1.HTTPRouteMatch) []*istiov1alpha3.HTTPMatchRequest {
+	result := []*istiov1alpha3.HTTPMatchRequest{}
+	for _, match := range matches {
+		if match.Path == nil || match.Path.Value == nil {
+			continue
+		}
+
+		path := *match.Path.Value
+		if match.Path.Type != nil && *match.Path.Type == gatewayv1.PathMatchExact {
+			result = append(result, uriMatch(&istiov1alpha3.StringMatch{MatchType: &istiov1alpha3.StringMatch_Exact{Exact: path}}))
+			continue
+		}
+
+		path = strings.TrimSuffix(path, "/")
+		if path == "" {
+			return nil
+		}
+		result = append(result,
+			uriMatch(&istiov1alpha3.StringMatch{MatchType: &istiov1alpha3.StringMatch_Exact{Exact: path}}),
+			uriMatch(&istiov1alpha3.StringMatch{MatchType: &istiov1alpha3.StringMatch_Prefix{Prefix: path + "/"}}),
+		)
+	}
+
+	return result
+}
+
+func uriMatch(uri *istiov1alpha3.StringMatch) *istiov1alpha3.HTTPMatchRequest {
+	return &istiov1alpha3.HTTPMatchRequest{Uri: uri}
+}
+
+// toDestinations translates the backend refs of a rule. Istio expects the
+// weights of the destinations to add up to 100, the remainder of the
+// proportional split is assigned to the first destination
+func toDestinations(namespace string, backendRefs []gatewayv1.HTTPBackendRef) []*istiov1alpha3.HTTPRouteDestination {
+	totalWeight := int32(0)
+	for _, backendRef := range backendRefs {
+		totalWeight += backendWeight(backendRef)
+	}
+	if totalWeight == 0 {
+		return nil
+	}
+
+	destinations := []*istiov1alpha3.HTTPRouteDestination{}
+	assignedWeight := int32(0)
+	for _, backendRef := range backendRefs {
+		backendNamespace := namespace
+		if backendRef.Namespace != nil {
+			backendNamespace = string(*backendRef.Namespace)
+		}
+
+		destination := &istiov1alpha3.Destination{
+			Host: fmt.Sprintf("%s.%s.svc.cluster.local", backendRef.Name, backendNamespace),
+		}
+		if backendRef.Port != nil {
+			destination.Port = &istiov1alpha3.PortSelector{Number: uint32(*backendRef.Port)}
+		}
+
+		weight := backendWeight(backendRef) * 100 / totalWeight
+		assignedWeight += weight
+		destinations = append(destinations, &istiov1alpha3.HTTPRouteDestination{
+			Destination: destination,
+			Weight:      weight,
+		})
+	}
+	destinations[0].Weight += 100 - assignedWeight
+
+	return destinations
+}
+
+func backendWeight(backendRef gatewayv1.HTTPBackendRef) int32 {
+	if backendRef.Weight == nil {
+		return 1
+	}
+	return *backendRef.Weight
+}
+
+func isOwnParentStatus(parentStatus gatewayv1.RouteParentStatus) bool {
+	return parentStatus.ControllerName == ControllerName
+}
+
+func setAccepted(httpRoute *gatewayv1.HTTPRoute, parentRef gatewayv1.ParentReference, status metav1.ConditionStatus, reason gatewayv1.RouteConditionReason, message string) {
+	parentStatusIndex := slices.IndexFunc(httpRoute.Status.Parents, isOwnParentStatus)
+	if parentStatusIndex < 0 {
+		httpRoute.Status.Parents = append(httpRoute.Status.Parents, gatewayv1.RouteParentStatus{
+			ControllerName: ControllerName,
+		})
+		parentStatusIndex = len(httpRoute.Status.Parents) - 1
+	}
+
+	parentStatus := &httpRoute.Status.Parents[parentStatusIndex]
+	parentStatus.ParentRef = parentRef
+	meta.SetStatusCondition(&parentStatus.Conditions, metav1.Condition{
+		Type:               string(gatewayv1.RouteConditionAccepted),
+		Status:             status,
+		ObservedGeneration: httpRoute.Generation,
+		LastTransitionTime: metav1.NewTime(time.Now()),
+		Reason:             string(reason),
+		Message:            message,
+	})
+}
@@ -0,0 +1,202 @@
+package routes_test
+
+import (
+	"github.com/google/uuid"
+	v1alpha1 "github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/routes"
+	. "github.com/kyma-project/cfapi/tests/helpers"
+	"github.com/kyma-project/cfapi/tools"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gstruct"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+)
+
+var _ = Describe("Routes", func() {
+	var (
+		cfAPI     *v1alpha1.CFAPI
+		httpRoute *gatewayv1.HTTPRoute
+		hostname  gatewayv1.Hostname
+	)
+
+	getVirtualService := func(g Gomega) *networkingv1beta1.VirtualService {
+		virtualService := &networkingv1beta1.VirtualService{}
+		g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(httpRoute), virtualService)).To(Succeed())
+		return virtualService
+	}
+
+	BeforeEach(func() {
+		hostname = "my-app.apps.kyma.example.com"
+
+		cfAPI = &v1alpha1.CFAPI{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      uuid.NewString(),
+				Namespace: testNamespace,
+			},
+		}
+		EnsureCreate(adminClient, cfAPI)
+
+		cfAPI.Status.InstallationConfig = v1alpha1.InstallationConfig{
+			CFDomain:         "kyma.example.com",
+			GatewayType:      v1alpha1.GatewayTypeIstioNative,
+			IstioGateway:     "kyma-system/kyma-gateway",
+			IstioAppsGateway: "cfapi-system/cfapi-apps",
+		}
+		Expect(adminClient.Status().Update(ctx, cfAPI)).To(Succeed())
+
+	})
+
+	JustBeforeEach(func() {
+		httpRoute = &gatewayv1.HTTPRoute{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      uuid.NewString(),
+				Namespace: testNamespace,
+			},
+			Spec: gatewayv1.HTTPRouteSpec{
+				CommonRouteSpec: gatewayv1.CommonRouteSpec{
+					ParentRefs: []gatewayv1.ParentReference{{
+						Name:      "korifi",
+						Namespace: tools.PtrTo(gatewayv1.Namespace("cfapi-system")),
+					}},
+				},
+				Hostnames: []gatewayv1.Hostname{hostname},
+				Rules: []gatewayv1.HTTPRouteRule{{
+					Matches: []gatewayv1.HTTPRouteMatch{{
+						Path: &gatewayv1.HTTPPathMatch{
+							Type:  tools.PtrTo(gatewayv1.PathMatchPathPrefix),
+							Value: tools.PtrTo("/foo"),
+						},
+					}},
+					BackendRefs: []gatewayv1.HTTPBackendRef{
+						{BackendRef: gatewayv1.BackendRef{
+							BackendObjectReference: gatewayv1.BackendObjectReference{Name: "app-one", Port: tools.PtrTo(gatewayv1.PortNumber(8080))},
+							Weight:                 tools.PtrTo(int32(1)),
+						}},
+						{BackendRef: gatewayv1.BackendRef{
+							BackendObjectReference: gatewayv1.BackendObjectReference{Name: "app-two", Port: tools.PtrTo(gatewayv1.PortNumber(8080))},
+							Weight:                 tools.PtrTo(int32(2)),
+						}},
+					},
+				}},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		EnsureCreate(adminClient, httpRoute)
+	})
+
+	It("exposes the route on the apps gateway", func() {
+		Eventually(func(g Gomega) {
+			virtualService := getVirtualService(g)
+			g.Expect(virtualService.Spec.Gateways).To(ConsistOf("cfapi-system/cfapi-apps"))
+			g.Expect(virtualService.Spec.Hosts).To(ConsistOf("my-app.apps.kyma.example.com"))
+			g.Expect(virtualService.Spec.Http).To(HaveLen(1))
+
+			http := virtualService.Spec.Http[0]
+			g.Expect(http.Match).To(HaveLen(2))
+			g.Expect(http.Match[0].Uri.GetExact()).To(Equal("/foo"))
+			g.Expect(http.Match[1].Uri.GetPrefix()).To(Equal("/foo/"))
+
+			g.Expect(http.Route).To(HaveLen(2))
+			g.Expect(http.Route[0].Destination.Host).To(Equal("app-one." + testNamespace + ".svc.cluster.local"))
+			g.Expect(http.Route[0].Destination.Port.Number).To(BeEquivalentTo(8080))
+			g.Expect(http.Route[0].Weight).To(BeEquivalentTo(34))
+			g.Expect(http.Route[1].Destination.Host).To(Equal("app-two." + testNamespace + ".svc.cluster.local"))
+			g.Expect(http.Route[1].Weight).To(BeEquivalentTo(66))
+
+			g.Expect(virtualService.OwnerReferences).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+				"Name": Equal(httpRoute.Name),
+				"Kind": Equal("HTTPRoute"),
+			})))
+		}).Should(Succeed())
+	})
+
+	It("marks the route as accepted", func() {
+		Eventually(func(g Gomega) {
+			g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(httpRoute), httpRoute)).To(Succeed())
+			g.Expect(httpRoute.Status.Parents).To(ConsistOf(MatchFields(IgnoreExtras, Fields{
+				"ControllerName": BeEquivalentTo(routes.ControllerName),
+				"ParentRef": MatchFields(IgnoreExtras, Fields{
+					"Name": BeEquivalentTo("korifi"),
+				}),
+			})))
+			g.Expect(meta.IsStatusConditionTrue(httpRoute.Status.Parents[0].Conditions, string(gatewayv1.RouteConditionAccepted))).To(BeTrue())
+		}).Should(Succeed())
+	})
+
+	When("the route has a host outside of the apps domain", func() {
+		BeforeEach(func() {
+			hostname = "my-app.custom.example.com"
+		})
+
+		It("exposes the route on the kyma gateway", func() {
+			Eventually(func(g Gomega) {
+				virtualService := getVirtualService(g)
+				g.Expect(virtualService.Spec.Gateways).To(ConsistOf("kyma-system/kyma-gateway"))
+				g.Expect(virtualService.Spec.Hosts).To(ConsistOf("my-app.custom.example.com"))
+			}).Should(Succeed())
+		})
+	})
+
+	When("the route has the host of the CF API", func() {
+		BeforeEach(func() {
+			hostname = "cfapi.kyma.example.com"
+		})
+
+		It("rejects the route", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(httpRoute), httpRoute)).To(Succeed())
+				g.Expect(httpRoute.Status.Parents).To(HaveLen(1))
+				g.Expect(meta.IsStatusConditionFalse(httpRoute.Status.Parents[0].Conditions, string(gatewayv1.RouteConditionAccepted))).To(BeTrue())
+			}).Should(Succeed())
+
+			Consistently(func(g Gomega) {
+				err := adminClient.Get(ctx, client.ObjectKeyFromObject(httpRoute), &networkingv1beta1.VirtualService{})
+				g.Expect(err).To(MatchError(ContainSubstring("not found")))
+			}).Should(Succeed())
+		})
+	})
+
+	When("the route is not attached to the korifi gateway", func() {
+		JustBeforeEach(func() {
+			EnsurePatch(adminClient, httpRoute, func(r *gatewayv1.HTTPRoute) {
+				r.Spec.ParentRefs[0].Name = "other"
+			})
+		})
+
+		It("does not create a virtual service", func() {
+			Consistently(func(g Gomega) {
+				err := adminClient.Get(ctx, client.ObjectKeyFromObject(httpRoute), &networkingv1beta1.VirtualService{})
+				g.Expect(err).To(MatchError(ContainSubstring("not found")))
+			}).Should(Succeed())
+		})
+	})
+
+	When("the gateway type is switched away from istio-native", func() {
+		JustBeforeEach(func() {
+			Eventually(func(g Gomega) {
+				getVirtualService(g)
+			}).Should(Succeed())
+
+			Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+			cfAPI.Status.InstallationConfig.GatewayType = v1alpha1.GatewayTypeContour
+			cfAPI.Status.InstallationConfig.IstioGateway = ""
+			Expect(adminClient.Status().Update(ctx, cfAPI)).To(Succeed())
+		})
+
+		It("deletes the virtual service and the route status", func() {
+			Eventually(func(g Gomega) {
+				err := adminClient.Get(ctx, client.ObjectKeyFromObject(httpRoute), &networkingv1beta1.VirtualService{})
+				g.Expect(err).To(MatchError(ContainSubstring("not found")))
+
+				g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(httpRoute), httpRoute)).To(Succeed())
+				g.Expect(httpRoute.Status.Parents).To(BeEmpty())
+			}).Should(Succeed())
+		})
+	})
+})
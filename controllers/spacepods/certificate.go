@@ -0,0 +1,70 @@
+package spacepods
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ServingCertificate serves the certificate of the webhook from the secret
+// the pods webhook installable issues it to. The secret only exists while
+// the webhook is registered, so it is read on demand rather than mounted
+type ServingCertificate struct {
+	k8sClient client.Client
+	secretKey client.ObjectKey
+
+	mu      sync.Mutex
+	certPEM []byte
+	cert    *tls.Certificate
+}
+
+func NewServingCertificate(k8sClient client.Client, namespace, secretName string) *ServingCertificate {
+	return &ServingCertificate{
+		k8sClient: k8sClient,
+		secretKey: client.ObjectKey{Namespace: namespace, Name: secretName},
+	}
+}
+
+// ConfigureTLS makes the webhook server serve the certificate
+func (c *ServingCertificate) ConfigureTLS(config *tls.Config) {
+	config.GetCertificate = c.GetCertificate
+}
+
+func (c *ServingCertificate) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	ctx := hello.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	secret := &corev1.Secret{}
+	if err := c.k8sClient.Get(ctx, c.secretKey, secret); err != nil {
+		return nil, fmt.Errorf("failed to get the webhook certificate: %w", err)
+	}
+
+	certPEM := secret.Data[corev1.TLSCertKey]
+	if len(certPEM) == 0 {
+		return nil, errors.New("the webhook certificate has not been issued yet")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cert != nil && bytes.Equal(c.certPEM, certPEM) {
+		return c.cert, nil
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the webhook certificate: %w", err)
+	}
+
+	c.certPEM = certPEM
+	c.cert = &cert
+	return c.cert, nil
+}
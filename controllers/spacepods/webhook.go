@@ -0,0 +1,132 @@
+package spacepods
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/installable"
+	"github.com/kyma-project/cfapi/controllers/proxy"
+	"github.com/kyma-project/cfapi/controllers/trustedca"
+	buildv1alpha2 "github.com/pivotal/kpack/pkg/apis/build/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Defaulter mutates the new pods in the CF spaces, i.e. the app, task and
+// kpack build pods:
+//   - it points their images to the address the nodes pull from when images
+//     are pushed to an in-cluster registry address the nodes cannot resolve,
+//     e.g. the internal address of the Kyma docker registry module. Korifi and
+//     kpack use a single image reference for pushing and pulling
+//   - it mounts the trusted CA bundle copied to the space, so that builds and
+//     apps trust it through `SSL_CERT_DIR`
+//   - it sets the proxy variables in the build pods. Korifi only passes the
+//     app environment to builds, which would expose the proxy to the apps too
+//
+// The webhook is only registered while any of them is configured, see
+// installable.PodsWebhook
+type Defaulter struct {
+	k8sClient client.Client
+}
+
+func NewDefaulter(k8sClient client.Client) *Defaulter {
+	return &Defaulter{
+		k8sClient: k8sClient,
+	}
+}
+
+func (d *Defaulter) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr, &corev1.Pod{}).
+		WithDefaulter(d).
+		WithDefaulterCustomPath(installable.PodsWebhookPath).
+		Complete()
+}
+
+func (d *Defaulter) Default(ctx context.Context, pod *corev1.Pod) error {
+	config, err := d.installationConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	if config == nil {
+		return nil
+	}
+
+	log := logr.FromContextOrDiscard(ctx)
+
+	if rewrite := imageRewriteOf(*config); rewrite != nil && rewrite.applies(pod) {
+		log.Info("pointing images to the pull address of the registry", "pushPrefix", rewrite.pushPrefix, "pullPrefix", rewrite.pullPrefix)
+		rewrite.apply(pod)
+	}
+
+	if config.TrustedCA != nil {
+		log.Info("mounting the trusted CA bundle", "hash", config.TrustedCA.Hash)
+		trustedca.InjectPod(pod, config.TrustedCA.Hash)
+	}
+
+	if _, isBuild := pod.Labels[buildv1alpha2.BuildLabel]; isBuild && config.Proxy != nil {
+		log.Info("setting the proxy variables of the build")
+		proxy.InjectPod(pod, config.Proxy)
+	}
+
+	return nil
+}
+
+// installationConfig returns the installation config of the installed CFAPI,
+// or nil if there is none
+func (d *Defaulter) installationConfig(ctx context.Context) (*v1alpha1.InstallationConfig, error) {
+	cfAPIs := &v1alpha1.CFAPIList{}
+	if err := d.k8sClient.List(ctx, cfAPIs); err != nil {
+		return nil, fmt.Errorf("failed to list CFAPIs: %w", err)
+	}
+
+	for _, cfAPI := range cfAPIs.Items {
+		if cfAPI.DeletionTimestamp.IsZero() && cfAPI.Status.InstallationConfig.RootNamespace != "" {
+			return &cfAPI.Status.InstallationConfig, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// imageRewriteOf returns the rewrite of the installation config, or nil if
+// images are pulled from the address they are pushed to
+func imageRewriteOf(config v1alpha1.InstallationConfig) *imageRewrite {
+	if config.ContainerRegistryURL == "" || config.ContainerRegistryPullURL == "" {
+		return nil
+	}
+
+	return &imageRewrite{
+		pushPrefix: strings.TrimSuffix(config.ContainerRegistryURL, "/") + "/",
+		pullPrefix: strings.TrimSuffix(config.ContainerRegistryPullURL, "/") + "/",
+	}
+}
+
+type imageRewrite struct {
+	pushPrefix string
+	pullPrefix string
+}
+
+func (r *imageRewrite) applies(pod *corev1.Pod) bool {
+	for _, container := range slices.Concat(pod.Spec.InitContainers, pod.Spec.Containers) {
+		if strings.HasPrefix(container.Image, r.pushPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *imageRewrite) apply(pod *corev1.Pod) {
+	for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for i := range containers {
+			if strings.HasPrefix(containers[i].Image, r.pushPrefix) {
+				containers[i].Image = r.pullPrefix + strings.TrimPrefix(containers[i].Image, r.pushPrefix)
+			}
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package spacepods_test
+
+import (
+	"github.com/google/uuid"
+	v1alpha1 "github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/spacepods"
+	"github.com/kyma-project/cfapi/controllers/trustedca"
+	. "github.com/kyma-project/cfapi/tests/helpers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Defaulter", func() {
+	var (
+		cfAPI     *v1alpha1.CFAPI
+		pod       *corev1.Pod
+		pullURL   string
+		trustedCA *v1alpha1.TrustedCA
+		proxy     *v1alpha1.ProxyConfig
+		err       error
+	)
+
+	getImages := func() []string {
+		images := []string{}
+		for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+			images = append(images, container.Image)
+		}
+		return images
+	}
+
+	BeforeEach(func() {
+		cfAPI = &v1alpha1.CFAPI{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      uuid.NewString(),
+				Namespace: testNamespace,
+			},
+		}
+		EnsureCreate(adminClient, cfAPI)
+
+		pullURL = "localhost:32137"
+		trustedCA = nil
+		proxy = nil
+
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      uuid.NewString(),
+				Namespace: testNamespace,
+			},
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{
+					Name:  "prepare",
+					Image: "gcr.io/kpack/prepare@sha256:abc",
+				}, {
+					Name:  "build",
+					Image: "dockerregistry.kyma-system.svc.cluster.local:5000/cfapi/kpack-builder@sha256:def",
+				}},
+				Containers: []corev1.Container{{
+					Name:  "application",
+					Image: "dockerregistry.kyma-system.svc.cluster.local:5000/my-app-droplets@sha256:123",
+				}},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		cfAPI.Status.InstallationConfig = v1alpha1.InstallationConfig{
+			RootNamespace:            "cf",
+			ContainerRegistryURL:     "dockerregistry.kyma-system.svc.cluster.local:5000",
+			ContainerRegistryPullURL: pullURL,
+			TrustedCA:                trustedCA,
+			Proxy:                    proxy,
+		}
+		Expect(adminClient.Status().Update(ctx, cfAPI)).To(Succeed())
+		Eventually(func(g Gomega) {
+			g.Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+			g.Expect(cfAPI.Status.InstallationConfig.ContainerRegistryURL).NotTo(BeEmpty())
+		}).Should(Succeed())
+
+		err = spacepods.NewDefaulter(adminClient).Default(ctx, pod)
+	})
+
+	It("points the images in the registry to its pull address", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(getImages()).To(Equal([]string{
+			"gcr.io/kpack/prepare@sha256:abc",
+			"localhost:32137/cfapi/kpack-builder@sha256:def",
+			"localhost:32137/my-app-droplets@sha256:123",
+		}))
+	})
+
+	It("does not mount a trusted CA bundle", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pod.Annotations).NotTo(HaveKey(trustedca.HashAnnotation))
+		Expect(pod.Spec.Volumes).To(BeEmpty())
+	})
+
+	When("a trusted CA bundle is configured", func() {
+		BeforeEach(func() {
+			trustedCA = &v1alpha1.TrustedCA{
+				Namespace: testNamespace,
+				Name:      "corporate-ca",
+				Key:       "ca.crt",
+				Hash:      "abc123",
+			}
+		})
+
+		It("mounts the bundle into all containers", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Annotations).To(HaveKeyWithValue(trustedca.HashAnnotation, "abc123"))
+			Expect(pod.Spec.Volumes).To(ConsistOf(HaveField("Name", "cfapi-trusted-ca")))
+			for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+				Expect(container.Env).To(ContainElement(corev1.EnvVar{
+					Name:  "SSL_CERT_DIR",
+					Value: "/etc/ssl/certs:/etc/cfapi/trusted-ca",
+				}))
+			}
+		})
+	})
+
+	When("a proxy is configured", func() {
+		BeforeEach(func() {
+			proxy = &v1alpha1.ProxyConfig{
+				HTTPProxy:  "http://proxy.example.com:3128",
+				HTTPSProxy: "http://proxy.example.com:3128",
+				NoProxy:    "localhost,.svc",
+			}
+		})
+
+		It("does not set the proxy variables of app pods", func() {
+			Expect(err).NotTo(HaveOccurred())
+			for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+				Expect(container.Env).To(BeEmpty())
+			}
+		})
+
+		When("the pod is a build pod", func() {
+			BeforeEach(func() {
+				pod.Labels = map[string]string{"kpack.io/build": "my-app-build-1"}
+			})
+
+			It("sets the proxy variables in all containers", func() {
+				Expect(err).NotTo(HaveOccurred())
+				for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+					Expect(container.Env).To(ContainElements(
+						corev1.EnvVar{Name: "HTTPS_PROXY", Value: "http://proxy.example.com:3128"},
+						corev1.EnvVar{Name: "no_proxy", Value: "localhost,.svc"},
+					))
+				}
+			})
+		})
+	})
+
+	When("images are pulled from the address they are pushed to", func() {
+		BeforeEach(func() {
+			pullURL = ""
+		})
+
+		It("does not change the images", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(getImages()).To(Equal([]string{
+				"gcr.io/kpack/prepare@sha256:abc",
+				"dockerregistry.kyma-system.svc.cluster.local:5000/cfapi/kpack-builder@sha256:def",
+				"dockerregistry.kyma-system.svc.cluster.local:5000/my-app-droplets@sha256:123",
+			}))
+		})
+	})
+})
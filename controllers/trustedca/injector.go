@@ -0,0 +1,147 @@
+package trustedca
+
+import (
+	"slices"
+
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// ConfigMapName is the copy of the trusted CA bundle in the namespaces
+	// of the installed components
+	ConfigMapName = "cfapi-trusted-ca"
+	// BundleKey is the key of the bundle in the copies
+	BundleKey = "ca.crt"
+	// HashAnnotation holds the hash of the bundle on the pod templates
+	// trusting it, so that they are rolled out when the bundle changes
+	HashAnnotation = "cfapi.kyma-project.io/trusted-ca-hash"
+
+	volumeName = "cfapi-trusted-ca"
+	mountPath  = "/etc/cfapi/trusted-ca"
+	// certDirs are the directories Go programs load trusted certificates
+	// from. The system certificates in /etc/ssl/certs are kept
+	certDirs = "/etc/ssl/certs:" + mountPath
+)
+
+var workloadKinds = []string{"Deployment", "StatefulSet", "DaemonSet", "Job", "CronJob"}
+
+// Injector mounts the trusted CA bundle into the workloads of the installed
+// components and points `SSL_CERT_DIR` to it. The components are Go programs,
+// which read the directories of `SSL_CERT_DIR` on top of the system
+// certificates
+type Injector struct {
+	namespaces []string
+	hash       string
+}
+
+// NewInjector returns the injector of the installation config for workloads
+// in the given namespaces, which hold a copy of the bundle, or nil if no
+// bundle is configured. A nil injector leaves workloads as they are
+func NewInjector(config v1alpha1.InstallationConfig, namespaces ...string) *Injector {
+	if config.TrustedCA == nil {
+		return nil
+	}
+
+	return &Injector{
+		namespaces: namespaces,
+		hash:       config.TrustedCA.Hash,
+	}
+}
+
+// Inject adds the bundle to the pod template of a workload. Objects without a
+// namespace are taken to be in the given default namespace, like the
+// resources of a helm release
+func (i *Injector) Inject(obj *unstructured.Unstructured, defaultNamespace string) {
+	if i == nil || !slices.Contains(workloadKinds, obj.GetKind()) {
+		return
+	}
+
+	namespace := obj.GetNamespace()
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+	if !slices.Contains(i.namespaces, namespace) {
+		return
+	}
+
+	templatePath := []string{"spec", "template"}
+	if obj.GetKind() == "CronJob" {
+		templatePath = []string{"spec", "jobTemplate", "spec", "template"}
+	}
+	template, ok := nestedMap(obj.Object, templatePath...)
+	if !ok {
+		return
+	}
+
+	annotations, _, _ := unstructured.NestedStringMap(template, "metadata", "annotations")
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[HashAnnotation] = i.hash
+	_ = unstructured.SetNestedStringMap(template, annotations, "metadata", "annotations")
+
+	podSpec, ok := nestedMap(template, "spec")
+	if !ok {
+		return
+	}
+
+	volumes, _, _ := unstructured.NestedSlice(podSpec, "volumes")
+	podSpec["volumes"] = setNamed(volumes, map[string]any{
+		"name": volumeName,
+		"configMap": map[string]any{
+			"name":  ConfigMapName,
+			"items": []any{map[string]any{"key": BundleKey, "path": BundleKey}},
+		},
+	})
+
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _ := podSpec[field].([]any)
+		for _, container := range containers {
+			if container, ok := container.(map[string]any); ok {
+				injectContainer(container)
+			}
+		}
+	}
+}
+
+func injectContainer(container map[string]any) {
+	volumeMounts, _, _ := unstructured.NestedSlice(container, "volumeMounts")
+	container["volumeMounts"] = setNamed(volumeMounts, map[string]any{
+		"name":      volumeName,
+		"mountPath": mountPath,
+		"readOnly":  true,
+	})
+
+	env, _, _ := unstructured.NestedSlice(container, "env")
+	container["env"] = setNamed(env, map[string]any{
+		"name":  "SSL_CERT_DIR",
+		"value": certDirs,
+	})
+}
+
+// setNamed replaces the item with the name of the given item, or appends it
+func setNamed(items []any, item map[string]any) []any {
+	for i, existing := range items {
+		if existingItem, ok := existing.(map[string]any); ok && existingItem["name"] == item["name"] {
+			items[i] = item
+			return items
+		}
+	}
+
+	return append(items, item)
+}
+
+// nestedMap returns the map at the given path without copying it, so that it
+// can be modified in place
+func nestedMap(obj map[string]any, path ...string) (map[string]any, bool) {
+	for _, field := range path {
+		next, ok := obj[field].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		obj = next
+	}
+
+	return obj, true
+}
@@ -0,0 +1,148 @@
+package trustedca_test
+
+import (
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/trustedca"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("Injector", func() {
+	var (
+		config   v1alpha1.InstallationConfig
+		injector *trustedca.Injector
+		obj      *unstructured.Unstructured
+	)
+
+	BeforeEach(func() {
+		config = v1alpha1.InstallationConfig{
+			TrustedCA: &v1alpha1.TrustedCA{
+				Namespace: "kyma-system",
+				Name:      "corporate-ca",
+				Key:       "ca.crt",
+				Hash:      "abc123",
+			},
+		}
+
+		obj = &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]any{
+				"name":      "korifi-api-deployment",
+				"namespace": "korifi",
+			},
+			"spec": map[string]any{
+				"template": map[string]any{
+					"spec": map[string]any{
+						"volumes": []any{
+							map[string]any{"name": "config", "configMap": map[string]any{"name": "korifi-api-config"}},
+						},
+						"containers": []any{
+							map[string]any{
+								"name":  "korifi-api",
+								"image": "korifi-api:latest",
+								"env": []any{
+									map[string]any{"name": "SSL_CERT_DIR", "value": "/etc/other"},
+								},
+							},
+						},
+					},
+				},
+			},
+		}}
+	})
+
+	JustBeforeEach(func() {
+		injector = trustedca.NewInjector(config, "korifi", "kpack")
+		injector.Inject(obj, "")
+	})
+
+	It("mounts the bundle into the pod template", func() {
+		annotations, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "annotations")
+		Expect(annotations).To(HaveKeyWithValue(trustedca.HashAnnotation, "abc123"))
+
+		volumes, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "volumes")
+		Expect(volumes).To(ConsistOf(
+			HaveKeyWithValue("name", "config"),
+			And(
+				HaveKeyWithValue("name", "cfapi-trusted-ca"),
+				HaveKeyWithValue("configMap", HaveKeyWithValue("name", trustedca.ConfigMapName)),
+			),
+		))
+
+		containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+		Expect(containers).To(HaveLen(1))
+		container := containers[0].(map[string]any)
+		Expect(container["volumeMounts"]).To(ConsistOf(And(
+			HaveKeyWithValue("name", "cfapi-trusted-ca"),
+			HaveKeyWithValue("mountPath", "/etc/cfapi/trusted-ca"),
+		)))
+		Expect(container["env"]).To(ConsistOf(And(
+			HaveKeyWithValue("name", "SSL_CERT_DIR"),
+			HaveKeyWithValue("value", "/etc/ssl/certs:/etc/cfapi/trusted-ca"),
+		)))
+	})
+
+	When("the workload is in another namespace", func() {
+		BeforeEach(func() {
+			obj.SetNamespace("default")
+		})
+
+		It("leaves the workload as it is", func() {
+			_, found, _ := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "annotations")
+			Expect(found).To(BeFalse())
+		})
+	})
+
+	When("the workload has no namespace", func() {
+		BeforeEach(func() {
+			obj.SetNamespace("")
+		})
+
+		It("leaves the workload as it is", func() {
+			_, found, _ := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "annotations")
+			Expect(found).To(BeFalse())
+		})
+	})
+
+	When("the object is not a workload", func() {
+		BeforeEach(func() {
+			obj.SetKind("ConfigMap")
+		})
+
+		It("leaves the object as it is", func() {
+			_, found, _ := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "annotations")
+			Expect(found).To(BeFalse())
+		})
+	})
+
+	When("the workload is a cron job", func() {
+		BeforeEach(func() {
+			template := obj.Object["spec"].(map[string]any)["template"]
+			obj.SetKind("CronJob")
+			obj.Object["spec"] = map[string]any{
+				"jobTemplate": map[string]any{
+					"spec": map[string]any{"template": template},
+				},
+			}
+		})
+
+		It("mounts the bundle into the job template", func() {
+			annotations, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "jobTemplate", "spec", "template", "metadata", "annotations")
+			Expect(annotations).To(HaveKeyWithValue(trustedca.HashAnnotation, "abc123"))
+		})
+	})
+
+	When("no bundle is configured", func() {
+		BeforeEach(func() {
+			config.TrustedCA = nil
+		})
+
+		It("leaves the workload as it is", func() {
+			Expect(injector).To(BeNil())
+			_, found, _ := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "annotations")
+			Expect(found).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,59 @@
+package trustedca
+
+import (
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// InjectPod mounts the copy of the trusted CA bundle in the namespace of the
+// pod into all its containers and points `SSL_CERT_DIR` to it, like Inject
+// does for the pod templates of the installed components. The pod waits for
+// the copy to exist before its containers are started
+func InjectPod(pod *corev1.Pod, hash string) {
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[HashAnnotation] = hash
+
+	pod.Spec.Volumes = setNamedVolume(pod.Spec.Volumes, corev1.Volume{
+		Name: volumeName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: ConfigMapName},
+				Items:                []corev1.KeyToPath{{Key: BundleKey, Path: BundleKey}},
+			},
+		},
+	})
+
+	for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for i := range containers {
+			injectPodContainer(&containers[i])
+		}
+	}
+}
+
+func injectPodContainer(container *corev1.Container) {
+	mount := corev1.VolumeMount{Name: volumeName, MountPath: mountPath, ReadOnly: true}
+	if i := slices.IndexFunc(container.VolumeMounts, func(m corev1.VolumeMount) bool { return m.Name == mount.Name }); i >= 0 {
+		container.VolumeMounts[i] = mount
+	} else {
+		container.VolumeMounts = append(container.VolumeMounts, mount)
+	}
+
+	env := corev1.EnvVar{Name: "SSL_CERT_DIR", Value: certDirs}
+	if i := slices.IndexFunc(container.Env, func(e corev1.EnvVar) bool { return e.Name == env.Name }); i >= 0 {
+		container.Env[i] = env
+	} else {
+		container.Env = append(container.Env, env)
+	}
+}
+
+func setNamedVolume(volumes []corev1.Volume, volume corev1.Volume) []corev1.Volume {
+	if i := slices.IndexFunc(volumes, func(v corev1.Volume) bool { return v.Name == volume.Name }); i >= 0 {
+		volumes[i] = volume
+		return volumes
+	}
+
+	return append(volumes, volume)
+}
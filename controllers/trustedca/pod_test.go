@@ -0,0 +1,66 @@
+package trustedca_test
+
+import (
+	"github.com/kyma-project/cfapi/controllers/trustedca"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("InjectPod", func() {
+	var pod *corev1.Pod
+
+	BeforeEach(func() {
+		pod = &corev1.Pod{
+			Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{{Name: "layers"}},
+				InitContainers: []corev1.Container{{
+					Name:  "build",
+					Image: "kpack-builder:latest",
+				}},
+				Containers: []corev1.Container{{
+					Name:  "application",
+					Image: "my-app-droplet:latest",
+					Env:   []corev1.EnvVar{{Name: "SSL_CERT_DIR", Value: "/etc/other"}},
+				}},
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		trustedca.InjectPod(pod, "abc123")
+	})
+
+	It("mounts the bundle into all containers", func() {
+		Expect(pod.Annotations).To(HaveKeyWithValue(trustedca.HashAnnotation, "abc123"))
+
+		Expect(pod.Spec.Volumes).To(HaveLen(2))
+		Expect(pod.Spec.Volumes[1].Name).To(Equal("cfapi-trusted-ca"))
+		Expect(pod.Spec.Volumes[1].ConfigMap.Name).To(Equal(trustedca.ConfigMapName))
+
+		for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+			Expect(container.VolumeMounts).To(ConsistOf(corev1.VolumeMount{
+				Name:      "cfapi-trusted-ca",
+				MountPath: "/etc/cfapi/trusted-ca",
+				ReadOnly:  true,
+			}))
+			Expect(container.Env).To(ConsistOf(corev1.EnvVar{
+				Name:  "SSL_CERT_DIR",
+				Value: "/etc/ssl/certs:/etc/cfapi/trusted-ca",
+			}))
+		}
+	})
+
+	When("the bundle is already mounted", func() {
+		JustBeforeEach(func() {
+			trustedca.InjectPod(pod, "def456")
+		})
+
+		It("updates the mount", func() {
+			Expect(pod.Annotations).To(HaveKeyWithValue(trustedca.HashAnnotation, "def456"))
+			Expect(pod.Spec.Volumes).To(HaveLen(2))
+			Expect(pod.Spec.Containers[0].VolumeMounts).To(HaveLen(1))
+			Expect(pod.Spec.Containers[0].Env).To(HaveLen(1))
+		})
+	})
+})
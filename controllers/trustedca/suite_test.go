@@ -0,0 +1,13 @@
+package trustedca_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestTrustedCA(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Trusted CA Suite")
+}
@@ -0,0 +1,155 @@
+package trustedcaspaces
+
+import (
+	"context"
+	"fmt"
+
+	korifiv1alpha1 "code.cloudfoundry.org/korifi/controllers/api/v1alpha1"
+	"github.com/go-logr/logr"
+	"github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/trustedca"
+	"github.com/kyma-project/cfapi/tools/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// ManagedLabel marks the copies of the trusted CA bundle in the space
+// namespaces
+const ManagedLabel = "cfapi.kyma-project.io/trusted-ca"
+
+// Reconciler copies the trusted CA bundle to every space namespace, where the
+// pods webhook mounts it into the app, task and kpack build pods. New pods
+// get the current bundle, running apps get it with their next restart
+type Reconciler struct {
+	k8sClient client.Client
+}
+
+func NewReconciler(
+	k8sClient client.Client,
+	log logr.Logger,
+) *k8s.PatchingReconciler[v1alpha1.CFAPI] {
+	return k8s.NewPatchingReconciler(log, k8sClient, &Reconciler{
+		k8sClient: k8sClient,
+	})
+}
+
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) *builder.Builder {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("trustedcaspaces").
+		For(&v1alpha1.CFAPI{}).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.enqueueCFAPIs)).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.enqueueCFAPIs))
+}
+
+func (r *Reconciler) enqueueCFAPIs(ctx context.Context, obj client.Object) []reconcile.Request {
+	cfAPIs := &v1alpha1.CFAPIList{}
+	if err := r.k8sClient.List(ctx, cfAPIs); err != nil {
+		logr.FromContextOrDiscard(ctx).Error(err, "failed to list CFAPIs")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, cfAPI := range cfAPIs.Items {
+		if configMap, ok := obj.(*corev1.ConfigMap); ok && !isBundleOrCopy(configMap, cfAPI.Status.InstallationConfig.TrustedCA) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&cfAPI)})
+	}
+	return requests
+}
+
+// isBundleOrCopy returns whether the config map is the source of the bundle
+// or one of its copies, which are restored when changed
+func isBundleOrCopy(configMap *corev1.ConfigMap, trustedCA *v1alpha1.TrustedCA) bool {
+	if _, ok := configMap.Labels[ManagedLabel]; ok {
+		return true
+	}
+
+	return trustedCA != nil && trustedCA.Namespace == configMap.Namespace && trustedCA.Name == configMap.Name
+}
+
+func (r *Reconciler) ReconcileResource(ctx context.Context, cfAPI *v1alpha1.CFAPI) (ctrl.Result, error) {
+	log := logr.FromContextOrDiscard(ctx)
+
+	config := cfAPI.Status.InstallationConfig
+	if config.RootNamespace == "" || !cfAPI.DeletionTimestamp.IsZero() {
+		log.Info("cfapi is not installed, skipping trusted CA bundle of spaces")
+		return ctrl.Result{}, nil
+	}
+
+	if config.TrustedCA == nil {
+		return ctrl.Result{}, r.deleteCopies(ctx)
+	}
+
+	source := &corev1.ConfigMap{}
+	if err := r.k8sClient.Get(ctx, client.ObjectKey{Namespace: config.TrustedCA.Namespace, Name: config.TrustedCA.Name}, source); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to get trusted CA bundle %s/%s: %w", config.TrustedCA.Namespace, config.TrustedCA.Name, err)
+	}
+
+	namespaces := &corev1.NamespaceList{}
+	if err := r.k8sClient.List(ctx, namespaces, client.HasLabels{korifiv1alpha1.SpaceGUIDLabelKey}); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list the space namespaces: %w", err)
+	}
+
+	for _, namespace := range namespaces.Items {
+		if !namespace.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		if err := r.ensureCopy(ctx, namespace.Name, source.Data[config.TrustedCA.Key], config.TrustedCA.Hash); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func (r *Reconciler) ensureCopy(ctx context.Context, namespace, bundle, hash string) error {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      trustedca.ConfigMapName,
+		},
+	}
+
+	_, err := controllerutil.CreateOrPatch(ctx, r.k8sClient, configMap, func() error {
+		if configMap.Labels == nil {
+			configMap.Labels = map[string]string{}
+		}
+		configMap.Labels[ManagedLabel] = "true"
+		if configMap.Annotations == nil {
+			configMap.Annotations = map[string]string{}
+		}
+		configMap.Annotations[trustedca.HashAnnotation] = hash
+		configMap.Data = map[string]string{trustedca.BundleKey: bundle}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy the trusted CA bundle to namespace %s: %w", namespace, err)
+	}
+
+	return nil
+}
+
+// deleteCopies removes the bundle from the spaces. Running pods keep it until
+// they are restarted
+func (r *Reconciler) deleteCopies(ctx context.Context) error {
+	copies := &corev1.ConfigMapList{}
+	if err := r.k8sClient.List(ctx, copies, client.HasLabels{ManagedLabel}); err != nil {
+		return fmt.Errorf("failed to list the copies of the trusted CA bundle: %w", err)
+	}
+
+	for _, configMap := range copies.Items {
+		if err := client.IgnoreNotFound(r.k8sClient.Delete(ctx, &configMap)); err != nil {
+			return fmt.Errorf("failed to delete the trusted CA bundle in namespace %s: %w", configMap.Namespace, err)
+		}
+	}
+
+	return nil
+}
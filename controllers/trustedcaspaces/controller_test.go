@@ -0,0 +1,163 @@
+package trustedcaspaces_test
+
+import (
+	korifiv1alpha1 "code.cloudfoundry.org/korifi/controllers/api/v1alpha1"
+	"github.com/google/uuid"
+	v1alpha1 "github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/trustedca"
+	"github.com/kyma-project/cfapi/controllers/trustedcaspaces"
+	. "github.com/kyma-project/cfapi/tests/helpers"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Trusted CA Spaces", func() {
+	var (
+		cfAPI     *v1alpha1.CFAPI
+		bundle    *corev1.ConfigMap
+		trustedCA *v1alpha1.TrustedCA
+		spaceNS   string
+		otherNS   string
+	)
+
+	getCopy := func(namespace string) (*corev1.ConfigMap, error) {
+		configMap := &corev1.ConfigMap{}
+		err := adminClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: trustedca.ConfigMapName}, configMap)
+		return configMap, err
+	}
+
+	BeforeEach(func() {
+		bundle = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: cfAPINamespace,
+				Name:      "corporate-ca",
+			},
+			Data: map[string]string{"ca.crt": "<PEM encoded certificates>"},
+		}
+		EnsureCreate(adminClient, bundle)
+
+		trustedCA = &v1alpha1.TrustedCA{
+			Namespace: cfAPINamespace,
+			Name:      "corporate-ca",
+			Key:       "ca.crt",
+			Hash:      "abc123",
+		}
+
+		spaceNS = uuid.NewString()
+		EnsureCreate(adminClient, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   spaceNS,
+				Labels: map[string]string{korifiv1alpha1.SpaceGUIDLabelKey: spaceNS},
+			},
+		})
+
+		otherNS = uuid.NewString()
+		EnsureCreate(adminClient, &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: otherNS,
+			},
+		})
+	})
+
+	JustBeforeEach(func() {
+		cfAPI = &v1alpha1.CFAPI{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      uuid.NewString(),
+				Namespace: cfAPINamespace,
+			},
+		}
+		EnsureCreate(adminClient, cfAPI)
+
+		cfAPI.Status.InstallationConfig = v1alpha1.InstallationConfig{
+			RootNamespace: "cf",
+			TrustedCA:     trustedCA,
+		}
+		Expect(adminClient.Status().Update(ctx, cfAPI)).To(Succeed())
+	})
+
+	It("copies the bundle to the space namespaces", func() {
+		Eventually(func(g Gomega) {
+			configMap, err := getCopy(spaceNS)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(configMap.Data).To(Equal(map[string]string{trustedca.BundleKey: "<PEM encoded certificates>"}))
+			g.Expect(configMap.Labels).To(HaveKey(trustedcaspaces.ManagedLabel))
+			g.Expect(configMap.Annotations).To(HaveKeyWithValue(trustedca.HashAnnotation, "abc123"))
+		}).Should(Succeed())
+
+		Consistently(func(g Gomega) {
+			_, err := getCopy(otherNS)
+			g.Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+		}).Should(Succeed())
+	})
+
+	When("a space is created", func() {
+		var newSpaceNS string
+
+		JustBeforeEach(func() {
+			Eventually(func(g Gomega) {
+				_, err := getCopy(spaceNS)
+				g.Expect(err).NotTo(HaveOccurred())
+			}).Should(Succeed())
+
+			newSpaceNS = uuid.NewString()
+			EnsureCreate(adminClient, &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   newSpaceNS,
+					Labels: map[string]string{korifiv1alpha1.SpaceGUIDLabelKey: newSpaceNS},
+				},
+			})
+		})
+
+		It("copies the bundle to it", func() {
+			Eventually(func(g Gomega) {
+				_, err := getCopy(newSpaceNS)
+				g.Expect(err).NotTo(HaveOccurred())
+			}).Should(Succeed())
+		})
+	})
+
+	When("the bundle changes", func() {
+		JustBeforeEach(func() {
+			Eventually(func(g Gomega) {
+				_, err := getCopy(spaceNS)
+				g.Expect(err).NotTo(HaveOccurred())
+			}).Should(Succeed())
+
+			EnsurePatch(adminClient, bundle, func(configMap *corev1.ConfigMap) {
+				configMap.Data["ca.crt"] = "<updated certificates>"
+			})
+		})
+
+		It("updates the copies", func() {
+			Eventually(func(g Gomega) {
+				configMap, err := getCopy(spaceNS)
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(configMap.Data).To(HaveKeyWithValue(trustedca.BundleKey, "<updated certificates>"))
+			}).Should(Succeed())
+		})
+	})
+
+	When("the bundle is removed from the config", func() {
+		JustBeforeEach(func() {
+			Eventually(func(g Gomega) {
+				_, err := getCopy(spaceNS)
+				g.Expect(err).NotTo(HaveOccurred())
+			}).Should(Succeed())
+
+			Expect(adminClient.Get(ctx, client.ObjectKeyFromObject(cfAPI), cfAPI)).To(Succeed())
+			cfAPI.Status.InstallationConfig.TrustedCA = nil
+			Expect(adminClient.Status().Update(ctx, cfAPI)).To(Succeed())
+		})
+
+		It("deletes the copies", func() {
+			Eventually(func(g Gomega) {
+				_, err := getCopy(spaceNS)
+				g.Expect(k8serrors.IsNotFound(err)).To(BeTrue())
+			}).Should(Succeed())
+		})
+	})
+})
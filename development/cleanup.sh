@@ -9,5 +9,5 @@ helm delete --ignore-not-found korifi-prerequisites -n korifi --wait
 helm delete --ignore-not-found contour -n cfapi-system --wait
 kubectl delete --ignore-not-found namespace korifi
 kubectl delete --ignore-not-found -f $HOME/workspace/cfapi/module-data/issuers/issuers.yaml
-kubectl delete --ignore-not-found -f $HOME/workspace/cfapi/module-data/vendor/gateway-api
+kubectl delete --ignore-not-found -f $HOME/workspace/cfapi/module-data/vendor/gateway-api/experimental-install.yaml
 kubectl delete --ignore-not-found -f $HOME/workspace/cfapi/module-data/vendor/kpack
@@ -177,7 +177,7 @@ EOF
 
 install_gardener_cert_manager() {
   echo ">>> Installing Gateway API"
-  kubectl apply --server-side=true -f "$VENDOR_DIR/gateway-api"
+  kubectl apply --server-side=true -f "$VENDOR_DIR/gateway-api/experimental-install.yaml"
 
   echo ">>> Installing Vertical Pod Autoscaler"
   kubectl apply -f https://raw.githubusercontent.com/kubernetes/autoscaler/vpa-release-1.0/vertical-pod-autoscaler/deploy/vpa-v1-crd-gen.yaml
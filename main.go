@@ -23,12 +23,15 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
+	"net/http"
 	"os"
 	"time"
 
 	korifiv1alpha1 "code.cloudfoundry.org/korifi/controllers/api/v1alpha1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 
@@ -43,15 +46,28 @@ import (
 
 	certv1alpha1 "github.com/gardener/cert-management/pkg/apis/cert/v1alpha1"
 	v1alpha1 "github.com/kyma-project/cfapi/api/v1alpha1"
+	"github.com/kyma-project/cfapi/controllers/buildcache"
+	"github.com/kyma-project/cfapi/controllers/buildupdates"
 	"github.com/kyma-project/cfapi/controllers/cfapi"
 	"github.com/kyma-project/cfapi/controllers/cfapi/secrets"
+	"github.com/kyma-project/cfapi/controllers/cfroles"
 	"github.com/kyma-project/cfapi/controllers/helm"
 	"github.com/kyma-project/cfapi/controllers/installable"
 	"github.com/kyma-project/cfapi/controllers/installable/values"
 	"github.com/kyma-project/cfapi/controllers/kyma"
+	"github.com/kyma-project/cfapi/controllers/proxy"
+	"github.com/kyma-project/cfapi/controllers/proxyenv"
+	"github.com/kyma-project/cfapi/controllers/registrycredentials"
+	"github.com/kyma-project/cfapi/controllers/registrygc"
+	"github.com/kyma-project/cfapi/controllers/registrysecrets"
+	"github.com/kyma-project/cfapi/controllers/routes"
+	"github.com/kyma-project/cfapi/controllers/spacepods"
+	"github.com/kyma-project/cfapi/controllers/trustedcaspaces"
 	kymaistiov1alpha2 "github.com/kyma-project/istio/operator/api/v1alpha2"
+	buildv1alpha2 "github.com/pivotal/kpack/pkg/apis/build/v1alpha2"
 	istiov1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
 	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -64,8 +80,38 @@ var (
 	setupLog = ctrl.Log.WithName("setup")
 )
 
+// ContourEnabled keeps contour installed while migrating away from it, until
+// the cutover to the new gateway has completed
 var ContourEnabled installable.Predicate = func(ctx context.Context, config v1alpha1.InstallationConfig) bool {
-	return config.GatewayType == v1alpha1.GatewayTypeContour
+	return config.GatewayType == v1alpha1.GatewayTypeContour || config.PreviousGatewayType == v1alpha1.GatewayTypeContour
+}
+
+var IstioNative installable.Predicate = func(ctx context.Context, config v1alpha1.InstallationConfig) bool {
+	return config.GatewayType == v1alpha1.GatewayTypeIstioNative
+}
+
+// korifiGatewayKinds are not applied with the istio-native gateway type, as
+// no gateway api implementation is installed to serve them
+var korifiGatewayKinds installable.KindsFilter = func(config v1alpha1.InstallationConfig) []schema.GroupKind {
+	if config.GatewayType != v1alpha1.GatewayTypeIstioNative {
+		return nil
+	}
+	return []schema.GroupKind{
+		{Group: gatewayv1.GroupName, Kind: "Gateway"},
+		{Group: gatewayv1.GroupName, Kind: "TLSRoute"},
+	}
+}
+
+var LocalProfile installable.Predicate = func(ctx context.Context, config v1alpha1.InstallationConfig) bool {
+	return config.Profile == v1alpha1.ProfileLocal
+}
+
+var KymaProfile installable.Predicate = func(ctx context.Context, config v1alpha1.InstallationConfig) bool {
+	return config.Profile != v1alpha1.ProfileLocal
+}
+
+var LocalRegistryEnabled installable.Predicate = func(ctx context.Context, config v1alpha1.InstallationConfig) bool {
+	return config.Profile == v1alpha1.ProfileLocal && config.ContainerRegistrySecret == cfapi.LocalRegistrySecretName
 }
 
 type FlagVar struct {
@@ -82,6 +128,8 @@ func init() { //nolint:gochecknoinits
 	utilruntime.Must(certv1alpha1.AddToScheme(scheme))
 	utilruntime.Must(istiov1beta1.AddToScheme(scheme))
 	utilruntime.Must(korifiv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(buildv1alpha2.AddToScheme(scheme))
+	utilruntime.Must(gatewayv1.Install(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -100,6 +148,9 @@ func main() {
 
 	setupLog.Info("Starting CFAPI Operator", "version", buildVersion)
 
+	// the webhook server serves the certificate the pods webhook installable
+	// issues, read with the client of the manager once it is created
+	var podsCertificate *spacepods.ServingCertificate
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
 		Metrics: metricsserver.Options{
@@ -107,6 +158,9 @@ func main() {
 		},
 		WebhookServer: webhook.NewServer(webhook.Options{
 			Port: 9443,
+			TLSOpts: []func(*tls.Config){func(config *tls.Config) {
+				podsCertificate.ConfigureTLS(config)
+			}},
 		}),
 		HealthProbeBindAddress: flagVar.probeAddr,
 		LeaderElection:         flagVar.enableLeaderElection,
@@ -116,26 +170,45 @@ func main() {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
+	podsCertificate = spacepods.NewServingCertificate(mgr.GetClient(), "cfapi-system", installable.PodsWebhookCertSecretName)
 
 	helmClient := helm.NewClient()
 	systemNs := installable.NewYaml(mgr.GetClient(), "./module-data/namespaces/system.yaml", "System Namespaces")
 	cfRootNs := installable.NewYaml(mgr.GetClient(), "./module-data/namespaces/cfroot.yaml", "Root Namespace")
-	certIssuers := installable.NewYaml(mgr.GetClient(), "./module-data/issuers/issuers.yaml", "CertIssuers")
-	gwAPI := installable.NewYaml(mgr.GetClient(), "./module-data/vendor/gateway-api/experimental-install.yaml", "Gateway API")
+	trustedCA := installable.NewTrustedCA(mgr.GetClient(), installable.SystemNamespaces...)
+	certIssuers := installable.NewConditional(
+		KymaProfile,
+		installable.NewYaml(mgr.GetClient(), "./module-data/issuers/issuers.yaml", "CertIssuers"),
+	)
+	localCA := installable.NewConditional(LocalProfile, installable.NewLocalCA(mgr.GetClient(), "cfapi-system", "korifi"))
+	localRegistry := installable.NewConditional(
+		LocalRegistryEnabled,
+		installable.NewYaml(mgr.GetClient(), "./module-data/local-registry/registry.yaml", "Local Registry"),
+	)
+	podsWebhook := installable.NewPodsWebhook(mgr.GetClient(), "cfapi-system", "cfapi-webhook-service")
+	gwAPI := installable.NewAlternative(
+		IstioNative,
+		installable.NewSharedYaml(mgr.GetClient(), "./module-data/vendor/gateway-api/standard-install.yaml", "Gateway API (standard)"),
+		installable.NewSharedYaml(mgr.GetClient(), "./module-data/vendor/gateway-api/experimental-install.yaml", "Gateway API"),
+	)
 	contour := installable.NewConditional(
 		ContourEnabled,
-		installable.NewHelmChart("./module-data/vendor/contour-chart", "cfapi-system", "contour", values.ContourValues, helmClient),
+		installable.NewHelmChart("./module-data/vendor/contour-chart", "cfapi-system", "contour", values.NewContour(), helmClient),
 	)
-	kpack := installable.NewYaml(mgr.GetClient(), "./module-data/vendor/kpack/release-*.yaml", "kpack")
+	kpack := installable.NewSharedYaml(mgr.GetClient(), "./module-data/vendor/kpack/release-*.yaml", "kpack")
 	korifiPrerequisites := installable.NewHelmChart("./module-data/korifi-prerequisites-chart", "korifi", "korifi-prerequisites", values.NewPrerequisites(mgr.GetClient()), helmClient)
-	korifi := installable.NewHelmChart("./module-data/vendor/korifi-chart", "korifi", "korifi", values.NewKorifi(mgr.GetClient(), "korifi"), helmClient)
+	korifi := installable.NewFilteredHelmChart("./module-data/vendor/korifi-chart", "korifi", "korifi", values.NewKorifi(mgr.GetClient(), "korifi"), korifiGatewayKinds, helmClient)
 	cfAPIConfig := installable.NewHelmChart("./module-data/cfapi-config-chart", "korifi", "cfapi-config", values.NewCFAPIConfig(mgr.GetClient()), helmClient)
 	btpServiceBroker := installable.NewHelmChart("./module-data/btp-service-broker/helm", "cfapi-system", "btp-service-broker", values.Override{}, helmClient)
 
 	installOrder := []installable.Installable{
 		systemNs,
 		cfRootNs,
+		trustedCA,
 		certIssuers,
+		localCA,
+		localRegistry,
+		podsWebhook,
 		gwAPI,
 		contour,
 		kpack,
@@ -146,6 +219,7 @@ func main() {
 	}
 
 	uninstallOrder := []installable.Installable{
+		cfroles.NewRoleBindings(mgr.GetClient()),
 		installable.NewOrgs(mgr.GetClient()),
 		cfRootNs,
 		btpServiceBroker,
@@ -155,16 +229,30 @@ func main() {
 		kpack,
 		contour,
 		gwAPI,
+		podsWebhook,
+		localRegistry,
+		localCA,
 		certIssuers,
+		trustedCA,
 		systemNs,
 	}
 
 	controllersLog := ctrl.Log.WithName(operatorName)
+	// the outgoing requests of the operator follow the proxy of the CFAPI
+	proxySettings := proxy.NewSettings()
+	if err := proxySettings.Load(context.Background(), mgr.GetAPIReader()); err != nil {
+		setupLog.Error(err, "unable to load the proxy of the installed CFAPI")
+		os.Exit(1)
+	}
 	if err := cfapi.NewReconciler(
 		mgr.GetClient(),
 		mgr.GetScheme(),
-		kyma.NewClient(mgr.GetClient()),
+		kyma.NewClient(mgr.GetClient(), &http.Client{Timeout: 10 * time.Second, Transport: proxySettings.Transport()}),
 		secrets.NewDocker(mgr.GetClient()),
+		secrets.NewRegistryChecker(&http.Client{Timeout: 10 * time.Second, Transport: proxySettings.Transport()}),
+		// cached DNS records expire within the TTL of the CF DNS entries
+		cfapi.NewGatewayMigrator(mgr.GetClient(), cfapi.NewHTTPSProbe(10*time.Second), 10*time.Minute),
+		proxySettings,
 		mgr.GetEventRecorder(operatorName),
 		controllersLog,
 		10*time.Second,
@@ -175,6 +263,103 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := cfroles.NewReconciler(
+		mgr.GetClient(),
+		mgr.GetEventRecorder(operatorName),
+		controllersLog,
+	).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "CFRoles")
+		os.Exit(1)
+	}
+
+	if err := registrysecrets.NewReconciler(
+		mgr.GetClient(),
+		mgr.GetEventRecorder(operatorName),
+		controllersLog,
+	).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RegistrySecrets")
+		os.Exit(1)
+	}
+
+	if err := proxyenv.NewReconciler(
+		mgr.GetClient(),
+		mgr.GetCache(),
+		mgr.GetEventRecorder(operatorName),
+		controllersLog,
+	).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ProxyEnv")
+		os.Exit(1)
+	}
+
+	if err := trustedcaspaces.NewReconciler(
+		mgr.GetClient(),
+		controllersLog,
+	).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "TrustedCASpaces")
+		os.Exit(1)
+	}
+
+	if err := registrycredentials.NewReconciler(
+		mgr.GetClient(),
+		mgr.GetEventRecorder(operatorName),
+		secrets.NewCredentialRefresher(mgr.GetClient(), map[string]secrets.CredentialProvider{
+			secrets.CredentialProviderOAuth2: secrets.NewOAuth2CredentialProvider(&http.Client{Timeout: 10 * time.Second, Transport: proxySettings.Transport()}),
+		}),
+		controllersLog,
+	).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RegistryCredentials")
+		os.Exit(1)
+	}
+
+	if err := registrygc.NewReconciler(
+		mgr.GetClient(),
+		mgr.GetEventRecorder(operatorName),
+		registrygc.NewCollector(
+			mgr.GetClient(),
+			secrets.NewDocker(mgr.GetClient()),
+			secrets.NewRegistryClient(&http.Client{Timeout: 30 * time.Second, Transport: proxySettings.Transport()}),
+		),
+		controllersLog,
+	).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RegistryGC")
+		os.Exit(1)
+	}
+
+	if err := buildcache.NewReconciler(
+		mgr.GetClient(),
+		mgr.GetCache(),
+		controllersLog,
+	).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BuildCache")
+		os.Exit(1)
+	}
+
+	if err := buildupdates.NewReconciler(
+		mgr.GetClient(),
+		mgr.GetEventRecorder(operatorName),
+		secrets.NewDocker(mgr.GetClient()),
+		secrets.NewRegistryClient(&http.Client{Timeout: 30 * time.Second, Transport: proxySettings.Transport()}),
+		controllersLog,
+	).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BuildUpdates")
+		os.Exit(1)
+	}
+
+	if err := routes.NewReconciler(
+		mgr.GetClient(),
+		mgr.GetScheme(),
+		mgr.GetCache(),
+		controllersLog,
+	).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Routes")
+		os.Exit(1)
+	}
+
+	if err := spacepods.NewDefaulter(mgr.GetClient()).SetupWebhookWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create webhook", "webhook", "SpacePods")
+		os.Exit(1)
+	}
+
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,45 @@
+package helpers
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+)
+
+// NewStandInHTTPClient returns an http client which sends all requests to the
+// given server, regardless of the requested host
+func NewStandInHTTPClient(server *httptest.Server) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+			},
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec // the stand-in serves a self-signed certificate
+		},
+	}
+}
+
+// NewOIDCStandIn starts a TLS server serving a minimal OIDC discovery document
+// and JWKS for the given hosts. Requests to any other host result in 404
+func NewOIDCStandIn(hosts ...string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"issuer":"https://` + r.Host + `/oauth/token","jwks_uri":"https://` + r.Host + `/token_keys"}`))
+	})
+	mux.HandleFunc("/token_keys", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"keys":[{"kty":"RSA","kid":"key-id-1","use":"sig","alg":"RS256","n":"AQAB","e":"AQAB"}]}`))
+	})
+
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !slices.Contains(hosts, r.Host) {
+			http.NotFound(w, r)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+}
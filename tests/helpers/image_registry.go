@@ -0,0 +1,134 @@
+package helpers
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// imageRegistryStandInPageSize is the maximum page size of the catalog and
+// tag lists of the stand-in, so that pagination is exercised
+const imageRegistryStandInPageSize = 2
+
+// ImageRegistryStandIn is a TLS server serving the catalog, tag, manifest
+// and manifest deletion endpoints of the distribution API for images kept in
+// memory. It does not require authentication
+type ImageRegistryStandIn struct {
+	*httptest.Server
+
+	mu sync.Mutex
+	// images maps repository names to their tags and the digests the tags
+	// refer to
+	images map[string]map[string]string
+}
+
+func NewImageRegistryStandIn() *ImageRegistryStandIn {
+	standIn := &ImageRegistryStandIn{
+		images: map[string]map[string]string{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v2/_catalog", func(w http.ResponseWriter, r *http.Request) {
+		standIn.mu.Lock()
+		defer standIn.mu.Unlock()
+
+		writePage(w, r, "repositories", slices.Sorted(maps.Keys(standIn.images)))
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		standIn.mu.Lock()
+		defer standIn.mu.Unlock()
+
+		path := strings.TrimPrefix(r.URL.Path, "/v2/")
+		if name, ok := strings.CutSuffix(path, "/tags/list"); ok && r.Method == http.MethodGet {
+			tags, ok := standIn.images[name]
+			if !ok {
+				writeRegistryError(w, http.StatusNotFound, "NAME_UNKNOWN", "repository name not known to registry")
+				return
+			}
+			writePage(w, r, "tags", slices.Sorted(maps.Keys(tags)))
+			return
+		}
+
+		name, reference, ok := strings.Cut(path, "/manifests/")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodHead:
+			digest, ok := standIn.images[name][reference]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", digest)
+			w.WriteHeader(http.StatusOK)
+		case http.MethodDelete:
+			deleted := false
+			for tag, digest := range standIn.images[name] {
+				if digest == reference {
+					delete(standIn.images[name], tag)
+					deleted = true
+				}
+			}
+			if !deleted {
+				writeRegistryError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", "manifest unknown")
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	standIn.Server = httptest.NewTLSServer(mux)
+	return standIn
+}
+
+// AddImage tags the manifest with the given digest in the repository.
+// Repositories are kept when their last image is deleted, like distribution
+// registries do
+func (s *ImageRegistryStandIn) AddImage(repository, tag, digest string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.images[repository] == nil {
+		s.images[repository] = map[string]string{}
+	}
+	s.images[repository][tag] = digest
+}
+
+// Tags returns the tags of a repository
+func (s *ImageRegistryStandIn) Tags(repository string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Sorted(maps.Keys(s.images[repository]))
+}
+
+// writePage writes the page of the sorted items following the `last` query
+// parameter and links the next page, if any
+func writePage(w http.ResponseWriter, r *http.Request, key string, items []string) {
+	if last := r.URL.Query().Get("last"); last != "" {
+		index, _ := slices.BinarySearch(items, last)
+		if index < len(items) && items[index] == last {
+			index++
+		}
+		items = items[index:]
+	}
+
+	if len(items) > imageRegistryStandInPageSize {
+		items = items[:imageRegistryStandInPageSize]
+		next := url.URL{Path: r.URL.Path, RawQuery: url.Values{"n": {r.URL.Query().Get("n")}, "last": {items[len(items)-1]}}.Encode()}
+		w.Header().Set("Link", "<"+next.String()+`>; rel="next"`)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string][]string{key: items})
+}
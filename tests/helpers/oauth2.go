@@ -0,0 +1,84 @@
+package helpers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+)
+
+const (
+	OAuth2StandInAccessToken  = "stand-in-access-token"
+	OAuth2StandInRefreshToken = "stand-in-refresh-token"
+	// OAuth2StandInRotatedRefreshToken is the refresh token the stand-in
+	// returns in exchange for OAuth2StandInRefreshToken
+	OAuth2StandInRotatedRefreshToken = "stand-in-rotated-refresh-token"
+	OAuth2StandInSubjectToken        = "stand-in-subject-token"
+	// OAuth2StandInExpiresIn is the lifetime in seconds of the access tokens
+	// issued by the stand-in
+	OAuth2StandInExpiresIn = 3600
+)
+
+// OAuth2StandIn is a TLS server behaving like an OAuth2 token endpoint
+type OAuth2StandIn struct {
+	*httptest.Server
+	requests atomic.Int64
+}
+
+// NewOAuth2StandIn starts a token endpoint at `/oauth/token` issuing access
+// tokens for the client credentials grant with the given client ID and
+// secret, for the refresh token grant with OAuth2StandInRefreshToken and for
+// the token exchange grant with OAuth2StandInSubjectToken
+func NewOAuth2StandIn(clientID, clientSecret string) *OAuth2StandIn {
+	standIn := &OAuth2StandIn{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		standIn.requests.Add(1)
+
+		response := map[string]any{
+			"access_token": OAuth2StandInAccessToken,
+			"token_type":   "bearer",
+			"expires_in":   OAuth2StandInExpiresIn,
+		}
+
+		switch r.FormValue("grant_type") {
+		case "client_credentials":
+			if requestClientID, requestClientSecret, ok := r.BasicAuth(); !ok || requestClientID != clientID || requestClientSecret != clientSecret {
+				writeOAuth2Error(w, http.StatusUnauthorized, "invalid_client")
+				return
+			}
+		case "refresh_token":
+			if r.FormValue("refresh_token") != OAuth2StandInRefreshToken {
+				writeOAuth2Error(w, http.StatusBadRequest, "invalid_grant")
+				return
+			}
+			response["refresh_token"] = OAuth2StandInRotatedRefreshToken
+		case "urn:ietf:params:oauth:grant-type:token-exchange":
+			if r.FormValue("subject_token") != OAuth2StandInSubjectToken {
+				writeOAuth2Error(w, http.StatusBadRequest, "invalid_grant")
+				return
+			}
+		default:
+			writeOAuth2Error(w, http.StatusBadRequest, "unsupported_grant_type")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	})
+
+	standIn.Server = httptest.NewTLSServer(mux)
+	return standIn
+}
+
+// Requests returns the number of token requests the stand-in received
+func (s *OAuth2StandIn) Requests() int64 {
+	return s.requests.Load()
+}
+
+func writeOAuth2Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
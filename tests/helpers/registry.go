@@ -0,0 +1,79 @@
+package helpers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	RegistryStandInToken         = "stand-in-registry-token"
+	RegistryStandInIdentityToken = "stand-in-identity-token"
+	// RegistryStandInDeniedPrefix is the prefix of repositories the stand-in
+	// refuses pushes to
+	RegistryStandInDeniedPrefix = "denied/"
+)
+
+// NewRegistryStandIn starts a TLS server behaving like a distribution registry
+// with token authentication. Tokens are issued for the given username and
+// password, or for RegistryStandInIdentityToken via a refresh token grant.
+// Blob uploads can be started and cancelled in every repository except the
+// ones prefixed with RegistryStandInDeniedPrefix
+func NewRegistryStandIn(username, password string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if !registryStandInAuthenticated(r, username, password) {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"details":"incorrect username or password"}`))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": RegistryStandInToken})
+	})
+
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+RegistryStandInToken {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="https://`+r.Host+`/token",service="stand-in-registry"`)
+			writeRegistryError(w, http.StatusUnauthorized, "UNAUTHORIZED", "authentication required")
+			return
+		}
+
+		name, upload, isUpload := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v2/"), "/blobs/uploads/")
+		switch {
+		case r.URL.Path == "/v2/" && r.Method == http.MethodGet:
+			w.WriteHeader(http.StatusOK)
+		case isUpload && strings.HasPrefix(name, RegistryStandInDeniedPrefix):
+			writeRegistryError(w, http.StatusForbidden, "DENIED", "requested access to the resource is denied")
+		case isUpload && upload == "" && r.Method == http.MethodPost:
+			w.Header().Set("Location", "/v2/"+name+"/blobs/uploads/"+uuid.NewString())
+			w.WriteHeader(http.StatusAccepted)
+		case isUpload && upload != "" && r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	return httptest.NewTLSServer(mux)
+}
+
+func registryStandInAuthenticated(r *http.Request, username, password string) bool {
+	if r.Method == http.MethodPost {
+		return r.FormValue("grant_type") == "refresh_token" && r.FormValue("refresh_token") == RegistryStandInIdentityToken
+	}
+
+	requestUsername, requestPassword, ok := r.BasicAuth()
+	return ok && requestUsername == username && requestPassword == password
+}
+
+func writeRegistryError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"errors": []map[string]string{{"code": code, "message": message}},
+	})
+}
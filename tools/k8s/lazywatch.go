@@ -0,0 +1,95 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// LazyWatch watches a kind that may not exist when the manager starts, e.g.
+// a kind of Korifi or Istio, through an informer registered on demand rather
+// than through a watch of the controller, which would fail to start. The
+// changed objects are mapped to requests when they change, so that requests
+// always target the objects existing at that time
+// Example:
+//
+//	appWatch := k8s.NewLazyWatch(mgr.GetCache(), &korifiv1alpha1.CFApp{}, r.enqueueCFAPIs)
+//
+//	ctrl.NewControllerManagedBy(mgr).
+//		For(&v1alpha1.CFAPI{}).
+//		WatchesRawSource(appWatch.Source())
+//
+//	// in the reconciler, once the kind is expected to exist
+//	if err := appWatch.Start(ctx); meta.IsNoMatchError(err) {
+//		return ctrl.Result{RequeueAfter: time.Minute}, nil
+//	}
+type LazyWatch struct {
+	informers cache.Informers
+	obj       client.Object
+	mapFunc   handler.MapFunc
+	events    chan event.GenericEvent
+
+	mu      sync.Mutex
+	started bool
+}
+
+func NewLazyWatch(informers cache.Informers, obj client.Object, mapFunc handler.MapFunc) *LazyWatch {
+	return &LazyWatch{
+		informers: informers,
+		obj:       obj,
+		mapFunc:   mapFunc,
+		events:    make(chan event.GenericEvent),
+	}
+}
+
+// Source is the source of the controller the changed objects are sent to
+func (w *LazyWatch) Source() source.Source {
+	return source.Channel(w.events, handler.EnqueueRequestsFromMapFunc(w.mapFunc))
+}
+
+// Start registers the informer of the kind unless it is registered already.
+// It fails with a NoMatch error as long as the kind does not exist
+func (w *LazyWatch) Start(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.started {
+		return nil
+	}
+
+	informer, err := w.informers.GetInformer(ctx, w.obj)
+	if err != nil {
+		return err
+	}
+
+	_, err = informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    w.send,
+		UpdateFunc: func(_, obj any) { w.send(obj) },
+		DeleteFunc: w.send,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch %T: %w", w.obj, err)
+	}
+
+	w.started = true
+	return nil
+}
+
+func (w *LazyWatch) send(obj any) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	clientObj, ok := obj.(client.Object)
+	if !ok {
+		return
+	}
+	w.events <- event.GenericEvent{Object: clientObj}
+}
@@ -0,0 +1,116 @@
+package k8s_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/kyma-project/cfapi/tools/k8s"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("LazyWatch", func() {
+	var (
+		informers *informertest.FakeInformers
+		target    string
+		queue     workqueue.TypedRateLimitingInterface[reconcile.Request]
+		mapCalls  *atomic.Int32
+		lazyWatch *k8s.LazyWatch
+		startErr  error
+	)
+
+	nextRequest := func() reconcile.Request {
+		GinkgoHelper()
+
+		var request reconcile.Request
+		Eventually(func(g Gomega) {
+			g.Expect(queue.Len()).To(BeNumerically(">", 0))
+			request, _ = queue.Get()
+			queue.Done(request)
+		}).Should(Succeed())
+		return request
+	}
+
+	BeforeEach(func() {
+		informers = &informertest.FakeInformers{}
+		target = "first"
+		mapCalls = &atomic.Int32{}
+		queue = workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+		DeferCleanup(queue.ShutDown)
+
+		lazyWatch = k8s.NewLazyWatch(informers, &corev1.ConfigMap{}, func(_ context.Context, obj client.Object) []reconcile.Request {
+			mapCalls.Add(1)
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: obj.GetName(), Name: target}}}
+		})
+
+		sourceCtx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		Expect(lazyWatch.Source().Start(sourceCtx, queue)).To(Succeed())
+	})
+
+	JustBeforeEach(func() {
+		startErr = lazyWatch.Start(ctx)
+	})
+
+	It("enqueues the requests of changed objects", func() {
+		Expect(startErr).NotTo(HaveOccurred())
+
+		informer, err := informers.FakeInformerFor(ctx, &corev1.ConfigMap{})
+		Expect(err).NotTo(HaveOccurred())
+
+		informer.Add(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "my-config"}})
+		Expect(nextRequest().NamespacedName).To(Equal(types.NamespacedName{Namespace: "my-config", Name: "first"}))
+
+		target = "second"
+		informer.Delete(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "my-config"}})
+		Expect(nextRequest().NamespacedName).To(Equal(types.NamespacedName{Namespace: "my-config", Name: "second"}))
+	})
+
+	When("it is started again", func() {
+		JustBeforeEach(func() {
+			Expect(lazyWatch.Start(ctx)).To(Succeed())
+		})
+
+		It("registers the informer only once", func() {
+			informer, err := informers.FakeInformerFor(ctx, &corev1.ConfigMap{})
+			Expect(err).NotTo(HaveOccurred())
+
+			informer.Add(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "my-config"}})
+			nextRequest()
+			Consistently(mapCalls.Load).Should(Equal(int32(1)))
+		})
+	})
+
+	When("the informer cannot be registered", func() {
+		BeforeEach(func() {
+			informers.Error = errors.New("no kind")
+		})
+
+		It("returns the error", func() {
+			Expect(startErr).To(MatchError("no kind"))
+		})
+
+		When("it is started once the kind exists", func() {
+			JustBeforeEach(func() {
+				informers.Error = nil
+				Expect(lazyWatch.Start(ctx)).To(Succeed())
+			})
+
+			It("registers the informer", func() {
+				informer, err := informers.FakeInformerFor(ctx, &corev1.ConfigMap{})
+				Expect(err).NotTo(HaveOccurred())
+
+				informer.Add(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "my-config"}})
+				Expect(nextRequest().NamespacedName.Namespace).To(Equal("my-config"))
+			})
+		})
+	})
+})